@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -26,6 +29,7 @@ import (
 	"github.com/yourusername/bus-booking/internal/infrastructure"
 	"github.com/yourusername/bus-booking/internal/infrastructure/chatbot"
 	"github.com/yourusername/bus-booking/internal/infrastructure/payment"
+	"github.com/yourusername/bus-booking/internal/infrastructure/scheduler"
 	"github.com/yourusername/bus-booking/internal/repositories"
 	"github.com/yourusername/bus-booking/internal/repositories/cache"
 	"github.com/yourusername/bus-booking/internal/repositories/postgres"
@@ -76,11 +80,16 @@ func main() {
 	// Initialize dependencies
 	container := initDependencies(db, redisClient)
 
+	// Register background jobs
+	if err := registerJobs(container); err != nil {
+		log.Fatalf("Failed to register background jobs: %v", err)
+	}
+
 	// Setup Gin router
 	router := setupRouter(container)
 
 	// Start background jobs
-	go startBackgroundJobs(container)
+	container.Scheduler.Start()
 
 	// Start WebSocket server
 	go websocket.StartWebSocketServer(container.RedisCache)
@@ -116,12 +125,17 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	if err := container.Scheduler.Stop(ctx); err != nil {
+		log.Printf("Background jobs did not stop cleanly: %v", err)
+	}
+
 	log.Println("Server exited")
 }
 
 func initDatabase() (*gorm.DB, error) {
+	// The session runs in UTC so nothing in SQL depends on the server's timezone
 	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
 		getEnv("DB_HOST", "localhost"),
 		getEnv("DB_PORT", "5432"),
 		getEnv("DB_USER", "postgres"),
@@ -158,17 +172,167 @@ func initRedis() *redis.Client {
 }
 
 func runMigrations(db *gorm.DB) error {
-	return db.AutoMigrate(
+	// Routes made before the location catalog need their locations before
+	// the columns can be required
+	if err := db.AutoMigrate(&entities.Location{}, &entities.LocationAlias{}); err != nil {
+		return err
+	}
+	if err := migrateRouteLocations(db); err != nil {
+		return fmt.Errorf("failed to give routes their locations: %w", err)
+	}
+	if err := migratePaymentStatuses(db); err != nil {
+		return fmt.Errorf("failed to allow refunding payments: %w", err)
+	}
+
+	err := db.AutoMigrate(
 		&entities.User{},
 		&entities.Bus{},
+		&entities.Driver{},
 		&entities.Route{},
+		&entities.RouteStop{},
 		&entities.Trip{},
 		&entities.SeatInfo{},
 		&entities.Booking{},
 		&entities.Payment{},
 		&entities.Ticket{},
 		&entities.RefreshToken{},
+		&entities.Fulfilment{},
+		&entities.OutboxEvent{},
+		&entities.FareRule{},
+		&entities.Promotion{},
+		&entities.PromoCode{},
+		&entities.PromoRedemption{},
+		&entities.LoyaltyAccount{},
+		&entities.LoyaltyTransaction{},
+		&entities.LoyaltyEntry{},
+		&entities.HoldViolation{},
+		&entities.SeatDiscrepancy{},
+		&entities.BusSwap{},
+		&entities.TripSchedule{},
+		&entities.Holiday{},
+		&entities.TripCancellation{},
+		&entities.TripCancellationOutcome{},
 	)
+	if err != nil {
+		return err
+	}
+
+	// Needs the segment column AutoMigrate adds
+	if err := migrateSeatSegments(db); err != nil {
+		return fmt.Errorf("failed to make seats unique per segment: %w", err)
+	}
+	// Replaced by idx_loyalty_txn_booking_attempt, which allows redeeming again
+	if err := db.Exec("DROP INDEX IF EXISTS idx_loyalty_txn_booking_type").Error; err != nil {
+		return fmt.Errorf("failed to drop the old loyalty transaction index: %w", err)
+	}
+	return nil
+}
+
+// migrateRouteLocations points routes without locations at the locations of
+// the catalog their cities fold to, adding the locations that are missing,
+// and then requires the location columns. It does nothing on a database
+// without routes or whose routes all have their locations.
+func migrateRouteLocations(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&entities.Route{}) {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, column := range []string{"from_location_id", "to_location_id"} {
+			if err := tx.Exec("ALTER TABLE routes ADD COLUMN IF NOT EXISTS " + column + " UUID").Error; err != nil {
+				return err
+			}
+		}
+
+		var cities []string
+		err := tx.Raw(`
+			SELECT from_city FROM routes WHERE from_location_id IS NULL
+			UNION
+			SELECT to_city FROM routes WHERE to_location_id IS NULL`).
+			Scan(&cities).Error
+		if err != nil {
+			return err
+		}
+
+		for _, city := range cities {
+			searchName := entities.FoldLocationName(city)
+			if searchName == "" {
+				return fmt.Errorf("route city %q has no name to match a location by", city)
+			}
+
+			var location entities.Location
+			err := tx.Where("search_name = ? OR id IN (SELECT location_id FROM location_aliases WHERE search_name = ?)", searchName, searchName).
+				First(&location).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				location = entities.Location{Name: strings.TrimSpace(city), SearchName: searchName, IsActive: true}
+				err = tx.Omit("Aliases").Create(&location).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Exec("UPDATE routes SET from_location_id = ? WHERE from_location_id IS NULL AND from_city = ?", location.ID, city).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("UPDATE routes SET to_location_id = ? WHERE to_location_id IS NULL AND to_city = ?", location.ID, city).Error; err != nil {
+				return err
+			}
+			log.Printf("Route city %q now points at location %s", city, location.Name)
+		}
+
+		for _, column := range []string{"from_location_id", "to_location_id"} {
+			if err := tx.Exec("ALTER TABLE routes ALTER COLUMN " + column + " SET NOT NULL").Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migratePaymentStatuses lets payments created from schema.sql take the
+// refunding status, which their status check did not allow at first
+func migratePaymentStatuses(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&entities.Payment{}) {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check").Error; err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE payments ADD CONSTRAINT payments_status_check
+			CHECK (status IN ('pending', 'completed', 'failed', 'refunding', 'refunded', 'cancelled'))`).Error
+	})
+}
+
+// migrateSeatSegments makes seats unique per trip, seat and segment instead of
+// per trip and seat. AutoMigrate leaves an existing idx_trip_seat as it is,
+// and databases made from schema.sql carry the old constraint and index too.
+func migrateSeatSegments(db *gorm.DB) error {
+	var current int64
+	err := db.Raw(`SELECT COUNT(*) FROM pg_indexes
+		WHERE tablename = 'seats_status' AND indexname = 'idx_trip_seat' AND indexdef LIKE '%segment%'`).Scan(&current).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"ALTER TABLE seats_status DROP CONSTRAINT IF EXISTS seats_status_trip_id_seat_number_key",
+			"DROP INDEX IF EXISTS idx_seats_trip_seat",
+		}
+		if current == 0 {
+			statements = append(statements,
+				"DROP INDEX IF EXISTS idx_trip_seat",
+				"CREATE UNIQUE INDEX idx_trip_seat ON seats_status (trip_id, seat_number, segment)",
+			)
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 type Container struct {
@@ -179,21 +343,46 @@ type Container struct {
 	RedisCache  *cache.RedisCache
 
 	// Usecases
-	AuthUsecase    *usecases.AuthUsecase
-	BookingUsecase *usecases.BookingUsecase
-	PaymentUsecase *usecases.PaymentUsecase
-	ChatbotUsecase *usecases.ChatbotUsecase
-	TripUsecase    *usecases.TripUsecase
-	BusUsecase     *usecases.BusUsecase
-	RouteUsecase   *usecases.RouteUsecase
+	AuthUsecase               *usecases.AuthUsecase
+	BookingUsecase            *usecases.BookingUsecase
+	PaymentUsecase            *usecases.PaymentUsecase
+	FulfilmentUsecase         *usecases.FulfilmentUsecase
+	OutboxUsecase             *usecases.OutboxUsecase
+	FareUsecase               *usecases.FareUsecase
+	FareCalendarUsecase       *usecases.FareCalendarUsecase
+	PromotionUsecase          *usecases.PromotionUsecase
+	LoyaltyUsecase            *usecases.LoyaltyUsecase
+	HoldLimitUsecase          *usecases.HoldLimitUsecase
+	SeatReconciliationUsecase *usecases.SeatReconciliationUsecase
+	SeatBlockUsecase          *usecases.SeatBlockUsecase
+	ChatbotUsecase            *usecases.ChatbotUsecase
+	TripUsecase               *usecases.TripUsecase
+	TripScheduleUsecase       *usecases.TripScheduleUsecase
+	TripLifecycleUsecase      *usecases.TripLifecycleUsecase
+	TripCancellationUsecase   *usecases.TripCancellationUsecase
+	ScheduleConflictUsecase   *usecases.ScheduleConflictUsecase
+	BusUsecase                *usecases.BusUsecase
+	BusSwapUsecase            *usecases.BusSwapUsecase
+	DriverUsecase             *usecases.DriverUsecase
+	RouteUsecase              *usecases.RouteUsecase
+	LocationUsecase           *usecases.LocationUsecase
 
 	// Infrastructure
 	EmailService *infrastructure.EmailService
 	PDFGenerator *infrastructure.PDFGenerator
+	Scheduler    *scheduler.Scheduler
 }
 
 func initDependencies(db *gorm.DB, redisClient *redis.Client) *Container {
+	// Timezone of the operator's days and of the trip times shown to customers
+	businessLocation, err := time.LoadLocation(getEnv("BUSINESS_TIMEZONE", "Asia/Ho_Chi_Minh"))
+	if err != nil {
+		log.Fatalf("Invalid BUSINESS_TIMEZONE: %v", err)
+	}
+
 	// Repositories
+	transactor := postgres.NewTransactor(db)
+	outboxRepo := postgres.NewOutboxRepository(db)
 	userRepo := postgres.NewUserRepository(db)
 	bookingRepo := postgres.NewBookingRepository(db)
 	seatRepo := postgres.NewSeatRepository(db)
@@ -202,6 +391,18 @@ func initDependencies(db *gorm.DB, redisClient *redis.Client) *Container {
 	ticketRepo := postgres.NewTicketRepository(db)
 	busRepo := postgres.NewBusRepository(db)
 	routeRepo := postgres.NewRouteRepository(db)
+	locationRepo := postgres.NewLocationRepository(db)
+	fulfilmentRepo := postgres.NewFulfilmentRepository(db)
+	fareRuleRepo := postgres.NewFareRuleRepository(db)
+	promotionRepo := postgres.NewPromotionRepository(db)
+	loyaltyRepo := postgres.NewLoyaltyRepository(db)
+	holdViolationRepo := postgres.NewHoldViolationRepository(db)
+	seatDiscrepancyRepo := postgres.NewSeatDiscrepancyRepository(db)
+	busSwapRepo := postgres.NewBusSwapRepository(db)
+	tripScheduleRepo := postgres.NewTripScheduleRepository(db)
+	holidayRepo := postgres.NewHolidayRepository(db)
+	tripCancellationRepo := postgres.NewTripCancellationRepository(db)
+	driverRepo := postgres.NewDriverRepository(db)
 
 	// Cache
 	redisCache := cache.NewRedisCache(redisClient)
@@ -209,6 +410,8 @@ func initDependencies(db *gorm.DB, redisClient *redis.Client) *Container {
 	// Infrastructure
 	emailService := infrastructure.NewEmailService()
 	pdfGenerator := infrastructure.NewPDFGenerator()
+	hostname, _ := os.Hostname()
+	jobScheduler := scheduler.New(redisCache, fmt.Sprintf("%s-%d", hostname, os.Getpid()))
 
 	// Payment gateways
 	momoGateway := &payment.MoMoGateway{
@@ -247,49 +450,147 @@ func initDependencies(db *gorm.DB, redisClient *redis.Client) *Container {
 	refreshTokenExpiry, _ := time.ParseDuration(getEnv("JWT_REFRESH_EXPIRY", "7d"))
 	seatLockDuration, _ := time.ParseDuration(getEnv("SEAT_LOCK_DURATION", "10m"))
 	bookingExpiry, _ := time.ParseDuration(getEnv("BOOKING_EXPIRY", "15m"))
+	roundTripDiscount, _ := strconv.ParseFloat(getEnv("ROUND_TRIP_DISCOUNT", "0"), 64)
 
 	authUsecase := usecases.NewAuthUsecase(userRepo, jwtSecret, accessTokenExpiry, refreshTokenExpiry)
+	fareUsecase := usecases.NewFareUsecase(fareRuleRepo, tripRepo)
+	locationUsecase := usecases.NewLocationUsecase(transactor, locationRepo, routeRepo)
+	promotionUsecase := usecases.NewPromotionUsecase(promotionRepo, bookingRepo)
+
+	// Loyalty points program
+	loyaltyConfig := usecases.LoyaltyConfig{}
+	loyaltyConfig.VNDPerPoint, _ = strconv.ParseFloat(getEnv("LOYALTY_VND_PER_POINT", "10000"), 64)
+	loyaltyConfig.PointValue, _ = strconv.ParseFloat(getEnv("LOYALTY_POINT_VALUE", "100"), 64)
+	loyaltyConfig.ExpiryMonths, _ = strconv.Atoi(getEnv("LOYALTY_EXPIRY_MONTHS", "12"))
+	loyaltyConfig.SilverPoints, _ = strconv.Atoi(getEnv("LOYALTY_SILVER_POINTS", "1000"))
+	loyaltyConfig.GoldPoints, _ = strconv.Atoi(getEnv("LOYALTY_GOLD_POINTS", "3000"))
+	loyaltyConfig.SilverHoldBonus, _ = time.ParseDuration(getEnv("LOYALTY_SILVER_HOLD_BONUS", "5m"))
+	loyaltyConfig.GoldHoldBonus, _ = time.ParseDuration(getEnv("LOYALTY_GOLD_HOLD_BONUS", "10m"))
+	loyaltyUsecase := usecases.NewLoyaltyUsecase(transactor, loyaltyRepo, bookingRepo, loyaltyConfig)
+
+	// Anti-hoarding limits on seat holds
+	holdLimitConfig := usecases.HoldLimitConfig{}
+	holdLimitConfig.MaxSeatsPerBooking, _ = strconv.Atoi(getEnv("HOLD_MAX_SEATS_PER_BOOKING", "10"))
+	holdLimitConfig.MaxPendingBookings, _ = strconv.Atoi(getEnv("HOLD_MAX_PENDING_BOOKINGS", "3"))
+	holdLimitConfig.MaxPendingPerIP, _ = strconv.Atoi(getEnv("HOLD_MAX_PENDING_BOOKINGS_PER_IP", "20"))
+	holdLimitConfig.MaxTripSeats, _ = strconv.Atoi(getEnv("HOLD_MAX_TRIP_SEATS", "10"))
+	holdLimitConfig.MaxTripSeatsPerIP, _ = strconv.Atoi(getEnv("HOLD_MAX_TRIP_SEATS_PER_IP", "30"))
+	holdLimitConfig.ExpiryThreshold, _ = strconv.Atoi(getEnv("HOLD_EXPIRY_THRESHOLD", "3"))
+	holdLimitConfig.ExpiryWindow, _ = time.ParseDuration(getEnv("HOLD_EXPIRY_WINDOW", "24h"))
+	holdLimitConfig.Cooldown, _ = time.ParseDuration(getEnv("HOLD_COOLDOWN", "1h"))
+	holdLimitUsecase := usecases.NewHoldLimitUsecase(redisCache, holdViolationRepo, holdLimitConfig)
 
 	bookingUsecase := usecases.NewBookingUsecase(
+		transactor,
+		outboxRepo,
 		bookingRepo,
 		seatRepo,
 		tripRepo,
 		paymentRepo,
 		ticketRepo,
 		redisCache,
+		fareUsecase,
+		promotionUsecase,
+		loyaltyUsecase,
+		holdLimitUsecase,
 		seatLockDuration,
 		bookingExpiry,
+		roundTripDiscount,
+		businessLocation,
+	)
+
+	fulfilmentUsecase := usecases.NewFulfilmentUsecase(
+		transactor,
+		outboxRepo,
+		fulfilmentRepo,
+		bookingRepo,
+		paymentRepo,
+		seatRepo,
+		ticketRepo,
+		bookingUsecase,
+		gateways,
+		pdfGenerator,
+		emailService,
 	)
 
 	paymentUsecase := usecases.NewPaymentUsecase(
+		transactor,
+		outboxRepo,
 		paymentRepo,
 		bookingRepo,
 		gateways,
+		fulfilmentUsecase,
+		promotionUsecase,
+		loyaltyUsecase,
 	)
 
 	// Chatbot
 	bot := chatbot.NewMockChatbot(getEnv("CHATBOT_USE_MOCK", "true") == "true")
 	chatbotUsecase := usecases.NewChatbotUsecase(bot)
 
+	// Bus and driver scheduling rules; the driving limits follow the Vietnamese
+	// Law on Road Traffic Order and Safety
+	minTurnaround, _ := time.ParseDuration(getEnv("SCHEDULE_MIN_TURNAROUND", "30m"))
+	drivingLimits := usecases.DrivingLimits{}
+	drivingLimits.MaxContinuous, _ = time.ParseDuration(getEnv("DRIVING_MAX_CONTINUOUS", "4h"))
+	drivingLimits.MinBreak, _ = time.ParseDuration(getEnv("DRIVING_MIN_BREAK", "15m"))
+	drivingLimits.MaxDaily, _ = time.ParseDuration(getEnv("DRIVING_MAX_DAILY", "10h"))
+	drivingLimits.MaxWeekly, _ = time.ParseDuration(getEnv("DRIVING_MAX_WEEKLY", "48h"))
+	scheduleConflictUsecase := usecases.NewScheduleConflictUsecase(tripRepo, routeRepo, busRepo, driverRepo, minTurnaround, drivingLimits, businessLocation)
+	driverUsecase := usecases.NewDriverUsecase(driverRepo, tripRepo)
+
 	// Additional usecases
-	tripUsecase := usecases.NewTripUsecase(tripRepo, busRepo, routeRepo, seatRepo)
+	fareCalendarUsecase := usecases.NewFareCalendarUsecase(tripRepo, locationUsecase, redisCache, businessLocation)
+	tripUsecase := usecases.NewTripUsecase(transactor, outboxRepo, tripRepo, busRepo, routeRepo, seatRepo, bookingRepo, driverRepo, scheduleConflictUsecase, locationUsecase, roundTripDiscount, businessLocation)
+
+	// Recurring trip schedules
+	scheduleHorizonDays, _ := strconv.Atoi(getEnv("SCHEDULE_HORIZON_DAYS", "30"))
+	tripScheduleUsecase := usecases.NewTripScheduleUsecase(transactor, tripScheduleRepo, holidayRepo, tripRepo, seatRepo, bookingRepo, busRepo, routeRepo, driverRepo, tripUsecase, businessLocation, scheduleHorizonDays)
+
+	// Event outbox relay
+	outboxStreamMaxLen, _ := strconv.ParseInt(getEnv("OUTBOX_STREAM_MAXLEN", "100000"), 10, 64)
+	outboxRetention, _ := time.ParseDuration(getEnv("OUTBOX_RETENTION", "168h"))
+	outboxUsecase := usecases.NewOutboxUsecase(transactor, outboxRepo, redisCache, outboxStreamMaxLen, outboxRetention)
+	seatReconciliationUsecase := usecases.NewSeatReconciliationUsecase(tripRepo, seatRepo, bookingRepo, seatDiscrepancyRepo, redisCache, seatLockDuration)
+	seatBlockUsecase := usecases.NewSeatBlockUsecase(transactor, outboxRepo, tripRepo, seatRepo, redisCache)
 	busUsecase := usecases.NewBusUsecase(busRepo)
-	routeUsecase := usecases.NewRouteUsecase(routeRepo)
+	busSwapUsecase := usecases.NewBusSwapUsecase(transactor, outboxRepo, tripRepo, busRepo, seatRepo, bookingRepo, ticketRepo, busSwapRepo, driverRepo, redisCache, pdfGenerator, emailService, scheduleConflictUsecase)
+	routeUsecase := usecases.NewRouteUsecase(transactor, routeRepo, tripRepo, seatRepo, locationUsecase)
+	tripLifecycleUsecase := usecases.NewTripLifecycleUsecase(transactor, outboxRepo, tripRepo, bookingRepo, emailService, businessLocation)
+	cancellationChoiceURL := strings.TrimRight(getEnv("API_BASE_URL", "http://localhost:8080"), "/") + "/api/v1/trip-cancellations/choices/"
+	tripCancellationUsecase := usecases.NewTripCancellationUsecase(transactor, outboxRepo, tripRepo, bookingRepo, seatRepo, paymentRepo, ticketRepo, tripCancellationRepo, redisCache, bookingUsecase, paymentUsecase, tripLifecycleUsecase, pdfGenerator, emailService, cancellationChoiceURL)
 
 	return &Container{
-		UserRepo:       userRepo,
-		BookingRepo:    bookingRepo,
-		SeatRepo:       seatRepo,
-		RedisCache:     redisCache,
-		AuthUsecase:    authUsecase,
-		BookingUsecase: bookingUsecase,
-		PaymentUsecase: paymentUsecase,
-		ChatbotUsecase: chatbotUsecase,
-		TripUsecase:    tripUsecase,
-		BusUsecase:     busUsecase,
-		RouteUsecase:   routeUsecase,
-		EmailService:   emailService,
-		PDFGenerator:   pdfGenerator,
+		UserRepo:                  userRepo,
+		BookingRepo:               bookingRepo,
+		SeatRepo:                  seatRepo,
+		RedisCache:                redisCache,
+		AuthUsecase:               authUsecase,
+		BookingUsecase:            bookingUsecase,
+		PaymentUsecase:            paymentUsecase,
+		FulfilmentUsecase:         fulfilmentUsecase,
+		OutboxUsecase:             outboxUsecase,
+		FareUsecase:               fareUsecase,
+		FareCalendarUsecase:       fareCalendarUsecase,
+		PromotionUsecase:          promotionUsecase,
+		LoyaltyUsecase:            loyaltyUsecase,
+		HoldLimitUsecase:          holdLimitUsecase,
+		SeatReconciliationUsecase: seatReconciliationUsecase,
+		SeatBlockUsecase:          seatBlockUsecase,
+		ChatbotUsecase:            chatbotUsecase,
+		TripUsecase:               tripUsecase,
+		TripScheduleUsecase:       tripScheduleUsecase,
+		TripLifecycleUsecase:      tripLifecycleUsecase,
+		TripCancellationUsecase:   tripCancellationUsecase,
+		ScheduleConflictUsecase:   scheduleConflictUsecase,
+		BusUsecase:                busUsecase,
+		BusSwapUsecase:            busSwapUsecase,
+		DriverUsecase:             driverUsecase,
+		RouteUsecase:              routeUsecase,
+		LocationUsecase:           locationUsecase,
+		EmailService:              emailService,
+		PDFGenerator:              pdfGenerator,
+		Scheduler:                 jobScheduler,
 	}
 }
 
@@ -299,6 +600,11 @@ func setupRouter(container *Container) *gin.Engine {
 	}
 
 	router := gin.New()
+	// c.ClientIP() feeds the seat hold limits, so X-Forwarded-For is only
+	// believed from the proxies listed in TRUSTED_PROXIES, none by default
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(middleware.CORS())
@@ -327,13 +633,27 @@ func setupRouter(container *Container) *gin.Engine {
 			auth.GET("/github/callback", authHandler.GitHubCallback)
 		}
 
+		// City suggestions for the search forms (public)
+		locations := v1.Group("/locations")
+		{
+			locationHandler := handlers.NewLocationHandler(container.LocationUsecase)
+			locations.GET("/autocomplete", locationHandler.Autocomplete)
+		}
+
 		// Trip search (public)
 		trips := v1.Group("/trips")
 		{
-			tripHandler := handlers.NewTripHandler(container.TripUsecase, container.BookingUsecase)
+			tripHandler := handlers.NewTripHandler(container.TripUsecase, container.BookingUsecase, container.FareUsecase)
 			trips.GET("", tripHandler.Search)
+
+			fareCalendarHandler := handlers.NewFareCalendarHandler(container.FareCalendarUsecase)
+			trips.GET("/fare-calendar", fareCalendarHandler.GetFareCalendar)
+
 			trips.GET("/:id", tripHandler.GetByID)
 			trips.GET("/:id/seats", tripHandler.GetSeats)
+			trips.GET("/:id/seat-map", tripHandler.GetSeatMap)
+			trips.GET("/:id/seats/suggest", tripHandler.SuggestSeats)
+			trips.GET("/:id/fares", tripHandler.GetFares)
 		}
 
 		// Protected routes
@@ -358,6 +678,13 @@ func setupRouter(container *Container) *gin.Engine {
 				payments.GET("/:id", paymentHandler.GetPaymentStatus)
 			}
 
+			// Current user
+			me := authorized.Group("/users/me")
+			{
+				loyaltyHandler := handlers.NewLoyaltyHandler(container.LoyaltyUsecase)
+				me.GET("/loyalty", loyaltyHandler.GetMyLoyalty)
+			}
+
 			// Tickets
 			tickets := authorized.Group("/tickets")
 			{
@@ -367,6 +694,14 @@ func setupRouter(container *Container) *gin.Engine {
 			}
 		}
 
+		// Choice links emailed to passengers of cancelled trips (no auth required, the token is the secret)
+		tripCancellations := v1.Group("/trip-cancellations")
+		{
+			cancellationHandler := handlers.NewTripCancellationHandler(container.TripCancellationUsecase)
+			tripCancellations.GET("/choices/:token", cancellationHandler.GetChoice)
+			tripCancellations.POST("/choices/:token", cancellationHandler.Choose)
+		}
+
 		// Payment webhooks (no auth required)
 		webhooks := v1.Group("/webhooks")
 		{
@@ -397,6 +732,20 @@ func setupRouter(container *Container) *gin.Engine {
 				buses.GET("/:id", busHandler.GetByID)
 				buses.PUT("/:id", busHandler.Update)
 				buses.DELETE("/:id", busHandler.Delete)
+
+				seatBlockHandler := handlers.NewSeatBlockHandler(container.SeatBlockUsecase, container.BusUsecase)
+				buses.PUT("/:id/blocked-seats", seatBlockHandler.SetBusDefaults)
+			}
+
+			// Cities and towns routes run between
+			locations := admin.Group("/locations")
+			{
+				locationHandler := handlers.NewLocationHandler(container.LocationUsecase)
+				locations.POST("", locationHandler.Create)
+				locations.GET("", locationHandler.List)
+				locations.GET("/:id", locationHandler.GetByID)
+				locations.PUT("/:id", locationHandler.Update)
+				locations.DELETE("/:id", locationHandler.Delete)
 			}
 
 			// Route management
@@ -408,35 +757,211 @@ func setupRouter(container *Container) *gin.Engine {
 				routes.GET("/:id", routeHandler.GetByID)
 				routes.PUT("/:id", routeHandler.Update)
 				routes.DELETE("/:id", routeHandler.Delete)
+				routes.PUT("/:id/stops", routeHandler.SetStops)
 			}
 
 			// Trip management
 			trips := admin.Group("/trips")
 			{
-				tripHandler := handlers.NewTripHandler(container.TripUsecase, container.BookingUsecase)
+				tripHandler := handlers.NewTripHandler(container.TripUsecase, container.BookingUsecase, container.FareUsecase)
 				trips.POST("", tripHandler.Create)
 				trips.PUT("/:id", tripHandler.Update)
 				trips.DELETE("/:id", tripHandler.Delete)
+				trips.GET("/:id/manifest", tripHandler.GetManifest)
+
+				seatBlockHandler := handlers.NewSeatBlockHandler(container.SeatBlockUsecase, container.BusUsecase)
+				trips.POST("/:id/seats/block", seatBlockHandler.Block)
+				trips.POST("/:id/seats/unblock", seatBlockHandler.Unblock)
+
+				busSwapHandler := handlers.NewBusSwapHandler(container.BusSwapUsecase)
+				trips.POST("/:id/replace-bus", busSwapHandler.ReplaceBus)
+				trips.GET("/:id/bus-swaps", busSwapHandler.ListSwaps)
+
+				lifecycleHandler := handlers.NewTripLifecycleHandler(container.TripLifecycleUsecase)
+				trips.POST("/:id/boarding", lifecycleHandler.StartBoarding)
+				trips.POST("/:id/depart", lifecycleHandler.Depart)
+				trips.POST("/:id/delay", lifecycleHandler.Delay)
+				trips.POST("/:id/arrive", lifecycleHandler.Arrive)
+
+				cancellationHandler := handlers.NewTripCancellationHandler(container.TripCancellationUsecase)
+				trips.POST("/:id/cancel", cancellationHandler.Cancel)
+				trips.GET("/:id/cancellation", cancellationHandler.GetReport)
+				trips.POST("/:id/cancellation/resume", cancellationHandler.Resume)
+			}
+
+			// Recurring trip schedules and the holidays they can skip
+			tripSchedules := admin.Group("/trip-schedules")
+			tripScheduleHandler := handlers.NewTripScheduleHandler(container.TripScheduleUsecase)
+			{
+				tripSchedules.POST("", tripScheduleHandler.Create)
+				tripSchedules.GET("", tripScheduleHandler.List)
+				tripSchedules.GET("/:id", tripScheduleHandler.GetByID)
+				tripSchedules.PUT("/:id", tripScheduleHandler.Update)
+				tripSchedules.DELETE("/:id", tripScheduleHandler.Deactivate)
 			}
+			admin.GET("/holidays", tripScheduleHandler.ListHolidays)
+			admin.POST("/holidays", tripScheduleHandler.CreateHoliday)
+			admin.DELETE("/holidays/:id", tripScheduleHandler.DeleteHoliday)
+
+			// Drivers and their timetables
+			drivers := admin.Group("/drivers")
+			{
+				driverHandler := handlers.NewDriverHandler(container.DriverUsecase)
+				drivers.POST("", driverHandler.Create)
+				drivers.GET("", driverHandler.List)
+				drivers.GET("/:id", driverHandler.GetByID)
+				drivers.PUT("/:id", driverHandler.Update)
+				drivers.DELETE("/:id", driverHandler.Deactivate)
+				drivers.GET("/:id/timetable", driverHandler.GetTimetable)
+			}
+
+			// Buses and drivers given trips they cannot run
+			scheduleConflictHandler := handlers.NewScheduleConflictHandler(container.ScheduleConflictUsecase)
+			admin.GET("/schedule-conflicts", scheduleConflictHandler.Report)
+
+			// Fare category rules
+			fareRules := admin.Group("/fare-rules")
+			{
+				fareHandler := handlers.NewFareHandler(container.FareUsecase)
+				fareRules.POST("", fareHandler.Create)
+				fareRules.GET("", fareHandler.List)
+				fareRules.GET("/:id", fareHandler.GetByID)
+				fareRules.PUT("/:id", fareHandler.Update)
+				fareRules.DELETE("/:id", fareHandler.Delete)
+			}
+
+			// Promotions and promo codes
+			promotions := admin.Group("/promotions")
+			{
+				promotionHandler := handlers.NewPromotionHandler(container.PromotionUsecase)
+				promotions.POST("", promotionHandler.Create)
+				promotions.GET("", promotionHandler.List)
+				promotions.GET("/:id", promotionHandler.GetByID)
+				promotions.PUT("/:id", promotionHandler.Update)
+				promotions.GET("/:id/codes", promotionHandler.ListCodes)
+				promotions.POST("/:id/codes", promotionHandler.CreateCode)
+				promotions.POST("/:id/vouchers", promotionHandler.GenerateVouchers)
+			}
+
+			// Seat holds refused by the anti-hoarding limits
+			holdViolationHandler := handlers.NewHoldViolationHandler(container.HoldLimitUsecase)
+			admin.GET("/hold-violations", holdViolationHandler.List)
+
+			// Seat state reconciliation between Redis, seats_status and bookings
+			seatReconciliationHandler := handlers.NewSeatReconciliationHandler(container.SeatReconciliationUsecase)
+			admin.POST("/seat-reconciliation", seatReconciliationHandler.Run)
+			admin.GET("/seat-discrepancies", seatReconciliationHandler.ListDiscrepancies)
+			admin.POST("/seat-discrepancies/:id/resolve", seatReconciliationHandler.ResolveDiscrepancy)
+
+			// Background jobs
+			jobHandler := handlers.NewJobHandler(container.Scheduler)
+			admin.GET("/jobs", jobHandler.List)
 		}
 	}
 
 	return router
 }
 
-func startBackgroundJobs(container *Container) {
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		ctx := context.Background()
+func registerJobs(container *Container) error {
+	jobs := []scheduler.Job{
+		{
+			Name:     "expire-bookings",
+			Schedule: scheduler.Every(time.Minute),
+			Timeout:  30 * time.Second,
+			Retries:  2,
+			Run:      container.BookingUsecase.ExpireOldBookings,
+		},
+		{
+			Name:     "unlock-expired-seats",
+			Schedule: scheduler.Every(time.Minute),
+			Timeout:  30 * time.Second,
+			Retries:  2,
+			Run: func(ctx context.Context) error {
+				_, err := container.SeatRepo.UnlockExpiredSeats(ctx)
+				return err
+			},
+		},
+		{
+			Name:     "unblock-expired-seats",
+			Schedule: scheduler.Every(time.Minute),
+			Timeout:  30 * time.Second,
+			Retries:  2,
+			Run:      container.SeatBlockUsecase.UnblockExpiredSeats,
+		},
+		{
+			// Create the trips of recurring schedules ahead of time
+			Name:     "generate-scheduled-trips",
+			Schedule: scheduler.Every(time.Hour),
+			Timeout:  10 * time.Minute,
+			Retries:  2,
+			Run:      container.TripScheduleUsecase.GenerateTrips,
+		},
+		{
+			// Repair drift between Redis locks, seats_status and bookings
+			Name:     "reconcile-seats",
+			Schedule: scheduler.Every(5 * time.Minute),
+			Timeout:  2 * time.Minute,
+			Run: func(ctx context.Context) error {
+				_, err := container.SeatReconciliationUsecase.Reconcile(ctx)
+				return err
+			},
+		},
+		{
+			// Retry payment fulfilments that are due
+			Name:     "process-fulfilments",
+			Schedule: scheduler.Every(time.Minute),
+			Timeout:  5 * time.Minute,
+			Run:      container.FulfilmentUsecase.ProcessDue,
+		},
+		{
+			Name:     "relay-outbox",
+			Schedule: scheduler.Every(time.Second),
+			Timeout:  30 * time.Second,
+			Run: func(ctx context.Context) error {
+				_, err := container.OutboxUsecase.RelayPending(ctx)
+				return err
+			},
+		},
+		{
+			Name:     "expire-loyalty-points",
+			Schedule: scheduler.MustCron("30 0 * * *"),
+			Timeout:  30 * time.Minute,
+			Retries:  3,
+			Backoff:  5 * time.Minute,
+			Run:      container.LoyaltyUsecase.ExpirePoints,
+		},
+		{
+			// Drop relayed events past the retention window
+			Name:     "purge-outbox",
+			Schedule: scheduler.MustCron("@hourly"),
+			Timeout:  5 * time.Minute,
+			Retries:  3,
+			Backoff:  time.Minute,
+			Run: func(ctx context.Context) error {
+				_, err := container.OutboxUsecase.PurgePublished(ctx)
+				return err
+			},
+		},
+	}
 
-		// Expire old bookings
-		_ = container.BookingUsecase.ExpireOldBookings(ctx)
+	for _, job := range jobs {
+		if err := container.Scheduler.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		// Unlock expired seats
-		_, _ = container.SeatRepo.UnlockExpiredSeats(ctx)
+// trustedProxies reads the comma-separated IPs and CIDRs of the reverse
+// proxies in front of the API
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
 	}
+	return proxies
 }
 
 func getEnv(key, defaultValue string) string {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
 	"github.com/yourusername/bus-booking/internal/usecases"
 )
 
@@ -18,23 +19,67 @@ func NewBookingHandler(usecase *usecases.BookingUsecase) *BookingHandler {
 }
 
 type InitiateBookingRequest struct {
-	TripID       string   `json:"trip_id" binding:"required"`
-	SeatNumbers  []string `json:"seat_numbers" binding:"required,min=1"`
-	ContactName  string   `json:"contact_name" binding:"required"`
-	ContactEmail string   `json:"contact_email" binding:"required,email"`
-	ContactPhone string   `json:"contact_phone" binding:"required"`
+	TripID            string   `json:"trip_id" binding:"required"`
+	SeatNumbers       []string `json:"seat_numbers"`                         // Either seat_numbers or seat_count
+	SeatCount         int      `json:"seat_count" binding:"omitempty,min=1"` // Assigns this many seats automatically
+	ReturnTripID      string   `json:"return_trip_id"`                       // Optional, books a round trip
+	ReturnSeatNumbers []string `json:"return_seat_numbers"`                  // Optional; assigned automatically when left out
+	ContactName       string   `json:"contact_name" binding:"required"`
+	ContactEmail      string   `json:"contact_email" binding:"required,email"`
+	ContactPhone      string   `json:"contact_phone" binding:"required"`
+	PromoCode         string   `json:"promo_code"` // Optional
+
+	// Optional; where to board and leave the bus among the route's stops,
+	// defaulting to its origin and destination
+	PickupStopID        *uuid.UUID `json:"pickup_stop_id"`
+	DropoffStopID       *uuid.UUID `json:"dropoff_stop_id"`
+	ReturnPickupStopID  *uuid.UUID `json:"return_pickup_stop_id"`
+	ReturnDropoffStopID *uuid.UUID `json:"return_dropoff_stop_id"`
+
+	// Optional; steer automatic seat assignment
+	SeatPreferences SeatPreferencesRequest `json:"seat_preferences"`
+
+	// Optional; seated passengers take the selected seats in order
+	Passengers []PassengerRequest `json:"passengers" binding:"omitempty,dive"`
+}
+
+type SeatPreferencesRequest struct {
+	Window         bool `json:"window" form:"window"`
+	LowerDeck      bool `json:"lower_deck" form:"lower_deck"`
+	Front          bool `json:"front" form:"front"`
+	AwayFromToilet bool `json:"away_from_toilet" form:"away_from_toilet"`
+}
+
+func (r SeatPreferencesRequest) toUsecase() usecases.SeatPreferences {
+	return usecases.SeatPreferences{
+		Window:         r.Window,
+		LowerDeck:      r.LowerDeck,
+		Front:          r.Front,
+		AwayFromToilet: r.AwayFromToilet,
+	}
+}
+
+type PassengerRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Phone    string `json:"phone"`
+	Email    string `json:"email" binding:"omitempty,email"`
+	IDCard   string `json:"id_card"`
+	Age      *int   `json:"age" binding:"omitempty,min=0"`
+	Category string `json:"category"` // adult, child, senior, student or infant; defaults to adult
 }
 
 // InitiateBooking godoc
 // @Summary Initiate a new booking
-// @Description Create a pending booking and lock seats
+// @Description Create a pending booking and lock seats. With return_trip_id, both legs of a round trip are locked together or not at all. Send seat_count instead of seat_numbers to have seats assigned from the bus layout, keeping the group together where possible.
 // @Tags bookings
 // @Accept json
 // @Produce json
 // @Param request body InitiateBookingRequest true "Booking details"
 // @Success 201 {object} entities.Booking
 // @Failure 400 {object} ErrorResponse
-// @Failure 409 {object} ErrorResponse "Seat already locked or booked"
+// @Param X-Device-ID header string false "Stable ID of the client device, used for the seat hold limits"
+// @Failure 409 {object} ErrorResponse "Seat already locked or booked, promo code used up, or too many seats held"
+// @Failure 429 {object} ErrorResponse "Too many unpaid bookings, or on cooldown after holds expired unpaid"
 // @Security BearerAuth
 // @Router /bookings [post]
 func (h *BookingHandler) InitiateBooking(c *gin.Context) {
@@ -50,6 +95,21 @@ func (h *BookingHandler) InitiateBooking(c *gin.Context) {
 		return
 	}
 
+	if len(req.SeatNumbers) == 0 && req.SeatCount == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Either seat_numbers or seat_count is required"})
+		return
+	}
+
+	var returnTripID *uuid.UUID
+	if req.ReturnTripID != "" {
+		parsed, err := uuid.Parse(req.ReturnTripID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid return trip ID"})
+			return
+		}
+		returnTripID = &parsed
+	}
+
 	// Get user ID from context (set by auth middleware)
 	var userID *uuid.UUID
 	if uid, exists := c.Get("user_id"); exists {
@@ -58,17 +118,46 @@ func (h *BookingHandler) InitiateBooking(c *gin.Context) {
 		}
 	}
 
-	booking, err := h.usecase.InitiateBooking(c.Request.Context(), tripID, req.SeatNumbers, userID)
+	passengers := make([]usecases.PassengerInput, 0, len(req.Passengers))
+	for _, p := range req.Passengers {
+		passengers = append(passengers, usecases.PassengerInput{
+			Name:     p.Name,
+			Phone:    p.Phone,
+			Email:    p.Email,
+			IDCard:   p.IDCard,
+			Age:      p.Age,
+			Category: entities.FareCategory(p.Category),
+		})
+	}
+
+	booking, err := h.usecase.InitiateBooking(c.Request.Context(), usecases.InitiateBookingInput{
+		TripID:              tripID,
+		SeatNumbers:         req.SeatNumbers,
+		SeatCount:           req.SeatCount,
+		SeatPreferences:     req.SeatPreferences.toUsecase(),
+		ReturnTripID:        returnTripID,
+		ReturnSeatNumbers:   req.ReturnSeatNumbers,
+		PickupStopID:        req.PickupStopID,
+		DropoffStopID:       req.DropoffStopID,
+		ReturnPickupStopID:  req.ReturnPickupStopID,
+		ReturnDropoffStopID: req.ReturnDropoffStopID,
+		Passengers:          passengers,
+		PromoCode:           req.PromoCode,
+		UserID:              userID,
+		ClientIP:            c.ClientIP(),
+		DeviceID:            deviceID(c),
+		ContactName:         req.ContactName,
+		ContactEmail:        req.ContactEmail,
+		ContactPhone:        req.ContactPhone,
+	})
 	if err != nil {
-		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		if writeHoldLimitError(c, err) {
+			return
+		}
+		c.JSON(promoErrorStatus(err, http.StatusConflict), ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	// Update contact details
-	booking.ContactName = req.ContactName
-	booking.ContactEmail = req.ContactEmail
-	booking.ContactPhone = req.ContactPhone
-
 	c.JSON(http.StatusCreated, booking)
 }
 
@@ -154,7 +243,7 @@ func (h *BookingHandler) GetUserBookings(c *gin.Context) {
 
 // CancelBooking godoc
 // @Summary Cancel a booking
-// @Description Cancel a booking and release seats
+// @Description Cancel a booking and release seats; cancelling either leg of a round trip cancels both
 // @Tags bookings
 // @Param id path string true "Booking ID"
 // @Success 200 {object} SuccessResponse
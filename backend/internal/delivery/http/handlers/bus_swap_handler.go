@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+type BusSwapHandler struct {
+	busSwapUsecase *usecases.BusSwapUsecase
+}
+
+func NewBusSwapHandler(busSwapUsecase *usecases.BusSwapUsecase) *BusSwapHandler {
+	return &BusSwapHandler{busSwapUsecase: busSwapUsecase}
+}
+
+type ReplaceBusRequest struct {
+	BusID  string `json:"bus_id" binding:"required"`
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// ReplaceBus godoc
+// @Summary Replace the bus of a trip
+// @Description Move a trip onto another active bus whose schedule allows it; clashes are answered with 409 and the list of conflicts. Passengers keep their seat number where the new bus has it, otherwise they get the nearest free seat; those that cannot be seated are listed as unseated. Affected customers are emailed.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Param request body ReplaceBusRequest true "Replacement bus"
+// @Success 200 {object} entities.BusSwap
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trips/{id}/replace-bus [post]
+func (h *BusSwapHandler) ReplaceBus(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	var req ReplaceBusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	busID, err := uuid.Parse(req.BusID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid bus ID"})
+		return
+	}
+
+	var swappedBy *uuid.UUID
+	if uid, exists := c.Get("user_id"); exists {
+		if parsed, err := uuid.Parse(uid.(string)); err == nil {
+			swappedBy = &parsed
+		}
+	}
+
+	swap, err := h.busSwapUsecase.ReplaceBus(c.Request.Context(), usecases.ReplaceBusInput{
+		TripID:    tripID,
+		NewBusID:  busID,
+		Reason:    req.Reason,
+		SwappedBy: swappedBy,
+	})
+	if err != nil {
+		if writeScheduleConflictError(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, swap)
+}
+
+// ListSwaps godoc
+// @Summary List bus swaps of a trip
+// @Description Past bus replacements of a trip with the seat moves they made, newest first
+// @Tags admin
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Success 200 {object} map[string][]entities.BusSwap
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trips/{id}/bus-swaps [get]
+func (h *BusSwapHandler) ListSwaps(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	swaps, err := h.busSwapUsecase.ListSwaps(c.Request.Context(), tripID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list bus swaps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bus_swaps": swaps})
+}
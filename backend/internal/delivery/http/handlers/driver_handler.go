@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+// defaultTimetablePeriod is how far ahead a driver's timetable looks when no
+// end is given
+const defaultTimetablePeriod = 7 * 24 * time.Hour
+
+type DriverHandler struct {
+	driverUsecase *usecases.DriverUsecase
+}
+
+func NewDriverHandler(driverUsecase *usecases.DriverUsecase) *DriverHandler {
+	return &DriverHandler{driverUsecase: driverUsecase}
+}
+
+// Create godoc
+// @Summary Create a driver
+// @Description Add a driver to an operator; licence class D allows buses of up to 30 seats and class E larger ones
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body entities.Driver true "Driver"
+// @Success 201 {object} entities.Driver
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/drivers [post]
+func (h *DriverHandler) Create(c *gin.Context) {
+	var driver entities.Driver
+	if err := c.ShouldBindJSON(&driver); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.driverUsecase.CreateDriver(c.Request.Context(), &driver); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, driver)
+}
+
+// List godoc
+// @Summary List drivers
+// @Tags admin
+// @Produce json
+// @Param operator query string false "Only drivers of this operator"
+// @Param status query string false "Only drivers with this status: active or inactive"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Drivers per page" default(50)
+// @Success 200 {object} map[string][]entities.Driver
+// @Security BearerAuth
+// @Router /admin/drivers [get]
+func (h *DriverHandler) List(c *gin.Context) {
+	page := 1
+	limit := 50
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := parsePositiveInt(pageStr); err == nil {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parsePositiveInt(limitStr); err == nil && l <= 100 {
+			limit = l
+		}
+	}
+
+	drivers, err := h.driverUsecase.ListDrivers(c.Request.Context(), c.Query("operator"), entities.DriverStatus(c.Query("status")), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list drivers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"drivers": drivers})
+}
+
+// GetByID godoc
+// @Summary Get a driver
+// @Tags admin
+// @Produce json
+// @Param id path string true "Driver ID"
+// @Success 200 {object} entities.Driver
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/drivers/{id} [get]
+func (h *DriverHandler) GetByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid driver ID"})
+		return
+	}
+
+	driver, err := h.driverUsecase.GetDriver(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Driver not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, driver)
+}
+
+// Update godoc
+// @Summary Update a driver
+// @Description Change a driver's details; trips the driver is already on are not checked again
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Driver ID"
+// @Param request body entities.Driver true "Driver"
+// @Success 200 {object} entities.Driver
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/drivers/{id} [put]
+func (h *DriverHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid driver ID"})
+		return
+	}
+
+	var driver entities.Driver
+	if err := c.ShouldBindJSON(&driver); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	driver.ID = id
+	if err := h.driverUsecase.UpdateDriver(c.Request.Context(), &driver); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, driver)
+}
+
+// Deactivate godoc
+// @Summary Deactivate a driver
+// @Description Stop giving a driver new trips; trips the driver is already on keep them
+// @Tags admin
+// @Produce json
+// @Param id path string true "Driver ID"
+// @Success 200 {object} entities.Driver
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/drivers/{id} [delete]
+func (h *DriverHandler) Deactivate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid driver ID"})
+		return
+	}
+
+	driver, err := h.driverUsecase.DeactivateDriver(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, driver)
+}
+
+// GetTimetable godoc
+// @Summary Get a driver's timetable
+// @Description The trips a driver is on as main or co-driver in a period, with the time at the wheel on each
+// @Tags admin
+// @Produce json
+// @Param id path string true "Driver ID"
+// @Param from query string false "Start of the period (RFC3339), default now"
+// @Param to query string false "End of the period (RFC3339), default 7 days after from"
+// @Success 200 {object} usecases.DriverTimetable
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/drivers/{id}/timetable [get]
+func (h *DriverHandler) GetTimetable(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid driver ID"})
+		return
+	}
+
+	from := time.Now()
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid from time format"})
+			return
+		}
+		from = parsed
+	}
+
+	to := from.Add(defaultTimetablePeriod)
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid to time format"})
+			return
+		}
+		to = parsed
+	}
+
+	timetable, err := h.driverUsecase.GetTimetable(c.Request.Context(), id, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, timetable)
+}
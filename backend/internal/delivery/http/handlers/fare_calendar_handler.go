@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+type FareCalendarHandler struct {
+	fareCalendarUsecase *usecases.FareCalendarUsecase
+}
+
+func NewFareCalendarHandler(fareCalendarUsecase *usecases.FareCalendarUsecase) *FareCalendarHandler {
+	return &FareCalendarHandler{fareCalendarUsecase: fareCalendarUsecase}
+}
+
+type FareCalendarRequest struct {
+	FromCity  string `form:"from_city" binding:"required"`
+	ToCity    string `form:"to_city" binding:"required"`
+	Month     string `form:"month"`      // YYYY-MM, the whole month
+	Date      string `form:"date"`       // YYYY-MM-DD, the days around it
+	FlexDays  *int   `form:"flex_days"`  // Days either side of date, 7 by default
+	StartDate string `form:"start_date"` // YYYY-MM-DD, with end_date
+	EndDate   string `form:"end_date"`   // YYYY-MM-DD, included
+}
+
+// window turns whichever of the month, the date or the start and end dates
+// was given into the first and last day of the calendar
+func (r FareCalendarRequest) window() (start, end time.Time, err error) {
+	switch {
+	case r.Month != "":
+		month, err := time.Parse("2006-01", r.Month)
+		if err != nil {
+			return start, end, errors.New("Invalid month format, use YYYY-MM")
+		}
+		return month, month.AddDate(0, 1, -1), nil
+	case r.Date != "":
+		date, err := time.Parse("2006-01-02", r.Date)
+		if err != nil {
+			return start, end, errors.New("Invalid date format, use YYYY-MM-DD")
+		}
+		flex := 7
+		if r.FlexDays != nil {
+			flex = *r.FlexDays
+		}
+		if flex < 0 {
+			return start, end, errors.New("flex_days must not be negative")
+		}
+		return date.AddDate(0, 0, -flex), date.AddDate(0, 0, flex), nil
+	case r.StartDate != "" && r.EndDate != "":
+		if start, err = time.Parse("2006-01-02", r.StartDate); err != nil {
+			return start, end, errors.New("Invalid start date format, use YYYY-MM-DD")
+		}
+		if end, err = time.Parse("2006-01-02", r.EndDate); err != nil {
+			return start, end, errors.New("Invalid end date format, use YYYY-MM-DD")
+		}
+		return start, end, nil
+	}
+	return start, end, errors.New("Give a month, a date or a start and end date")
+}
+
+// GetFareCalendar godoc
+// @Summary Fare calendar between two cities
+// @Description Lowest seat fare and number of departures per day between two cities, for a whole month, the days around a date or a range of at most 31 days. Cities are matched however they are spelled, as in the trip search. Days are in the business timezone and days already gone are left out.
+// @Tags trips
+// @Produce json
+// @Param from_city query string true "Departure city"
+// @Param to_city query string true "Arrival city"
+// @Param month query string false "Month (YYYY-MM)"
+// @Param date query string false "Date to search around (YYYY-MM-DD)"
+// @Param flex_days query int false "Days either side of date, 7 by default"
+// @Param start_date query string false "First day (YYYY-MM-DD)"
+// @Param end_date query string false "Last day (YYYY-MM-DD)"
+// @Success 200 {object} usecases.FareCalendar
+// @Failure 400 {object} ErrorResponse
+// @Router /trips/fare-calendar [get]
+func (h *FareCalendarHandler) GetFareCalendar(c *gin.Context) {
+	var req FareCalendarRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	start, end, err := req.window()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	calendar, err := h.fareCalendarUsecase.GetFareCalendar(c.Request.Context(), req.FromCity, req.ToCity, start, end)
+	if err != nil {
+		if errors.Is(err, usecases.ErrInvalidFareCalendar) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get fare calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+type FareHandler struct {
+	fareUsecase *usecases.FareUsecase
+}
+
+func NewFareHandler(fareUsecase *usecases.FareUsecase) *FareHandler {
+	return &FareHandler{fareUsecase: fareUsecase}
+}
+
+func (h *FareHandler) Create(c *gin.Context) {
+	var rule entities.FareRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	err := h.fareUsecase.CreateRule(c.Request.Context(), &rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *FareHandler) List(c *gin.Context) {
+	page := 1
+	limit := 50
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := parsePositiveInt(pageStr); err == nil {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parsePositiveInt(limitStr); err == nil && l <= 100 {
+			limit = l
+		}
+	}
+
+	category := entities.FareCategory(c.Query("category"))
+
+	rules, err := h.fareUsecase.ListRules(c.Request.Context(), category, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list fare rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fare_rules": rules})
+}
+
+func (h *FareHandler) GetByID(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid fare rule ID"})
+		return
+	}
+
+	rule, err := h.fareUsecase.GetRuleByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Fare rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *FareHandler) Update(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid fare rule ID"})
+		return
+	}
+
+	var rule entities.FareRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	rule.ID = id
+	err = h.fareUsecase.UpdateRule(c.Request.Context(), &rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *FareHandler) Delete(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid fare rule ID"})
+		return
+	}
+
+	err = h.fareUsecase.DeleteRule(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{Message: "Fare rule deleted successfully"})
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,23 +16,110 @@ import (
 type TripHandler struct {
 	tripUsecase    *usecases.TripUsecase
 	bookingUsecase *usecases.BookingUsecase
+	fareUsecase    *usecases.FareUsecase
 }
 
-func NewTripHandler(tripUsecase *usecases.TripUsecase, bookingUsecase *usecases.BookingUsecase) *TripHandler {
+func NewTripHandler(tripUsecase *usecases.TripUsecase, bookingUsecase *usecases.BookingUsecase, fareUsecase *usecases.FareUsecase) *TripHandler {
 	return &TripHandler{
 		tripUsecase:    tripUsecase,
 		bookingUsecase: bookingUsecase,
+		fareUsecase:    fareUsecase,
 	}
 }
 
 type SearchTripsRequest struct {
-	FromCity string `form:"from_city" binding:"required"`
-	ToCity   string `form:"to_city" binding:"required"`
-	Date     string `form:"date" binding:"required"` // YYYY-MM-DD format
-	Page     int    `form:"page"`
-	Limit    int    `form:"limit"`
+	FromCity       string   `form:"from_city" binding:"required_without=FromLocationID"` // Any spelling, e.g. "Sai Gon" or "HCM"
+	ToCity         string   `form:"to_city" binding:"required_without=ToLocationID"`
+	FromLocationID string   `form:"from_location_id"` // As suggested by the location autocomplete, instead of from_city
+	ToLocationID   string   `form:"to_location_id"`
+	Date           string   `form:"date" binding:"required"` // YYYY-MM-DD in the business timezone
+	ReturnDate     string   `form:"return_date"`             // YYYY-MM-DD, searches a round trip when set
+	DepartAfter    string   `form:"departure_after"`         // HH:MM
+	DepartBefore   string   `form:"departure_before"`        // HH:MM
+	MinPrice       *float64 `form:"min_price"`
+	MaxPrice       *float64 `form:"max_price"`
+	BusTypes       []string `form:"bus_type"` // Repeated or comma-separated
+	Operators      []string `form:"operator"`
+	Amenities      []string `form:"amenity"`
+	MinSeats       int      `form:"min_seats"`
+	Sort           string   `form:"sort"`  // departure, price, duration or rating
+	Order          string   `form:"order"` // asc or desc; rating sorts best first by default
+	Page           int      `form:"page"`
+	Limit          int      `form:"limit"`
 }
 
+func (r SearchTripsRequest) toUsecase(date time.Time) (usecases.TripSearchInput, error) {
+	fromLocationID, err := parseOptionalUUID(r.FromLocationID)
+	if err != nil {
+		return usecases.TripSearchInput{}, err
+	}
+	toLocationID, err := parseOptionalUUID(r.ToLocationID)
+	if err != nil {
+		return usecases.TripSearchInput{}, err
+	}
+
+	sort := entities.TripSearchSort(r.Sort)
+	return usecases.TripSearchInput{
+		FromCity:       r.FromCity,
+		ToCity:         r.ToCity,
+		FromLocationID: fromLocationID,
+		ToLocationID:   toLocationID,
+		Date:           date,
+		DepartAfter:    r.DepartAfter,
+		DepartBefore:   r.DepartBefore,
+		MinPrice:       r.MinPrice,
+		MaxPrice:       r.MaxPrice,
+		BusTypes:       splitQueryList(r.BusTypes),
+		Operators:      splitQueryList(r.Operators),
+		Amenities:      splitQueryList(r.Amenities),
+		MinSeats:       r.MinSeats,
+		Sort:           sort,
+		Descending:     r.Order == "desc" || (r.Order == "" && sort == entities.TripSortRating),
+		Page:           r.Page,
+		Limit:          r.Limit,
+	}, nil
+}
+
+// splitQueryList flattens a query parameter given repeatedly and/or as a
+// comma-separated list, dropping empty entries
+func splitQueryList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// Search godoc
+// @Summary Search trips
+// @Description Bookable trips between two cities on a day in the business timezone, with their free seats and lowest fare, and facet counts for the filters. Each facet applies every other filter, so its counts say how many trips picking that value would give. Passing return_date searches a round trip.
+// @Tags trips
+// @Produce json
+// @Param from_city query string false "Departure city, however it is spelled; required without from_location_id"
+// @Param to_city query string false "Arrival city, however it is spelled; required without to_location_id"
+// @Param from_location_id query string false "Departure location ID"
+// @Param to_location_id query string false "Arrival location ID"
+// @Param date query string true "Departure date (YYYY-MM-DD)"
+// @Param return_date query string false "Return date (YYYY-MM-DD)"
+// @Param departure_after query string false "Earliest departure (HH:MM)"
+// @Param departure_before query string false "Departure before (HH:MM)"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param bus_type query []string false "Bus types, e.g. sleeper, limousine" collectionFormat(multi)
+// @Param operator query []string false "Operators" collectionFormat(multi)
+// @Param amenity query []string false "Amenities the bus must all have, e.g. wifi, ac, charging" collectionFormat(multi)
+// @Param min_seats query int false "Minimum free seats"
+// @Param sort query string false "departure, price, duration or rating"
+// @Param order query string false "asc or desc"
+// @Param page query int false "Page"
+// @Param limit query int false "Results per page, at most 100"
+// @Success 200 {object} usecases.TripSearchResults
+// @Failure 400 {object} ErrorResponse
+// @Router /trips [get]
 func (h *TripHandler) Search(c *gin.Context) {
 	var req SearchTripsRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
@@ -44,6 +133,10 @@ func (h *TripHandler) Search(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid date format, use YYYY-MM-DD"})
 		return
 	}
+	if req.Order != "" && req.Order != "asc" && req.Order != "desc" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid order, use asc or desc"})
+		return
+	}
 
 	// Set defaults
 	if req.Page <= 0 {
@@ -53,13 +146,45 @@ func (h *TripHandler) Search(c *gin.Context) {
 		req.Limit = 20
 	}
 
-	trips, err := h.tripUsecase.SearchTrips(c.Request.Context(), req.FromCity, req.ToCity, date, req.Page, req.Limit)
+	input, err := req.toUsecase(date)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to search trips"})
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid location ID"})
+		return
+	}
+
+	if req.ReturnDate != "" {
+		returnDate, err := time.Parse("2006-01-02", req.ReturnDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid return date format, use YYYY-MM-DD"})
+			return
+		}
+
+		result, err := h.tripUsecase.SearchRoundTrips(c.Request.Context(), input, returnDate)
+		if err != nil {
+			respondSearchError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"trips": trips})
+	result, err := h.tripUsecase.SearchTrips(c.Request.Context(), input)
+	if err != nil {
+		respondSearchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondSearchError answers 400 for a malformed search and 500 otherwise
+func respondSearchError(c *gin.Context, err error) {
+	if errors.Is(err, usecases.ErrInvalidTripSearch) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to search trips"})
 }
 
 func (h *TripHandler) GetByID(c *gin.Context) {
@@ -87,8 +212,17 @@ func (h *TripHandler) GetSeats(c *gin.Context) {
 		return
 	}
 
-	seats, err := h.bookingUsecase.GetAvailableSeats(c.Request.Context(), id)
+	pickupStopID, dropoffStopID, ok := bindStopRange(c)
+	if !ok {
+		return
+	}
+
+	seats, err := h.bookingUsecase.GetAvailableSeats(c.Request.Context(), id, pickupStopID, dropoffStopID)
 	if err != nil {
+		if pickupStopID != nil || dropoffStopID != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get seats"})
 		return
 	}
@@ -96,14 +230,165 @@ func (h *TripHandler) GetSeats(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"seats": seats})
 }
 
+// GetSeatMap godoc
+// @Summary Get the seat map of a trip
+// @Description The bus's seat layout per deck with each seat's live status and price between the pickup and drop-off stops, the whole trip by default. A seat is available only when it is free on every segment in between. Pass the booking code of a pending booking to see which seats it holds. Supports conditional requests with If-None-Match.
+// @Tags trips
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Param pickup_stop_id query string false "Stop to board at; the origin when empty"
+// @Param dropoff_stop_id query string false "Stop to get off at; the destination when empty"
+// @Param booking_code query string false "Code of the caller's pending booking"
+// @Success 200 {object} entities.SeatMap
+// @Success 304 "Seat map unchanged"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /trips/{id}/seat-map [get]
+func (h *TripHandler) GetSeatMap(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	pickupStopID, dropoffStopID, ok := bindStopRange(c)
+	if !ok {
+		return
+	}
+
+	seatMap, err := h.bookingUsecase.GetSeatMap(c.Request.Context(), id, pickupStopID, dropoffStopID, c.Query("booking_code"))
+	if err != nil {
+		if pickupStopID != nil || dropoffStopID != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Trip not found"})
+		return
+	}
+
+	etag := fmt.Sprintf("%q", seatMap.Version)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, seatMap)
+}
+
+// bindStopRange reads the optional pickup_stop_id and dropoff_stop_id query
+// parameters, answering 400 when either is malformed
+func bindStopRange(c *gin.Context) (pickupStopID, dropoffStopID *uuid.UUID, ok bool) {
+	pickupStopID, err := parseOptionalUUID(c.Query("pickup_stop_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid pickup stop ID"})
+		return nil, nil, false
+	}
+	dropoffStopID, err = parseOptionalUUID(c.Query("dropoff_stop_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid drop-off stop ID"})
+		return nil, nil, false
+	}
+	return pickupStopID, dropoffStopID, true
+}
+
+// etagMatches reports whether an If-None-Match header lists the ETag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+type SuggestSeatsRequest struct {
+	Count int `form:"count" binding:"required,min=1,max=50"`
+	SeatPreferencesRequest
+}
+
+// SuggestSeats proposes seats for a group from the bus layout without holding them
+func (h *TripHandler) SuggestSeats(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	var req SuggestSeatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	pickupStopID, dropoffStopID, ok := bindStopRange(c)
+	if !ok {
+		return
+	}
+
+	allocation, err := h.bookingUsecase.SuggestSeats(c.Request.Context(), id, pickupStopID, dropoffStopID, req.Count, req.SeatPreferencesRequest.toUsecase())
+	if err != nil {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, allocation)
+}
+
+func (h *TripHandler) GetFares(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	pickupStopID, dropoffStopID, ok := bindStopRange(c)
+	if !ok {
+		return
+	}
+
+	fares, err := h.fareUsecase.GetTripFares(c.Request.Context(), id, pickupStopID, dropoffStopID)
+	if err != nil {
+		if pickupStopID != nil || dropoffStopID != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Trip not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fares": fares})
+}
+
+func (h *TripHandler) GetManifest(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	manifest, err := h.bookingUsecase.GetTripManifest(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to build manifest"})
+		return
+	}
+
+	c.JSON(http.StatusOK, manifest)
+}
+
 type CreateTripRequest struct {
-	BusID         string  `json:"bus_id" binding:"required"`
-	RouteID       string  `json:"route_id" binding:"required"`
-	DepartureTime string  `json:"departure_time" binding:"required"`
-	ArrivalTime   string  `json:"arrival_time" binding:"required"`
-	Price         float64 `json:"price" binding:"required,gt=0"`
-	DriverName    string  `json:"driver_name"`
-	DriverPhone   string  `json:"driver_phone"`
+	BusID         string     `json:"bus_id" binding:"required"`
+	RouteID       string     `json:"route_id" binding:"required"`
+	DepartureTime string     `json:"departure_time" binding:"required"`
+	ArrivalTime   string     `json:"arrival_time" binding:"required"`
+	Price         float64    `json:"price" binding:"required,gt=0"`
+	DriverID      *uuid.UUID `json:"driver_id"`
+	CoDriverID    *uuid.UUID `json:"co_driver_id"`
 }
 
 func (h *TripHandler) Create(c *gin.Context) {
@@ -143,13 +428,16 @@ func (h *TripHandler) Create(c *gin.Context) {
 		DepartureTime: departureTime,
 		ArrivalTime:   arrivalTime,
 		Price:         req.Price,
-		DriverName:    req.DriverName,
-		DriverPhone:   req.DriverPhone,
+		DriverID:      req.DriverID,
+		CoDriverID:    req.CoDriverID,
 		Status:        entities.TripStatusScheduled,
 	}
 
 	err = h.tripUsecase.CreateTrip(c.Request.Context(), trip)
 	if err != nil {
+		if writeScheduleConflictError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -158,12 +446,11 @@ func (h *TripHandler) Create(c *gin.Context) {
 }
 
 type UpdateTripRequest struct {
-	DepartureTime string              `json:"departure_time"`
-	ArrivalTime   string              `json:"arrival_time"`
-	Price         float64             `json:"price,omitempty"`
-	Status        entities.TripStatus `json:"status,omitempty"`
-	DriverName    string              `json:"driver_name"`
-	DriverPhone   string              `json:"driver_phone"`
+	DepartureTime string  `json:"departure_time"`
+	ArrivalTime   string  `json:"arrival_time"`
+	Price         float64 `json:"price,omitempty"`
+	DriverID      *string `json:"driver_id"`    // "" takes the driver off the trip
+	CoDriverID    *string `json:"co_driver_id"` // "" takes the co-driver off the trip
 }
 
 func (h *TripHandler) Update(c *gin.Context) {
@@ -210,20 +497,29 @@ func (h *TripHandler) Update(c *gin.Context) {
 		trip.Price = req.Price
 	}
 
-	if req.Status != "" {
-		trip.Status = req.Status
-	}
-
-	if req.DriverName != "" {
-		trip.DriverName = req.DriverName
+	if req.DriverID != nil {
+		driverID, err := parseOptionalUUID(*req.DriverID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid driver ID"})
+			return
+		}
+		trip.DriverID, trip.Driver = driverID, nil
 	}
 
-	if req.DriverPhone != "" {
-		trip.DriverPhone = req.DriverPhone
+	if req.CoDriverID != nil {
+		coDriverID, err := parseOptionalUUID(*req.CoDriverID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid co-driver ID"})
+			return
+		}
+		trip.CoDriverID, trip.CoDriver = coDriverID, nil
 	}
 
 	err = h.tripUsecase.UpdateTrip(c.Request.Context(), trip)
 	if err != nil {
+		if writeScheduleConflictError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -474,6 +770,45 @@ func (h *RouteHandler) Delete(c *gin.Context) {
 	c.JSON(http.StatusOK, SuccessResponse{Message: "Route deleted successfully"})
 }
 
+// SetRouteStopsRequest is the full, ordered list of a route's stops
+type SetRouteStopsRequest struct {
+	Stops []entities.RouteStop `json:"stops"`
+}
+
+// SetStops godoc
+// @Summary Set a route's stops
+// @Description Replace a route's pickup and drop-off points with the given list, in the order the bus calls at them. Stops with an ID keep it; stops left out are deleted unless bookings board or leave there.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Route ID"
+// @Param request body SetRouteStopsRequest true "Stops"
+// @Success 200 {object} entities.Route
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/routes/{id}/stops [put]
+func (h *RouteHandler) SetStops(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid route ID"})
+		return
+	}
+
+	var req SetRouteStopsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	route, err := h.routeUsecase.SetRouteStops(c.Request.Context(), id, req.Stops)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, route)
+}
+
 func parsePositiveInt(s string) (int, error) {
 	var i int
 	_, err := fmt.Sscanf(s, "%d", &i)
@@ -482,3 +817,15 @@ func parsePositiveInt(s string) (int, error) {
 	}
 	return i, nil
 }
+
+// parseOptionalUUID parses an ID that may be left empty to mean none
+func parseOptionalUUID(s string) (*uuid.UUID, error) {
+	if s == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
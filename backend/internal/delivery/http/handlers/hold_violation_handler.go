@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+// deviceIDHeader carries a client-generated ID that stays the same across a
+// guest's requests, so anti-hoarding limits can follow a device
+const (
+	deviceIDHeader    = "X-Device-ID"
+	maxDeviceIDLength = 100
+)
+
+type HoldViolationHandler struct {
+	holdLimitUsecase *usecases.HoldLimitUsecase
+}
+
+func NewHoldViolationHandler(holdLimitUsecase *usecases.HoldLimitUsecase) *HoldViolationHandler {
+	return &HoldViolationHandler{holdLimitUsecase: holdLimitUsecase}
+}
+
+// deviceID reads the caller's device ID, if it sent one
+func deviceID(c *gin.Context) string {
+	id := c.GetHeader(deviceIDHeader)
+	if len(id) > maxDeviceIDLength {
+		id = id[:maxDeviceIDLength]
+	}
+	return id
+}
+
+// writeHoldLimitError answers a refused seat hold and reports whether err was
+// one. Holding too much at once or being on cooldown is 429 Too Many Requests;
+// asking for too many seats is 409 Conflict.
+func writeHoldLimitError(c *gin.Context, err error) bool {
+	var holdErr *usecases.HoldLimitError
+	if !errors.As(err, &holdErr) {
+		return false
+	}
+
+	status := http.StatusConflict
+	switch holdErr.Policy {
+	case entities.HoldPolicyPendingBookings, entities.HoldPolicyCooldown:
+		status = http.StatusTooManyRequests
+	}
+	if holdErr.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(holdErr.RetryAfter.Seconds()))))
+	}
+
+	c.JSON(status, ErrorResponse{Error: holdErr.Error()})
+	return true
+}
+
+// List godoc
+// @Summary List refused seat holds
+// @Description Seat holds refused by the anti-hoarding limits, newest first, for fraud review
+// @Tags admin
+// @Produce json
+// @Param policy query string false "Only this policy: seats_per_booking, pending_bookings, trip_seats or cooldown"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Violations per page" default(50)
+// @Success 200 {object} map[string][]entities.HoldViolation
+// @Security BearerAuth
+// @Router /admin/hold-violations [get]
+func (h *HoldViolationHandler) List(c *gin.Context) {
+	page := 1
+	limit := 50
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := parsePositiveInt(pageStr); err == nil {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parsePositiveInt(limitStr); err == nil && l <= 100 {
+			limit = l
+		}
+	}
+
+	policy := entities.HoldPolicy(c.Query("policy"))
+
+	violations, err := h.holdLimitUsecase.ListViolations(c.Request.Context(), policy, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list hold violations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"violations": violations})
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/bus-booking/internal/infrastructure/scheduler"
+)
+
+type JobHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewJobHandler(scheduler *scheduler.Scheduler) *JobHandler {
+	return &JobHandler{scheduler: scheduler}
+}
+
+// List godoc
+// @Summary List background jobs
+// @Description Schedule, last-run status and next run time of every background job
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string][]scheduler.JobStatus
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/jobs [get]
+func (h *JobHandler) List(c *gin.Context) {
+	statuses, err := h.scheduler.Statuses(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to load job statuses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": statuses})
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+type LocationHandler struct {
+	locationUsecase *usecases.LocationUsecase
+}
+
+func NewLocationHandler(locationUsecase *usecases.LocationUsecase) *LocationHandler {
+	return &LocationHandler{locationUsecase: locationUsecase}
+}
+
+type LocationRequest struct {
+	Name     string   `json:"name" binding:"required"` // e.g. "TP. Hồ Chí Minh"
+	Province string   `json:"province"`
+	Aliases  []string `json:"aliases"`   // e.g. ["Sài Gòn", "HCM"]; replaces the current ones
+	IsActive *bool    `json:"is_active"` // Unchanged when left out
+}
+
+func (r LocationRequest) toUsecase() usecases.LocationInput {
+	return usecases.LocationInput{
+		Name:     r.Name,
+		Province: r.Province,
+		Aliases:  r.Aliases,
+		IsActive: r.IsActive,
+	}
+}
+
+// Autocomplete godoc
+// @Summary Suggest cities
+// @Description Locations whose name or alias starts like or resembles the text typed so far, with or without Vietnamese diacritics, best match first
+// @Tags locations
+// @Produce json
+// @Param q query string true "Text typed so far"
+// @Param limit query int false "Suggestions, at most 20" default(8)
+// @Success 200 {object} map[string][]entities.LocationMatch
+// @Router /locations/autocomplete [get]
+func (h *LocationHandler) Autocomplete(c *gin.Context) {
+	limit := 8
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parsePositiveInt(limitStr); err == nil && l <= 20 {
+			limit = l
+		}
+	}
+
+	matches, err := h.locationUsecase.Autocomplete(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to suggest locations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"locations": matches})
+}
+
+// Create godoc
+// @Summary Create a location
+// @Description Add a city or town routes can run between, with the other names customers know it by
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body LocationRequest true "Location"
+// @Success 201 {object} entities.Location
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/locations [post]
+func (h *LocationHandler) Create(c *gin.Context) {
+	var req LocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	location, err := h.locationUsecase.CreateLocation(c.Request.Context(), req.toUsecase())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, location)
+}
+
+// List godoc
+// @Summary List locations
+// @Tags admin
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Locations per page" default(50)
+// @Success 200 {object} map[string][]entities.Location
+// @Security BearerAuth
+// @Router /admin/locations [get]
+func (h *LocationHandler) List(c *gin.Context) {
+	page := 1
+	limit := 50
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := parsePositiveInt(pageStr); err == nil {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parsePositiveInt(limitStr); err == nil && l <= 100 {
+			limit = l
+		}
+	}
+
+	locations, err := h.locationUsecase.ListLocations(c.Request.Context(), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list locations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"locations": locations})
+}
+
+// GetByID godoc
+// @Summary Get a location
+// @Tags admin
+// @Produce json
+// @Param id path string true "Location ID"
+// @Success 200 {object} entities.Location
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/locations/{id} [get]
+func (h *LocationHandler) GetByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid location ID"})
+		return
+	}
+
+	location, err := h.locationUsecase.GetLocation(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Location not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+// Update godoc
+// @Summary Update a location
+// @Description Change a location's name, province, status and aliases; routes from or to it take the new name
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Location ID"
+// @Param request body LocationRequest true "Location"
+// @Success 200 {object} entities.Location
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/locations/{id} [put]
+func (h *LocationHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid location ID"})
+		return
+	}
+
+	var req LocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	location, err := h.locationUsecase.UpdateLocation(c.Request.Context(), id, req.toUsecase())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+// Delete godoc
+// @Summary Delete a location
+// @Description Remove a location no route runs from or to; deactivate it otherwise
+// @Tags admin
+// @Param id path string true "Location ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/locations/{id} [delete]
+func (h *LocationHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid location ID"})
+		return
+	}
+
+	if err := h.locationUsecase.DeleteLocation(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{Message: "Location deleted"})
+}
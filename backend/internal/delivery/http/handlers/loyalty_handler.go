@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+type LoyaltyHandler struct {
+	loyaltyUsecase *usecases.LoyaltyUsecase
+}
+
+func NewLoyaltyHandler(loyaltyUsecase *usecases.LoyaltyUsecase) *LoyaltyHandler {
+	return &LoyaltyHandler{loyaltyUsecase: loyaltyUsecase}
+}
+
+// GetMyLoyalty godoc
+// @Summary Get my loyalty statement
+// @Description Points balance, tier with its perks, and the ledger transactions of the authenticated user, newest first
+// @Tags loyalty
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Transactions per page" default(20)
+// @Success 200 {object} usecases.LoyaltyStatement
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/loyalty [get]
+func (h *LoyaltyHandler) GetMyLoyalty(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+
+	userIDStr, ok := userIDValue.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Invalid user ID format"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	page := 1
+	limit := 20
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := parsePositiveInt(pageStr); err == nil {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parsePositiveInt(limitStr); err == nil && l <= 100 {
+			limit = l
+		}
+	}
+
+	statement, err := h.loyaltyUsecase.GetStatement(c.Request.Context(), userID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to load loyalty statement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
@@ -29,6 +29,7 @@ func NewPaymentHandler(paymentUsecase *usecases.PaymentUsecase) *PaymentHandler
 // @Param request body CreatePaymentRequest true "Payment request"
 // @Success 200 {object} CreatePaymentResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Promo code used up"
 // @Security BearerAuth
 // @Router /payments [post]
 func (h *PaymentHandler) CreatePayment(c *gin.Context) {
@@ -58,9 +59,22 @@ func (h *PaymentHandler) CreatePayment(c *gin.Context) {
 		return
 	}
 
-	payment, paymentURL, err := h.paymentUsecase.CreatePayment(c.Request.Context(), bookingID, gateway)
+	var userID *uuid.UUID
+	if uid, exists := c.Get("user_id"); exists {
+		if parsed, err := uuid.Parse(uid.(string)); err == nil {
+			userID = &parsed
+		}
+	}
+
+	payment, paymentURL, err := h.paymentUsecase.CreatePayment(c.Request.Context(), usecases.CreatePaymentInput{
+		BookingID:    bookingID,
+		Gateway:      gateway,
+		PromoCode:    req.PromoCode,
+		RedeemPoints: req.RedeemPoints,
+		UserID:       userID,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.JSON(promoErrorStatus(err, http.StatusBadRequest), ErrorResponse{Error: err.Error()})
 		return
 	}
 
@@ -175,8 +189,10 @@ func (h *PaymentHandler) GetPaymentStatus(c *gin.Context) {
 
 // Request/Response types
 type CreatePaymentRequest struct {
-	BookingID string `json:"booking_id" binding:"required"`
-	Gateway   string `json:"gateway" binding:"required,oneof=momo zalopay payos"`
+	BookingID    string `json:"booking_id" binding:"required"`
+	Gateway      string `json:"gateway" binding:"required,oneof=momo zalopay payos"`
+	PromoCode    string `json:"promo_code"`                              // Optional, if none was applied at booking
+	RedeemPoints int    `json:"redeem_points" binding:"omitempty,min=0"` // Optional loyalty points to spend
 }
 
 type CreatePaymentResponse struct {
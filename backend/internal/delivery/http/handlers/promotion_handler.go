@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+type PromotionHandler struct {
+	promotionUsecase *usecases.PromotionUsecase
+}
+
+func NewPromotionHandler(promotionUsecase *usecases.PromotionUsecase) *PromotionHandler {
+	return &PromotionHandler{promotionUsecase: promotionUsecase}
+}
+
+type CreatePromoCodeRequest struct {
+	Code       string `json:"code" binding:"required"`
+	UsageLimit *int   `json:"usage_limit" binding:"omitempty,min=1"` // Omit for unlimited
+}
+
+type GenerateVouchersRequest struct {
+	Count  int    `json:"count" binding:"required,min=1,max=1000"`
+	Prefix string `json:"prefix"`
+}
+
+// promoErrorStatus maps promo code errors to their HTTP status, falling back
+// to the given status for anything else
+func promoErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, usecases.ErrPromoCodeExhausted):
+		return http.StatusConflict
+	case errors.Is(err, usecases.ErrPromoCodeInvalid), errors.Is(err, usecases.ErrPromoCodeNotApplicable):
+		return http.StatusBadRequest
+	}
+	return fallback
+}
+
+func (h *PromotionHandler) Create(c *gin.Context) {
+	var promotion entities.Promotion
+	if err := c.ShouldBindJSON(&promotion); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	err := h.promotionUsecase.CreatePromotion(c.Request.Context(), &promotion)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, promotion)
+}
+
+func (h *PromotionHandler) List(c *gin.Context) {
+	page := 1
+	limit := 50
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := parsePositiveInt(pageStr); err == nil {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parsePositiveInt(limitStr); err == nil && l <= 100 {
+			limit = l
+		}
+	}
+
+	activeOnly := c.Query("active") == "true"
+
+	promotions, err := h.promotionUsecase.ListPromotions(c.Request.Context(), activeOnly, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list promotions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"promotions": promotions})
+}
+
+func (h *PromotionHandler) GetByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid promotion ID"})
+		return
+	}
+
+	promotion, err := h.promotionUsecase.GetPromotion(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Promotion not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+func (h *PromotionHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid promotion ID"})
+		return
+	}
+
+	var promotion entities.Promotion
+	if err := c.ShouldBindJSON(&promotion); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	promotion.ID = id
+	err = h.promotionUsecase.UpdatePromotion(c.Request.Context(), &promotion)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+func (h *PromotionHandler) ListCodes(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid promotion ID"})
+		return
+	}
+
+	page := 1
+	limit := 100
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := parsePositiveInt(pageStr); err == nil {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parsePositiveInt(limitStr); err == nil && l <= 1000 {
+			limit = l
+		}
+	}
+
+	codes, err := h.promotionUsecase.ListCodes(c.Request.Context(), id, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list promo codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"codes": codes})
+}
+
+func (h *PromotionHandler) CreateCode(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid promotion ID"})
+		return
+	}
+
+	var req CreatePromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	code, err := h.promotionUsecase.CreateCode(c.Request.Context(), id, req.Code, req.UsageLimit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, code)
+}
+
+func (h *PromotionHandler) GenerateVouchers(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid promotion ID"})
+		return
+	}
+
+	var req GenerateVouchersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	codes, err := h.promotionUsecase.GenerateVouchers(c.Request.Context(), id, req.Count, req.Prefix)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var batchID *uuid.UUID
+	if len(codes) > 0 {
+		batchID = codes[0].BatchID
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"batch_id": batchID, "codes": codes})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+// defaultConflictReportPeriod is how far ahead the conflicts report looks when
+// no end is given
+const defaultConflictReportPeriod = 30 * 24 * time.Hour
+
+type ScheduleConflictHandler struct {
+	conflictUsecase *usecases.ScheduleConflictUsecase
+}
+
+func NewScheduleConflictHandler(conflictUsecase *usecases.ScheduleConflictUsecase) *ScheduleConflictHandler {
+	return &ScheduleConflictHandler{conflictUsecase: conflictUsecase}
+}
+
+// ScheduleConflictResponse is the body of a 409 for a trip that clashes with the schedule
+type ScheduleConflictResponse struct {
+	Error     string                      `json:"error"`
+	Conflicts []entities.ScheduleConflict `json:"conflicts"`
+}
+
+// writeScheduleConflictError answers a trip that clashes with the schedule with
+// 409 Conflict and the list of clashes, and reports whether err was one
+func writeScheduleConflictError(c *gin.Context, err error) bool {
+	var conflictErr *usecases.ScheduleConflictError
+	if !errors.As(err, &conflictErr) {
+		return false
+	}
+
+	c.JSON(http.StatusConflict, ScheduleConflictResponse{
+		Error:     conflictErr.Error(),
+		Conflicts: conflictErr.Conflicts,
+	})
+	return true
+}
+
+// Report godoc
+// @Summary Report schedule conflicts
+// @Description Overlapping trips, too short turnarounds and trips departing from another city than the bus was left in, among the trips of a period
+// @Tags admin
+// @Produce json
+// @Param from query string false "Start of the period (RFC3339), default now"
+// @Param to query string false "End of the period (RFC3339), default 30 days after from"
+// @Success 200 {object} usecases.ScheduleConflictReport
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/schedule-conflicts [get]
+func (h *ScheduleConflictHandler) Report(c *gin.Context) {
+	from := time.Now()
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid from time format"})
+			return
+		}
+		from = parsed
+	}
+
+	to := from.Add(defaultConflictReportPeriod)
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid to time format"})
+			return
+		}
+		to = parsed
+	}
+
+	report, err := h.conflictUsecase.Report(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+type SeatBlockHandler struct {
+	seatBlockUsecase *usecases.SeatBlockUsecase
+	busUsecase       *usecases.BusUsecase
+}
+
+func NewSeatBlockHandler(seatBlockUsecase *usecases.SeatBlockUsecase, busUsecase *usecases.BusUsecase) *SeatBlockHandler {
+	return &SeatBlockHandler{
+		seatBlockUsecase: seatBlockUsecase,
+		busUsecase:       busUsecase,
+	}
+}
+
+type BlockSeatsRequest struct {
+	SeatNumbers []string   `json:"seat_numbers" binding:"required,min=1"`
+	Reason      string     `json:"reason" binding:"required,max=255"`
+	Until       *time.Time `json:"until"`
+}
+
+type UnblockSeatsRequest struct {
+	SeatNumbers []string `json:"seat_numbers" binding:"required,min=1"`
+}
+
+type SetBlockedSeatsRequest struct {
+	BlockedSeats entities.SeatBlocks `json:"blocked_seats"`
+}
+
+// Block godoc
+// @Summary Block seats of a trip
+// @Description Take seats off sale, e.g. for staff, VIPs or broken seats; without until the block lasts until the seats are unblocked
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Param request body BlockSeatsRequest true "Seats to block"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trips/{id}/seats/block [post]
+func (h *SeatBlockHandler) Block(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	var req BlockSeatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var blockedBy *uuid.UUID
+	if uid, exists := c.Get("user_id"); exists {
+		if parsed, err := uuid.Parse(uid.(string)); err == nil {
+			blockedBy = &parsed
+		}
+	}
+
+	err = h.seatBlockUsecase.BlockSeats(c.Request.Context(), usecases.BlockSeatsInput{
+		TripID:      tripID,
+		SeatNumbers: req.SeatNumbers,
+		Reason:      req.Reason,
+		Until:       req.Until,
+		BlockedBy:   blockedBy,
+	})
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, usecases.ErrSeatNotBlockable) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{Message: "Seats blocked"})
+}
+
+// Unblock godoc
+// @Summary Unblock seats of a trip
+// @Description Put blocked seats back on sale; seats that are not blocked are left alone
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Param request body UnblockSeatsRequest true "Seats to unblock"
+// @Success 200 {object} map[string][]string
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trips/{id}/seats/unblock [post]
+func (h *SeatBlockHandler) Unblock(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	var req UnblockSeatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	unblocked, err := h.seatBlockUsecase.UnblockSeats(c.Request.Context(), tripID, req.SeatNumbers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unblocked": unblocked})
+}
+
+// SetBusDefaults godoc
+// @Summary Set a bus's default blocked seats
+// @Description Seats blocked on every trip created for the bus from now on; existing trips are not changed
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Bus ID"
+// @Param request body SetBlockedSeatsRequest true "Default blocked seats"
+// @Success 200 {object} entities.Bus
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/buses/{id}/blocked-seats [put]
+func (h *SeatBlockHandler) SetBusDefaults(c *gin.Context) {
+	busID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid bus ID"})
+		return
+	}
+
+	var req SetBlockedSeatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	bus, err := h.busUsecase.SetBlockedSeats(c.Request.Context(), busID, req.BlockedSeats)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bus)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+type SeatReconciliationHandler struct {
+	reconciliationUsecase *usecases.SeatReconciliationUsecase
+}
+
+func NewSeatReconciliationHandler(reconciliationUsecase *usecases.SeatReconciliationUsecase) *SeatReconciliationHandler {
+	return &SeatReconciliationHandler{reconciliationUsecase: reconciliationUsecase}
+}
+
+// Run godoc
+// @Summary Reconcile seat state now
+// @Description Compare Redis seat locks, seats_status and live bookings of upcoming trips, repairing what is safe to repair and flagging the rest
+// @Tags admin
+// @Produce json
+// @Success 200 {object} usecases.SeatReconciliationReport
+// @Security BearerAuth
+// @Router /admin/seat-reconciliation [post]
+func (h *SeatReconciliationHandler) Run(c *gin.Context) {
+	report, err := h.reconciliationUsecase.Reconcile(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListDiscrepancies godoc
+// @Summary List seat discrepancies
+// @Description Findings of the seat reconciler, most recently seen first
+// @Tags admin
+// @Produce json
+// @Param kind query string false "Only this kind of discrepancy"
+// @Param trip_id query string false "Only this trip"
+// @Param open query bool false "Only flagged discrepancies not yet resolved"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Discrepancies per page" default(50)
+// @Success 200 {object} map[string][]entities.SeatDiscrepancy
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/seat-discrepancies [get]
+func (h *SeatReconciliationHandler) ListDiscrepancies(c *gin.Context) {
+	page := 1
+	limit := 50
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := parsePositiveInt(pageStr); err == nil {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parsePositiveInt(limitStr); err == nil && l <= 100 {
+			limit = l
+		}
+	}
+
+	var tripID *uuid.UUID
+	if tripIDStr := c.Query("trip_id"); tripIDStr != "" {
+		parsed, err := uuid.Parse(tripIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+			return
+		}
+		tripID = &parsed
+	}
+
+	kind := entities.SeatDiscrepancyKind(c.Query("kind"))
+	openOnly := c.Query("open") == "true"
+
+	discrepancies, err := h.reconciliationUsecase.ListDiscrepancies(c.Request.Context(), kind, tripID, openOnly, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list seat discrepancies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"discrepancies": discrepancies})
+}
+
+// ResolveDiscrepancy godoc
+// @Summary Resolve a seat discrepancy
+// @Description Close a flagged discrepancy after dealing with it by hand
+// @Tags admin
+// @Produce json
+// @Param id path string true "Discrepancy ID"
+// @Success 200 {object} entities.SeatDiscrepancy
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/seat-discrepancies/{id}/resolve [post]
+func (h *SeatReconciliationHandler) ResolveDiscrepancy(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid discrepancy ID"})
+		return
+	}
+
+	discrepancy, err := h.reconciliationUsecase.ResolveDiscrepancy(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, discrepancy)
+}
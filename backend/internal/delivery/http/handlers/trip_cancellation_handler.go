@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+type TripCancellationHandler struct {
+	cancellationUsecase *usecases.TripCancellationUsecase
+}
+
+func NewTripCancellationHandler(cancellationUsecase *usecases.TripCancellationUsecase) *TripCancellationHandler {
+	return &TripCancellationHandler{cancellationUsecase: cancellationUsecase}
+}
+
+type CancelTripRequest struct {
+	Reason            string     `json:"reason" binding:"required,max=255"`
+	AlternativeTripID *uuid.UUID `json:"alternative_trip_id"`
+}
+
+type CancellationChoiceRequest struct {
+	Choice entities.CancellationResolution `json:"choice" binding:"required,oneof=rebooked refunded"`
+}
+
+// Cancel godoc
+// @Summary Cancel a trip
+// @Description Cancel a trip that has not departed. Paid bookings move to the alternative trip where seats allow and are refunded in full otherwise; customers are emailed their outcome and seat-map viewers get a trip_update event.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Param request body CancelTripRequest true "Reason and optional alternative trip"
+// @Success 200 {object} usecases.TripCancellationReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trips/{id}/cancel [post]
+func (h *TripCancellationHandler) Cancel(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	var req CancelTripRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var cancelledBy *uuid.UUID
+	if uid, exists := c.Get("user_id"); exists {
+		if parsed, err := uuid.Parse(uid.(string)); err == nil {
+			cancelledBy = &parsed
+		}
+	}
+
+	report, err := h.cancellationUsecase.CancelTrip(c.Request.Context(), usecases.CancelTripInput{
+		TripID:            tripID,
+		AlternativeTripID: req.AlternativeTripID,
+		Reason:            req.Reason,
+		CancelledBy:       cancelledBy,
+	})
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetReport godoc
+// @Summary Get the cancellation report of a trip
+// @Description What became of every paid booking of a cancelled trip, with totals
+// @Tags admin
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Success 200 {object} usecases.TripCancellationReport
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trips/{id}/cancellation [get]
+func (h *TripCancellationHandler) GetReport(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	report, err := h.cancellationUsecase.GetReport(c.Request.Context(), tripID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Resume godoc
+// @Summary Resume a trip cancellation
+// @Description Retry failed refunds and settle paid bookings still on the cancelled trip
+// @Tags admin
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Success 200 {object} usecases.TripCancellationReport
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trips/{id}/cancellation/resume [post]
+func (h *TripCancellationHandler) Resume(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	report, err := h.cancellationUsecase.Resume(c.Request.Context(), tripID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetChoice godoc
+// @Summary Get the outcome behind a cancellation choice link
+// @Description Shows what became of a booking on a cancelled trip and whether a refund can still be taken instead of the new trip
+// @Tags trips
+// @Produce json
+// @Param token path string true "Choice token from the cancellation email"
+// @Success 200 {object} usecases.CancellationChoice
+// @Failure 404 {object} ErrorResponse
+// @Router /trip-cancellations/choices/{token} [get]
+func (h *TripCancellationHandler) GetChoice(c *gin.Context) {
+	choice, err := h.cancellationUsecase.GetChoice(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Choice not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, choice)
+}
+
+// Choose godoc
+// @Summary Choose between the new trip and a refund
+// @Description Keep the seats on the alternative trip (rebooked) or give them up for a full refund (refunded)
+// @Tags trips
+// @Accept json
+// @Produce json
+// @Param token path string true "Choice token from the cancellation email"
+// @Param request body CancellationChoiceRequest true "Choice"
+// @Success 200 {object} usecases.CancellationChoice
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /trip-cancellations/choices/{token} [post]
+func (h *TripCancellationHandler) Choose(c *gin.Context) {
+	var req CancellationChoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	choice, err := h.cancellationUsecase.Choose(c.Request.Context(), c.Param("token"), req.Choice)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, usecases.ErrCancellationChoiceClosed) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, choice)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+type TripLifecycleHandler struct {
+	lifecycleUsecase *usecases.TripLifecycleUsecase
+}
+
+func NewTripLifecycleHandler(lifecycleUsecase *usecases.TripLifecycleUsecase) *TripLifecycleHandler {
+	return &TripLifecycleHandler{lifecycleUsecase: lifecycleUsecase}
+}
+
+type DelayTripRequest struct {
+	DepartureTime *time.Time `json:"departure_time"`
+	ArrivalTime   time.Time  `json:"arrival_time" binding:"required"`
+	Reason        string     `json:"reason" binding:"max=255"`
+}
+
+// StartBoarding godoc
+// @Summary Start boarding a trip
+// @Description Move a scheduled or delayed trip to boarding
+// @Tags admin
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Success 200 {object} entities.Trip
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trips/{id}/boarding [post]
+func (h *TripLifecycleHandler) StartBoarding(c *gin.Context) {
+	h.transition(c, h.lifecycleUsecase.StartBoarding)
+}
+
+// Depart godoc
+// @Summary Mark a trip as departed
+// @Description Move a boarding trip to in transit
+// @Tags admin
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Success 200 {object} entities.Trip
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trips/{id}/depart [post]
+func (h *TripLifecycleHandler) Depart(c *gin.Context) {
+	h.transition(c, h.lifecycleUsecase.Depart)
+}
+
+// Arrive godoc
+// @Summary Mark a trip as arrived
+// @Description Complete a trip in transit
+// @Tags admin
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Success 200 {object} entities.Trip
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trips/{id}/arrive [post]
+func (h *TripLifecycleHandler) Arrive(c *gin.Context) {
+	h.transition(c, h.lifecycleUsecase.Arrive)
+}
+
+// Delay godoc
+// @Summary Delay a trip
+// @Description Set a new ETA, and before departure a new departure time; booked passengers are emailed and seat-map viewers get a trip_update event
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Param request body DelayTripRequest true "New times"
+// @Success 200 {object} entities.Trip
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trips/{id}/delay [post]
+func (h *TripLifecycleHandler) Delay(c *gin.Context) {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	var req DelayTripRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	trip, err := h.lifecycleUsecase.Delay(c.Request.Context(), usecases.DelayTripInput{
+		TripID:        tripID,
+		DepartureTime: req.DepartureTime,
+		ArrivalTime:   req.ArrivalTime,
+		Reason:        req.Reason,
+	})
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, trip)
+}
+
+func (h *TripLifecycleHandler) transition(c *gin.Context, move func(ctx context.Context, tripID uuid.UUID) (*entities.Trip, error)) {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trip ID"})
+		return
+	}
+
+	trip, err := move(c.Request.Context(), tripID)
+	if err != nil {
+		c.JSON(transitionErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, trip)
+}
+
+// transitionErrorStatus maps a lifecycle error to its HTTP status
+func transitionErrorStatus(err error) int {
+	if errors.Is(err, usecases.ErrInvalidTripTransition) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/usecases"
+)
+
+type TripScheduleHandler struct {
+	scheduleUsecase *usecases.TripScheduleUsecase
+}
+
+func NewTripScheduleHandler(scheduleUsecase *usecases.TripScheduleUsecase) *TripScheduleHandler {
+	return &TripScheduleHandler{scheduleUsecase: scheduleUsecase}
+}
+
+// Create godoc
+// @Summary Create a trip schedule
+// @Description Create a timetable template; its trips up to the generation horizon are created right away
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body entities.TripSchedule true "Schedule"
+// @Success 201 {object} usecases.ScheduleSyncResult
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trip-schedules [post]
+func (h *TripScheduleHandler) Create(c *gin.Context) {
+	var schedule entities.TripSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := h.scheduleUsecase.CreateSchedule(c.Request.Context(), &schedule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// List godoc
+// @Summary List trip schedules
+// @Tags admin
+// @Produce json
+// @Param active query bool false "Only active schedules"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Schedules per page" default(50)
+// @Success 200 {object} map[string][]entities.TripSchedule
+// @Security BearerAuth
+// @Router /admin/trip-schedules [get]
+func (h *TripScheduleHandler) List(c *gin.Context) {
+	page := 1
+	limit := 50
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := parsePositiveInt(pageStr); err == nil {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := parsePositiveInt(limitStr); err == nil && l <= 100 {
+			limit = l
+		}
+	}
+
+	schedules, err := h.scheduleUsecase.ListSchedules(c.Request.Context(), c.Query("active") == "true", page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list trip schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+// GetByID godoc
+// @Summary Get a trip schedule
+// @Tags admin
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} entities.TripSchedule
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trip-schedules/{id} [get]
+func (h *TripScheduleHandler) GetByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid schedule ID"})
+		return
+	}
+
+	schedule, err := h.scheduleUsecase.GetSchedule(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Schedule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// Update godoc
+// @Summary Update a trip schedule
+// @Description Change a timetable template. Future trips without bookings are regenerated from it; trips with bookings are kept and listed.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Param request body entities.TripSchedule true "Schedule"
+// @Success 200 {object} usecases.ScheduleSyncResult
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trip-schedules/{id} [put]
+func (h *TripScheduleHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid schedule ID"})
+		return
+	}
+
+	var schedule entities.TripSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	schedule.ID = id
+	result, err := h.scheduleUsecase.UpdateSchedule(c.Request.Context(), &schedule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Deactivate godoc
+// @Summary Deactivate a trip schedule
+// @Description Stop generating trips from a schedule and drop its future trips without bookings
+// @Tags admin
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} usecases.ScheduleSyncResult
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/trip-schedules/{id} [delete]
+func (h *TripScheduleHandler) Deactivate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid schedule ID"})
+		return
+	}
+
+	result, err := h.scheduleUsecase.DeactivateSchedule(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListHolidays godoc
+// @Summary List holidays
+// @Description Holidays that schedules with skip_holidays do not run on
+// @Tags admin
+// @Produce json
+// @Param from query string false "First date, YYYY-MM-DD"
+// @Param to query string false "Last date, YYYY-MM-DD"
+// @Success 200 {object} map[string][]entities.Holiday
+// @Security BearerAuth
+// @Router /admin/holidays [get]
+func (h *TripScheduleHandler) ListHolidays(c *gin.Context) {
+	holidays, err := h.scheduleUsecase.ListHolidays(c.Request.Context(), c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to list holidays"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"holidays": holidays})
+}
+
+// CreateHoliday godoc
+// @Summary Add a holiday
+// @Description Trips already generated on the date are not removed
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body entities.Holiday true "Holiday"
+// @Success 201 {object} entities.Holiday
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/holidays [post]
+func (h *TripScheduleHandler) CreateHoliday(c *gin.Context) {
+	var holiday entities.Holiday
+	if err := c.ShouldBindJSON(&holiday); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.scheduleUsecase.CreateHoliday(c.Request.Context(), &holiday); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, holiday)
+}
+
+// DeleteHoliday godoc
+// @Summary Remove a holiday
+// @Tags admin
+// @Produce json
+// @Param id path string true "Holiday ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/holidays/{id} [delete]
+func (h *TripScheduleHandler) DeleteHoliday(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid holiday ID"})
+		return
+	}
+
+	if err := h.scheduleUsecase.DeleteHoliday(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{Message: "Holiday deleted successfully"})
+}
@@ -132,9 +132,22 @@ func (h *Hub) subscribeToTripUpdates(tripID string) {
 	pubsub := h.cache.SubscribeTripUpdates(ctx, tripUUID)
 	defer pubsub.Close()
 
+	statusChannel := cache.TripStatusChannel(tripUUID)
 	ch := pubsub.Channel()
 	for msg := range ch {
 		// Broadcast to all clients in this room
+		if msg.Channel == statusChannel {
+			var event entities.TripEventPayload
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err == nil {
+				message, _ := json.Marshal(map[string]interface{}{
+					"type": "trip_update",
+					"data": event,
+				})
+				h.broadcast(tripID, message)
+			}
+			continue
+		}
+
 		var seat entities.SeatInfo
 		if err := json.Unmarshal([]byte(msg.Payload), &seat); err == nil {
 			message, _ := json.Marshal(map[string]interface{}{
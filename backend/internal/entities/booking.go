@@ -1,6 +1,8 @@
 package entities
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,10 +22,37 @@ const (
 
 // PassengerInfo represents passenger details for a booking
 type PassengerInfo struct {
-	Name   string `json:"name"`
-	Phone  string `json:"phone"`
-	Email  string `json:"email"`
-	IDCard string `json:"id_card,omitempty"`
+	Name       string       `json:"name"`
+	Phone      string       `json:"phone"`
+	Email      string       `json:"email"`
+	IDCard     string       `json:"id_card,omitempty"`
+	Age        *int         `json:"age,omitempty"`
+	Category   FareCategory `json:"category"`
+	SeatNumber string       `json:"seat_number,omitempty"` // Empty for passengers without a seat (lap infants)
+	Fare       float64      `json:"fare"`
+}
+
+// Passengers is the JSONB list of passengers on a booking
+type Passengers []PassengerInfo
+
+// Scan implements sql.Scanner interface for JSONB
+func (p *Passengers) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, p)
+}
+
+// Value implements driver.Valuer interface for JSONB
+func (p Passengers) Value() (driver.Value, error) {
+	if p == nil {
+		return "[]", nil
+	}
+	return json.Marshal(p)
 }
 
 // Booking represents a ticket booking
@@ -35,16 +64,46 @@ type Booking struct {
 	ContactPhone string        `json:"contact_phone" gorm:"not null"`
 	ContactName  string        `json:"contact_name" gorm:"not null"`
 	Seats        []string      `json:"seats" gorm:"type:text[];not null"` // ["A1", "A2"]
+	Passengers   Passengers    `json:"passengers" gorm:"type:jsonb"`
 	TotalPrice   float64       `json:"total_price" gorm:"not null"`
 	Status       BookingStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
 	ExpiresAt    *time.Time    `json:"expires_at,omitempty" gorm:"index"`        // For pending bookings
 	BookingCode  string        `json:"booking_code" gorm:"uniqueIndex;not null"` // Human-readable code
+	LockID       *uuid.UUID    `json:"-" gorm:"type:uuid"`                       // Token of the seat locks in Redis and PostgreSQL
+
+	// Who asked for the hold, for anti-hoarding limits and fraud review
+	ClientIP string `json:"-" gorm:"type:varchar(45)"`
+	DeviceID string `json:"-" gorm:"type:varchar(100)"`
+
+	// Round trips: the return leg points at the outbound booking, which carries the payment
+	ParentBookingID *uuid.UUID `json:"parent_booking_id,omitempty" gorm:"type:uuid;index"`
+	DiscountAmount  float64    `json:"discount_amount" gorm:"not null;default:0"` // Already deducted from TotalPrice
+
+	// Where the passengers board and leave the bus; nil means the route's
+	// origin and destination
+	PickupStopID  *uuid.UUID `json:"pickup_stop_id,omitempty" gorm:"type:uuid"`
+	DropoffStopID *uuid.UUID `json:"dropoff_stop_id,omitempty" gorm:"type:uuid"`
+
+	// The stop points the seats are taken between, as in StopRange
+	FromStop int `json:"from_stop" gorm:"not null;default:0"`
+	ToStop   int `json:"to_stop" gorm:"not null;default:1"`
+
+	// Promo code redeemed for the journey; set on the outbound booking only
+	PromoCode     string  `json:"promo_code,omitempty" gorm:"type:varchar(50)"`
+	PromoDiscount float64 `json:"promo_discount" gorm:"not null;default:0"` // Deducted from AmountDue
+
+	// Loyalty points spent at payment; set on the outbound booking only
+	PointsRedeemed int     `json:"points_redeemed" gorm:"not null;default:0"`
+	PointsDiscount float64 `json:"points_discount" gorm:"not null;default:0"` // Deducted from AmountDue
 
 	// Associations
-	Trip    *Trip    `json:"trip,omitempty" gorm:"foreignKey:TripID"`
-	User    *User    `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Payment *Payment `json:"payment,omitempty" gorm:"foreignKey:BookingID"`
-	Tickets []Ticket `json:"tickets,omitempty" gorm:"foreignKey:BookingID"`
+	Trip          *Trip      `json:"trip,omitempty" gorm:"foreignKey:TripID"`
+	User          *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Payment       *Payment   `json:"payment,omitempty" gorm:"foreignKey:BookingID"`
+	Tickets       []Ticket   `json:"tickets,omitempty" gorm:"foreignKey:BookingID"`
+	ReturnBooking *Booking   `json:"return_booking,omitempty" gorm:"foreignKey:ParentBookingID"`
+	PickupStop    *RouteStop `json:"pickup_stop,omitempty" gorm:"foreignKey:PickupStopID"`
+	DropoffStop   *RouteStop `json:"dropoff_stop,omitempty" gorm:"foreignKey:DropoffStopID"`
 
 	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
@@ -63,3 +122,45 @@ func (b *Booking) IsExpired() bool {
 	}
 	return time.Now().After(*b.ExpiresAt)
 }
+
+// StopRange returns the part of the trip the booking holds its seats on
+func (b *Booking) StopRange() StopRange {
+	return StopRange{From: b.FromStop, To: b.ToStop}
+}
+
+// IsReturnLeg reports whether the booking is the return half of a round trip
+func (b *Booking) IsReturnLeg() bool {
+	return b.ParentBookingID != nil
+}
+
+// Legs returns the booking followed by its return leg, if one is loaded
+func (b *Booking) Legs() []*Booking {
+	if b.ReturnBooking != nil {
+		return []*Booking{b, b.ReturnBooking}
+	}
+	return []*Booking{b}
+}
+
+// InLocation puts the departure and arrival times of every loaded leg's trip
+// in location
+func (b *Booking) InLocation(location *time.Location) {
+	for _, leg := range b.Legs() {
+		if leg.Trip != nil {
+			leg.Trip.InLocation(location)
+		}
+	}
+}
+
+// Subtotal is the combined price of every loaded leg before any promo code
+func (b *Booking) Subtotal() float64 {
+	total := 0.0
+	for _, leg := range b.Legs() {
+		total += leg.TotalPrice
+	}
+	return total
+}
+
+// AmountDue is the subtotal less the promo and loyalty points discounts
+func (b *Booking) AmountDue() float64 {
+	return b.Subtotal() - b.PromoDiscount - b.PointsDiscount
+}
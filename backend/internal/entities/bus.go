@@ -18,13 +18,109 @@ const (
 	BusStatusInactive    BusStatus = "inactive"
 )
 
+// Non-seat cells of a seat layout
+const (
+	SeatCellAisle  = "aisle"
+	SeatCellEmpty  = "empty"
+	SeatCellToilet = "toilet"
+)
+
 // SeatLayout represents the seat configuration of a bus
 type SeatLayout struct {
 	Rows       int        `json:"rows"`
 	Columns    int        `json:"columns"`
 	TotalSeats int        `json:"total_seats"`
 	Floors     int        `json:"floors"` // 1 or 2 for single/double decker
-	Layout     [][]string `json:"layout"` // 2D array: "A1", "A2", "aisle", "empty"
+	Layout     [][]string `json:"layout"` // 2D array: "A1", "A2", "aisle", "empty", "toilet"; a double decker lists the lower deck's rows first
+}
+
+// SeatPosition locates a seat within a bus
+type SeatPosition struct {
+	SeatNumber string `json:"seat_number"`
+	Floor      int    `json:"floor"` // 1 is the lower deck
+	Row        int    `json:"row"`   // From the front of the deck, starting at 0
+	Column     int    `json:"column"`
+	Side       int    `json:"side"` // Seats on the same side of the aisle share a side number
+	Window     bool   `json:"window"`
+	NearToilet bool   `json:"near_toilet"`
+}
+
+// IsSeatCell reports whether a layout cell holds a seat
+func IsSeatCell(cell string) bool {
+	return cell != "" && cell != SeatCellAisle && cell != SeatCellEmpty && cell != SeatCellToilet
+}
+
+// Positions maps every seat of the layout to its position. A double decker's
+// layout holds Rows rows per deck; if it holds fewer, all seats are taken to
+// be on one deck.
+func (sl SeatLayout) Positions() map[string]SeatPosition {
+	rowsPerFloor := sl.rowsPerFloor()
+
+	positions := make(map[string]SeatPosition)
+	for i, row := range sl.Layout {
+		floor, rowIndex := 1, i
+		if rowsPerFloor > 0 {
+			floor, rowIndex = i/rowsPerFloor+1, i%rowsPerFloor
+		}
+
+		side := 0
+		for col, cell := range row {
+			if cell == SeatCellAisle {
+				side++
+			}
+			if !IsSeatCell(cell) {
+				continue
+			}
+			positions[cell] = SeatPosition{
+				SeatNumber: cell,
+				Floor:      floor,
+				Row:        rowIndex,
+				Column:     col,
+				Side:       side,
+				Window:     col == 0 || col == len(row)-1,
+				NearToilet: sl.hasToiletNear(i, rowsPerFloor),
+			}
+		}
+	}
+	return positions
+}
+
+// Decks splits the layout rows by deck, lower deck first, in the same way as Positions
+func (sl SeatLayout) Decks() [][][]string {
+	rowsPerFloor := sl.rowsPerFloor()
+	if rowsPerFloor == 0 {
+		return nil
+	}
+
+	var decks [][][]string
+	for start := 0; start < len(sl.Layout); start += rowsPerFloor {
+		end := min(start+rowsPerFloor, len(sl.Layout))
+		decks = append(decks, sl.Layout[start:end])
+	}
+	return decks
+}
+
+func (sl SeatLayout) rowsPerFloor() int {
+	if sl.Floors > 1 && sl.Rows > 0 && len(sl.Layout) >= sl.Rows*sl.Floors {
+		return sl.Rows
+	}
+	return len(sl.Layout)
+}
+
+// hasToiletNear reports whether a toilet is in the given layout row or the rows
+// either side of it on the same deck
+func (sl SeatLayout) hasToiletNear(index, rowsPerFloor int) bool {
+	for i := index - 1; i <= index+1; i++ {
+		if i < 0 || i >= len(sl.Layout) || (rowsPerFloor > 0 && i/rowsPerFloor != index/rowsPerFloor) {
+			continue
+		}
+		for _, cell := range sl.Layout[i] {
+			if cell == SeatCellToilet {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // Scan implements sql.Scanner interface for JSONB
@@ -44,6 +140,35 @@ func (sl SeatLayout) Value() (driver.Value, error) {
 	return json.Marshal(sl)
 }
 
+// SeatBlock is a seat an operator keeps off sale on every trip of a bus
+type SeatBlock struct {
+	SeatNumber string `json:"seat_number"`
+	Reason     string `json:"reason"`
+}
+
+// SeatBlocks is the JSONB list of a bus's default blocked seats
+type SeatBlocks []SeatBlock
+
+// Scan implements sql.Scanner interface for JSONB
+func (sb *SeatBlocks) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, sb)
+}
+
+// Value implements driver.Valuer interface for JSONB
+func (sb SeatBlocks) Value() (driver.Value, error) {
+	if sb == nil {
+		return "[]", nil
+	}
+	return json.Marshal(sb)
+}
+
 // Bus represents a bus/vehicle in the fleet
 type Bus struct {
 	ID              uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
@@ -54,7 +179,9 @@ type Bus struct {
 	Year            int            `json:"year"`
 	OperatorName    string         `json:"operator_name" gorm:"type:varchar(255)"` // Vietnamese bus operator name
 	SeatLayout      SeatLayout     `json:"seat_layout" gorm:"type:jsonb;not null"`
-	Amenities       pq.StringArray `json:"amenities" gorm:"type:text[]"` // ["wifi", "ac", "charging"]
+	Amenities       pq.StringArray `json:"amenities" gorm:"type:text[]"`              // ["wifi", "ac", "charging"]
+	Rating          *float64       `json:"rating,omitempty" gorm:"type:decimal(2,1)"` // Average passenger rating from 1 to 5, unset until rated
+	BlockedSeats    SeatBlocks     `json:"blocked_seats" gorm:"type:jsonb"`           // Blocked on every new trip of the bus
 	Status          BusStatus      `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
 	LastMaintenance *time.Time     `json:"last_maintenance,omitempty"`
 	CreatedAt       time.Time      `json:"created_at" gorm:"autoCreateTime"`
@@ -0,0 +1,69 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SeatMoveMethod tells how a passenger's seat on the new bus was chosen
+type SeatMoveMethod string
+
+const (
+	SeatMoveByLabel    SeatMoveMethod = "label"    // The new bus has a seat with the same number
+	SeatMoveByPosition SeatMoveMethod = "position" // Nearest free seat to where the old one was
+	SeatMoveUnseated   SeatMoveMethod = "unseated" // No seat left; staff must sort it out
+)
+
+// SeatMove is one seat of a booking carried over to the new bus
+type SeatMove struct {
+	BookingID     uuid.UUID      `json:"booking_id"`
+	BookingCode   string         `json:"booking_code"`
+	PassengerName string         `json:"passenger_name,omitempty"`
+	OldSeat       string         `json:"old_seat"`
+	NewSeat       string         `json:"new_seat,omitempty"` // Empty when unseated
+	Method        SeatMoveMethod `json:"method"`
+}
+
+// SeatMoves is the JSONB list of seat moves of a bus swap
+type SeatMoves []SeatMove
+
+// Scan implements sql.Scanner interface for JSONB
+func (sm *SeatMoves) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, sm)
+}
+
+// Value implements driver.Valuer interface for JSONB
+func (sm SeatMoves) Value() (driver.Value, error) {
+	if sm == nil {
+		return "[]", nil
+	}
+	return json.Marshal(sm)
+}
+
+// BusSwap records the bus of a trip being replaced and where its passengers went
+type BusSwap struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TripID    uuid.UUID  `json:"trip_id" gorm:"type:uuid;not null;index"`
+	OldBusID  uuid.UUID  `json:"old_bus_id" gorm:"type:uuid;not null"`
+	NewBusID  uuid.UUID  `json:"new_bus_id" gorm:"type:uuid;not null"`
+	Reason    string     `json:"reason"`
+	SwappedBy *uuid.UUID `json:"swapped_by,omitempty" gorm:"type:uuid"`
+	Moves     SeatMoves  `json:"moves" gorm:"type:jsonb"`
+	Unseated  int        `json:"unseated" gorm:"not null;default:0"` // Seats in Moves that could not be carried over
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName overrides the table name
+func (BusSwap) TableName() string {
+	return "bus_swaps"
+}
@@ -0,0 +1,79 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LicenceClass is a Vietnamese driving licence class. Only the classes that
+// allow driving passenger buses are listed.
+type LicenceClass string
+
+const (
+	LicenceClassD LicenceClass = "D" // Buses with 10 to 30 seats
+	LicenceClassE LicenceClass = "E" // Buses with more than 30 seats
+)
+
+// maxSeatsClassD is the largest bus a class D licence allows
+const maxSeatsClassD = 30
+
+// RequiredLicenceClass returns the licence class needed to drive a bus with the given seats
+func RequiredLicenceClass(seats int) LicenceClass {
+	if seats > maxSeatsClassD {
+		return LicenceClassE
+	}
+	return LicenceClassD
+}
+
+// Covers reports whether a licence of class c allows driving buses that need required
+func (c LicenceClass) Covers(required LicenceClass) bool {
+	switch c {
+	case LicenceClassE:
+		return required == LicenceClassD || required == LicenceClassE
+	case LicenceClassD:
+		return required == LicenceClassD
+	}
+	return false
+}
+
+// IsValid reports whether c is a known licence class
+func (c LicenceClass) IsValid() bool {
+	return c == LicenceClassD || c == LicenceClassE
+}
+
+// DriverStatus says whether a driver can be given trips
+type DriverStatus string
+
+const (
+	DriverStatusActive   DriverStatus = "active"
+	DriverStatusInactive DriverStatus = "inactive"
+)
+
+// DriverRole is the part a driver plays on a trip
+type DriverRole string
+
+const (
+	DriverRoleMain DriverRole = "main"
+	DriverRoleCo   DriverRole = "co_driver"
+)
+
+// Driver is a bus driver employed by an operator
+type Driver struct {
+	ID            uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	OperatorName  string       `json:"operator_name" gorm:"type:varchar(255);not null;index"` // Matches Bus.OperatorName
+	FullName      string       `json:"full_name" gorm:"not null"`
+	Phone         string       `json:"phone" gorm:"type:varchar(20);not null"`
+	Email         string       `json:"email,omitempty"`
+	LicenceNumber string       `json:"licence_number" gorm:"type:varchar(20);uniqueIndex;not null"`
+	LicenceClass  LicenceClass `json:"licence_class" gorm:"type:varchar(2);not null"`
+	LicenceExpiry time.Time    `json:"licence_expiry" gorm:"not null"`
+	Status        DriverStatus `json:"status" gorm:"type:varchar(20);not null;default:'active';index"`
+	CreatedAt     time.Time    `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (Driver) TableName() string {
+	return "drivers"
+}
@@ -0,0 +1,105 @@
+package entities
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FareCategory is the passenger type a fare is charged for
+type FareCategory string
+
+const (
+	FareCategoryAdult   FareCategory = "adult"
+	FareCategoryChild   FareCategory = "child"
+	FareCategorySenior  FareCategory = "senior"
+	FareCategoryStudent FareCategory = "student"
+	FareCategoryInfant  FareCategory = "infant"
+)
+
+// IsValid reports whether c is a known fare category
+func (c FareCategory) IsValid() bool {
+	switch c {
+	case FareCategoryAdult, FareCategoryChild, FareCategorySenior, FareCategoryStudent, FareCategoryInfant:
+		return true
+	}
+	return false
+}
+
+// FareDiscountType selects how a fare rule reduces the trip price
+type FareDiscountType string
+
+const (
+	FareDiscountPercentage FareDiscountType = "percentage"
+	FareDiscountFixed      FareDiscountType = "fixed"
+)
+
+// FareRule defines the discount and eligibility of a fare category. A rule can
+// be scoped to an operator and/or a route; unscoped fields apply to all, and the
+// most specific active rule for a trip wins.
+type FareRule struct {
+	ID            uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Category      FareCategory     `json:"category" gorm:"type:varchar(20);not null;index"`
+	OperatorName  *string          `json:"operator_name,omitempty" gorm:"type:varchar(255)"` // Matches Bus.OperatorName
+	RouteID       *uuid.UUID       `json:"route_id,omitempty" gorm:"type:uuid;index"`
+	DiscountType  FareDiscountType `json:"discount_type" gorm:"type:varchar(20);not null"`
+	DiscountValue float64          `json:"discount_value" gorm:"not null"`   // Percent (0-100) or VND amount
+	MinAge        *int             `json:"min_age,omitempty"`                // Inclusive
+	MaxAge        *int             `json:"max_age,omitempty"`                // Inclusive
+	RequiresID    bool             `json:"requires_id" gorm:"default:false"` // e.g. student card, citizen ID for seniors
+	RequiresSeat  bool             `json:"requires_seat" gorm:"not null"`    // false for lap infants
+	IsActive      bool             `json:"is_active" gorm:"default:true"`
+	CreatedAt     time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (FareRule) TableName() string {
+	return "fare_rules"
+}
+
+// Specificity ranks rules so route-specific ones beat operator-wide ones,
+// which beat global ones
+func (r *FareRule) Specificity() int {
+	score := 0
+	if r.RouteID != nil {
+		score += 2
+	}
+	if r.OperatorName != nil {
+		score++
+	}
+	return score
+}
+
+// Fare applies the rule's discount to a base price, rounded to whole dong
+func (r *FareRule) Fare(basePrice float64) float64 {
+	fare := basePrice
+	switch r.DiscountType {
+	case FareDiscountPercentage:
+		fare = basePrice * (1 - r.DiscountValue/100)
+	case FareDiscountFixed:
+		fare = basePrice - r.DiscountValue
+	}
+	return math.Max(0, math.Round(fare))
+}
+
+// AcceptsAge reports whether a passenger of the given age qualifies
+func (r *FareRule) AcceptsAge(age int) bool {
+	if r.MinAge != nil && age < *r.MinAge {
+		return false
+	}
+	if r.MaxAge != nil && age > *r.MaxAge {
+		return false
+	}
+	return true
+}
+
+// FareCalendarDay sums up the departures between two cities on one day in the
+// business timezone
+type FareCalendarDay struct {
+	Date        string   `json:"date"`         // YYYY-MM-DD
+	Departures  int      `json:"departures"`   // Bookable trips, sold out or not
+	Available   int      `json:"available"`    // Trips with a seat free over the whole trip
+	LowestPrice *float64 `json:"lowest_price"` // Cheapest adult fare among the available trips, null when all are sold out
+}
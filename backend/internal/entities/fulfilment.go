@@ -0,0 +1,58 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FulfilmentStatus represents the overall state of a payment-to-ticket fulfilment
+type FulfilmentStatus string
+
+const (
+	FulfilmentStatusPending     FulfilmentStatus = "pending"
+	FulfilmentStatusCompleted   FulfilmentStatus = "completed"
+	FulfilmentStatusCompensated FulfilmentStatus = "compensated"
+	FulfilmentStatusFailed      FulfilmentStatus = "failed"
+)
+
+// FulfilmentStep represents the next step a fulfilment has to run
+type FulfilmentStep string
+
+const (
+	FulfilmentStepConfirm    FulfilmentStep = "confirm"    // Confirm booking, mark seats booked, create tickets
+	FulfilmentStepDocuments  FulfilmentStep = "documents"  // Generate ticket PDFs
+	FulfilmentStepEmail      FulfilmentStep = "email"      // Email tickets to the customer
+	FulfilmentStepCompensate FulfilmentStep = "compensate" // Refund and release after an unrecoverable failure
+	FulfilmentStepDone       FulfilmentStep = "done"
+)
+
+// Fulfilment tracks the post-payment pipeline for a booking so that each
+// step runs once and failed steps can be retried
+type Fulfilment struct {
+	ID            uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	BookingID     uuid.UUID        `json:"booking_id" gorm:"type:uuid;not null;uniqueIndex"`
+	PaymentID     uuid.UUID        `json:"payment_id" gorm:"type:uuid;not null"`
+	Status        FulfilmentStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	Step          FulfilmentStep   `json:"step" gorm:"type:varchar(20);not null;default:'confirm'"`
+	Attempts      int              `json:"attempts" gorm:"not null;default:0"`
+	LastError     *string          `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time       `json:"next_attempt_at,omitempty" gorm:"index"`
+	CompletedAt   *time.Time       `json:"completed_at,omitempty"`
+
+	// Associations
+	Booking *Booking `json:"booking,omitempty" gorm:"foreignKey:BookingID"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (Fulfilment) TableName() string {
+	return "fulfilments"
+}
+
+// IsFinished checks if the fulfilment no longer needs processing
+func (f *Fulfilment) IsFinished() bool {
+	return f.Status != FulfilmentStatusPending
+}
@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HoldPolicy names an anti-hoarding limit on seat holds
+type HoldPolicy string
+
+const (
+	HoldPolicySeatsPerBooking HoldPolicy = "seats_per_booking" // Too many seats in one booking
+	HoldPolicyPendingBookings HoldPolicy = "pending_bookings"  // Too many unpaid bookings at once
+	HoldPolicyTripSeats       HoldPolicy = "trip_seats"        // Too many seats held on one trip
+	HoldPolicyCooldown        HoldPolicy = "cooldown"          // Held seats expired unpaid too often
+)
+
+// HoldViolation records a seat hold refused by an anti-hoarding limit, for
+// fraud review. Identity fields are whatever the request carried.
+type HoldViolation struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Policy     HoldPolicy `json:"policy" gorm:"type:varchar(30);not null;index"`
+	Identity   string     `json:"identity" gorm:"not null;index"` // The identity that hit the limit, e.g. "ip:203.0.113.7"
+	UserID     *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid;index"`
+	ClientIP   string     `json:"client_ip" gorm:"type:varchar(45)"`
+	DeviceID   string     `json:"device_id,omitempty" gorm:"type:varchar(100)"`
+	TripID     uuid.UUID  `json:"trip_id" gorm:"type:uuid;not null"`
+	SeatCount  int        `json:"seat_count" gorm:"not null"` // Seats requested across all legs
+	Limit      int        `json:"limit" gorm:"not null"`
+	RetryAfter int        `json:"retry_after_seconds,omitempty"` // Remaining cooldown, if any
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// TableName overrides the table name
+func (HoldViolation) TableName() string {
+	return "hold_violations"
+}
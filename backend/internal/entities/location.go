@@ -0,0 +1,109 @@
+package entities
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Location is a city or town of the catalog routes run between. Customers
+// find it by its name or any of its aliases, compared in their folded form
+// (see FoldLocationName), so "Sài Gòn", "Sai Gon" and "HCM" can all name
+// TP. Hồ Chí Minh.
+type Location struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name       string    `json:"name" gorm:"not null"`                   // Canonical name, e.g. "TP. Hồ Chí Minh"
+	SearchName string    `json:"-" gorm:"not null;uniqueIndex"`          // Folded Name
+	Province   string    `json:"province"`                               // e.g. "Lâm Đồng" for Đà Lạt
+	IsActive   bool      `json:"is_active" gorm:"not null;default:true"` // Inactive locations are not suggested
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Aliases []LocationAlias `json:"aliases,omitempty" gorm:"foreignKey:LocationID"`
+}
+
+// TableName overrides the table name
+func (Location) TableName() string {
+	return "locations"
+}
+
+// LocationAlias is another name customers use for a location: a spelling
+// without diacritics, a former name or an abbreviation
+type LocationAlias struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	LocationID uuid.UUID `json:"location_id" gorm:"type:uuid;not null;index"`
+	Name       string    `json:"name" gorm:"not null"`          // e.g. "Sài Gòn", "HCM"
+	SearchName string    `json:"-" gorm:"not null;uniqueIndex"` // Folded Name
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName overrides the table name
+func (LocationAlias) TableName() string {
+	return "location_aliases"
+}
+
+// LocationMatch is a location suggested for what a customer typed
+type LocationMatch struct {
+	*Location
+	MatchedName string  `json:"matched_name"` // The name or alias that matched
+	Score       float64 `json:"score"`        // Higher is better: 3 exact, 2 prefix, 1 word prefix, below 1 fuzzy
+}
+
+// vietnameseLetters maps the Vietnamese letters with diacritics to the
+// letter they are written on
+var vietnameseLetters = func() map[rune]rune {
+	folds := map[rune]string{
+		'a': "àáảãạăằắẳẵặâầấẩẫậ",
+		'd': "đ",
+		'e': "èéẻẽẹêềếểễệ",
+		'i': "ìíỉĩị",
+		'o': "òóỏõọôồốổỗộơờớởỡợ",
+		'u': "ùúủũụưừứửữự",
+		'y': "ỳýỷỹỵ",
+	}
+	letters := make(map[rune]rune)
+	for base, marked := range folds {
+		for _, r := range marked {
+			letters[r] = base
+		}
+	}
+	return letters
+}()
+
+// administrativePrefixes are the folded words place names are often written
+// with, as in "TP. Hồ Chí Minh" or "Tỉnh Lâm Đồng", which customers leave out
+var administrativePrefixes = []string{"thanh pho ", "tp ", "tinh ", "thi xa ", "tx ", "thi tran "}
+
+// FoldLocationName folds a place name for matching: lower case without
+// diacritics, đ as d, punctuation as spaces, and no leading administrative
+// prefix, so "TP. Hồ Chí Minh" folds to "ho chi minh". Text typed with
+// combining marks folds the same as precomposed text.
+func FoldLocationName(name string) string {
+	var b strings.Builder
+	space := true // Drops leading and repeated spaces
+	for _, r := range strings.ToLower(name) {
+		if base, ok := vietnameseLetters[r]; ok {
+			r = base
+		}
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			space = false
+		case !space:
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	folded := strings.TrimSuffix(b.String(), " ")
+
+	for _, prefix := range administrativePrefixes {
+		if rest := strings.TrimPrefix(folded, prefix); rest != folded && rest != "" {
+			return rest
+		}
+	}
+	return folded
+}
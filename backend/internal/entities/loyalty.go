@@ -0,0 +1,124 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoyaltyTier is a member's level, earned through recent travel
+type LoyaltyTier string
+
+const (
+	LoyaltyTierMember LoyaltyTier = "member"
+	LoyaltyTierSilver LoyaltyTier = "silver"
+	LoyaltyTierGold   LoyaltyTier = "gold"
+)
+
+// LoyaltyTransactionType is the business event behind a ledger transaction
+type LoyaltyTransactionType string
+
+const (
+	LoyaltyTxnEarn           LoyaltyTransactionType = "earn"            // Booking confirmed
+	LoyaltyTxnEarnReversal   LoyaltyTransactionType = "earn_reversal"   // Booking refunded
+	LoyaltyTxnRedeem         LoyaltyTransactionType = "redeem"          // Points spent on a payment
+	LoyaltyTxnRedeemReversal LoyaltyTransactionType = "redeem_reversal" // Points returned when that booking fell through
+	LoyaltyTxnExpire         LoyaltyTransactionType = "expire"
+)
+
+// LedgerAccount names an account of the points ledger. Every member has their
+// own member account; the others are system accounts that balance it.
+type LedgerAccount string
+
+const (
+	LedgerAccountMember   LedgerAccount = "member"
+	LedgerAccountIssued   LedgerAccount = "issued"   // Source of earned points
+	LedgerAccountRedeemed LedgerAccount = "redeemed" // Points spent as discounts
+	LedgerAccountExpired  LedgerAccount = "expired"  // Points that lapsed
+)
+
+// LoyaltyAccount holds a member's running balance; its row is locked to
+// serialise ledger postings for the member
+type LoyaltyAccount struct {
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;primaryKey"`
+	Balance   int       `json:"balance" gorm:"not null;default:0"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (LoyaltyAccount) TableName() string {
+	return "loyalty_accounts"
+}
+
+// LoyaltyTransaction is one posting to the points ledger. Its entries always
+// sum to zero, and it is never updated or deleted; mistakes are corrected by
+// posting a reversal.
+type LoyaltyTransaction struct {
+	ID          uuid.UUID              `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID      uuid.UUID              `json:"user_id" gorm:"type:uuid;not null;index"`
+	BookingID   *uuid.UUID             `json:"booking_id,omitempty" gorm:"type:uuid;uniqueIndex:idx_loyalty_txn_booking_attempt"`
+	Type        LoyaltyTransactionType `json:"type" gorm:"type:varchar(20);not null;uniqueIndex:idx_loyalty_txn_booking_attempt"`
+	Points      int                    `json:"points" gorm:"not null"` // Change to the member's balance
+	Description string                 `json:"description"`
+	// Attempt counts up each time points are redeemed again on a booking
+	// after the previous redemption was returned
+	Attempt int `json:"attempt" gorm:"not null;default:0;uniqueIndex:idx_loyalty_txn_booking_attempt"`
+
+	// Associations
+	Entries []LoyaltyEntry `json:"entries,omitempty" gorm:"foreignKey:TransactionID"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// TableName overrides the table name
+func (LoyaltyTransaction) TableName() string {
+	return "loyalty_transactions"
+}
+
+// LoyaltyEntry is one side of a ledger transaction: a credit (positive) or
+// debit (negative) of points on an account
+type LoyaltyEntry struct {
+	ID            uuid.UUID     `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TransactionID uuid.UUID     `json:"transaction_id" gorm:"type:uuid;not null;index"`
+	UserID        uuid.UUID     `json:"user_id" gorm:"type:uuid;not null;index"`
+	Account       LedgerAccount `json:"account" gorm:"type:varchar(20);not null"`
+	Amount        int           `json:"amount" gorm:"not null"`
+	ExpiresAt     *time.Time    `json:"expires_at,omitempty" gorm:"index"` // Set on member credits
+	CreatedAt     time.Time     `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName overrides the table name
+func (LoyaltyEntry) TableName() string {
+	return "loyalty_entries"
+}
+
+// NewLoyaltyTransaction builds a balanced transaction moving points between a
+// member's account and a system account. Positive points credit the member.
+func NewLoyaltyTransaction(userID uuid.UUID, bookingID *uuid.UUID, txnType LoyaltyTransactionType, points int, counterpart LedgerAccount, expiresAt *time.Time, description string) *LoyaltyTransaction {
+	member := LoyaltyEntry{UserID: userID, Account: LedgerAccountMember, Amount: points}
+	if points > 0 {
+		member.ExpiresAt = expiresAt
+	}
+
+	return &LoyaltyTransaction{
+		UserID:      userID,
+		BookingID:   bookingID,
+		Type:        txnType,
+		Points:      points,
+		Description: description,
+		Entries: []LoyaltyEntry{
+			member,
+			{UserID: userID, Account: counterpart, Amount: -points},
+		},
+	}
+}
+
+// IsBalanced reports whether the transaction's entries sum to zero
+func (t *LoyaltyTransaction) IsBalanced() bool {
+	sum := 0
+	for _, entry := range t.Entries {
+		sum += entry.Amount
+	}
+	return len(t.Entries) >= 2 && sum == 0
+}
@@ -0,0 +1,115 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType identifies a domain event
+type EventType string
+
+const (
+	EventBookingCreated    EventType = "BookingCreated"
+	EventBookingConfirmed  EventType = "BookingConfirmed"
+	EventSeatsReleased     EventType = "SeatsReleased"
+	EventSeatsBlocked      EventType = "SeatsBlocked"
+	EventSeatsUnblocked    EventType = "SeatsUnblocked"
+	EventPaymentCompleted  EventType = "PaymentCompleted"
+	EventPaymentRefunded   EventType = "PaymentRefunded"
+	EventTripDelayed       EventType = "TripDelayed"
+	EventTripStatusChanged EventType = "TripStatusChanged"
+	EventTripCancelled     EventType = "TripCancelled"
+	EventTripBusReplaced   EventType = "TripBusReplaced"
+)
+
+// Aggregate types that events are published under
+const (
+	AggregateBooking = "booking"
+	AggregatePayment = "payment"
+	AggregateTrip    = "trip"
+)
+
+// OutboxEvent is a domain event stored in the same transaction as the state
+// change that produced it, and relayed to the event stream afterwards.
+// The serial ID gives the publish order.
+type OutboxEvent struct {
+	ID            int64      `json:"id" gorm:"primaryKey;autoIncrement"`
+	EventID       uuid.UUID  `json:"event_id" gorm:"type:uuid;not null;uniqueIndex"` // Deduplication key for consumers
+	AggregateType string     `json:"aggregate_type" gorm:"type:varchar(50);not null"`
+	AggregateID   uuid.UUID  `json:"aggregate_id" gorm:"type:uuid;not null;index"`
+	EventType     EventType  `json:"event_type" gorm:"type:varchar(50);not null"`
+	Payload       string     `json:"payload" gorm:"type:jsonb;not null"`
+	OccurredAt    time.Time  `json:"occurred_at" gorm:"not null"`
+	PublishedAt   *time.Time `json:"published_at,omitempty" gorm:"index"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0"`
+	LastError     *string    `json:"last_error,omitempty"`
+	ClaimedUntil  *time.Time `json:"claimed_until,omitempty"` // Lease of the relay publishing it
+}
+
+// TableName overrides the table name
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// NewOutboxEvent builds an unpublished event with a JSON-encoded payload
+func NewOutboxEvent(aggregateType string, aggregateID uuid.UUID, eventType EventType, payload interface{}) (*OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{
+		EventID:       uuid.New(),
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       string(data),
+		OccurredAt:    time.Now(),
+	}, nil
+}
+
+// SeatsEventPayload describes seats of a booking changing status
+type SeatsEventPayload struct {
+	BookingID     uuid.UUID     `json:"booking_id"`
+	TripID        uuid.UUID     `json:"trip_id"`
+	Seats         []string      `json:"seats"`
+	StopRange                   // The segments of the seats that changed
+	SeatStatus    SeatStatus    `json:"seat_status"`
+	BookingStatus BookingStatus `json:"booking_status"`
+}
+
+// BusSwapEventPayload describes the bus of a trip being replaced and where its passengers went
+type BusSwapEventPayload struct {
+	TripID   uuid.UUID `json:"trip_id"`
+	OldBusID uuid.UUID `json:"old_bus_id"`
+	NewBusID uuid.UUID `json:"new_bus_id"`
+	Moves    SeatMoves `json:"moves"`
+}
+
+// SeatBlockEventPayload describes an operator blocking or unblocking seats of a trip
+type SeatBlockEventPayload struct {
+	TripID       uuid.UUID  `json:"trip_id"`
+	Seats        []string   `json:"seats"`
+	SeatStatus   SeatStatus `json:"seat_status"`
+	Reason       string     `json:"reason,omitempty"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+}
+
+// PaymentEventPayload describes a payment state change
+type PaymentEventPayload struct {
+	PaymentID uuid.UUID     `json:"payment_id"`
+	BookingID uuid.UUID     `json:"booking_id"`
+	Amount    float64       `json:"amount"`
+	Status    PaymentStatus `json:"status"`
+}
+
+// TripEventPayload describes a trip schedule or status change
+type TripEventPayload struct {
+	TripID         uuid.UUID  `json:"trip_id"`
+	Status         TripStatus `json:"status"`
+	PreviousStatus TripStatus `json:"previous_status,omitempty"`
+	DepartureTime  time.Time  `json:"departure_time"`
+	ArrivalTime    time.Time  `json:"arrival_time"`
+	Reason         string     `json:"reason,omitempty"`
+}
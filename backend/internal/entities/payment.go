@@ -21,6 +21,7 @@ const (
 	PaymentStatusPending   PaymentStatus = "pending"
 	PaymentStatusCompleted PaymentStatus = "completed"
 	PaymentStatusFailed    PaymentStatus = "failed"
+	PaymentStatusRefunding PaymentStatus = "refunding" // Claimed for a refund not yet recorded as done
 	PaymentStatusRefunded  PaymentStatus = "refunded"
 	PaymentStatusCancelled PaymentStatus = "cancelled"
 )
@@ -0,0 +1,132 @@
+package entities
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PromotionDiscountType selects how a promotion reduces the amount due
+type PromotionDiscountType string
+
+const (
+	PromotionDiscountPercentage PromotionDiscountType = "percentage"
+	PromotionDiscountFixed      PromotionDiscountType = "fixed"
+)
+
+// Promotion is a discount campaign; customers redeem it through its promo codes
+type Promotion struct {
+	ID            uuid.UUID             `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name          string                `json:"name" gorm:"not null"`
+	Description   string                `json:"description"`
+	DiscountType  PromotionDiscountType `json:"discount_type" gorm:"type:varchar(20);not null"`
+	DiscountValue float64               `json:"discount_value" gorm:"not null"` // Percent (0-100) or VND amount
+	MinSpend      float64               `json:"min_spend" gorm:"not null;default:0"`
+	MaxDiscount   *float64              `json:"max_discount,omitempty"` // Cap for percentage discounts
+	StartsAt      *time.Time            `json:"starts_at,omitempty"`
+	EndsAt        *time.Time            `json:"ends_at,omitempty"`
+
+	// Restrictions; empty means unrestricted
+	RouteIDs      pq.StringArray `json:"route_ids" gorm:"type:text[]"`
+	OperatorNames pq.StringArray `json:"operator_names" gorm:"type:text[]"`
+	DepartureFrom *time.Time     `json:"departure_from,omitempty"` // Earliest departure the promotion applies to
+	DepartureTo   *time.Time     `json:"departure_to,omitempty"`   // Latest departure the promotion applies to
+	FirstTimeOnly bool           `json:"first_time_only" gorm:"default:false"`
+
+	// Usage caps; nil means unlimited
+	UsageLimit   *int `json:"usage_limit,omitempty"`    // Across all codes of the promotion
+	PerUserLimit *int `json:"per_user_limit,omitempty"` // Per account, or per contact email for guests
+	TimesUsed    int  `json:"times_used" gorm:"not null;default:0"`
+
+	IsActive  bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (Promotion) TableName() string {
+	return "promotions"
+}
+
+// IsRunning reports whether the promotion is active at the given time
+func (p *Promotion) IsRunning(at time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+	if p.StartsAt != nil && at.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && at.After(*p.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// Discount returns the discount on an amount, rounded to whole dong and never
+// more than the amount itself
+func (p *Promotion) Discount(amount float64) float64 {
+	discount := 0.0
+	switch p.DiscountType {
+	case PromotionDiscountPercentage:
+		discount = amount * p.DiscountValue / 100
+		if p.MaxDiscount != nil && discount > *p.MaxDiscount {
+			discount = *p.MaxDiscount
+		}
+	case PromotionDiscountFixed:
+		discount = p.DiscountValue
+	}
+	return math.Min(amount, math.Round(discount))
+}
+
+// PromoCode is a code customers enter to redeem a promotion. Generated
+// vouchers are single-use codes sharing a batch ID.
+type PromoCode struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PromotionID uuid.UUID  `json:"promotion_id" gorm:"type:uuid;not null;index"`
+	Code        string     `json:"code" gorm:"type:varchar(50);uniqueIndex;not null"` // Stored upper-case
+	UsageLimit  *int       `json:"usage_limit,omitempty"`                             // nil means unlimited
+	TimesUsed   int        `json:"times_used" gorm:"not null;default:0"`
+	BatchID     *uuid.UUID `json:"batch_id,omitempty" gorm:"type:uuid;index"`
+	IsActive    bool       `json:"is_active" gorm:"default:true"`
+
+	// Associations
+	Promotion *Promotion `json:"promotion,omitempty" gorm:"foreignKey:PromotionID"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (PromoCode) TableName() string {
+	return "promo_codes"
+}
+
+// RedemptionStatus tracks whether a redemption still counts against usage caps
+type RedemptionStatus string
+
+const (
+	RedemptionStatusActive   RedemptionStatus = "active"
+	RedemptionStatusReleased RedemptionStatus = "released" // Booking cancelled, expired or refunded
+)
+
+// PromoRedemption records a promo code applied to a booking
+type PromoRedemption struct {
+	ID             uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PromotionID    uuid.UUID        `json:"promotion_id" gorm:"type:uuid;not null;index"`
+	PromoCodeID    uuid.UUID        `json:"promo_code_id" gorm:"type:uuid;not null;index"`
+	BookingID      uuid.UUID        `json:"booking_id" gorm:"type:uuid;not null;uniqueIndex"`
+	UserID         *uuid.UUID       `json:"user_id,omitempty" gorm:"type:uuid;index"`
+	ContactEmail   string           `json:"contact_email" gorm:"not null;index"`
+	DiscountAmount float64          `json:"discount_amount" gorm:"not null"`
+	Status         RedemptionStatus `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
+	ReleasedAt     *time.Time       `json:"released_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (PromoRedemption) TableName() string {
+	return "promo_redemptions"
+}
@@ -0,0 +1,63 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RouteStop is a station or roadside point a route calls at. Stops are ordered
+// by Sequence and the bus reaches each Offset minutes after the trip departs.
+type RouteStop struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	RouteID       uuid.UUID `json:"route_id" gorm:"type:uuid;not null;index"`
+	Sequence      int       `json:"sequence" gorm:"not null"`
+	Name          string    `json:"name" gorm:"not null"` // e.g., "Ben xe Giap Bat"
+	Address       string    `json:"address"`
+	City          string    `json:"city" gorm:"not null"`
+	Distance      float64   `json:"distance" gorm:"not null;default:0"` // Kilometres from the route's origin
+	Latitude      *float64  `json:"latitude,omitempty"`
+	Longitude     *float64  `json:"longitude,omitempty"`
+	Offset        int       `json:"offset_minutes" gorm:"column:offset_minutes;not null;default:0"` // Minutes after departure
+	AllowsPickup  bool      `json:"allows_pickup" gorm:"not null"`
+	AllowsDropoff bool      `json:"allows_dropoff" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (RouteStop) TableName() string {
+	return "route_stops"
+}
+
+// TimeAt returns when a trip departing at departure reaches the stop
+func (s *RouteStop) TimeAt(departure time.Time) time.Time {
+	return departure.Add(time.Duration(s.Offset) * time.Minute)
+}
+
+// Label describes the stop for tickets and manifests
+func (s *RouteStop) Label() string {
+	if s.Address == "" {
+		return s.Name
+	}
+	return s.Name + ", " + s.Address
+}
+
+// StopRange is the part of a trip between two of its stop points, by index:
+// 0 is the route's origin, its stops follow in order and the destination
+// comes last. Segment i runs from point i to point i+1, so a range takes a
+// seat on segments From to To-1.
+type StopRange struct {
+	From int `json:"from_stop"`
+	To   int `json:"to_stop"`
+}
+
+// Segments returns how many segments the range covers
+func (r StopRange) Segments() int {
+	return r.To - r.From
+}
+
+// Covers reports whether the range takes a seat on segment
+func (r StopRange) Covers(segment int) bool {
+	return segment >= r.From && segment < r.To
+}
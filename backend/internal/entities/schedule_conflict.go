@@ -0,0 +1,38 @@
+package entities
+
+import "github.com/google/uuid"
+
+// ScheduleResource is what two trips compete for
+type ScheduleResource string
+
+const (
+	ScheduleResourceBus    ScheduleResource = "bus"
+	ScheduleResourceDriver ScheduleResource = "driver"
+)
+
+// ScheduleConflictKind tells how a trip clashes with the schedule of its bus or drivers
+type ScheduleConflictKind string
+
+const (
+	ScheduleConflictOverlap    ScheduleConflictKind = "overlap"    // Both trips run at the same time
+	ScheduleConflictTurnaround ScheduleConflictKind = "turnaround" // Too little time between arriving and departing again
+	ScheduleConflictContinuity ScheduleConflictKind = "continuity" // The next trip departs from another city than the last one ended in
+
+	// Drivers only
+	ScheduleConflictLicence           ScheduleConflictKind = "licence"            // Licence of the wrong class for the bus, or expired
+	ScheduleConflictContinuousDriving ScheduleConflictKind = "continuous_driving" // Too long at the wheel without a break
+	ScheduleConflictDailyDriving      ScheduleConflictKind = "daily_driving"      // Too many hours at the wheel in a day
+	ScheduleConflictWeeklyDriving     ScheduleConflictKind = "weekly_driving"     // Too many hours at the wheel in a week
+)
+
+// ScheduleConflict is a clash between a trip and the schedule of its bus or of
+// one of its drivers
+type ScheduleConflict struct {
+	Kind              ScheduleConflictKind `json:"kind"`
+	Resource          ScheduleResource     `json:"resource"`
+	ResourceID        uuid.UUID            `json:"resource_id"`
+	TripID            *uuid.UUID           `json:"trip_id,omitempty"`             // Unset for a trip that is not created yet
+	ConflictingTripID *uuid.UUID           `json:"conflicting_trip_id,omitempty"` // The other trip, for clashes between two trips
+	Period            string               `json:"period,omitempty"`              // The day, week or stretch a driving limit is broken in
+	Message           string               `json:"message"`
+}
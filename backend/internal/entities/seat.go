@@ -13,19 +13,29 @@ const (
 	SeatStatusAvailable SeatStatus = "available"
 	SeatStatusLocked    SeatStatus = "locked"
 	SeatStatusBooked    SeatStatus = "booked"
+	SeatStatusBlocked   SeatStatus = "blocked" // Held back by the operator, e.g. for staff or because it is broken
 )
 
-// SeatInfo represents seat availability for a specific trip
+// SeatInfo represents seat availability on one segment of a trip. A trip has
+// a row per seat for each segment between consecutive stop points, so a seat
+// sold for part of the trip can be sold again for the rest.
 type SeatInfo struct {
 	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
 	TripID      uuid.UUID  `json:"trip_id" gorm:"type:uuid;not null;index:idx_trip_seat,unique"`
-	SeatNumber  string     `json:"seat_number" gorm:"not null;index:idx_trip_seat,unique"` // e.g., "A1", "B2"
+	SeatNumber  string     `json:"seat_number" gorm:"not null;index:idx_trip_seat,unique"`       // e.g., "A1", "B2"
+	Segment     int        `json:"segment" gorm:"not null;default:0;index:idx_trip_seat,unique"` // See StopRange
 	Status      SeatStatus `json:"status" gorm:"type:varchar(20);not null;default:'available';index"`
 	LockedUntil *time.Time `json:"locked_until,omitempty" gorm:"index"`
-	LockedBy    *uuid.UUID `json:"locked_by,omitempty" gorm:"type:uuid"` // User ID or session ID
+	LockedBy    *uuid.UUID `json:"locked_by,omitempty" gorm:"type:uuid"` // Lock token of the holding booking, as in Redis
 	BookingID   *uuid.UUID `json:"booking_id,omitempty" gorm:"type:uuid;index"`
-	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Set while the seat is blocked; without BlockedUntil it stays blocked until unblocked
+	BlockReason  string     `json:"block_reason,omitempty"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty" gorm:"index"`
+	BlockedBy    *uuid.UUID `json:"blocked_by,omitempty" gorm:"type:uuid"` // Admin who blocked it; empty for bus defaults
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 // TableName overrides the table name
@@ -40,3 +50,14 @@ func (s *SeatInfo) IsLockExpired() bool {
 	}
 	return time.Now().After(*s.LockedUntil)
 }
+
+// Key identifies the seat's row among the trip's seats
+func (s *SeatInfo) Key() SeatSegment {
+	return SeatSegment{SeatNumber: s.SeatNumber, Segment: s.Segment}
+}
+
+// SeatSegment identifies one segment of a seat on a trip
+type SeatSegment struct {
+	SeatNumber string
+	Segment    int
+}
@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SeatDiscrepancyKind names a way the seat state in Redis, seats_status and
+// the bookings can disagree
+type SeatDiscrepancyKind string
+
+const (
+	SeatDiscrepancyBookedWithoutBooking SeatDiscrepancyKind = "booked_without_booking" // Seat booked, but not by a live booking
+	SeatDiscrepancyConfirmedNotBooked   SeatDiscrepancyKind = "confirmed_not_booked"   // Confirmed booking whose seat is not booked for it
+	SeatDiscrepancyOrphanedDBLock       SeatDiscrepancyKind = "orphaned_db_lock"       // Seat locked by no pending booking
+	SeatDiscrepancyOrphanedCacheLock    SeatDiscrepancyKind = "orphaned_redis_lock"    // Redis lock with no matching seat lock
+	SeatDiscrepancyMissingCacheLock     SeatDiscrepancyKind = "missing_redis_lock"     // Pending booking's seat locked in the database only
+	SeatDiscrepancyStaleSeatMap         SeatDiscrepancyKind = "stale_seat_map"         // Cached seat map disagrees with the database
+)
+
+// SeatDiscrepancyResolution tells whether the reconciler fixed a discrepancy
+// itself or left it for an admin
+type SeatDiscrepancyResolution string
+
+const (
+	SeatDiscrepancyRepaired SeatDiscrepancyResolution = "repaired"
+	SeatDiscrepancyFlagged  SeatDiscrepancyResolution = "flagged"
+)
+
+// SeatDiscrepancy is one finding of the seat reconciler. A flagged
+// discrepancy found again by later runs is updated rather than duplicated
+// until an admin resolves it.
+type SeatDiscrepancy struct {
+	ID          uuid.UUID                 `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	RunID       uuid.UUID                 `json:"run_id" gorm:"type:uuid;not null;index"` // Last run that found it
+	TripID      uuid.UUID                 `json:"trip_id" gorm:"type:uuid;not null;index"`
+	SeatNumber  string                    `json:"seat_number,omitempty"` // Empty for trip-wide findings
+	Kind        SeatDiscrepancyKind       `json:"kind" gorm:"type:varchar(30);not null;index"`
+	Resolution  SeatDiscrepancyResolution `json:"resolution" gorm:"type:varchar(20);not null"`
+	BookingID   *uuid.UUID                `json:"booking_id,omitempty" gorm:"type:uuid"`
+	Details     string                    `json:"details"`
+	Occurrences int                       `json:"occurrences" gorm:"not null;default:1"`
+	LastSeenAt  time.Time                 `json:"last_seen_at" gorm:"not null"`
+	ResolvedAt  *time.Time                `json:"resolved_at,omitempty"` // Set when repaired, or when an admin closes a flagged one
+	CreatedAt   time.Time                 `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// TableName overrides the table name
+func (SeatDiscrepancy) TableName() string {
+	return "seat_discrepancies"
+}
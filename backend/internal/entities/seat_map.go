@@ -0,0 +1,44 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SeatMapCellSeat is the type of seat map cells holding a seat; other cells
+// carry their layout cell (SeatCellAisle, SeatCellEmpty or SeatCellToilet)
+const SeatMapCellSeat = "seat"
+
+// SeatMapCell is one cell of a trip's seat map
+type SeatMapCell struct {
+	Type         string     `json:"type"`
+	SeatNumber   string     `json:"seat_number,omitempty"`
+	Status       SeatStatus `json:"status,omitempty"`
+	Price        float64    `json:"price,omitempty"`
+	BlockReason  string     `json:"block_reason,omitempty"`
+	HeldByMe     bool       `json:"held_by_me,omitempty"` // Locked for the booking the request asked about
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+}
+
+// SeatMapFloor is the layout grid of one deck
+type SeatMapFloor struct {
+	Floor int             `json:"floor"` // 1 is the lower deck
+	Rows  [][]SeatMapCell `json:"rows"`
+}
+
+// SeatMap is the read model behind a trip's seat map: the bus's seat layout
+// merged with the live seat status of the trip
+type SeatMap struct {
+	TripID         uuid.UUID      `json:"trip_id"`
+	BusID          uuid.UUID      `json:"bus_id"`
+	StopRange                     // The part of the trip the statuses and prices are for
+	Columns        int            `json:"columns"`
+	Floors         []SeatMapFloor `json:"floors"`
+	Unplaced       []SeatMapCell  `json:"unplaced,omitempty"` // Seats of the trip missing from the layout
+	TotalSeats     int            `json:"total_seats"`
+	AvailableSeats int            `json:"available_seats"`
+	Version        string         `json:"version"` // Changes whenever anything else in the map does
+	GeneratedAt    time.Time      `json:"generated_at"`
+}
@@ -8,17 +8,20 @@ import (
 
 // Ticket represents an e-ticket for a passenger
 type Ticket struct {
-	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	BookingID      uuid.UUID  `json:"booking_id" gorm:"type:uuid;not null;index"`
-	PassengerName  string     `json:"passenger_name" gorm:"not null"`
-	PassengerPhone string     `json:"passenger_phone"`
-	PassengerEmail string     `json:"passenger_email"`
-	SeatNumber     string     `json:"seat_number" gorm:"not null"`
-	TicketCode     string     `json:"ticket_code" gorm:"uniqueIndex;not null"` // Unique code for QR
-	QRCodePath     string     `json:"qr_code_path"`
-	PDFPath        string     `json:"pdf_path"`
-	IsCheckedIn    bool       `json:"is_checked_in" gorm:"default:false"`
-	CheckedInAt    *time.Time `json:"checked_in_at,omitempty"`
+	ID             uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	BookingID      uuid.UUID    `json:"booking_id" gorm:"type:uuid;not null;index"`
+	PassengerName  string       `json:"passenger_name" gorm:"not null"`
+	PassengerPhone string       `json:"passenger_phone"`
+	PassengerEmail string       `json:"passenger_email"`
+	SeatNumber     string       `json:"seat_number" gorm:"not null"` // Empty for lap infants
+	FareCategory   FareCategory `json:"fare_category" gorm:"type:varchar(20);not null;default:'adult'"`
+	Fare           float64      `json:"fare" gorm:"not null;default:0"`
+	TicketCode     string       `json:"ticket_code" gorm:"uniqueIndex;not null"` // Unique code for QR
+	QRCodePath     string       `json:"qr_code_path"`
+	PDFPath        string       `json:"pdf_path"`
+	IsCheckedIn    bool         `json:"is_checked_in" gorm:"default:false"`
+	CheckedInAt    *time.Time   `json:"checked_in_at,omitempty"`
+	NeedsSeat      bool         `json:"needs_seat" gorm:"not null;default:false"` // Lost its seat in a bus swap; staff must assign one
 
 	// Associations
 	Booking *Booking `json:"booking,omitempty" gorm:"foreignKey:BookingID"`
@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,15 +10,25 @@ import (
 // Route represents a bus route from one city to another
 type Route struct {
 	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	Name        string    `json:"name" gorm:"not null"` // e.g., "Hanoi - Ho Chi Minh"
-	FromCity    string    `json:"from_city" gorm:"index;not null"`
-	ToCity      string    `json:"to_city" gorm:"index;not null"`
+	Name        string    `json:"name" gorm:"not null"`       // e.g., "Hanoi - Ho Chi Minh"
+	FromCity    string    `json:"from_city" gorm:"not null"`  // Name of FromLocation, kept in step for display
+	ToCity      string    `json:"to_city" gorm:"not null"`    // Name of ToLocation
 	Distance    float64   `json:"distance" gorm:"not null"`   // in kilometers
 	BasePrice   float64   `json:"base_price" gorm:"not null"` // base ticket price
 	Description string    `json:"description"`
 	IsActive    bool      `json:"is_active" gorm:"default:true"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Canonical locations of the route's ends
+	FromLocationID uuid.UUID `json:"from_location_id" gorm:"type:uuid;not null;index"`
+	ToLocationID   uuid.UUID `json:"to_location_id" gorm:"type:uuid;not null;index"`
+	FromLocation   *Location `json:"from_location,omitempty" gorm:"foreignKey:FromLocationID"`
+	ToLocation     *Location `json:"to_location,omitempty" gorm:"foreignKey:ToLocationID"`
+
+	// Pickup and drop-off points in order; empty when the route only runs
+	// from FromCity to ToCity
+	Stops []RouteStop `json:"stops,omitempty" gorm:"foreignKey:RouteID"`
 }
 
 // TableName overrides the table name
@@ -25,6 +36,61 @@ func (Route) TableName() string {
 	return "routes"
 }
 
+// Stop returns the route's stop with the given ID, if the stops are loaded
+func (r *Route) Stop(id uuid.UUID) *RouteStop {
+	for i := range r.Stops {
+		if r.Stops[i].ID == id {
+			return &r.Stops[i]
+		}
+	}
+	return nil
+}
+
+// SegmentCount is how many segments the route's stops split its trips into
+func (r *Route) SegmentCount() int {
+	return len(r.Stops) + 1
+}
+
+// FullRange is the whole trip from the origin to the destination
+func (r *Route) FullRange() StopRange {
+	return StopRange{From: 0, To: r.SegmentCount()}
+}
+
+// StopRange returns the range from a pickup stop to a drop-off stop, where nil
+// or a stop the route does not have means the origin or the destination
+func (r *Route) StopRange(pickupID, dropoffID *uuid.UUID) StopRange {
+	stops := r.FullRange()
+	for i, stop := range r.Stops {
+		if pickupID != nil && stop.ID == *pickupID {
+			stops.From = i + 1
+		}
+		if dropoffID != nil && stop.ID == *dropoffID {
+			stops.To = i + 1
+		}
+	}
+	return stops
+}
+
+// DistanceTo returns how far the stop point at index is from the origin in kilometres
+func (r *Route) DistanceTo(index int) float64 {
+	switch {
+	case index <= 0:
+		return 0
+	case index > len(r.Stops):
+		return r.Distance
+	}
+	return r.Stops[index-1].Distance
+}
+
+// FareShare is the part of the full trip's fare a range pays, by distance
+func (r *Route) FareShare(stops StopRange) float64 {
+	if r.Distance <= 0 || stops == r.FullRange() {
+		return 1
+	}
+	share := (r.DistanceTo(stops.To) - r.DistanceTo(stops.From)) / r.Distance
+	return math.Min(1, math.Max(0, share))
+}
+
 // TripStatus represents the status of a trip
 type TripStatus string
 
@@ -37,22 +103,46 @@ const (
 	TripStatusDelayed   TripStatus = "delayed"
 )
 
+// tripTransitions lists the statuses each trip status can move to. A delay
+// reported while in transit only moves the ETA, so in_transit may "move" to itself.
+var tripTransitions = map[TripStatus][]TripStatus{
+	TripStatusScheduled: {TripStatusBoarding, TripStatusDelayed, TripStatusCancelled},
+	TripStatusDelayed:   {TripStatusBoarding, TripStatusDelayed, TripStatusCancelled},
+	TripStatusBoarding:  {TripStatusInTransit, TripStatusDelayed, TripStatusCancelled},
+	TripStatusInTransit: {TripStatusInTransit, TripStatusCompleted},
+}
+
+// CanTransitionTo reports whether a trip in status s may move to next
+func (s TripStatus) CanTransitionTo(next TripStatus) bool {
+	for _, allowed := range tripTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 // Trip represents a scheduled trip for a specific route
 type Trip struct {
 	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
 	BusID         uuid.UUID  `json:"bus_id" gorm:"type:uuid;not null;index"`
 	RouteID       uuid.UUID  `json:"route_id" gorm:"type:uuid;not null;index"`
-	DepartureTime time.Time  `json:"departure_time" gorm:"index;not null"`
+	DepartureTime time.Time  `json:"departure_time" gorm:"index;not null;uniqueIndex:idx_trips_schedule_departure,priority:2"`
 	ArrivalTime   time.Time  `json:"arrival_time" gorm:"not null"`
 	Duration      int        `json:"duration"` // in minutes
 	Price         float64    `json:"price" gorm:"not null"`
 	Status        TripStatus `json:"status" gorm:"type:varchar(20);not null;default:'scheduled'"`
-	DriverName    string     `json:"driver_name"`
-	DriverPhone   string     `json:"driver_phone"`
+	DriverID      *uuid.UUID `json:"driver_id,omitempty" gorm:"type:uuid;index"`
+	CoDriverID    *uuid.UUID `json:"co_driver_id,omitempty" gorm:"type:uuid;index"`
+	DriverName    string     `json:"driver_name"`                                                                                // Copied from the main driver
+	DriverPhone   string     `json:"driver_phone"`                                                                               // Copied from the main driver
+	ScheduleID    *uuid.UUID `json:"schedule_id,omitempty" gorm:"type:uuid;uniqueIndex:idx_trips_schedule_departure,priority:1"` // Set on trips generated from a schedule
 
 	// Associations
-	Bus   *Bus   `json:"bus,omitempty" gorm:"foreignKey:BusID"`
-	Route *Route `json:"route,omitempty" gorm:"foreignKey:RouteID"`
+	Bus      *Bus    `json:"bus,omitempty" gorm:"foreignKey:BusID"`
+	Route    *Route  `json:"route,omitempty" gorm:"foreignKey:RouteID"`
+	Driver   *Driver `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
+	CoDriver *Driver `json:"co_driver,omitempty" gorm:"foreignKey:CoDriverID"`
 
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
@@ -62,3 +152,47 @@ type Trip struct {
 func (Trip) TableName() string {
 	return "trips"
 }
+
+// PriceFor is the adult fare for travelling the stop range, the trip's price
+// scaled by the distance it covers. Without the route loaded it is the full price.
+func (t *Trip) PriceFor(stops StopRange) float64 {
+	if t.Route == nil {
+		return t.Price
+	}
+	return math.Round(t.Price * t.Route.FareShare(stops))
+}
+
+// InLocation puts the trip's departure and arrival times in location, the
+// timezone they are shown to customers in
+func (t *Trip) InLocation(location *time.Location) {
+	t.DepartureTime = t.DepartureTime.In(location)
+	t.ArrivalTime = t.ArrivalTime.In(location)
+}
+
+// IsBookable reports whether seats of the trip can still be sold; a delayed
+// trip has not left yet, so it stays on sale
+func (t *Trip) IsBookable() bool {
+	return t.Status == TripStatusScheduled || t.Status == TripStatusDelayed
+}
+
+// DriverRole returns the part the driver plays on the trip, or "" if the
+// driver is not on it
+func (t *Trip) DriverRole(driverID uuid.UUID) DriverRole {
+	switch {
+	case t.DriverID != nil && *t.DriverID == driverID:
+		return DriverRoleMain
+	case t.CoDriverID != nil && *t.CoDriverID == driverID:
+		return DriverRoleCo
+	}
+	return ""
+}
+
+// DrivingTime is how long each driver of the trip is at the wheel: all of it
+// for a driver alone, half of it when a co-driver takes turns
+func (t *Trip) DrivingTime() time.Duration {
+	total := t.ArrivalTime.Sub(t.DepartureTime)
+	if t.CoDriverID != nil {
+		return total / 2
+	}
+	return total
+}
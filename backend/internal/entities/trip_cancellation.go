@@ -0,0 +1,63 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// CancellationResolution is what became of a booking on a cancelled trip
+type CancellationResolution string
+
+const (
+	CancellationRebooked     CancellationResolution = "rebooked"      // Moved to the alternative trip
+	CancellationRefunded     CancellationResolution = "refunded"      // Paid amount returned in full
+	CancellationRefundFailed CancellationResolution = "refund_failed" // The gateway refused; retried by the operator
+)
+
+// TripCancellation records an operator cancelling a trip, with the outcome for
+// each booking that was on it
+type TripCancellation struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TripID            uuid.UUID  `json:"trip_id" gorm:"type:uuid;not null;uniqueIndex"`
+	AlternativeTripID *uuid.UUID `json:"alternative_trip_id,omitempty" gorm:"type:uuid"`
+	Reason            string     `json:"reason" gorm:"not null"`
+	CancelledBy       *uuid.UUID `json:"cancelled_by,omitempty" gorm:"type:uuid"`
+	ChoiceDeadline    *time.Time `json:"choice_deadline,omitempty"` // Rebooked passengers may take a refund instead until then
+
+	Outcomes []*TripCancellationOutcome `json:"outcomes" gorm:"foreignKey:CancellationID"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName overrides the table name
+func (TripCancellation) TableName() string {
+	return "trip_cancellations"
+}
+
+// TripCancellationOutcome is what happened to one booking of a cancelled trip
+type TripCancellationOutcome struct {
+	ID                uuid.UUID              `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	CancellationID    uuid.UUID              `json:"cancellation_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_trip_cancellation_outcomes_booking_once"`
+	BookingID         uuid.UUID              `json:"booking_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_trip_cancellation_outcomes_booking_once"`
+	BookingCode       string                 `json:"booking_code" gorm:"not null"`
+	ContactEmail      string                 `json:"contact_email" gorm:"not null"`
+	Passengers        int                    `json:"passengers" gorm:"not null;default:0"`
+	OldSeats          pq.StringArray         `json:"old_seats" gorm:"type:text[]"`
+	NewSeats          pq.StringArray         `json:"new_seats,omitempty" gorm:"type:text[]"` // Seats on the alternative trip
+	Resolution        CancellationResolution `json:"resolution" gorm:"type:varchar(20);not null"`
+	RefundAmount      float64                `json:"refund_amount" gorm:"not null;default:0"`
+	Error             string                 `json:"error,omitempty"`                                // Why the last refund attempt failed
+	ChoiceToken       string                 `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"` // Secret of the passenger's choice link
+	ChosenByPassenger bool                   `json:"chosen_by_passenger" gorm:"not null;default:false"`
+	ResolvedAt        *time.Time             `json:"resolved_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (TripCancellationOutcome) TableName() string {
+	return "trip_cancellation_outcomes"
+}
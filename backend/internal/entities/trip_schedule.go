@@ -0,0 +1,98 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ScheduleRecurrence says on which days a trip schedule runs
+type ScheduleRecurrence string
+
+const (
+	RecurrenceDaily    ScheduleRecurrence = "daily"
+	RecurrenceWeekdays ScheduleRecurrence = "weekdays" // Monday to Friday
+	RecurrenceDays     ScheduleRecurrence = "days"     // The days listed in DaysOfWeek
+)
+
+// ScheduleDateLayout is the format of schedule and holiday dates
+const ScheduleDateLayout = "2006-01-02"
+
+// ScheduleTimeLayout is the format of a schedule's departure time
+const ScheduleTimeLayout = "15:04"
+
+// TripSchedule is a timetable entry from which trips are generated ahead of time.
+// Dates and the departure time are in the business timezone.
+type TripSchedule struct {
+	ID            uuid.UUID          `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	RouteID       uuid.UUID          `json:"route_id" gorm:"type:uuid;not null;index"`
+	BusID         uuid.UUID          `json:"bus_id" gorm:"type:uuid;not null;index"`
+	DepartureTime string             `json:"departure_time" gorm:"type:varchar(5);not null"` // "HH:MM"
+	Duration      int                `json:"duration" gorm:"not null"`                       // in minutes
+	Price         float64            `json:"price"`                                          // 0 uses the route's base price
+	Recurrence    ScheduleRecurrence `json:"recurrence" gorm:"type:varchar(20);not null"`
+	DaysOfWeek    pq.Int64Array      `json:"days_of_week" gorm:"type:integer[]"` // 0 is Sunday; for RecurrenceDays
+	ExceptDates   pq.StringArray     `json:"except_dates" gorm:"type:text[]"`    // "YYYY-MM-DD" dates it does not run
+	SkipHolidays  bool               `json:"skip_holidays" gorm:"not null;default:false"`
+	StartDate     string             `json:"start_date" gorm:"type:varchar(10);not null"` // "YYYY-MM-DD"
+	EndDate       string             `json:"end_date,omitempty" gorm:"type:varchar(10)"`  // Last day it runs; empty for no end
+	DriverID      *uuid.UUID         `json:"driver_id,omitempty" gorm:"type:uuid"`
+	CoDriverID    *uuid.UUID         `json:"co_driver_id,omitempty" gorm:"type:uuid"`
+	IsActive      bool               `json:"is_active" gorm:"not null;default:true;index"`
+
+	// Associations
+	Bus   *Bus   `json:"bus,omitempty" gorm:"foreignKey:BusID"`
+	Route *Route `json:"route,omitempty" gorm:"foreignKey:RouteID"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (TripSchedule) TableName() string {
+	return "trip_schedules"
+}
+
+// RunsOn reports whether the schedule has a trip on the date, given as
+// "YYYY-MM-DD" together with its weekday; holidays holds the holiday dates
+func (s *TripSchedule) RunsOn(date string, weekday time.Weekday, holidays map[string]bool) bool {
+	if date < s.StartDate || (s.EndDate != "" && date > s.EndDate) {
+		return false
+	}
+	if s.SkipHolidays && holidays[date] {
+		return false
+	}
+	for _, except := range s.ExceptDates {
+		if except == date {
+			return false
+		}
+	}
+
+	switch s.Recurrence {
+	case RecurrenceDaily:
+		return true
+	case RecurrenceWeekdays:
+		return weekday != time.Saturday && weekday != time.Sunday
+	case RecurrenceDays:
+		for _, day := range s.DaysOfWeek {
+			if time.Weekday(day) == weekday {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Holiday is a public holiday that schedules can skip
+type Holiday struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Date      string    `json:"date" gorm:"type:varchar(10);uniqueIndex;not null"` // "YYYY-MM-DD"
+	Name      string    `json:"name" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName overrides the table name
+func (Holiday) TableName() string {
+	return "holidays"
+}
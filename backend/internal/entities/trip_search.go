@@ -0,0 +1,85 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TripSearchSort selects the order of trip search results
+type TripSearchSort string
+
+const (
+	TripSortDeparture TripSearchSort = "departure"
+	TripSortPrice     TripSearchSort = "price"
+	TripSortDuration  TripSearchSort = "duration"
+	TripSortRating    TripSearchSort = "rating"
+)
+
+// IsValid reports whether the sort is one of the known orders
+func (s TripSearchSort) IsValid() bool {
+	switch s {
+	case TripSortDeparture, TripSortPrice, TripSortDuration, TripSortRating:
+		return true
+	}
+	return false
+}
+
+// TripSearch is a search for bookable trips between two locations on one day.
+// Empty filters match every trip.
+type TripSearch struct {
+	FromLocationID uuid.UUID
+	ToLocationID   uuid.UUID
+
+	// The searched day as a half-open period starting at midnight in the
+	// business timezone, and the departure window within it
+	From       time.Time
+	To         time.Time
+	DepartFrom time.Time
+	DepartTo   time.Time
+
+	MinPrice  *float64
+	MaxPrice  *float64
+	BusTypes  []string
+	Operators []string
+	Amenities []string // The bus must have all of them
+	MinSeats  int      // Seats free over the whole trip
+
+	Sort       TripSearchSort
+	Descending bool
+}
+
+// TripSearchResult is a trip found by a search with what it has left to sell
+type TripSearchResult struct {
+	*Trip
+	AvailableSeats int     `json:"available_seats"` // Free over the whole trip
+	LowestPrice    float64 `json:"lowest_price"`    // Adult fare, which the price filters and sort go by
+}
+
+// FacetCount is how many trips a filter value would leave
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Departure windows of the trip search facets, in the business timezone
+const (
+	DepartureWindowNight     = "night"     // 00:00-06:00
+	DepartureWindowMorning   = "morning"   // 06:00-12:00
+	DepartureWindowAfternoon = "afternoon" // 12:00-18:00
+	DepartureWindowEvening   = "evening"   // 18:00-24:00
+)
+
+// TripSearchFacets counts the trips of a search per filter value. Each facet
+// applies every other filter, so the counts of a facet say how many trips
+// choosing that value instead would give; amenities add to the filters
+// instead, as a bus must have all of them.
+type TripSearchFacets struct {
+	Total            int          `json:"total"` // Trips matching every filter
+	BusTypes         []FacetCount `json:"bus_types"`
+	Operators        []FacetCount `json:"operators"`
+	Amenities        []FacetCount `json:"amenities"`
+	DepartureWindows []FacetCount `json:"departure_windows"`
+	MinPrice         float64      `json:"min_price"` // Price range of the trips matching every other filter
+	MaxPrice         float64      `json:"max_price"`
+}
@@ -3,7 +3,9 @@ package infrastructure
 import (
 	"crypto/tls"
 	"fmt"
+	"html"
 	"os"
+	"strings"
 
 	gomail "gopkg.in/gomail.v2"
 )
@@ -31,8 +33,8 @@ func NewEmailService() *EmailService {
 	}
 }
 
-// SendBookingConfirmation sends booking confirmation email
-func (s *EmailService) SendBookingConfirmation(to, bookingCode string, attachmentPath string) error {
+// SendBookingConfirmation sends booking confirmation email with the e-tickets attached
+func (s *EmailService) SendBookingConfirmation(to, bookingCode string, attachmentPaths ...string) error {
 	m := gomail.NewMessage()
 	m.SetHeader("From", s.from)
 	m.SetHeader("To", to)
@@ -47,8 +49,10 @@ func (s *EmailService) SendBookingConfirmation(to, bookingCode string, attachmen
 
 	m.SetBody("text/html", body)
 
-	if attachmentPath != "" {
-		m.Attach(attachmentPath)
+	for _, path := range attachmentPaths {
+		if path != "" {
+			m.Attach(path)
+		}
 	}
 
 	return s.dialer.DialAndSend(m)
@@ -73,3 +77,102 @@ func (s *EmailService) SendTicket(to, ticketCode, pdfPath string) error {
 
 	return s.dialer.DialAndSend(m)
 }
+
+// SendSeatChange tells a customer their trip now runs with another bus, listing
+// where each passenger sits on it; updated e-tickets are attached
+func (s *EmailService) SendSeatChange(to, bookingCode string, changes []string, attachmentPaths ...string) error {
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "Change to your bus - "+bookingCode)
+
+	var items strings.Builder
+	for _, change := range changes {
+		items.WriteString("<li>" + html.EscapeString(change) + "</li>")
+	}
+
+	body := fmt.Sprintf(`
+		<h2>Your Bus Has Changed</h2>
+		<p>The trip of booking <strong>%s</strong> will now run with a different bus.</p>
+		<ul>%s</ul>
+		<p>Please use the attached e-tickets, if any, when boarding.</p>
+	`, bookingCode, items.String())
+
+	m.SetBody("text/html", body)
+
+	for _, path := range attachmentPaths {
+		if path != "" {
+			m.Attach(path)
+		}
+	}
+
+	return s.dialer.DialAndSend(m)
+}
+
+// SendTripDelay tells a customer their trip has been delayed and when it is now expected
+func (s *EmailService) SendTripDelay(to, bookingCode, route, departure, arrival, reason string) error {
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "Your trip is delayed - "+bookingCode)
+
+	note := ""
+	if reason != "" {
+		note = "<p>Reason: " + html.EscapeString(reason) + "</p>"
+	}
+
+	body := fmt.Sprintf(`
+		<h2>Your Trip Is Delayed</h2>
+		<p>The %s trip of booking <strong>%s</strong> is running late.</p>
+		<p>Expected departure: <strong>%s</strong><br>Expected arrival: <strong>%s</strong></p>
+		%s
+		<p>Your booking and e-tickets remain valid.</p>
+	`, html.EscapeString(route), bookingCode, departure, arrival, note)
+
+	m.SetBody("text/html", body)
+
+	return s.dialer.DialAndSend(m)
+}
+
+// SendTripCancellation tells a customer their trip has been cancelled and what
+// became of their booking. With a choice link the customer can still switch
+// between the new trip and a refund; e-tickets for a new trip are attached.
+func (s *EmailService) SendTripCancellation(to, bookingCode, reason string, details []string, choiceURL string, attachmentPaths ...string) error {
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "Your trip is cancelled - "+bookingCode)
+
+	var items strings.Builder
+	for _, detail := range details {
+		items.WriteString("<li>" + html.EscapeString(detail) + "</li>")
+	}
+
+	note := ""
+	if reason != "" {
+		note = "<p>Reason: " + html.EscapeString(reason) + "</p>"
+	}
+
+	choice := ""
+	if choiceURL != "" {
+		choice = fmt.Sprintf(`<p>Prefer a full refund instead? <a href="%s">Choose here</a>.</p>`, html.EscapeString(choiceURL))
+	}
+
+	body := fmt.Sprintf(`
+		<h2>Your Trip Is Cancelled</h2>
+		<p>We are sorry, the trip of booking <strong>%s</strong> has been cancelled.</p>
+		%s
+		<ul>%s</ul>
+		%s
+	`, bookingCode, note, items.String(), choice)
+
+	m.SetBody("text/html", body)
+
+	for _, path := range attachmentPaths {
+		if path != "" {
+			m.Attach(path)
+		}
+	}
+
+	return s.dialer.DialAndSend(m)
+}
@@ -18,7 +18,9 @@ type Gateway interface {
 	CreatePayment(ctx context.Context, req PaymentRequest) (*PaymentResponse, error)
 	VerifyWebhook(ctx context.Context, signature string, payload []byte) error
 	CheckPaymentStatus(ctx context.Context, gatewayPaymentID string) (*PaymentStatus, error)
-	RefundPayment(ctx context.Context, gatewayPaymentID string, amount float64) error
+	// RefundPayment refunds a payment; the gateway refunds at most once per
+	// idempotency key, so a refund retried after a crash is not paid twice
+	RefundPayment(ctx context.Context, gatewayPaymentID string, amount float64, idempotencyKey string) error
 }
 
 // PaymentRequest represents a payment creation request
@@ -123,7 +125,7 @@ func (g *MockGateway) CheckPaymentStatus(ctx context.Context, gatewayPaymentID s
 	}, nil
 }
 
-func (g *MockGateway) RefundPayment(ctx context.Context, gatewayPaymentID string, amount float64) error {
+func (g *MockGateway) RefundPayment(ctx context.Context, gatewayPaymentID string, amount float64, idempotencyKey string) error {
 	// Simulate refund - in real implementation, call gateway API
 	return nil
 }
@@ -101,7 +101,7 @@ func (g *MoMoGateway) CheckPaymentStatus(ctx context.Context, gatewayPaymentID s
 	return nil, fmt.Errorf("real MoMo status check not yet implemented")
 }
 
-func (g *MoMoGateway) RefundPayment(ctx context.Context, gatewayPaymentID string, amount float64) error {
+func (g *MoMoGateway) RefundPayment(ctx context.Context, gatewayPaymentID string, amount float64, idempotencyKey string) error {
 	if g.UseMock {
 		return nil
 	}
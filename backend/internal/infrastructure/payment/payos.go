@@ -94,7 +94,7 @@ func (g *PayOSGateway) CheckPaymentStatus(ctx context.Context, gatewayPaymentID
 	return nil, fmt.Errorf("real PayOS status check not yet implemented")
 }
 
-func (g *PayOSGateway) RefundPayment(ctx context.Context, gatewayPaymentID string, amount float64) error {
+func (g *PayOSGateway) RefundPayment(ctx context.Context, gatewayPaymentID string, amount float64, idempotencyKey string) error {
 	if g.UseMock {
 		return nil
 	}
@@ -92,7 +92,7 @@ func (g *ZaloPayGateway) CheckPaymentStatus(ctx context.Context, gatewayPaymentI
 	return nil, fmt.Errorf("real ZaloPay status check not yet implemented")
 }
 
-func (g *ZaloPayGateway) RefundPayment(ctx context.Context, gatewayPaymentID string, amount float64) error {
+func (g *ZaloPayGateway) RefundPayment(ctx context.Context, gatewayPaymentID string, amount float64, idempotencyKey string) error {
 	if g.UseMock {
 		return nil
 	}
@@ -27,8 +27,10 @@ func NewPDFGenerator() *PDFGenerator {
 	}
 }
 
-// GenerateTicket generates a PDF ticket with QR code
-func (g *PDFGenerator) GenerateTicket(ticketCode, passengerName, fromCity, toCity, seatNumber, departureTime string) (string, error) {
+// GenerateTicket generates a PDF ticket with QR code. pickupPoint and
+// dropoffPoint name the stops the passenger boards and leaves at; they are
+// left off the ticket when empty.
+func (g *PDFGenerator) GenerateTicket(ticketCode, passengerName, fareCategory, fromCity, toCity, pickupPoint, dropoffPoint, seatNumber, departureTime string) (string, error) {
 	// Generate QR code
 	qrPath, err := g.generateQRCode(ticketCode)
 	if err != nil {
@@ -57,6 +59,12 @@ func (g *PDFGenerator) GenerateTicket(ticketCode, passengerName, fromCity, toCit
 	pdf.Cell(130, 8, passengerName)
 	pdf.Ln(10)
 
+	pdf.SetFont("Arial", "", 12)
+	pdf.Cell(60, 8, "Fare Type:")
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(130, 8, fareCategory)
+	pdf.Ln(10)
+
 	pdf.SetFont("Arial", "", 12)
 	pdf.Cell(60, 8, "From:")
 	pdf.SetFont("Arial", "B", 12)
@@ -69,9 +77,26 @@ func (g *PDFGenerator) GenerateTicket(ticketCode, passengerName, fromCity, toCit
 	pdf.Cell(130, 8, toCity)
 	pdf.Ln(8)
 
+	if pickupPoint != "" {
+		pdf.SetFont("Arial", "", 12)
+		pdf.Cell(60, 8, "Pickup Point:")
+		pdf.SetFont("Arial", "B", 12)
+		pdf.MultiCell(130, 8, pickupPoint, "", "L", false)
+	}
+
+	if dropoffPoint != "" {
+		pdf.SetFont("Arial", "", 12)
+		pdf.Cell(60, 8, "Drop-off Point:")
+		pdf.SetFont("Arial", "B", 12)
+		pdf.MultiCell(130, 8, dropoffPoint, "", "L", false)
+	}
+
 	pdf.SetFont("Arial", "", 12)
 	pdf.Cell(60, 8, "Seat Number:")
 	pdf.SetFont("Arial", "B", 12)
+	if seatNumber == "" {
+		seatNumber = "No seat (travels on lap)"
+	}
 	pdf.Cell(130, 8, seatNumber)
 	pdf.Ln(8)
 
@@ -81,8 +106,8 @@ func (g *PDFGenerator) GenerateTicket(ticketCode, passengerName, fromCity, toCit
 	pdf.Cell(130, 8, departureTime)
 	pdf.Ln(15)
 
-	// Add QR code
-	pdf.Image(qrPath, 80, 120, 50, 50, false, "", 0, "")
+	// Add QR code below the details, which grow with the boarding points
+	pdf.Image(qrPath, 80, pdf.GetY(), 50, 50, false, "", 0, "")
 
 	pdf.Ln(55)
 	pdf.SetFont("Arial", "I", 10)
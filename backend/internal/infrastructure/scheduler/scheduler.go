@@ -0,0 +1,351 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// lockLease is how long a job lock lives without being refreshed; a crashed
+	// leader therefore blocks the job for at most this long
+	lockLease = 30 * time.Second
+
+	defaultTimeout = 5 * time.Minute
+	defaultBackoff = 5 * time.Second
+)
+
+// Store is the shared state instances coordinate through
+type Store interface {
+	AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, key, token string) error
+	SetJobStatus(ctx context.Context, name string, status interface{}) error
+	GetJobStatus(ctx context.Context, name string, dest interface{}) (bool, error)
+}
+
+// Job is a named unit of background work
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Timeout bounds a single attempt (default 5m)
+	Timeout time.Duration
+	// Retries is how many times a failed attempt is retried within one run
+	Retries int
+	// Backoff is the delay before the first retry, doubled for each further one (default 5s)
+	Backoff time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// RunState is the outcome of the latest run of a job
+type RunState string
+
+const (
+	RunStateRunning   RunState = "running"
+	RunStateSucceeded RunState = "succeeded"
+	RunStateFailed    RunState = "failed"
+	RunStateCancelled RunState = "cancelled"
+)
+
+// JobStatus is the last-run record shared by all instances
+type JobStatus struct {
+	Name         string     `json:"name"`
+	Schedule     string     `json:"schedule"`
+	State        RunState   `json:"state,omitempty"`
+	Instance     string     `json:"instance,omitempty"`
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	DurationMs   int64      `json:"duration_ms"`
+	Attempts     int        `json:"attempts"`
+	LastError    string     `json:"last_error,omitempty"`
+	LastSuccess  *time.Time `json:"last_success_at,omitempty"`
+	NextRunAt    *time.Time `json:"next_run_at,omitempty"`
+}
+
+// Scheduler runs registered jobs on their schedules. Every instance runs the
+// same loop, but a Redis lock plus the shared last-run record make sure each
+// scheduled run executes on exactly one instance.
+type Scheduler struct {
+	store    Store
+	instance string
+
+	mu      sync.Mutex
+	jobs    []*Job
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New creates a scheduler; instance identifies this process in job statuses
+func New(store Store, instance string) *Scheduler {
+	if instance == "" {
+		instance = uuid.New().String()
+	}
+	return &Scheduler{
+		store:    store,
+		instance: instance,
+	}
+}
+
+func jobLockKey(name string) string {
+	return fmt.Sprintf("jobs:lock:%s", name)
+}
+
+// Register adds a job. Jobs must be registered before Start.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil {
+		return errors.New("job needs a name, a schedule and a run function")
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = defaultTimeout
+	}
+	if job.Backoff <= 0 {
+		job.Backoff = defaultBackoff
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return errors.New("scheduler already started")
+	}
+	for _, existing := range s.jobs {
+		if existing.Name == job.Name {
+			return fmt.Errorf("job %s already registered", job.Name)
+		}
+	}
+	s.jobs = append(s.jobs, &job)
+	return nil
+}
+
+// Start launches one loop per job
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Stop cancels running jobs and waits for them to return or for ctx to expire
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("jobs still running at shutdown: %w", ctx.Err())
+	}
+}
+
+// Statuses returns the shared last-run status of every registered job
+func (s *Scheduler) Statuses(ctx context.Context) ([]*JobStatus, error) {
+	s.mu.Lock()
+	jobs := append([]*Job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]*JobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		status := &JobStatus{}
+		if _, err := s.store.GetJobStatus(ctx, job.Name, status); err != nil {
+			return nil, fmt.Errorf("failed to load status of job %s: %w", job.Name, err)
+		}
+		status.Name = job.Name
+		status.Schedule = job.Schedule.String()
+		next := job.Schedule.Next(now)
+		status.NextRunAt = &next
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+func (s *Scheduler) loop(ctx context.Context, job *Job) {
+	defer s.wg.Done()
+
+	for {
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Job %s has no upcoming run, stopping", job.Name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.runScheduled(ctx, job, next)
+	}
+}
+
+// runScheduled executes the run planned for slot unless another instance
+// holds the job or has already executed that slot
+func (s *Scheduler) runScheduled(ctx context.Context, job *Job, slot time.Time) {
+	key := jobLockKey(job.Name)
+	token := uuid.New().String()
+
+	acquired, err := s.store.AcquireLock(ctx, key, token, lockLease)
+	if err != nil {
+		log.Printf("Job %s: failed to acquire lock: %v", job.Name, err)
+		return
+	}
+	if !acquired {
+		return
+	}
+	defer func() {
+		// The run context may already be cancelled at shutdown
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.store.ReleaseLock(releaseCtx, key, token); err != nil {
+			log.Printf("Job %s: failed to release lock: %v", job.Name, err)
+		}
+	}()
+
+	status := &JobStatus{}
+	if _, err := s.store.GetJobStatus(ctx, job.Name, status); err != nil {
+		log.Printf("Job %s: failed to load status: %v", job.Name, err)
+		return
+	}
+	if status.ScheduledFor != nil && !status.ScheduledFor.Before(slot) {
+		// Another instance ran this slot before we got the lock
+		return
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.keepLock(runCtx, cancel, job.Name, key, token)
+
+	startedAt := time.Now()
+	status.State = RunStateRunning
+	status.Instance = s.instance
+	status.ScheduledFor = &slot
+	status.StartedAt = &startedAt
+	status.FinishedAt = nil
+	status.Attempts = 0
+	s.saveStatus(job, status)
+
+	attempts, err := s.execute(runCtx, job)
+
+	finishedAt := time.Now()
+	status.FinishedAt = &finishedAt
+	status.DurationMs = finishedAt.Sub(startedAt).Milliseconds()
+	status.Attempts = attempts
+	switch {
+	case err == nil:
+		status.State = RunStateSucceeded
+		status.LastError = ""
+		status.LastSuccess = &finishedAt
+	case runCtx.Err() != nil && ctx.Err() != nil:
+		status.State = RunStateCancelled
+		status.LastError = err.Error()
+	default:
+		status.State = RunStateFailed
+		status.LastError = err.Error()
+		log.Printf("Job %s failed after %d attempt(s): %v", job.Name, attempts, err)
+	}
+	s.saveStatus(job, status)
+}
+
+// execute runs the job with a per-attempt timeout, retrying with exponential
+// backoff until it succeeds, runs out of retries or ctx is cancelled
+func (s *Scheduler) execute(ctx context.Context, job *Job) (int, error) {
+	backoff := job.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = runAttempt(ctx, job)
+		if err == nil || attempt > job.Retries || ctx.Err() != nil {
+			return attempt, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+func runAttempt(ctx context.Context, job *Job) (err error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return job.Run(attemptCtx)
+}
+
+// keepLock refreshes the job lock while the run is in progress and cancels the
+// run if the lock is lost, so two instances never run the job at once
+func (s *Scheduler) keepLock(ctx context.Context, cancel context.CancelFunc, name, key, token string) {
+	ticker := time.NewTicker(lockLease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			held, err := s.store.RefreshLock(ctx, key, token, lockLease)
+			if err != nil && ctx.Err() != nil {
+				return
+			}
+			if err != nil || !held {
+				log.Printf("Job %s: lost lock, cancelling run: %v", name, err)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func (s *Scheduler) saveStatus(job *Job, status *JobStatus) {
+	status.Name = job.Name
+	status.Schedule = job.Schedule.String()
+	status.NextRunAt = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.store.SetJobStatus(ctx, job.Name, status); err != nil {
+		log.Printf("Job %s: failed to save status: %v", job.Name, err)
+	}
+}
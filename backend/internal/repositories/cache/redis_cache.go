@@ -3,7 +3,10 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,36 +25,206 @@ func NewRedisCache(client *redis.Client) *RedisCache {
 }
 
 // Seat Lock Keys
-func seatLockKey(tripID uuid.UUID, seatNumber string) string {
-	return fmt.Sprintf("seat:lock:%s:%s", tripID.String(), seatNumber)
+func seatLockKey(tripID uuid.UUID, seatNumber string, segment int) string {
+	return fmt.Sprintf("%s%s:%d", seatLockPrefix(tripID), seatNumber, segment)
+}
+
+func seatLockPrefix(tripID uuid.UUID) string {
+	return fmt.Sprintf("seat:lock:%s:", tripID.String())
 }
 
 func tripSeatsKey(tripID uuid.UUID) string {
 	return fmt.Sprintf("trip:seats:%s", tripID.String())
 }
 
-// LockSeat attempts to lock a seat using Redis SETNX with expiry
-func (c *RedisCache) LockSeat(ctx context.Context, tripID uuid.UUID, seatNumber string, lockedBy uuid.UUID, duration time.Duration) error {
-	key := seatLockKey(tripID, seatNumber)
-	success, err := c.client.SetNX(ctx, key, lockedBy.String(), duration).Result()
+func tripSeatMapKey(tripID uuid.UUID) string {
+	return fmt.Sprintf("trip:seatmap:%s", tripID.String())
+}
+
+func fareCalendarKey(fromLocationID, toLocationID uuid.UUID, date string) string {
+	return fmt.Sprintf("fare:calendar:%s:%s:%s", date, fromLocationID.String(), toLocationID.String())
+}
+
+// fareCalendarTripKey holds the keys of the fare calendar days a trip was
+// counted in, so a change to its seats can drop them
+func fareCalendarTripKey(tripID uuid.UUID) string {
+	return fmt.Sprintf("fare:calendar:trip:%s", tripID.String())
+}
+
+// ErrSeatLocked is returned when a seat is locked by another holder
+var ErrSeatLocked = errors.New("seat is already locked")
+
+var (
+	// lockSeatsScript locks every seat for the token, or none if any of them is
+	// held by someone else; in that case it returns the positions of those seats.
+	// Seats the token already holds are locked again with the new expiry.
+	lockSeatsScript = redis.NewScript(`
+local taken = {}
+for i, key in ipairs(KEYS) do
+	local holder = redis.call("GET", key)
+	if holder and holder ~= ARGV[1] then
+		table.insert(taken, i)
+	end
+end
+if #taken > 0 then
+	return taken
+end
+for _, key in ipairs(KEYS) do
+	redis.call("SET", key, ARGV[1], "PX", ARGV[2])
+end
+return taken`)
+
+	// unlockSeatsScript deletes the seat locks still held by the token and
+	// returns how many it deleted
+	unlockSeatsScript = redis.NewScript(`
+local released = 0
+for _, key in ipairs(KEYS) do
+	if redis.call("GET", key) == ARGV[1] then
+		released = released + redis.call("DEL", key)
+	end
+end
+return released`)
+
+	// extendSeatLocksScript pushes back the expiry of every seat lock if the
+	// token still holds all of them, and of none otherwise
+	extendSeatLocksScript = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+	if redis.call("GET", key) ~= ARGV[1] then
+		return 0
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call("PEXPIRE", key, ARGV[2])
+end
+return 1`)
+)
+
+// seatLockKeys returns the lock keys of the seats on every segment of the
+// range, seat by seat
+func seatLockKeys(tripID uuid.UUID, seatNumbers []string, stops entities.StopRange) []string {
+	keys := make([]string, 0, len(seatNumbers)*stops.Segments())
+	for _, seatNumber := range seatNumbers {
+		for segment := stops.From; segment < stops.To; segment++ {
+			keys = append(keys, seatLockKey(tripID, seatNumber, segment))
+		}
+	}
+	return keys
+}
+
+// LockSeats locks all the seats on the segments of the range for token, or
+// none of them if any is locked by another holder. The token is stored as the
+// lock value, so it should be the same one written to the database lock.
+func (c *RedisCache) LockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange, token uuid.UUID, duration time.Duration) error {
+	if len(seatNumbers) == 0 || stops.Segments() <= 0 {
+		return nil
+	}
+
+	taken, err := lockSeatsScript.Run(ctx, c.client, seatLockKeys(tripID, seatNumbers, stops), token.String(), duration.Milliseconds()).Int64Slice()
 	if err != nil {
 		return err
 	}
-	if !success {
-		return fmt.Errorf("seat is already locked")
+	if len(taken) > 0 {
+		seats := make([]string, 0, len(taken))
+		for _, i := range taken {
+			seatNumber := seatNumbers[(int(i)-1)/stops.Segments()]
+			if len(seats) == 0 || seats[len(seats)-1] != seatNumber {
+				seats = append(seats, seatNumber)
+			}
+		}
+		return fmt.Errorf("seat %s: %w", strings.Join(seats, ", "), ErrSeatLocked)
 	}
 	return nil
 }
 
-// UnlockSeat releases a seat lock
-func (c *RedisCache) UnlockSeat(ctx context.Context, tripID uuid.UUID, seatNumber string) error {
-	key := seatLockKey(tripID, seatNumber)
-	return c.client.Del(ctx, key).Err()
+// UnlockSeats releases the seats on the segments of the range still locked by
+// token, leaving locks taken by anyone else alone, and returns how many
+// segment locks it released
+func (c *RedisCache) UnlockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange, token uuid.UUID) (int, error) {
+	if len(seatNumbers) == 0 || stops.Segments() <= 0 {
+		return 0, nil
+	}
+	return unlockSeatsScript.Run(ctx, c.client, seatLockKeys(tripID, seatNumbers, stops), token.String()).Int()
 }
 
-// IsS eatLocked checks if a seat is currently locked
-func (c *RedisCache) IsSeatLocked(ctx context.Context, tripID uuid.UUID, seatNumber string) (bool, error) {
-	key := seatLockKey(tripID, seatNumber)
+// ExtendSeatLocks renews the locks on all the seats of the range for another
+// duration and reports whether token still held every one of them; if not,
+// none is renewed
+func (c *RedisCache) ExtendSeatLocks(ctx context.Context, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange, token uuid.UUID, duration time.Duration) (bool, error) {
+	if len(seatNumbers) == 0 || stops.Segments() <= 0 {
+		return true, nil
+	}
+	res, err := extendSeatLocksScript.Run(ctx, c.client, seatLockKeys(tripID, seatNumbers, stops), token.String(), duration.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// SeatLock is a seat lock as found in Redis
+type SeatLock struct {
+	Token string
+	TTL   time.Duration // Time left on the lock
+}
+
+// GetSeatLocks returns the Redis locks on a trip's seats by seat and segment
+func (c *RedisCache) GetSeatLocks(ctx context.Context, tripID uuid.UUID) (map[entities.SeatSegment]SeatLock, error) {
+	prefix := seatLockPrefix(tripID)
+
+	var keys []string
+	iter := c.client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	locks := make(map[entities.SeatSegment]SeatLock, len(keys))
+	if len(keys) == 0 {
+		return locks, nil
+	}
+
+	pipe := c.client.Pipeline()
+	gets := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		gets[i] = pipe.Get(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		token, err := gets[i].Result()
+		if err != nil {
+			continue // Expired since the scan
+		}
+		seat, ok := parseSeatLockKey(strings.TrimPrefix(key, prefix))
+		if !ok {
+			continue // Not a seat lock key of this format
+		}
+		locks[seat] = SeatLock{Token: token, TTL: ttls[i].Val()}
+	}
+	return locks, nil
+}
+
+// parseSeatLockKey splits the end of a seat lock key into its seat and segment
+func parseSeatLockKey(suffix string) (entities.SeatSegment, bool) {
+	i := strings.LastIndex(suffix, ":")
+	if i < 0 {
+		return entities.SeatSegment{}, false
+	}
+	segment, err := strconv.Atoi(suffix[i+1:])
+	if err != nil {
+		return entities.SeatSegment{}, false
+	}
+	return entities.SeatSegment{SeatNumber: suffix[:i], Segment: segment}, true
+}
+
+// IsSeatLocked checks if a seat is currently locked on a segment
+func (c *RedisCache) IsSeatLocked(ctx context.Context, tripID uuid.UUID, seatNumber string, segment int) (bool, error) {
+	key := seatLockKey(tripID, seatNumber, segment)
 	exists, err := c.client.Exists(ctx, key).Result()
 	return exists > 0, err
 }
@@ -82,10 +255,96 @@ func (c *RedisCache) GetTripSeats(ctx context.Context, tripID uuid.UUID) ([]*ent
 	return seats, err
 }
 
-// InvalidateTripSeats removes cached seat data for a trip
+// invalidateTripSeatsScript deletes the given keys along with every fare
+// calendar day listed in the last one
+var invalidateTripSeatsScript = redis.NewScript(`
+for _, key in ipairs(redis.call("SMEMBERS", KEYS[#KEYS])) do
+	redis.call("DEL", key)
+end
+return redis.call("DEL", unpack(KEYS))`)
+
+// InvalidateTripSeats removes cached seat data, the seat map and the fare
+// calendar days of a trip
 func (c *RedisCache) InvalidateTripSeats(ctx context.Context, tripID uuid.UUID) error {
-	key := tripSeatsKey(tripID)
-	return c.client.Del(ctx, key).Err()
+	keys := []string{tripSeatsKey(tripID), tripSeatMapKey(tripID), fareCalendarTripKey(tripID)}
+	return invalidateTripSeatsScript.Run(ctx, c.client, keys).Err()
+}
+
+// CacheSeatMap caches the seat map read model of a trip
+func (c *RedisCache) CacheSeatMap(ctx context.Context, seatMap *entities.SeatMap, ttl time.Duration) error {
+	data, err := json.Marshal(seatMap)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, tripSeatMapKey(seatMap.TripID), data, ttl).Err()
+}
+
+// GetSeatMap retrieves the cached seat map of a trip
+func (c *RedisCache) GetSeatMap(ctx context.Context, tripID uuid.UUID) (*entities.SeatMap, error) {
+	data, err := c.client.Get(ctx, tripSeatMapKey(tripID)).Result()
+	if err == redis.Nil {
+		return nil, nil // Cache miss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var seatMap entities.SeatMap
+	if err := json.Unmarshal([]byte(data), &seatMap); err != nil {
+		return nil, err
+	}
+	return &seatMap, nil
+}
+
+// CacheFareCalendarDays caches fare calendar days between two locations, each
+// listed under the trips counted in it so that InvalidateTripSeats drops it
+func (c *RedisCache) CacheFareCalendarDays(ctx context.Context, fromLocationID, toLocationID uuid.UUID, days []*entities.FareCalendarDay, tripIDs map[string][]uuid.UUID, ttl time.Duration) error {
+	pipe := c.client.TxPipeline()
+	for _, day := range days {
+		data, err := json.Marshal(day)
+		if err != nil {
+			return err
+		}
+		key := fareCalendarKey(fromLocationID, toLocationID, day.Date)
+		pipe.Set(ctx, key, data, ttl)
+		for _, tripID := range tripIDs[day.Date] {
+			pipe.SAdd(ctx, fareCalendarTripKey(tripID), key)
+			pipe.Expire(ctx, fareCalendarTripKey(tripID), ttl)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetFareCalendarDays retrieves the cached fare calendar days between two
+// locations by date; dates missing from the cache are left out
+func (c *RedisCache) GetFareCalendarDays(ctx context.Context, fromLocationID, toLocationID uuid.UUID, dates []string) (map[string]*entities.FareCalendarDay, error) {
+	days := make(map[string]*entities.FareCalendarDay, len(dates))
+	if len(dates) == 0 {
+		return days, nil
+	}
+
+	keys := make([]string, len(dates))
+	for i, date := range dates {
+		keys[i] = fareCalendarKey(fromLocationID, toLocationID, date)
+	}
+	values, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue // Cache miss
+		}
+		var day entities.FareCalendarDay
+		if err := json.Unmarshal([]byte(data), &day); err != nil {
+			return nil, err
+		}
+		days[day.Date] = &day
+	}
+	return days, nil
 }
 
 // PublishSeatUpdate publishes seat update event to Redis Pub/Sub
@@ -98,10 +357,309 @@ func (c *RedisCache) PublishSeatUpdate(ctx context.Context, tripID uuid.UUID, se
 	return c.client.Publish(ctx, channel, data).Err()
 }
 
-// SubscribeTripUpdates subscribes to seat updates for a trip
+// PublishTripEvent publishes a trip status or schedule change to Redis Pub/Sub
+func (c *RedisCache) PublishTripEvent(ctx context.Context, tripID uuid.UUID, payload *entities.TripEventPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return c.client.Publish(ctx, TripStatusChannel(tripID), data).Err()
+}
+
+// TripStatusChannel is the Pub/Sub channel carrying a trip's status changes
+func TripStatusChannel(tripID uuid.UUID) string {
+	return fmt.Sprintf("trip:%s:status", tripID.String())
+}
+
+// SubscribeTripUpdates subscribes to seat updates and status changes of a trip
 func (c *RedisCache) SubscribeTripUpdates(ctx context.Context, tripID uuid.UUID) *redis.PubSub {
 	channel := fmt.Sprintf("trip:%s:seats", tripID.String())
-	return c.client.Subscribe(ctx, channel)
+	return c.client.Subscribe(ctx, channel, TripStatusChannel(tripID))
+}
+
+// Event Streams
+func eventStreamKey(aggregateType string) string {
+	return fmt.Sprintf("events:%s", aggregateType)
+}
+
+// PublishEvent appends a domain event to the stream of its aggregate type.
+// Streams are trimmed to roughly maxLen entries to bound retention.
+func (c *RedisCache) PublishEvent(ctx context.Context, event *entities.OutboxEvent, maxLen int64) error {
+	return c.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: eventStreamKey(event.AggregateType),
+		MaxLen: maxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"event_id":     event.EventID.String(),
+			"event_type":   string(event.EventType),
+			"aggregate_id": event.AggregateID.String(),
+			"sequence":     event.ID,
+			"occurred_at":  event.OccurredAt.Format(time.RFC3339Nano),
+			"payload":      event.Payload,
+		},
+	}).Err()
+}
+
+// Distributed Locks
+var (
+	// refreshLockScript extends a lock only if it is still held by the caller's token
+	refreshLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	// releaseLockScript deletes a lock only if it is still held by the caller's token
+	releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// AcquireLock takes the lock at key for token if nobody holds it
+func (c *RedisCache) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, token, ttl).Result()
+}
+
+// RefreshLock extends the lock's TTL and reports whether token still holds it
+func (c *RedisCache) RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	res, err := refreshLockScript.Run(ctx, c.client, []string{key}, token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// ReleaseLock frees the lock if token still holds it
+func (c *RedisCache) ReleaseLock(ctx context.Context, key, token string) error {
+	return releaseLockScript.Run(ctx, c.client, []string{key}, token).Err()
+}
+
+// Background Jobs
+func jobStatusKey(name string) string {
+	return fmt.Sprintf("jobs:status:%s", name)
+}
+
+// SetJobStatus stores the last-run status of a background job
+func (c *RedisCache) SetJobStatus(ctx context.Context, name string, status interface{}) error {
+	jsonData, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, jobStatusKey(name), jsonData, 0).Err()
+}
+
+// GetJobStatus loads the last-run status of a background job and reports
+// whether the job has run before
+func (c *RedisCache) GetJobStatus(ctx context.Context, name string, dest interface{}) (bool, error) {
+	data, err := c.client.Get(ctx, jobStatusKey(name)).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(data), dest)
+}
+
+// Seat Hold Limits
+func holdCooldownKey(identity string) string {
+	return fmt.Sprintf("hold:cooldown:%s", identity)
+}
+
+func holdPendingKey(identity string) string {
+	return fmt.Sprintf("hold:pending:%s", identity)
+}
+
+func holdTripKey(tripID uuid.UUID, identity string) string {
+	return fmt.Sprintf("hold:trip:%s:%s", tripID.String(), identity)
+}
+
+func holdExpiredKey(identity string) string {
+	return fmt.Sprintf("hold:expired:%s", identity)
+}
+
+// HoldIdentity is someone who can hold seats, such as "user:<id>" or
+// "ip:<address>", with the limits that apply to them; a zero limit is off
+type HoldIdentity struct {
+	Key          string
+	MaxPending   int // Unpaid bookings at once
+	MaxTripSeats int // Seats held on one trip at once
+}
+
+// HoldRejection explains why ReserveHold refused a hold
+type HoldRejection struct {
+	Policy     entities.HoldPolicy
+	Identity   string
+	Limit      int
+	RetryAfter time.Duration // Remaining cooldown
+}
+
+var (
+	// reserveHoldScript checks every identity against its limits and, only if
+	// all of them pass, records the hold under each of them. Per identity the
+	// keys are its cooldown, its pending bookings, then one set per trip. Sets
+	// are scored by expiry, so holds that were never released still lapse.
+	reserveHoldScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local expires = tonumber(ARGV[2])
+local hold = ARGV[3]
+local identities = tonumber(ARGV[4])
+local trips = tonumber(ARGV[5])
+local stride = 2 + trips
+local limits = 6 + trips
+
+local function extend(key)
+	local last = redis.call("ZRANGE", key, -1, -1, "WITHSCORES")
+	if last[2] then
+		redis.call("PEXPIREAT", key, last[2])
+	end
+end
+
+for i = 0, identities - 1 do
+	local k = i * stride
+	local cooldown = redis.call("PTTL", KEYS[k + 1])
+	if cooldown > 0 then
+		return {1, i, cooldown}
+	end
+
+	local maxPending = tonumber(ARGV[limits + 2 * i])
+	redis.call("ZREMRANGEBYSCORE", KEYS[k + 2], "-inf", now)
+	if maxPending > 0 and redis.call("ZCARD", KEYS[k + 2]) >= maxPending then
+		return {2, i, maxPending}
+	end
+
+	local maxTripSeats = tonumber(ARGV[limits + 2 * i + 1])
+	for t = 1, trips do
+		redis.call("ZREMRANGEBYSCORE", KEYS[k + 2 + t], "-inf", now)
+		if maxTripSeats > 0 and redis.call("ZCARD", KEYS[k + 2 + t]) + tonumber(ARGV[5 + t]) > maxTripSeats then
+			return {3, i, maxTripSeats}
+		end
+	end
+end
+
+for i = 0, identities - 1 do
+	local k = i * stride
+	redis.call("ZADD", KEYS[k + 2], expires, hold)
+	extend(KEYS[k + 2])
+	for t = 1, trips do
+		for s = 1, tonumber(ARGV[5 + t]) do
+			redis.call("ZADD", KEYS[k + 2 + t], expires, hold .. ":" .. s)
+		end
+		extend(KEYS[k + 2 + t])
+	end
+end
+return {0}`)
+
+	// releaseHoldScript removes a hold, and the seats it held, from the given sets
+	releaseHoldScript = redis.NewScript(`
+local prefix = ARGV[1] .. ":"
+for _, key in ipairs(KEYS) do
+	redis.call("ZREM", key, ARGV[1])
+	for _, member in ipairs(redis.call("ZRANGE", key, 0, -1)) do
+		if string.sub(member, 1, #prefix) == prefix then
+			redis.call("ZREM", key, member)
+		end
+	end
+end
+return 0`)
+
+	// recordHoldExpiryScript counts an expired hold against each identity (keys
+	// come in counter, cooldown pairs) and puts those that reach the threshold
+	// within the window on cooldown
+	recordHoldExpiryScript = redis.NewScript(`
+local started = 0
+for i = 1, #KEYS, 2 do
+	local count = redis.call("INCR", KEYS[i])
+	if count == 1 then
+		redis.call("PEXPIRE", KEYS[i], ARGV[2])
+	end
+	if count >= tonumber(ARGV[1]) then
+		redis.call("SET", KEYS[i + 1], "1", "PX", ARGV[3])
+		redis.call("DEL", KEYS[i])
+		started = started + 1
+	end
+end
+return started`)
+)
+
+// ReserveHold atomically checks the identities' hold limits and, if none is
+// exceeded, records holdID as pending for all of them until expiresAt, with
+// seatsPerTrip seats on each trip. It returns the first limit hit, if any.
+func (c *RedisCache) ReserveHold(ctx context.Context, holdID string, identities []HoldIdentity, seatsPerTrip map[uuid.UUID]int, expiresAt time.Time) (*HoldRejection, error) {
+	if len(identities) == 0 {
+		return nil, nil
+	}
+
+	tripIDs := make([]uuid.UUID, 0, len(seatsPerTrip))
+	for tripID := range seatsPerTrip {
+		tripIDs = append(tripIDs, tripID)
+	}
+
+	keys := make([]string, 0, len(identities)*(2+len(tripIDs)))
+	args := []interface{}{time.Now().UnixMilli(), expiresAt.UnixMilli(), holdID, len(identities), len(tripIDs)}
+	for _, tripID := range tripIDs {
+		args = append(args, seatsPerTrip[tripID])
+	}
+	for _, identity := range identities {
+		keys = append(keys, holdCooldownKey(identity.Key), holdPendingKey(identity.Key))
+		for _, tripID := range tripIDs {
+			keys = append(keys, holdTripKey(tripID, identity.Key))
+		}
+		args = append(args, identity.MaxPending, identity.MaxTripSeats)
+	}
+
+	res, err := reserveHoldScript.Run(ctx, c.client, keys, args...).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if res[0] == 0 {
+		return nil, nil
+	}
+
+	rejection := &HoldRejection{Identity: identities[res[1]].Key}
+	switch res[0] {
+	case 1:
+		rejection.Policy = entities.HoldPolicyCooldown
+		rejection.RetryAfter = time.Duration(res[2]) * time.Millisecond
+	case 2:
+		rejection.Policy = entities.HoldPolicyPendingBookings
+		rejection.Limit = int(res[2])
+	default:
+		rejection.Policy = entities.HoldPolicyTripSeats
+		rejection.Limit = int(res[2])
+	}
+	return rejection, nil
+}
+
+// ReleaseHold stops counting holdID against the identities
+func (c *RedisCache) ReleaseHold(ctx context.Context, holdID string, identities []string, tripIDs []uuid.UUID) error {
+	keys := make([]string, 0, len(identities)*(1+len(tripIDs)))
+	for _, identity := range identities {
+		keys = append(keys, holdPendingKey(identity))
+		for _, tripID := range tripIDs {
+			keys = append(keys, holdTripKey(tripID, identity))
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return releaseHoldScript.Run(ctx, c.client, keys).Err()
+}
+
+// RecordHoldExpiry counts a hold that lapsed unpaid against the identities;
+// threshold lapses within window put an identity on cooldown. It returns how
+// many identities were put on cooldown.
+func (c *RedisCache) RecordHoldExpiry(ctx context.Context, identities []string, threshold int, window, cooldown time.Duration) (int, error) {
+	keys := make([]string, 0, 2*len(identities))
+	for _, identity := range identities {
+		keys = append(keys, holdExpiredKey(identity), holdCooldownKey(identity))
+	}
+	if len(keys) == 0 || threshold <= 0 {
+		return 0, nil
+	}
+	return recordHoldExpiryScript.Run(ctx, c.client, keys, threshold, window.Milliseconds(), cooldown.Milliseconds()).Int()
 }
 
 // Rate Limiting
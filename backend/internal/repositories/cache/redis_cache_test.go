@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/yourusername/bus-booking/internal/entities"
+)
+
+// newTestCache connects to the Redis at REDIS_HOST:REDIS_PORT, localhost:6379
+// by default, and skips the test when none is running. Every test works on
+// trips of its own and removes their locks when it ends.
+func newTestCache(t *testing.T) *RedisCache {
+	t.Helper()
+	host, port := os.Getenv("REDIS_HOST"), os.Getenv("REDIS_PORT")
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "6379"
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", host, port),
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		t.Skipf("Redis is not available at %s:%s: %v", host, port, err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return NewRedisCache(client)
+}
+
+// dropSeatLocks removes every seat lock of the trip
+func dropSeatLocks(t *testing.T, c *RedisCache, tripID uuid.UUID) {
+	t.Helper()
+	t.Cleanup(func() {
+		ctx := context.Background()
+		iter := c.client.Scan(ctx, 0, seatLockPrefix(tripID)+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			c.client.Del(ctx, iter.Val())
+		}
+	})
+}
+
+// TestLockSeatsConcurrentHoldersNeverOverlap races bookings for overlapping
+// seats and stop ranges and checks every seat segment ends up with exactly
+// one owner: a booking whose lock succeeded.
+func TestLockSeatsConcurrentHoldersNeverOverlap(t *testing.T) {
+	c := newTestCache(t)
+	ctx := context.Background()
+	tripID := uuid.New()
+	dropSeatLocks(t, c, tripID)
+
+	seats := []string{"A1", "A2", "A3", "A4", "A5", "A6"}
+	ranges := []entities.StopRange{{From: 0, To: 4}, {From: 0, To: 2}, {From: 1, To: 3}, {From: 2, To: 4}, {From: 3, To: 4}}
+
+	type attempt struct {
+		token uuid.UUID
+		seats []string
+		stops entities.StopRange
+		err   error
+	}
+	attempts := make([]*attempt, 200)
+	for i := range attempts {
+		first := i % len(seats)
+		attempts[i] = &attempt{
+			token: uuid.New(),
+			seats: []string{seats[first], seats[(first+1+i%3)%len(seats)]},
+			stops: ranges[i%len(ranges)],
+		}
+	}
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, a := range attempts {
+		wg.Add(1)
+		go func(a *attempt) {
+			defer wg.Done()
+			<-start
+			a.err = c.LockSeats(ctx, tripID, a.seats, a.stops, a.token, time.Minute)
+		}(a)
+	}
+	close(start)
+	wg.Wait()
+
+	owners := make(map[string]uuid.UUID)
+	winners := 0
+	for _, a := range attempts {
+		if a.err != nil {
+			if !errors.Is(a.err, ErrSeatLocked) {
+				t.Fatalf("LockSeats failed: %v", a.err)
+			}
+			continue
+		}
+		winners++
+		for _, key := range seatLockKeys(tripID, a.seats, a.stops) {
+			if owner, ok := owners[key]; ok {
+				t.Fatalf("%s was locked by both %s and %s", key, owner, a.token)
+			}
+			owners[key] = a.token
+		}
+	}
+	if winners == 0 {
+		t.Fatal("no booking got its seats")
+	}
+
+	locks, err := c.GetSeatLocks(ctx, tripID)
+	if err != nil {
+		t.Fatalf("GetSeatLocks failed: %v", err)
+	}
+	if len(locks) != len(owners) {
+		t.Fatalf("found %d seat locks, want %d held by the winners", len(locks), len(owners))
+	}
+	for seat, lock := range locks {
+		key := seatLockKey(tripID, seat.SeatNumber, seat.Segment)
+		if owner := owners[key]; lock.Token != owner.String() {
+			t.Errorf("%s is held by %s, want %s", key, lock.Token, owner)
+		}
+	}
+}
+
+// TestSeatLocksIgnoreOtherTokens checks a token that does not hold the locks
+// can neither release nor extend them
+func TestSeatLocksIgnoreOtherTokens(t *testing.T) {
+	c := newTestCache(t)
+	ctx := context.Background()
+	tripID := uuid.New()
+	dropSeatLocks(t, c, tripID)
+
+	seats := []string{"B1", "B2"}
+	stops := entities.StopRange{From: 0, To: 2}
+	owner, other := uuid.New(), uuid.New()
+	if err := c.LockSeats(ctx, tripID, seats, stops, owner, time.Minute); err != nil {
+		t.Fatalf("LockSeats failed: %v", err)
+	}
+
+	released, err := c.UnlockSeats(ctx, tripID, seats, stops, other)
+	if err != nil {
+		t.Fatalf("UnlockSeats failed: %v", err)
+	}
+	if released != 0 {
+		t.Errorf("UnlockSeats with another token released %d locks, want 0", released)
+	}
+
+	extended, err := c.ExtendSeatLocks(ctx, tripID, seats, stops, other, time.Hour)
+	if err != nil {
+		t.Fatalf("ExtendSeatLocks failed: %v", err)
+	}
+	if extended {
+		t.Error("ExtendSeatLocks with another token reported success")
+	}
+
+	for _, key := range seatLockKeys(tripID, seats, stops) {
+		holder, err := c.client.Get(ctx, key).Result()
+		if err != nil {
+			t.Fatalf("%s is no longer locked: %v", key, err)
+		}
+		if holder != owner.String() {
+			t.Errorf("%s is held by %s, want %s", key, holder, owner)
+		}
+		if ttl := c.client.PTTL(ctx, key).Val(); ttl > time.Minute {
+			t.Errorf("%s was extended to %s", key, ttl)
+		}
+	}
+
+	if err := c.LockSeats(ctx, tripID, seats[:1], stops, other, time.Minute); !errors.Is(err, ErrSeatLocked) {
+		t.Errorf("LockSeats with another token = %v, want ErrSeatLocked", err)
+	}
+}
@@ -0,0 +1,12 @@
+package repositories
+
+import "errors"
+
+// ErrSeatUnavailable is returned when a seat is held or booked by someone else
+var ErrSeatUnavailable = errors.New("seat is no longer available")
+
+// ErrTripStatusChanged is returned when a trip changed status while it was being updated
+var ErrTripStatusChanged = errors.New("trip status was changed concurrently")
+
+// ErrPointsAlreadyRedeemed is returned when loyalty points were already redeemed on a booking
+var ErrPointsAlreadyRedeemed = errors.New("loyalty points are already redeemed on this booking")
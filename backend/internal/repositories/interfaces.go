@@ -8,6 +8,12 @@ import (
 	"github.com/yourusername/bus-booking/internal/entities"
 )
 
+// Transactor runs a function inside a database transaction; repository calls
+// made with the context passed to fn take part in it
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 // UserRepository defines the interface for user data operations
 type UserRepository interface {
 	Create(ctx context.Context, user *entities.User) error
@@ -29,6 +35,38 @@ type BusRepository interface {
 	List(ctx context.Context, status entities.BusStatus, limit, offset int) ([]*entities.Bus, error)
 }
 
+// DriverRepository defines the interface for driver data operations
+type DriverRepository interface {
+	Create(ctx context.Context, driver *entities.Driver) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.Driver, error)
+	GetByLicenceNumber(ctx context.Context, licenceNumber string) (*entities.Driver, error)
+	Update(ctx context.Context, driver *entities.Driver) error
+	// List returns drivers by name; empty filters match everyone
+	List(ctx context.Context, operatorName string, status entities.DriverStatus, limit, offset int) ([]*entities.Driver, error)
+}
+
+// LocationRepository defines the interface for the location catalog
+type LocationRepository interface {
+	// Create saves the location along with its aliases
+	Create(ctx context.Context, location *entities.Location) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.Location, error)
+	// GetByIDs returns the locations with the given IDs, in no particular order
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Location, error)
+	// GetBySearchName returns the location whose name or one of whose aliases
+	// folds to searchName
+	GetBySearchName(ctx context.Context, searchName string) (*entities.Location, error)
+	// Update saves the location's own fields; aliases are changed with ReplaceAliases
+	Update(ctx context.Context, location *entities.Location) error
+	// ReplaceAliases makes aliases the location's full list of aliases
+	ReplaceAliases(ctx context.Context, locationID uuid.UUID, aliases []entities.LocationAlias) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, limit, offset int) ([]*entities.Location, error)
+	// Match ranks the active locations whose name or an alias starts like,
+	// contains a word starting like, or is close in trigrams to the folded
+	// query, best first
+	Match(ctx context.Context, searchName string, limit int) ([]*entities.LocationMatch, error)
+}
+
 // RouteRepository defines the interface for route data operations
 type RouteRepository interface {
 	Create(ctx context.Context, route *entities.Route) error
@@ -36,7 +74,14 @@ type RouteRepository interface {
 	Update(ctx context.Context, route *entities.Route) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	List(ctx context.Context, limit, offset int) ([]*entities.Route, error)
-	Search(ctx context.Context, fromCity, toCity string) ([]*entities.Route, error)
+	// ReplaceStops makes stops the route's full list, deleting its other stops
+	ReplaceStops(ctx context.Context, routeID uuid.UUID, stops []entities.RouteStop) error
+	// StopsInUse returns which of the given stops bookings board or leave at
+	StopsInUse(ctx context.Context, stopIDs []uuid.UUID) ([]uuid.UUID, error)
+	// RenameLocation sets the city names of the routes from or to the location
+	RenameLocation(ctx context.Context, locationID uuid.UUID, name string) error
+	// Search returns the active routes between the locations; a nil location matches any
+	Search(ctx context.Context, fromLocationID, toLocationID *uuid.UUID) ([]*entities.Route, error)
 }
 
 // TripRepository defines the interface for trip data operations
@@ -44,34 +89,74 @@ type TripRepository interface {
 	Create(ctx context.Context, trip *entities.Trip) error
 	GetByID(ctx context.Context, id uuid.UUID) (*entities.Trip, error)
 	GetByIDWithDetails(ctx context.Context, id uuid.UUID) (*entities.Trip, error)
+	// GetByIDForUpdate loads the trip and locks its row until the transaction
+	// ends; bookings of the trip cannot be created meanwhile
+	GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entities.Trip, error)
 	Update(ctx context.Context, trip *entities.Trip) error
+	// UpdateStatus saves the trip's status and times provided it is still in
+	// status from, otherwise it returns ErrTripStatusChanged
+	UpdateStatus(ctx context.Context, trip *entities.Trip, from entities.TripStatus) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	List(ctx context.Context, status entities.TripStatus, limit, offset int) ([]*entities.Trip, error)
-	Search(ctx context.Context, fromCity, toCity string, date time.Time, limit, offset int) ([]*entities.Trip, error)
+	// Search returns a page of the trips matching every filter of the search
+	// with their free seats, in the order it asks for; a limit of zero returns
+	// every trip
+	Search(ctx context.Context, search *entities.TripSearch, limit, offset int) ([]*entities.TripSearchResult, error)
+	// SearchFacets counts the trips of the search per filter value in one query
+	SearchFacets(ctx context.Context, search *entities.TripSearch) (*entities.TripSearchFacets, error)
 	GetUpcomingTrips(ctx context.Context, limit int) ([]*entities.Trip, error)
+	// GetBusTrips returns the bus's trips that are not cancelled and overlap the
+	// period from to to, with their routes, in order of departure
+	GetBusTrips(ctx context.Context, busID uuid.UUID, from, to time.Time) ([]*entities.Trip, error)
+	// GetDriverTrips returns the trips that are not cancelled and overlap the
+	// period from to to on which the driver is main or co-driver, with their
+	// routes and buses, in order of departure
+	GetDriverTrips(ctx context.Context, driverID uuid.UUID, from, to time.Time) ([]*entities.Trip, error)
+	// GetTripsBetween returns all trips that are not cancelled and overlap the
+	// period from to to, with their routes and buses, by bus and then departure
+	GetTripsBetween(ctx context.Context, from, to time.Time) ([]*entities.Trip, error)
+	// GetScheduleTrips returns the trips generated from a schedule departing from from up to to
+	GetScheduleTrips(ctx context.Context, scheduleID uuid.UUID, from, to time.Time) ([]*entities.Trip, error)
+	// GetRouteTrips returns the route's trips still open for booking that
+	// depart after from, in order of departure
+	GetRouteTrips(ctx context.Context, routeID uuid.UUID, from time.Time) ([]*entities.Trip, error)
 }
 
 // SeatRepository defines the interface for seat data operations
 type SeatRepository interface {
-	// Batch operations for trip initialization
-	InitializeSeatsForTrip(ctx context.Context, tripID uuid.UUID, seatNumbers []string) error
+	// Batch operations for trip initialization; every seat gets a row per segment
+	InitializeSeatsForTrip(ctx context.Context, tripID uuid.UUID, seatNumbers []string, segments int) error
+	DeleteByTrip(ctx context.Context, tripID uuid.UUID) error
+	CountSegments(ctx context.Context, tripID uuid.UUID) (int, error)
+	// CountHeldSeats counts the trip's seat segments that are booked or under an unexpired lock
+	CountHeldSeats(ctx context.Context, tripID uuid.UUID) (int, error)
 
 	// Individual seat operations
-	GetByTripAndSeat(ctx context.Context, tripID uuid.UUID, seatNumber string) (*entities.SeatInfo, error)
+	GetByTripAndSeat(ctx context.Context, tripID uuid.UUID, seatNumber string, segment int) (*entities.SeatInfo, error)
 	GetAllByTrip(ctx context.Context, tripID uuid.UUID) ([]*entities.SeatInfo, error)
 
-	// Lock management with row-level locking
-	LockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, lockedBy uuid.UUID, duration time.Duration) error
-	UnlockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string) error
+	// Lock management with row-level locking on the segments of a stop range
+	LockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange, lockedBy uuid.UUID, duration time.Duration) error
+	UnlockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange, lockedBy uuid.UUID) error
 	UnlockExpiredSeats(ctx context.Context) (int, error)
 
 	// Booking operations
-	MarkSeatsAsBooked(ctx context.Context, tripID uuid.UUID, seatNumbers []string, bookingID uuid.UUID) error
+	MarkSeatsAsBooked(ctx context.Context, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange, bookingID uuid.UUID) error
+	ConfirmSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange, lockedBy uuid.UUID, bookingID uuid.UUID) error
 	ReleaseSeats(ctx context.Context, bookingID uuid.UUID) error
 
-	// Query operations
-	GetAvailableSeats(ctx context.Context, tripID uuid.UUID) ([]*entities.SeatInfo, error)
-	CountAvailableSeats(ctx context.Context, tripID uuid.UUID) (int, error)
+	// Operator blocks keeping seats off sale on every segment
+	BlockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, reason string, until *time.Time, blockedBy *uuid.UUID) error
+	UnblockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string) ([]string, error)
+	UnblockExpiredSeats(ctx context.Context) ([]*entities.SeatInfo, error)
+
+	// RepairSeat sets a seat to the status, booked for bookingID if given and
+	// without a lock, provided it has not changed since it was read as seat
+	RepairSeat(ctx context.Context, seat *entities.SeatInfo, status entities.SeatStatus, bookingID *uuid.UUID) (bool, error)
+
+	// Query operations; a seat is available when it is free on every segment of the range
+	GetAvailableSeats(ctx context.Context, tripID uuid.UUID, stops entities.StopRange) ([]*entities.SeatInfo, error)
+	CountAvailableSeats(ctx context.Context, tripID uuid.UUID, stops entities.StopRange) (int, error)
 }
 
 // BookingRepository defines the interface for booking data operations
@@ -82,6 +167,9 @@ type BookingRepository interface {
 	GetByCode(ctx context.Context, code string) (*entities.Booking, error)
 	Update(ctx context.Context, booking *entities.Booking) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status entities.BookingStatus) error
+	UpdatePromo(ctx context.Context, id uuid.UUID, code string, discount float64) error
+	UpdatePointsRedemption(ctx context.Context, id uuid.UUID, points int, discount float64) error
+	ClearPointsRedemption(ctx context.Context, id uuid.UUID) error
 	Delete(ctx context.Context, id uuid.UUID) error
 
 	// User bookings
@@ -93,7 +181,14 @@ type BookingRepository interface {
 
 	// Statistics
 	GetTripBookings(ctx context.Context, tripID uuid.UUID) ([]*entities.Booking, error)
+	GetTripBookingsByStatus(ctx context.Context, tripID uuid.UUID, statuses []entities.BookingStatus) ([]*entities.Booking, error)
 	CountBookingsByStatus(ctx context.Context, status entities.BookingStatus) (int64, error)
+	// CountTripBookings counts the trip's bookings in any status
+	CountTripBookings(ctx context.Context, tripID uuid.UUID) (int64, error)
+
+	// CountCustomerBookings counts bookings in the given statuses made by the
+	// user or, for guests and accounts alike, under the contact email
+	CountCustomerBookings(ctx context.Context, userID *uuid.UUID, email string, statuses []entities.BookingStatus) (int64, error)
 }
 
 // PaymentRepository defines the interface for payment data operations
@@ -105,6 +200,9 @@ type PaymentRepository interface {
 	GetByIdempotencyKey(ctx context.Context, key string) (*entities.Payment, error)
 	Update(ctx context.Context, payment *entities.Payment) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status entities.PaymentStatus) error
+	// TransitionStatus moves a payment from status from to status to and
+	// reports whether it was still in status from
+	TransitionStatus(ctx context.Context, id uuid.UUID, from, to entities.PaymentStatus) (bool, error)
 	List(ctx context.Context, limit, offset int) ([]*entities.Payment, error)
 }
 
@@ -119,6 +217,147 @@ type TicketRepository interface {
 	CheckIn(ctx context.Context, ticketCode string) error
 }
 
+// FareRuleRepository defines the interface for fare category rules
+type FareRuleRepository interface {
+	Create(ctx context.Context, rule *entities.FareRule) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.FareRule, error)
+	Update(ctx context.Context, rule *entities.FareRule) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, category entities.FareCategory, limit, offset int) ([]*entities.FareRule, error)
+
+	// GetApplicable returns active rules that are global or scoped to the route and/or operator
+	GetApplicable(ctx context.Context, routeID uuid.UUID, operatorName string) ([]*entities.FareRule, error)
+}
+
+// PromotionRepository defines the interface for promotions, their codes and redemptions
+type PromotionRepository interface {
+	Create(ctx context.Context, promotion *entities.Promotion) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.Promotion, error)
+	Update(ctx context.Context, promotion *entities.Promotion) error
+	List(ctx context.Context, activeOnly bool, limit, offset int) ([]*entities.Promotion, error)
+
+	// Codes
+	CreateCodes(ctx context.Context, codes []*entities.PromoCode) error
+	GetCodeByCode(ctx context.Context, code string) (*entities.PromoCode, error)
+	ListCodes(ctx context.Context, promotionID uuid.UUID, limit, offset int) ([]*entities.PromoCode, error)
+
+	// IncrementUsage counts one more use of the code and its promotion unless
+	// either has reached its usage limit, in which case it returns false. The
+	// promotion row stays locked until the surrounding transaction ends.
+	IncrementUsage(ctx context.Context, codeID, promotionID uuid.UUID) (bool, error)
+
+	// Redemptions
+	CreateRedemption(ctx context.Context, redemption *entities.PromoRedemption) error
+	CountCustomerRedemptions(ctx context.Context, promotionID uuid.UUID, userID *uuid.UUID, email string) (int64, error)
+
+	// ReleaseRedemption gives back the use counted for a booking's active
+	// redemption; it returns false when there was nothing to release
+	ReleaseRedemption(ctx context.Context, bookingID uuid.UUID) (bool, error)
+}
+
+// LoyaltyRepository defines the interface for the loyalty points ledger
+type LoyaltyRepository interface {
+	// LockAccount returns the member's account, opening it if needed, locked
+	// until the surrounding transaction ends
+	LockAccount(ctx context.Context, userID uuid.UUID) (*entities.LoyaltyAccount, error)
+	// GetAccount returns the member's account, or an empty one if none was opened yet
+	GetAccount(ctx context.Context, userID uuid.UUID) (*entities.LoyaltyAccount, error)
+
+	// Post records a balanced transaction and applies it to the member's balance
+	Post(ctx context.Context, txn *entities.LoyaltyTransaction) error
+	GetBookingTransactions(ctx context.Context, bookingID uuid.UUID) ([]*entities.LoyaltyTransaction, error)
+	ListTransactions(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*entities.LoyaltyTransaction, error)
+
+	// SumPointsSince totals the member's points from transactions of the given types since a time
+	SumPointsSince(ctx context.Context, userID uuid.UUID, types []entities.LoyaltyTransactionType, since time.Time) (int, error)
+
+	// Expiry: credits are consumed oldest first, so the points due to expire are
+	// the member's credits that expired by now less everything debited so far
+	ListUsersWithExpiredPoints(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+	ExpiredPoints(ctx context.Context, userID uuid.UUID, now time.Time) (int, error)
+}
+
+// SeatDiscrepancyRepository defines the interface for the seat reconciler's findings
+type SeatDiscrepancyRepository interface {
+	// Record saves a finding; a flagged one that is still open is updated instead
+	Record(ctx context.Context, discrepancy *entities.SeatDiscrepancy) error
+	// List returns findings newest first; kind and tripID filter when set
+	List(ctx context.Context, kind entities.SeatDiscrepancyKind, tripID *uuid.UUID, openOnly bool, limit, offset int) ([]*entities.SeatDiscrepancy, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.SeatDiscrepancy, error)
+	Resolve(ctx context.Context, id uuid.UUID) error
+}
+
+// TripScheduleRepository defines the interface for trip schedule templates
+type TripScheduleRepository interface {
+	Create(ctx context.Context, schedule *entities.TripSchedule) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.TripSchedule, error)
+	Update(ctx context.Context, schedule *entities.TripSchedule) error
+	List(ctx context.Context, activeOnly bool, limit, offset int) ([]*entities.TripSchedule, error)
+	GetActive(ctx context.Context) ([]*entities.TripSchedule, error)
+}
+
+// HolidayRepository defines the interface for the holidays schedules can skip
+type HolidayRepository interface {
+	Create(ctx context.Context, holiday *entities.Holiday) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// List returns holidays between the "YYYY-MM-DD" dates, either of which may be empty
+	List(ctx context.Context, from, to string) ([]*entities.Holiday, error)
+}
+
+// BusSwapRepository defines the interface for the history of trip bus replacements
+type BusSwapRepository interface {
+	Create(ctx context.Context, swap *entities.BusSwap) error
+	ListByTrip(ctx context.Context, tripID uuid.UUID) ([]*entities.BusSwap, error)
+}
+
+// TripCancellationRepository defines the interface for operator trip cancellations and their per-booking outcomes
+type TripCancellationRepository interface {
+	Create(ctx context.Context, cancellation *entities.TripCancellation) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.TripCancellation, error)
+	GetByTrip(ctx context.Context, tripID uuid.UUID) (*entities.TripCancellation, error)
+	CreateOutcome(ctx context.Context, outcome *entities.TripCancellationOutcome) error
+	GetOutcomeByToken(ctx context.Context, token string) (*entities.TripCancellationOutcome, error)
+	UpdateOutcome(ctx context.Context, outcome *entities.TripCancellationOutcome) error
+	// ClaimOutcomeRefund marks a rebooked outcome as chosen for a refund that
+	// has yet to go through and reports whether it was still rebooked
+	ClaimOutcomeRefund(ctx context.Context, id uuid.UUID, reason string) (bool, error)
+}
+
+// HoldViolationRepository defines the interface for refused seat holds kept for fraud review
+type HoldViolationRepository interface {
+	Create(ctx context.Context, violation *entities.HoldViolation) error
+	// List returns violations newest first, optionally only those of one policy
+	List(ctx context.Context, policy entities.HoldPolicy, limit, offset int) ([]*entities.HoldViolation, error)
+}
+
+// FulfilmentRepository defines the interface for payment fulfilment tracking
+type FulfilmentRepository interface {
+	CreateIfNotExists(ctx context.Context, fulfilment *entities.Fulfilment) (bool, error)
+	GetByBookingID(ctx context.Context, bookingID uuid.UUID) (*entities.Fulfilment, error)
+	Update(ctx context.Context, fulfilment *entities.Fulfilment) error
+
+	// ClaimDue leases pending fulfilments whose next attempt is due so that
+	// concurrent workers never process the same fulfilment
+	ClaimDue(ctx context.Context, lease time.Duration, limit int) ([]*entities.Fulfilment, error)
+}
+
+// OutboxRepository defines the interface for the transactional event outbox
+type OutboxRepository interface {
+	Add(ctx context.Context, events ...*entities.OutboxEvent) error
+
+	// TryLockRelay takes a transaction-scoped lock so only one relay claims at a time
+	TryLockRelay(ctx context.Context) (bool, error)
+	// ClaimUnpublished leases the oldest unpublished events, in sequence order,
+	// to the caller; it claims none while another relay's lease is running
+	ClaimUnpublished(ctx context.Context, lease time.Duration, limit int) ([]*entities.OutboxEvent, error)
+	MarkPublished(ctx context.Context, ids []int64) error
+	MarkFailed(ctx context.Context, id int64, reason string) error
+	ReleaseClaims(ctx context.Context, ids []int64) error
+
+	// Retention
+	DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
 // RefreshTokenRepository defines the interface for refresh token operations
 type RefreshTokenRepository interface {
 	Create(ctx context.Context, token *entities.RefreshToken) error
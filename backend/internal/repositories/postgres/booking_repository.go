@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
 	"gorm.io/gorm"
 )
 
@@ -18,12 +19,12 @@ func NewBookingRepository(db *gorm.DB) *bookingRepository {
 }
 
 func (r *bookingRepository) Create(ctx context.Context, booking *entities.Booking) error {
-	return r.db.WithContext(ctx).Create(booking).Error
+	return dbFromContext(ctx, r.db).Create(booking).Error
 }
 
 func (r *bookingRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Booking, error) {
 	var booking entities.Booking
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&booking).Error
+	err := dbFromContext(ctx, r.db).Where("id = ?", id).First(&booking).Error
 	if err != nil {
 		return nil, err
 	}
@@ -32,12 +33,19 @@ func (r *bookingRepository) GetByID(ctx context.Context, id uuid.UUID) (*entitie
 
 func (r *bookingRepository) GetByIDWithDetails(ctx context.Context, id uuid.UUID) (*entities.Booking, error) {
 	var booking entities.Booking
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("Trip.Route").
 		Preload("Trip.Bus").
 		Preload("User").
 		Preload("Payment").
 		Preload("Tickets").
+		Preload("PickupStop").
+		Preload("DropoffStop").
+		Preload("ReturnBooking.Trip.Route").
+		Preload("ReturnBooking.Trip.Bus").
+		Preload("ReturnBooking.Tickets").
+		Preload("ReturnBooking.PickupStop").
+		Preload("ReturnBooking.DropoffStop").
 		Where("id = ?", id).
 		First(&booking).Error
 	if err != nil {
@@ -48,11 +56,18 @@ func (r *bookingRepository) GetByIDWithDetails(ctx context.Context, id uuid.UUID
 
 func (r *bookingRepository) GetByCode(ctx context.Context, code string) (*entities.Booking, error) {
 	var booking entities.Booking
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("Trip.Route").
 		Preload("Trip.Bus").
 		Preload("Payment").
 		Preload("Tickets").
+		Preload("PickupStop").
+		Preload("DropoffStop").
+		Preload("ReturnBooking.Trip.Route").
+		Preload("ReturnBooking.Trip.Bus").
+		Preload("ReturnBooking.Tickets").
+		Preload("ReturnBooking.PickupStop").
+		Preload("ReturnBooking.DropoffStop").
 		Where("booking_code = ?", code).
 		First(&booking).Error
 	if err != nil {
@@ -62,26 +77,66 @@ func (r *bookingRepository) GetByCode(ctx context.Context, code string) (*entiti
 }
 
 func (r *bookingRepository) Update(ctx context.Context, booking *entities.Booking) error {
-	return r.db.WithContext(ctx).Save(booking).Error
+	return dbFromContext(ctx, r.db).Save(booking).Error
 }
 
 func (r *bookingRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.BookingStatus) error {
-	return r.db.WithContext(ctx).Model(&entities.Booking{}).
+	return dbFromContext(ctx, r.db).Model(&entities.Booking{}).
 		Where("id = ?", id).
 		Update("status", status).Error
 }
 
+func (r *bookingRepository) UpdatePromo(ctx context.Context, id uuid.UUID, code string, discount float64) error {
+	return dbFromContext(ctx, r.db).Model(&entities.Booking{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"promo_code":     code,
+			"promo_discount": discount,
+		}).Error
+}
+
+// UpdatePointsRedemption records the points redeemed on a booking that has
+// none yet, so two concurrent redemptions cannot both land on it
+func (r *bookingRepository) UpdatePointsRedemption(ctx context.Context, id uuid.UUID, points int, discount float64) error {
+	result := dbFromContext(ctx, r.db).Model(&entities.Booking{}).
+		Where("id = ? AND points_redeemed = 0", id).
+		Updates(map[string]interface{}{
+			"points_redeemed": points,
+			"points_discount": discount,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repositories.ErrPointsAlreadyRedeemed
+	}
+	return nil
+}
+
+// ClearPointsRedemption takes the redeemed points and their discount off a booking
+func (r *bookingRepository) ClearPointsRedemption(ctx context.Context, id uuid.UUID) error {
+	return dbFromContext(ctx, r.db).Model(&entities.Booking{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"points_redeemed": 0,
+			"points_discount": 0,
+		}).Error
+}
+
 func (r *bookingRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&entities.Booking{}, id).Error
+	return dbFromContext(ctx, r.db).Delete(&entities.Booking{}, id).Error
 }
 
 func (r *bookingRepository) GetByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*entities.Booking, error) {
 	var bookings []*entities.Booking
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("Trip.Route").
 		Preload("Trip.Bus").
 		Preload("Payment").
+		Preload("ReturnBooking.Trip.Route").
 		Where("user_id = ?", userID).
+		// Return legs are listed under their outbound booking
+		Where("parent_booking_id IS NULL").
 		Order("created_at DESC").
 		Limit(limit).
 		Offset(offset).
@@ -91,22 +146,25 @@ func (r *bookingRepository) GetByUser(ctx context.Context, userID uuid.UUID, lim
 
 func (r *bookingRepository) GetExpiredBookings(ctx context.Context) ([]*entities.Booking, error) {
 	var bookings []*entities.Booking
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Where("status = ? AND expires_at < NOW()", entities.BookingStatusPending).
 		Find(&bookings).Error
 	return bookings, err
 }
 
 func (r *bookingRepository) MarkAsExpired(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Model(&entities.Booking{}).
+	return dbFromContext(ctx, r.db).Model(&entities.Booking{}).
 		Where("id = ?", id).
 		Update("status", entities.BookingStatusExpired).Error
 }
 
 func (r *bookingRepository) GetTripBookings(ctx context.Context, tripID uuid.UUID) ([]*entities.Booking, error) {
 	var bookings []*entities.Booking
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("User").
+		Preload("Tickets").
+		Preload("PickupStop").
+		Preload("DropoffStop").
 		Where("trip_id = ? AND status IN ?", tripID, []entities.BookingStatus{
 			entities.BookingStatusPaid,
 			entities.BookingStatusConfirmed,
@@ -115,10 +173,41 @@ func (r *bookingRepository) GetTripBookings(ctx context.Context, tripID uuid.UUI
 	return bookings, err
 }
 
+func (r *bookingRepository) GetTripBookingsByStatus(ctx context.Context, tripID uuid.UUID, statuses []entities.BookingStatus) ([]*entities.Booking, error) {
+	var bookings []*entities.Booking
+	err := dbFromContext(ctx, r.db).
+		Where("trip_id = ? AND status IN ?", tripID, statuses).
+		Find(&bookings).Error
+	return bookings, err
+}
+
 func (r *bookingRepository) CountBookingsByStatus(ctx context.Context, status entities.BookingStatus) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&entities.Booking{}).
+	err := dbFromContext(ctx, r.db).Model(&entities.Booking{}).
 		Where("status = ?", status).
 		Count(&count).Error
 	return count, err
 }
+
+func (r *bookingRepository) CountTripBookings(ctx context.Context, tripID uuid.UUID) (int64, error) {
+	var count int64
+	err := dbFromContext(ctx, r.db).Model(&entities.Booking{}).
+		Where("trip_id = ?", tripID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *bookingRepository) CountCustomerBookings(ctx context.Context, userID *uuid.UUID, email string, statuses []entities.BookingStatus) (int64, error) {
+	var count int64
+	query := dbFromContext(ctx, r.db).Model(&entities.Booking{}).
+		Where("status IN ?", statuses)
+
+	if userID != nil {
+		query = query.Where("user_id = ? OR LOWER(contact_email) = LOWER(?)", *userID, email)
+	} else {
+		query = query.Where("LOWER(contact_email) = LOWER(?)", email)
+	}
+
+	err := query.Count(&count).Error
+	return count, err
+}
@@ -17,12 +17,12 @@ func NewBusRepository(db *gorm.DB) *BusRepository {
 }
 
 func (r *BusRepository) Create(ctx context.Context, bus *entities.Bus) error {
-	return r.db.WithContext(ctx).Create(bus).Error
+	return dbFromContext(ctx, r.db).Create(bus).Error
 }
 
 func (r *BusRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Bus, error) {
 	var bus entities.Bus
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&bus).Error
+	err := dbFromContext(ctx, r.db).Where("id = ?", id).First(&bus).Error
 	if err != nil {
 		return nil, err
 	}
@@ -31,7 +31,7 @@ func (r *BusRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Bu
 
 func (r *BusRepository) GetByLicensePlate(ctx context.Context, licensePlate string) (*entities.Bus, error) {
 	var bus entities.Bus
-	err := r.db.WithContext(ctx).Where("license_plate = ?", licensePlate).First(&bus).Error
+	err := dbFromContext(ctx, r.db).Where("license_plate = ?", licensePlate).First(&bus).Error
 	if err != nil {
 		return nil, err
 	}
@@ -39,16 +39,16 @@ func (r *BusRepository) GetByLicensePlate(ctx context.Context, licensePlate stri
 }
 
 func (r *BusRepository) Update(ctx context.Context, bus *entities.Bus) error {
-	return r.db.WithContext(ctx).Save(bus).Error
+	return dbFromContext(ctx, r.db).Save(bus).Error
 }
 
 func (r *BusRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&entities.Bus{}, "id = ?", id).Error
+	return dbFromContext(ctx, r.db).Delete(&entities.Bus{}, "id = ?", id).Error
 }
 
 func (r *BusRepository) List(ctx context.Context, status entities.BusStatus, limit, offset int) ([]*entities.Bus, error) {
 	var buses []*entities.Bus
-	query := r.db.WithContext(ctx)
+	query := dbFromContext(ctx, r.db)
 
 	if status != "" {
 		query = query.Where("status = ?", status)
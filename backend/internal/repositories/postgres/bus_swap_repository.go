@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+)
+
+type busSwapRepository struct {
+	db *gorm.DB
+}
+
+// NewBusSwapRepository creates a new bus swap repository
+func NewBusSwapRepository(db *gorm.DB) *busSwapRepository {
+	return &busSwapRepository{db: db}
+}
+
+func (r *busSwapRepository) Create(ctx context.Context, swap *entities.BusSwap) error {
+	return dbFromContext(ctx, r.db).Create(swap).Error
+}
+
+func (r *busSwapRepository) ListByTrip(ctx context.Context, tripID uuid.UUID) ([]*entities.BusSwap, error) {
+	var swaps []*entities.BusSwap
+	err := dbFromContext(ctx, r.db).
+		Where("trip_id = ?", tripID).
+		Order("created_at DESC").
+		Find(&swaps).Error
+	return swaps, err
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+)
+
+type driverRepository struct {
+	db *gorm.DB
+}
+
+// NewDriverRepository creates a new driver repository
+func NewDriverRepository(db *gorm.DB) *driverRepository {
+	return &driverRepository{db: db}
+}
+
+func (r *driverRepository) Create(ctx context.Context, driver *entities.Driver) error {
+	return dbFromContext(ctx, r.db).Create(driver).Error
+}
+
+func (r *driverRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Driver, error) {
+	var driver entities.Driver
+	err := dbFromContext(ctx, r.db).Where("id = ?", id).First(&driver).Error
+	if err != nil {
+		return nil, err
+	}
+	return &driver, nil
+}
+
+func (r *driverRepository) GetByLicenceNumber(ctx context.Context, licenceNumber string) (*entities.Driver, error) {
+	var driver entities.Driver
+	err := dbFromContext(ctx, r.db).Where("licence_number = ?", licenceNumber).First(&driver).Error
+	if err != nil {
+		return nil, err
+	}
+	return &driver, nil
+}
+
+func (r *driverRepository) Update(ctx context.Context, driver *entities.Driver) error {
+	return dbFromContext(ctx, r.db).Save(driver).Error
+}
+
+func (r *driverRepository) List(ctx context.Context, operatorName string, status entities.DriverStatus, limit, offset int) ([]*entities.Driver, error) {
+	var drivers []*entities.Driver
+	query := dbFromContext(ctx, r.db)
+	if operatorName != "" {
+		query = query.Where("operator_name = ?", operatorName)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.
+		Order("full_name ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&drivers).Error
+	return drivers, err
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+)
+
+type fareRuleRepository struct {
+	db *gorm.DB
+}
+
+// NewFareRuleRepository creates a new fare rule repository
+func NewFareRuleRepository(db *gorm.DB) *fareRuleRepository {
+	return &fareRuleRepository{db: db}
+}
+
+func (r *fareRuleRepository) Create(ctx context.Context, rule *entities.FareRule) error {
+	return dbFromContext(ctx, r.db).Create(rule).Error
+}
+
+func (r *fareRuleRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.FareRule, error) {
+	var rule entities.FareRule
+	err := dbFromContext(ctx, r.db).Where("id = ?", id).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *fareRuleRepository) Update(ctx context.Context, rule *entities.FareRule) error {
+	return dbFromContext(ctx, r.db).Save(rule).Error
+}
+
+func (r *fareRuleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return dbFromContext(ctx, r.db).Delete(&entities.FareRule{}, "id = ?", id).Error
+}
+
+func (r *fareRuleRepository) List(ctx context.Context, category entities.FareCategory, limit, offset int) ([]*entities.FareRule, error) {
+	var rules []*entities.FareRule
+	query := dbFromContext(ctx, r.db)
+
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	err := query.Order("category ASC, created_at ASC").Limit(limit).Offset(offset).Find(&rules).Error
+	return rules, err
+}
+
+func (r *fareRuleRepository) GetApplicable(ctx context.Context, routeID uuid.UUID, operatorName string) ([]*entities.FareRule, error) {
+	var rules []*entities.FareRule
+	err := dbFromContext(ctx, r.db).
+		Where("is_active = ?", true).
+		Where("route_id IS NULL OR route_id = ?", routeID).
+		Where("operator_name IS NULL OR operator_name = ?", operatorName).
+		Find(&rules).Error
+	return rules, err
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type fulfilmentRepository struct {
+	db *gorm.DB
+}
+
+// NewFulfilmentRepository creates a new fulfilment repository
+func NewFulfilmentRepository(db *gorm.DB) *fulfilmentRepository {
+	return &fulfilmentRepository{db: db}
+}
+
+// CreateIfNotExists inserts the fulfilment unless one already exists for the booking.
+// It reports whether a new row was created.
+func (r *fulfilmentRepository) CreateIfNotExists(ctx context.Context, fulfilment *entities.Fulfilment) (bool, error) {
+	result := dbFromContext(ctx, r.db).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "booking_id"}}, DoNothing: true}).
+		Create(fulfilment)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *fulfilmentRepository) GetByBookingID(ctx context.Context, bookingID uuid.UUID) (*entities.Fulfilment, error) {
+	var fulfilment entities.Fulfilment
+	err := dbFromContext(ctx, r.db).Where("booking_id = ?", bookingID).First(&fulfilment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &fulfilment, nil
+}
+
+func (r *fulfilmentRepository) Update(ctx context.Context, fulfilment *entities.Fulfilment) error {
+	return dbFromContext(ctx, r.db).Save(fulfilment).Error
+}
+
+// ClaimDue pushes next_attempt_at forward by the lease for a batch of due fulfilments
+// using SKIP LOCKED, so each fulfilment is handed to a single worker
+func (r *fulfilmentRepository) ClaimDue(ctx context.Context, lease time.Duration, limit int) ([]*entities.Fulfilment, error) {
+	var fulfilments []*entities.Fulfilment
+	now := time.Now()
+	err := dbFromContext(ctx, r.db).Raw(`
+		UPDATE fulfilments SET next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM fulfilments
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at ASC
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(lease), now, entities.FulfilmentStatusPending, now, limit,
+	).Scan(&fulfilments).Error
+	return fulfilments, err
+}
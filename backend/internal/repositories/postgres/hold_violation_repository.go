@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+)
+
+type holdViolationRepository struct {
+	db *gorm.DB
+}
+
+// NewHoldViolationRepository creates a new hold violation repository
+func NewHoldViolationRepository(db *gorm.DB) *holdViolationRepository {
+	return &holdViolationRepository{db: db}
+}
+
+func (r *holdViolationRepository) Create(ctx context.Context, violation *entities.HoldViolation) error {
+	return dbFromContext(ctx, r.db).Create(violation).Error
+}
+
+func (r *holdViolationRepository) List(ctx context.Context, policy entities.HoldPolicy, limit, offset int) ([]*entities.HoldViolation, error) {
+	var violations []*entities.HoldViolation
+	query := dbFromContext(ctx, r.db)
+	if policy != "" {
+		query = query.Where("policy = ?", policy)
+	}
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&violations).Error
+	return violations, err
+}
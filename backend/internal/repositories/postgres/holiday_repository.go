@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+)
+
+type holidayRepository struct {
+	db *gorm.DB
+}
+
+// NewHolidayRepository creates a new holiday repository
+func NewHolidayRepository(db *gorm.DB) *holidayRepository {
+	return &holidayRepository{db: db}
+}
+
+func (r *holidayRepository) Create(ctx context.Context, holiday *entities.Holiday) error {
+	return dbFromContext(ctx, r.db).Create(holiday).Error
+}
+
+func (r *holidayRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return dbFromContext(ctx, r.db).Delete(&entities.Holiday{}, "id = ?", id).Error
+}
+
+func (r *holidayRepository) List(ctx context.Context, from, to string) ([]*entities.Holiday, error) {
+	var holidays []*entities.Holiday
+	query := dbFromContext(ctx, r.db)
+	if from != "" {
+		query = query.Where("date >= ?", from)
+	}
+	if to != "" {
+		query = query.Where("date <= ?", to)
+	}
+	err := query.Order("date ASC").Find(&holidays).Error
+	return holidays, err
+}
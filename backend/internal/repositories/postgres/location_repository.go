@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+)
+
+// likeEscaper makes a string match itself literally in a LIKE pattern, with
+// the default backslash escape
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type locationRepository struct {
+	db *gorm.DB
+}
+
+// NewLocationRepository creates a new location repository
+func NewLocationRepository(db *gorm.DB) *locationRepository {
+	return &locationRepository{db: db}
+}
+
+func (r *locationRepository) Create(ctx context.Context, location *entities.Location) error {
+	return dbFromContext(ctx, r.db).Create(location).Error
+}
+
+func (r *locationRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Location, error) {
+	var location entities.Location
+	err := dbFromContext(ctx, r.db).
+		Preload("Aliases", orderAliases).
+		Where("id = ?", id).
+		First(&location).Error
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (r *locationRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Location, error) {
+	var locations []*entities.Location
+	if len(ids) == 0 {
+		return locations, nil
+	}
+	err := dbFromContext(ctx, r.db).
+		Preload("Aliases", orderAliases).
+		Where("id IN ?", ids).
+		Find(&locations).Error
+	return locations, err
+}
+
+func (r *locationRepository) GetBySearchName(ctx context.Context, searchName string) (*entities.Location, error) {
+	var location entities.Location
+	err := dbFromContext(ctx, r.db).
+		Preload("Aliases", orderAliases).
+		Where("search_name = ? OR id IN (SELECT location_id FROM location_aliases WHERE search_name = ?)", searchName, searchName).
+		First(&location).Error
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+// Update saves the location's own fields; aliases are changed with ReplaceAliases
+func (r *locationRepository) Update(ctx context.Context, location *entities.Location) error {
+	return dbFromContext(ctx, r.db).Omit("Aliases").Save(location).Error
+}
+
+// ReplaceAliases makes aliases the location's full list of aliases
+func (r *locationRepository) ReplaceAliases(ctx context.Context, locationID uuid.UUID, aliases []entities.LocationAlias) error {
+	db := dbFromContext(ctx, r.db)
+	if err := db.Where("location_id = ?", locationID).Delete(&entities.LocationAlias{}).Error; err != nil {
+		return err
+	}
+	for i := range aliases {
+		aliases[i].ID = uuid.Nil
+		aliases[i].LocationID = locationID
+		if err := db.Create(&aliases[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *locationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return dbFromContext(ctx, r.db).Delete(&entities.Location{}, "id = ?", id).Error
+}
+
+func (r *locationRepository) List(ctx context.Context, limit, offset int) ([]*entities.Location, error) {
+	var locations []*entities.Location
+	err := dbFromContext(ctx, r.db).
+		Preload("Aliases", orderAliases).
+		Limit(limit).
+		Offset(offset).
+		Order("search_name ASC").
+		Find(&locations).Error
+	return locations, err
+}
+
+// Match scores every name and alias of the active locations against the
+// query, keeps the best name of each location and ranks the locations by it.
+// Exact names score 3, names starting with the query 2 and names with a word
+// starting with it 1, each plus their trigram similarity to break ties; other
+// names need a word similarity above pg_trgm's threshold and score just that.
+// All the conditions can use the trigram indexes on search_name, and folded
+// names hold no LIKE wildcards.
+func (r *locationRepository) Match(ctx context.Context, searchName string, limit int) ([]*entities.LocationMatch, error) {
+	var found []struct {
+		LocationID  uuid.UUID
+		MatchedName string
+		Score       float64
+	}
+	err := dbFromContext(ctx, r.db).Raw(`
+		SELECT location_id, matched_name, score FROM (
+			SELECT DISTINCT ON (location_id) location_id, name AS matched_name,
+				CASE
+					WHEN search_name = @query THEN 3
+					WHEN search_name LIKE @prefix THEN 2 + similarity(search_name, @query)
+					WHEN search_name LIKE @word THEN 1 + similarity(search_name, @query)
+					ELSE word_similarity(@query, search_name)
+				END::float8 AS score
+			FROM (
+				SELECT id AS location_id, name, search_name FROM locations WHERE is_active
+				UNION ALL
+				SELECT location_id, location_aliases.name, location_aliases.search_name
+				FROM location_aliases JOIN locations ON locations.id = location_aliases.location_id
+				WHERE locations.is_active
+			) names
+			WHERE search_name LIKE @prefix OR search_name LIKE @word OR @query <% search_name
+			ORDER BY location_id, score DESC
+		) best
+		ORDER BY score DESC, matched_name
+		LIMIT @limit`,
+		map[string]interface{}{
+			"query":  searchName,
+			"prefix": likeEscaper.Replace(searchName) + "%",
+			"word":   "% " + likeEscaper.Replace(searchName) + "%",
+			"limit":  limit,
+		}).
+		Scan(&found).Error
+	if err != nil || len(found) == 0 {
+		return []*entities.LocationMatch{}, err
+	}
+
+	ids := make([]uuid.UUID, len(found))
+	for i, match := range found {
+		ids[i] = match.LocationID
+	}
+	locations, err := r.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*entities.Location, len(locations))
+	for _, location := range locations {
+		byID[location.ID] = location
+	}
+
+	matches := make([]*entities.LocationMatch, 0, len(found))
+	for _, match := range found {
+		if byID[match.LocationID] != nil {
+			matches = append(matches, &entities.LocationMatch{
+				Location:    byID[match.LocationID],
+				MatchedName: match.MatchedName,
+				Score:       match.Score,
+			})
+		}
+	}
+	return matches, nil
+}
+
+// orderAliases preloads a location's aliases by name
+func orderAliases(db *gorm.DB) *gorm.DB {
+	return db.Order("location_aliases.name ASC")
+}
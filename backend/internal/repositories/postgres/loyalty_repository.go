@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type loyaltyRepository struct {
+	db *gorm.DB
+}
+
+// NewLoyaltyRepository creates a new loyalty ledger repository
+func NewLoyaltyRepository(db *gorm.DB) *loyaltyRepository {
+	return &loyaltyRepository{db: db}
+}
+
+// expiredPointsSQL sums, per member, credits whose expiry has passed plus all debits
+const expiredPointsSQL = `SUM(CASE WHEN amount > 0 AND expires_at <= ? THEN amount ELSE 0 END) +
+	SUM(CASE WHEN amount < 0 THEN amount ELSE 0 END)`
+
+func (r *loyaltyRepository) LockAccount(ctx context.Context, userID uuid.UUID) (*entities.LoyaltyAccount, error) {
+	db := dbFromContext(ctx, r.db)
+
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&entities.LoyaltyAccount{UserID: userID}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var account entities.LoyaltyAccount
+	err = db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *loyaltyRepository) GetAccount(ctx context.Context, userID uuid.UUID) (*entities.LoyaltyAccount, error) {
+	var accounts []*entities.LoyaltyAccount
+	err := dbFromContext(ctx, r.db).Where("user_id = ?", userID).Limit(1).Find(&accounts).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return &entities.LoyaltyAccount{UserID: userID}, nil
+	}
+	return accounts[0], nil
+}
+
+func (r *loyaltyRepository) Post(ctx context.Context, txn *entities.LoyaltyTransaction) error {
+	if !txn.IsBalanced() {
+		return fmt.Errorf("loyalty transaction %s is not balanced", txn.Type)
+	}
+
+	return dbFromContext(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(txn).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&entities.LoyaltyAccount{}).
+			Where("user_id = ?", txn.UserID).
+			Update("balance", gorm.Expr("balance + ?", txn.Points)).Error
+	})
+}
+
+func (r *loyaltyRepository) GetBookingTransactions(ctx context.Context, bookingID uuid.UUID) ([]*entities.LoyaltyTransaction, error) {
+	var txns []*entities.LoyaltyTransaction
+	err := dbFromContext(ctx, r.db).
+		Where("booking_id = ?", bookingID).
+		Order("created_at ASC").
+		Find(&txns).Error
+	return txns, err
+}
+
+func (r *loyaltyRepository) ListTransactions(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*entities.LoyaltyTransaction, error) {
+	var txns []*entities.LoyaltyTransaction
+	err := dbFromContext(ctx, r.db).
+		Preload("Entries").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&txns).Error
+	return txns, err
+}
+
+func (r *loyaltyRepository) SumPointsSince(ctx context.Context, userID uuid.UUID, types []entities.LoyaltyTransactionType, since time.Time) (int, error) {
+	var total int
+	err := dbFromContext(ctx, r.db).Model(&entities.LoyaltyTransaction{}).
+		Select("COALESCE(SUM(points), 0)").
+		Where("user_id = ? AND type IN ? AND created_at >= ?", userID, types, since).
+		Scan(&total).Error
+	return total, err
+}
+
+func (r *loyaltyRepository) ListUsersWithExpiredPoints(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	err := dbFromContext(ctx, r.db).Model(&entities.LoyaltyEntry{}).
+		Select("user_id").
+		Where("account = ?", entities.LedgerAccountMember).
+		Group("user_id").
+		Having(expiredPointsSQL+" > 0", now).
+		Scan(&userIDs).Error
+	return userIDs, err
+}
+
+func (r *loyaltyRepository) ExpiredPoints(ctx context.Context, userID uuid.UUID, now time.Time) (int, error) {
+	var points int
+	err := dbFromContext(ctx, r.db).Model(&entities.LoyaltyEntry{}).
+		Select("COALESCE("+expiredPointsSQL+", 0)", now).
+		Where("user_id = ? AND account = ?", userID, entities.LedgerAccountMember).
+		Scan(&points).Error
+	if err != nil {
+		return 0, err
+	}
+	if points < 0 {
+		return 0, nil
+	}
+	return points, nil
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+)
+
+// outboxRelayLockKey is the advisory lock key held by the active outbox relay
+const outboxRelayLockKey = 727001
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *gorm.DB) *outboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Add(ctx context.Context, events ...*entities.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return dbFromContext(ctx, r.db).Create(&events).Error
+}
+
+// TryLockRelay must be called inside a transaction; the lock is released on commit or rollback
+func (r *outboxRepository) TryLockRelay(ctx context.Context) (bool, error) {
+	var locked bool
+	err := dbFromContext(ctx, r.db).Raw("SELECT pg_try_advisory_xact_lock(?)", outboxRelayLockKey).Scan(&locked).Error
+	return locked, err
+}
+
+// ClaimUnpublished should be called under TryLockRelay so two relays never
+// check for a running lease at the same time
+func (r *outboxRepository) ClaimUnpublished(ctx context.Context, lease time.Duration, limit int) ([]*entities.OutboxEvent, error) {
+	var events []*entities.OutboxEvent
+	now := time.Now()
+	err := dbFromContext(ctx, r.db).Raw(`
+		UPDATE outbox_events SET claimed_until = ?
+		WHERE id IN (
+			SELECT id FROM outbox_events
+			WHERE published_at IS NULL
+			ORDER BY id ASC
+			LIMIT ?
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM outbox_events
+			WHERE published_at IS NULL AND claimed_until > ?
+		)
+		RETURNING *`,
+		now.Add(lease), limit, now,
+	).Scan(&events).Error
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, err
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return dbFromContext(ctx, r.db).Model(&entities.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"published_at": time.Now(),
+			"last_error":   nil,
+		}).Error
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int64, reason string) error {
+	return dbFromContext(ctx, r.db).Model(&entities.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": reason,
+		}).Error
+}
+
+func (r *outboxRepository) ReleaseClaims(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return dbFromContext(ctx, r.db).Model(&entities.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Update("claimed_until", nil).Error
+}
+
+func (r *outboxRepository) DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := dbFromContext(ctx, r.db).
+		Where("published_at IS NOT NULL AND published_at < ?", before).
+		Delete(&entities.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
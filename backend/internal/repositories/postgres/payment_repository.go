@@ -17,12 +17,12 @@ func NewPaymentRepository(db *gorm.DB) *paymentRepository {
 }
 
 func (r *paymentRepository) Create(ctx context.Context, payment *entities.Payment) error {
-	return r.db.WithContext(ctx).Create(payment).Error
+	return dbFromContext(ctx, r.db).Create(payment).Error
 }
 
 func (r *paymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Payment, error) {
 	var payment entities.Payment
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&payment).Error
+	err := dbFromContext(ctx, r.db).Where("id = ?", id).First(&payment).Error
 	if err != nil {
 		return nil, err
 	}
@@ -31,7 +31,7 @@ func (r *paymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*entitie
 
 func (r *paymentRepository) GetByBookingID(ctx context.Context, bookingID uuid.UUID) (*entities.Payment, error) {
 	var payment entities.Payment
-	err := r.db.WithContext(ctx).Where("booking_id = ?", bookingID).First(&payment).Error
+	err := dbFromContext(ctx, r.db).Where("booking_id = ?", bookingID).First(&payment).Error
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +40,7 @@ func (r *paymentRepository) GetByBookingID(ctx context.Context, bookingID uuid.U
 
 func (r *paymentRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entities.Payment, error) {
 	var payment entities.Payment
-	err := r.db.WithContext(ctx).Where("transaction_id = ?", transactionID).First(&payment).Error
+	err := dbFromContext(ctx, r.db).Where("transaction_id = ?", transactionID).First(&payment).Error
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +49,7 @@ func (r *paymentRepository) GetByTransactionID(ctx context.Context, transactionI
 
 func (r *paymentRepository) GetByGatewayPaymentID(ctx context.Context, gatewayPaymentID string) (*entities.Payment, error) {
 	var payment entities.Payment
-	err := r.db.WithContext(ctx).Where("gateway_payment_id = ?", gatewayPaymentID).First(&payment).Error
+	err := dbFromContext(ctx, r.db).Where("gateway_payment_id = ?", gatewayPaymentID).First(&payment).Error
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +58,7 @@ func (r *paymentRepository) GetByGatewayPaymentID(ctx context.Context, gatewayPa
 
 func (r *paymentRepository) GetByIdempotencyKey(ctx context.Context, key string) (*entities.Payment, error) {
 	var payment entities.Payment
-	err := r.db.WithContext(ctx).Where("idempotency_key = ?", key).First(&payment).Error
+	err := dbFromContext(ctx, r.db).Where("idempotency_key = ?", key).First(&payment).Error
 	if err != nil {
 		return nil, err
 	}
@@ -66,23 +66,31 @@ func (r *paymentRepository) GetByIdempotencyKey(ctx context.Context, key string)
 }
 
 func (r *paymentRepository) Update(ctx context.Context, payment *entities.Payment) error {
-	return r.db.WithContext(ctx).Save(payment).Error
+	return dbFromContext(ctx, r.db).Save(payment).Error
 }
 
 func (r *paymentRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.PaymentStatus) error {
-	return r.db.WithContext(ctx).
+	return dbFromContext(ctx, r.db).
 		Model(&entities.Payment{}).
 		Where("id = ?", id).
 		Update("status", status).Error
 }
 
+func (r *paymentRepository) TransitionStatus(ctx context.Context, id uuid.UUID, from, to entities.PaymentStatus) (bool, error) {
+	result := dbFromContext(ctx, r.db).
+		Model(&entities.Payment{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	return result.RowsAffected == 1, result.Error
+}
+
 func (r *paymentRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&entities.Payment{}, id).Error
+	return dbFromContext(ctx, r.db).Delete(&entities.Payment{}, id).Error
 }
 
 func (r *paymentRepository) List(ctx context.Context, limit, offset int) ([]*entities.Payment, error) {
 	var payments []*entities.Payment
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("Booking").
 		Limit(limit).
 		Offset(offset).
@@ -92,7 +100,7 @@ func (r *paymentRepository) List(ctx context.Context, limit, offset int) ([]*ent
 
 func (r *paymentRepository) GetByStatus(ctx context.Context, status entities.PaymentStatus, limit, offset int) ([]*entities.Payment, error) {
 	var payments []*entities.Payment
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("Booking").
 		Where("status = ?", status).
 		Limit(limit).
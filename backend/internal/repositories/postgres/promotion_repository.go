@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type promotionRepository struct {
+	db *gorm.DB
+}
+
+// NewPromotionRepository creates a new promotion repository
+func NewPromotionRepository(db *gorm.DB) *promotionRepository {
+	return &promotionRepository{db: db}
+}
+
+func (r *promotionRepository) Create(ctx context.Context, promotion *entities.Promotion) error {
+	return dbFromContext(ctx, r.db).Create(promotion).Error
+}
+
+func (r *promotionRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Promotion, error) {
+	var promotion entities.Promotion
+	err := dbFromContext(ctx, r.db).Where("id = ?", id).First(&promotion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+func (r *promotionRepository) Update(ctx context.Context, promotion *entities.Promotion) error {
+	// Usage counters are only changed through IncrementUsage and ReleaseRedemption
+	return dbFromContext(ctx, r.db).Omit("times_used", "created_at").Save(promotion).Error
+}
+
+func (r *promotionRepository) List(ctx context.Context, activeOnly bool, limit, offset int) ([]*entities.Promotion, error) {
+	var promotions []*entities.Promotion
+	query := dbFromContext(ctx, r.db)
+
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&promotions).Error
+	return promotions, err
+}
+
+func (r *promotionRepository) CreateCodes(ctx context.Context, codes []*entities.PromoCode) error {
+	return dbFromContext(ctx, r.db).CreateInBatches(codes, 500).Error
+}
+
+func (r *promotionRepository) GetCodeByCode(ctx context.Context, code string) (*entities.PromoCode, error) {
+	var promoCode entities.PromoCode
+	err := dbFromContext(ctx, r.db).
+		Preload("Promotion").
+		Where("code = ?", strings.ToUpper(code)).
+		First(&promoCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &promoCode, nil
+}
+
+func (r *promotionRepository) ListCodes(ctx context.Context, promotionID uuid.UUID, limit, offset int) ([]*entities.PromoCode, error) {
+	var codes []*entities.PromoCode
+	err := dbFromContext(ctx, r.db).
+		Where("promotion_id = ?", promotionID).
+		Order("created_at ASC, code ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&codes).Error
+	return codes, err
+}
+
+// errUsageLimitReached rolls back a partial increment in IncrementUsage
+var errUsageLimitReached = errors.New("usage limit reached")
+
+func (r *promotionRepository) IncrementUsage(ctx context.Context, codeID, promotionID uuid.UUID) (bool, error) {
+	err := dbFromContext(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// The conditional updates make the limit check and the increment one step,
+		// so concurrent redemptions can never push a counter past its limit
+		result := tx.Model(&entities.Promotion{}).
+			Where("id = ? AND (usage_limit IS NULL OR times_used < usage_limit)", promotionID).
+			Update("times_used", gorm.Expr("times_used + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errUsageLimitReached
+		}
+
+		result = tx.Model(&entities.PromoCode{}).
+			Where("id = ? AND (usage_limit IS NULL OR times_used < usage_limit)", codeID).
+			Update("times_used", gorm.Expr("times_used + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errUsageLimitReached
+		}
+		return nil
+	})
+	if errors.Is(err, errUsageLimitReached) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreateRedemption records a redemption for a booking. A released redemption
+// of the same booking, such as one given back when the gateway refused its
+// payment, is taken over; an active one makes it fail.
+func (r *promotionRepository) CreateRedemption(ctx context.Context, redemption *entities.PromoRedemption) error {
+	result := dbFromContext(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "booking_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"promotion_id", "promo_code_id", "user_id", "contact_email",
+				"discount_amount", "status", "released_at", "updated_at",
+			}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Eq{Column: clause.Column{Table: "promo_redemptions", Name: "status"}, Value: entities.RedemptionStatusReleased},
+			}},
+		}).
+		Create(redemption)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
+
+func (r *promotionRepository) CountCustomerRedemptions(ctx context.Context, promotionID uuid.UUID, userID *uuid.UUID, email string) (int64, error) {
+	var count int64
+	query := dbFromContext(ctx, r.db).Model(&entities.PromoRedemption{}).
+		Where("promotion_id = ? AND status = ?", promotionID, entities.RedemptionStatusActive)
+
+	if userID != nil {
+		query = query.Where("user_id = ? OR LOWER(contact_email) = LOWER(?)", *userID, email)
+	} else {
+		query = query.Where("LOWER(contact_email) = LOWER(?)", email)
+	}
+
+	err := query.Count(&count).Error
+	return count, err
+}
+
+func (r *promotionRepository) ReleaseRedemption(ctx context.Context, bookingID uuid.UUID) (bool, error) {
+	released := false
+	err := dbFromContext(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var redemption entities.PromoRedemption
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("booking_id = ? AND status = ?", bookingID, entities.RedemptionStatusActive).
+			First(&redemption).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err = tx.Model(&redemption).Updates(map[string]interface{}{
+			"status":      entities.RedemptionStatusReleased,
+			"released_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&entities.Promotion{}).
+			Where("id = ? AND times_used > 0", redemption.PromotionID).
+			Update("times_used", gorm.Expr("times_used - 1")).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&entities.PromoCode{}).
+			Where("id = ? AND times_used > 0", redemption.PromoCodeID).
+			Update("times_used", gorm.Expr("times_used - 1")).Error
+		if err != nil {
+			return err
+		}
+
+		released = true
+		return nil
+	})
+	return released, err
+}
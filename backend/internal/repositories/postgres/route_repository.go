@@ -17,29 +17,78 @@ func NewRouteRepository(db *gorm.DB) *RouteRepository {
 }
 
 func (r *RouteRepository) Create(ctx context.Context, route *entities.Route) error {
-	return r.db.WithContext(ctx).Create(route).Error
+	return dbFromContext(ctx, r.db).Omit("FromLocation", "ToLocation").Create(route).Error
 }
 
 func (r *RouteRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Route, error) {
 	var route entities.Route
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&route).Error
+	err := dbFromContext(ctx, r.db).
+		Preload("Stops", orderStops).
+		Preload("FromLocation").
+		Preload("ToLocation").
+		Where("id = ?", id).
+		First(&route).Error
 	if err != nil {
 		return nil, err
 	}
 	return &route, nil
 }
 
+// Update saves the route's own fields; stops are changed with ReplaceStops
 func (r *RouteRepository) Update(ctx context.Context, route *entities.Route) error {
-	return r.db.WithContext(ctx).Save(route).Error
+	return dbFromContext(ctx, r.db).Omit("Stops", "FromLocation", "ToLocation").Save(route).Error
+}
+
+// ReplaceStops makes stops the route's full list of stops, deleting any of
+// its stops not in the list
+func (r *RouteRepository) ReplaceStops(ctx context.Context, routeID uuid.UUID, stops []entities.RouteStop) error {
+	db := dbFromContext(ctx, r.db)
+
+	keep := make([]uuid.UUID, 0, len(stops))
+	for _, stop := range stops {
+		if stop.ID != uuid.Nil {
+			keep = append(keep, stop.ID)
+		}
+	}
+	query := db.Where("route_id = ?", routeID)
+	if len(keep) > 0 {
+		query = query.Where("id NOT IN ?", keep)
+	}
+	if err := query.Delete(&entities.RouteStop{}).Error; err != nil {
+		return err
+	}
+
+	for i := range stops {
+		stops[i].RouteID = routeID
+		if err := db.Save(&stops[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StopsInUse returns which of the given stops a booking boards or leaves at
+func (r *RouteRepository) StopsInUse(ctx context.Context, stopIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var inUse []uuid.UUID
+	if len(stopIDs) == 0 {
+		return inUse, nil
+	}
+	err := dbFromContext(ctx, r.db).Raw(`
+		SELECT pickup_stop_id FROM bookings WHERE pickup_stop_id IN @ids
+		UNION
+		SELECT dropoff_stop_id FROM bookings WHERE dropoff_stop_id IN @ids`,
+		map[string]interface{}{"ids": stopIDs}).
+		Scan(&inUse).Error
+	return inUse, err
 }
 
 func (r *RouteRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&entities.Route{}, "id = ?", id).Error
+	return dbFromContext(ctx, r.db).Delete(&entities.Route{}, "id = ?", id).Error
 }
 
 func (r *RouteRepository) List(ctx context.Context, limit, offset int) ([]*entities.Route, error) {
 	var routes []*entities.Route
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Where("is_active = ?", true).
 		Limit(limit).
 		Offset(offset).
@@ -48,17 +97,32 @@ func (r *RouteRepository) List(ctx context.Context, limit, offset int) ([]*entit
 	return routes, err
 }
 
-func (r *RouteRepository) Search(ctx context.Context, fromCity, toCity string) ([]*entities.Route, error) {
+// RenameLocation sets the city names of the routes from or to the location
+func (r *RouteRepository) RenameLocation(ctx context.Context, locationID uuid.UUID, name string) error {
+	db := dbFromContext(ctx, r.db)
+	err := db.Model(&entities.Route{}).Where("from_location_id = ?", locationID).Update("from_city", name).Error
+	if err != nil {
+		return err
+	}
+	return db.Model(&entities.Route{}).Where("to_location_id = ?", locationID).Update("to_city", name).Error
+}
+
+func (r *RouteRepository) Search(ctx context.Context, fromLocationID, toLocationID *uuid.UUID) ([]*entities.Route, error) {
 	var routes []*entities.Route
-	query := r.db.WithContext(ctx).Where("is_active = ?", true)
+	query := dbFromContext(ctx, r.db).Where("is_active = ?", true)
 
-	if fromCity != "" {
-		query = query.Where("LOWER(from_city) LIKE LOWER(?)", "%"+fromCity+"%")
+	if fromLocationID != nil {
+		query = query.Where("from_location_id = ?", *fromLocationID)
 	}
-	if toCity != "" {
-		query = query.Where("LOWER(to_city) LIKE LOWER(?)", "%"+toCity+"%")
+	if toLocationID != nil {
+		query = query.Where("to_location_id = ?", *toLocationID)
 	}
 
 	err := query.Order("name ASC").Find(&routes).Error
 	return routes, err
 }
+
+// orderStops preloads a route's stops in the order the bus calls at them
+func orderStops(db *gorm.DB) *gorm.DB {
+	return db.Order("route_stops.sequence ASC")
+}
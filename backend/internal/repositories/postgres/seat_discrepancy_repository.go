@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+)
+
+type seatDiscrepancyRepository struct {
+	db *gorm.DB
+}
+
+// NewSeatDiscrepancyRepository creates a new seat discrepancy repository
+func NewSeatDiscrepancyRepository(db *gorm.DB) *seatDiscrepancyRepository {
+	return &seatDiscrepancyRepository{db: db}
+}
+
+func (r *seatDiscrepancyRepository) Record(ctx context.Context, discrepancy *entities.SeatDiscrepancy) error {
+	db := dbFromContext(ctx, r.db)
+	if discrepancy.LastSeenAt.IsZero() {
+		discrepancy.LastSeenAt = time.Now()
+	}
+
+	if discrepancy.Resolution == entities.SeatDiscrepancyFlagged {
+		result := db.Model(&entities.SeatDiscrepancy{}).
+			Where("trip_id = ? AND seat_number = ? AND kind = ? AND resolution = ? AND resolved_at IS NULL",
+				discrepancy.TripID, discrepancy.SeatNumber, discrepancy.Kind, entities.SeatDiscrepancyFlagged).
+			Updates(map[string]interface{}{
+				"run_id":       discrepancy.RunID,
+				"booking_id":   discrepancy.BookingID,
+				"details":      discrepancy.Details,
+				"occurrences":  gorm.Expr("occurrences + 1"),
+				"last_seen_at": discrepancy.LastSeenAt,
+			})
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+	} else {
+		discrepancy.ResolvedAt = &discrepancy.LastSeenAt
+	}
+
+	return db.Create(discrepancy).Error
+}
+
+func (r *seatDiscrepancyRepository) List(ctx context.Context, kind entities.SeatDiscrepancyKind, tripID *uuid.UUID, openOnly bool, limit, offset int) ([]*entities.SeatDiscrepancy, error) {
+	var discrepancies []*entities.SeatDiscrepancy
+	query := dbFromContext(ctx, r.db)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if tripID != nil {
+		query = query.Where("trip_id = ?", *tripID)
+	}
+	if openOnly {
+		query = query.Where("resolved_at IS NULL")
+	}
+	err := query.
+		Order("last_seen_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&discrepancies).Error
+	return discrepancies, err
+}
+
+func (r *seatDiscrepancyRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.SeatDiscrepancy, error) {
+	var discrepancy entities.SeatDiscrepancy
+	err := dbFromContext(ctx, r.db).First(&discrepancy, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &discrepancy, nil
+}
+
+func (r *seatDiscrepancyRepository) Resolve(ctx context.Context, id uuid.UUID) error {
+	return dbFromContext(ctx, r.db).Model(&entities.SeatDiscrepancy{}).
+		Where("id = ? AND resolved_at IS NULL", id).
+		Update("resolved_at", time.Now()).Error
+}
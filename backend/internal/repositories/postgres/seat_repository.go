@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -20,22 +21,53 @@ func NewSeatRepository(db *gorm.DB) *seatRepository {
 	return &seatRepository{db: db}
 }
 
-func (r *seatRepository) InitializeSeatsForTrip(ctx context.Context, tripID uuid.UUID, seatNumbers []string) error {
-	seats := make([]*entities.SeatInfo, len(seatNumbers))
-	for i, seatNum := range seatNumbers {
-		seats[i] = &entities.SeatInfo{
-			TripID:     tripID,
-			SeatNumber: seatNum,
-			Status:     entities.SeatStatusAvailable,
+func (r *seatRepository) InitializeSeatsForTrip(ctx context.Context, tripID uuid.UUID, seatNumbers []string, segments int) error {
+	seats := make([]*entities.SeatInfo, 0, len(seatNumbers)*segments)
+	for _, seatNum := range seatNumbers {
+		for segment := 0; segment < segments; segment++ {
+			seats = append(seats, &entities.SeatInfo{
+				TripID:     tripID,
+				SeatNumber: seatNum,
+				Segment:    segment,
+				Status:     entities.SeatStatusAvailable,
+			})
 		}
 	}
-	return r.db.WithContext(ctx).CreateInBatches(seats, 100).Error
+	return dbFromContext(ctx, r.db).CreateInBatches(seats, 100).Error
 }
 
-func (r *seatRepository) GetByTripAndSeat(ctx context.Context, tripID uuid.UUID, seatNumber string) (*entities.SeatInfo, error) {
+// CountSegments returns how many segments the trip's seats are split into
+func (r *seatRepository) CountSegments(ctx context.Context, tripID uuid.UUID) (int, error) {
+	var segments int
+	err := dbFromContext(ctx, r.db).Model(&entities.SeatInfo{}).
+		Select("COALESCE(MAX(segment) + 1, 1)").
+		Where("trip_id = ?", tripID).
+		Scan(&segments).Error
+	return segments, err
+}
+
+// CountHeldSeats returns how many seat segments of the trip are booked or
+// under an unexpired lock
+func (r *seatRepository) CountHeldSeats(ctx context.Context, tripID uuid.UUID) (int, error) {
+	var count int64
+	err := dbFromContext(ctx, r.db).Model(&entities.SeatInfo{}).
+		Where("trip_id = ? AND (status = ? OR (status = ? AND locked_until >= ?))",
+			tripID,
+			entities.SeatStatusBooked,
+			entities.SeatStatusLocked,
+			time.Now()).
+		Count(&count).Error
+	return int(count), err
+}
+
+func (r *seatRepository) DeleteByTrip(ctx context.Context, tripID uuid.UUID) error {
+	return dbFromContext(ctx, r.db).Where("trip_id = ?", tripID).Delete(&entities.SeatInfo{}).Error
+}
+
+func (r *seatRepository) GetByTripAndSeat(ctx context.Context, tripID uuid.UUID, seatNumber string, segment int) (*entities.SeatInfo, error) {
 	var seat entities.SeatInfo
-	err := r.db.WithContext(ctx).
-		Where("trip_id = ? AND seat_number = ?", tripID, seatNumber).
+	err := dbFromContext(ctx, r.db).
+		Where("trip_id = ? AND seat_number = ? AND segment = ?", tripID, seatNumber, segment).
 		First(&seat).Error
 	if err != nil {
 		return nil, err
@@ -45,36 +77,54 @@ func (r *seatRepository) GetByTripAndSeat(ctx context.Context, tripID uuid.UUID,
 
 func (r *seatRepository) GetAllByTrip(ctx context.Context, tripID uuid.UUID) ([]*entities.SeatInfo, error) {
 	var seats []*entities.SeatInfo
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Where("trip_id = ?", tripID).
-		Order("seat_number ASC").
+		Order("seat_number ASC, segment ASC").
 		Find(&seats).Error
 	return seats, err
 }
 
-// LockSeats implements distributed locking with PostgreSQL row-level locks (SELECT FOR UPDATE)
-func (r *seatRepository) LockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, lockedBy uuid.UUID, duration time.Duration) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var seats []*entities.SeatInfo
+// onSegments narrows a query to the given seats of a trip on the segments the range covers
+func onSegments(db *gorm.DB, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange) *gorm.DB {
+	return db.Where("trip_id = ? AND seat_number IN ? AND segment >= ? AND segment < ?", tripID, seatNumbers, stops.From, stops.To)
+}
 
-		// Acquire row-level locks using SELECT FOR UPDATE
-		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("trip_id = ? AND seat_number IN ?", tripID, seatNumbers).
-			Find(&seats).Error
+// lockSegments takes row-level locks (SELECT FOR UPDATE) on the seats' rows of
+// the range, in a fixed order so overlapping ranges cannot deadlock, and
+// checks every row exists
+func lockSegments(tx *gorm.DB, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange) ([]*entities.SeatInfo, error) {
+	var seats []*entities.SeatInfo
+	err := onSegments(tx.Clauses(clause.Locking{Strength: "UPDATE"}), tripID, seatNumbers, stops).
+		Order("seat_number ASC, segment ASC").
+		Find(&seats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if stops.Segments() <= 0 || len(seats) != len(seatNumbers)*stops.Segments() {
+		return nil, fmt.Errorf("some seats not found")
+	}
+	return seats, nil
+}
+
+// LockSeats implements distributed locking with PostgreSQL row-level locks
+// (SELECT FOR UPDATE) on the segments of the range
+func (r *seatRepository) LockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange, lockedBy uuid.UUID, duration time.Duration) error {
+	return dbFromContext(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		seats, err := lockSegments(tx, tripID, seatNumbers, stops)
 		if err != nil {
 			return err
 		}
 
-		if len(seats) != len(seatNumbers) {
-			return fmt.Errorf("some seats not found")
-		}
-
 		// Check if all seats are available or lock expired
 		now := time.Now()
 		for _, seat := range seats {
 			if seat.Status == entities.SeatStatusBooked {
 				return fmt.Errorf("seat %s is already booked", seat.SeatNumber)
 			}
+			if seat.Status == entities.SeatStatusBlocked {
+				return fmt.Errorf("seat %s is blocked", seat.SeatNumber)
+			}
 			if seat.Status == entities.SeatStatusLocked {
 				if seat.LockedUntil != nil && now.Before(*seat.LockedUntil) {
 					return fmt.Errorf("seat %s is currently locked", seat.SeatNumber)
@@ -84,8 +134,7 @@ func (r *seatRepository) LockSeats(ctx context.Context, tripID uuid.UUID, seatNu
 
 		// Lock the seats
 		lockedUntil := now.Add(duration)
-		return tx.Model(&entities.SeatInfo{}).
-			Where("trip_id = ? AND seat_number IN ?", tripID, seatNumbers).
+		return onSegments(tx.Model(&entities.SeatInfo{}), tripID, seatNumbers, stops).
 			Updates(map[string]interface{}{
 				"status":       entities.SeatStatusLocked,
 				"locked_until": lockedUntil,
@@ -94,9 +143,11 @@ func (r *seatRepository) LockSeats(ctx context.Context, tripID uuid.UUID, seatNu
 	})
 }
 
-func (r *seatRepository) UnlockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string) error {
-	return r.db.WithContext(ctx).Model(&entities.SeatInfo{}).
-		Where("trip_id = ? AND seat_number IN ? AND status = ?", tripID, seatNumbers, entities.SeatStatusLocked).
+// UnlockSeats frees the seats on the segments of the range that are still
+// locked by lockedBy; seats locked by another booking are left alone
+func (r *seatRepository) UnlockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange, lockedBy uuid.UUID) error {
+	return onSegments(dbFromContext(ctx, r.db).Model(&entities.SeatInfo{}), tripID, seatNumbers, stops).
+		Where("status = ? AND locked_by = ?", entities.SeatStatusLocked, lockedBy).
 		Updates(map[string]interface{}{
 			"status":       entities.SeatStatusAvailable,
 			"locked_until": nil,
@@ -105,7 +156,7 @@ func (r *seatRepository) UnlockSeats(ctx context.Context, tripID uuid.UUID, seat
 }
 
 func (r *seatRepository) UnlockExpiredSeats(ctx context.Context) (int, error) {
-	result := r.db.WithContext(ctx).Model(&entities.SeatInfo{}).
+	result := dbFromContext(ctx, r.db).Model(&entities.SeatInfo{}).
 		Where("status = ? AND locked_until < ?", entities.SeatStatusLocked, time.Now()).
 		Updates(map[string]interface{}{
 			"status":       entities.SeatStatusAvailable,
@@ -115,9 +166,8 @@ func (r *seatRepository) UnlockExpiredSeats(ctx context.Context) (int, error) {
 	return int(result.RowsAffected), result.Error
 }
 
-func (r *seatRepository) MarkSeatsAsBooked(ctx context.Context, tripID uuid.UUID, seatNumbers []string, bookingID uuid.UUID) error {
-	return r.db.WithContext(ctx).Model(&entities.SeatInfo{}).
-		Where("trip_id = ? AND seat_number IN ?", tripID, seatNumbers).
+func (r *seatRepository) MarkSeatsAsBooked(ctx context.Context, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange, bookingID uuid.UUID) error {
+	return onSegments(dbFromContext(ctx, r.db).Model(&entities.SeatInfo{}), tripID, seatNumbers, stops).
 		Updates(map[string]interface{}{
 			"status":       entities.SeatStatusBooked,
 			"booking_id":   bookingID,
@@ -126,8 +176,148 @@ func (r *seatRepository) MarkSeatsAsBooked(ctx context.Context, tripID uuid.UUID
 		}).Error
 }
 
+// ConfirmSeats books seats for a booking only if they are still held by the booking's
+// lock holder (or free); seats taken by someone else yield repositories.ErrSeatUnavailable
+func (r *seatRepository) ConfirmSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, stops entities.StopRange, lockedBy uuid.UUID, bookingID uuid.UUID) error {
+	return dbFromContext(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		seats, err := lockSegments(tx, tripID, seatNumbers, stops)
+		if err != nil {
+			return err
+		}
+
+		for _, seat := range seats {
+			switch seat.Status {
+			case entities.SeatStatusBlocked:
+				return fmt.Errorf("seat %s: %w", seat.SeatNumber, repositories.ErrSeatUnavailable)
+			case entities.SeatStatusBooked:
+				if seat.BookingID == nil || *seat.BookingID != bookingID {
+					return fmt.Errorf("seat %s: %w", seat.SeatNumber, repositories.ErrSeatUnavailable)
+				}
+			case entities.SeatStatusLocked:
+				heldByUs := seat.LockedBy != nil && *seat.LockedBy == lockedBy
+				if !heldByUs && !seat.IsLockExpired() {
+					return fmt.Errorf("seat %s: %w", seat.SeatNumber, repositories.ErrSeatUnavailable)
+				}
+			}
+		}
+
+		return onSegments(tx.Model(&entities.SeatInfo{}), tripID, seatNumbers, stops).
+			Updates(map[string]interface{}{
+				"status":       entities.SeatStatusBooked,
+				"booking_id":   bookingID,
+				"locked_until": nil,
+				"locked_by":    nil,
+			}).Error
+	})
+}
+
+// BlockSeats takes seats off sale on every segment; seats that are booked or
+// held by an unexpired lock on any segment yield repositories.ErrSeatUnavailable,
+// blocked seats get the new block
+func (r *seatRepository) BlockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string, reason string, until *time.Time, blockedBy *uuid.UUID) error {
+	return dbFromContext(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var seats []*entities.SeatInfo
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("trip_id = ? AND seat_number IN ?", tripID, seatNumbers).
+			Order("seat_number ASC, segment ASC").
+			Find(&seats).Error
+		if err != nil {
+			return err
+		}
+
+		found := make(map[string]bool, len(seatNumbers))
+		for _, seat := range seats {
+			found[seat.SeatNumber] = true
+		}
+		if len(found) != len(seatNumbers) {
+			return fmt.Errorf("some seats not found")
+		}
+
+		for _, seat := range seats {
+			if seat.Status == entities.SeatStatusBooked ||
+				(seat.Status == entities.SeatStatusLocked && !seat.IsLockExpired()) {
+				return fmt.Errorf("seat %s: %w", seat.SeatNumber, repositories.ErrSeatUnavailable)
+			}
+		}
+
+		return tx.Model(&entities.SeatInfo{}).
+			Where("trip_id = ? AND seat_number IN ?", tripID, seatNumbers).
+			Updates(map[string]interface{}{
+				"status":        entities.SeatStatusBlocked,
+				"block_reason":  reason,
+				"blocked_until": until,
+				"blocked_by":    blockedBy,
+				"locked_until":  nil,
+				"locked_by":     nil,
+			}).Error
+	})
+}
+
+// UnblockSeats makes blocked seats available again and returns the seats it changed
+func (r *seatRepository) UnblockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string) ([]string, error) {
+	var seats []*entities.SeatInfo
+	err := dbFromContext(ctx, r.db).Model(&seats).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "seat_number"}, {Name: "segment"}}}).
+		Where("trip_id = ? AND seat_number IN ? AND status = ?", tripID, seatNumbers, entities.SeatStatusBlocked).
+		Updates(unblockedSeat()).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Blocks cover every segment, so the first segment stands for the seat
+	unblocked := make([]string, 0, len(seats))
+	for _, seat := range seats {
+		if seat.Segment == 0 {
+			unblocked = append(unblocked, seat.SeatNumber)
+		}
+	}
+	return unblocked, nil
+}
+
+// UnblockExpiredSeats releases blocks past their blocked_until and returns the
+// released seats, one row per seat
+func (r *seatRepository) UnblockExpiredSeats(ctx context.Context) ([]*entities.SeatInfo, error) {
+	var rows []*entities.SeatInfo
+	err := dbFromContext(ctx, r.db).Model(&rows).
+		Clauses(clause.Returning{}).
+		Where("status = ? AND blocked_until < ?", entities.SeatStatusBlocked, time.Now()).
+		Updates(unblockedSeat()).Error
+
+	seats := make([]*entities.SeatInfo, 0, len(rows))
+	for _, seat := range rows {
+		if seat.Segment == 0 {
+			seats = append(seats, seat)
+		}
+	}
+	return seats, err
+}
+
+func unblockedSeat() map[string]interface{} {
+	return map[string]interface{}{
+		"status":        entities.SeatStatusAvailable,
+		"block_reason":  "",
+		"blocked_until": nil,
+		"blocked_by":    nil,
+	}
+}
+
+func (r *seatRepository) RepairSeat(ctx context.Context, seat *entities.SeatInfo, status entities.SeatStatus, bookingID *uuid.UUID) (bool, error) {
+	// updated_at serves as the row version, so a seat changed by a booking in
+	// the meantime is left alone
+	result := dbFromContext(ctx, r.db).Model(&entities.SeatInfo{}).
+		Where("id = ? AND updated_at = ?", seat.ID, seat.UpdatedAt).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"booking_id":   bookingID,
+			"locked_until": nil,
+			"locked_by":    nil,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
 func (r *seatRepository) ReleaseSeats(ctx context.Context, bookingID uuid.UUID) error {
-	return r.db.WithContext(ctx).Model(&entities.SeatInfo{}).
+	return dbFromContext(ctx, r.db).Model(&entities.SeatInfo{}).
 		Where("booking_id = ?", bookingID).
 		Updates(map[string]interface{}{
 			"status":     entities.SeatStatusAvailable,
@@ -135,27 +325,33 @@ func (r *seatRepository) ReleaseSeats(ctx context.Context, bookingID uuid.UUID)
 		}).Error
 }
 
-func (r *seatRepository) GetAvailableSeats(ctx context.Context, tripID uuid.UUID) ([]*entities.SeatInfo, error) {
-	var seats []*entities.SeatInfo
-	err := r.db.WithContext(ctx).
-		Where("trip_id = ? AND (status = ? OR (status = ? AND locked_until < ?))",
-			tripID,
+// availableOn selects the seats of a trip that are free on every segment of the range
+func availableOn(db *gorm.DB, tripID uuid.UUID, stops entities.StopRange) *gorm.DB {
+	return db.Model(&entities.SeatInfo{}).
+		Select("seat_number").
+		Where("trip_id = ? AND segment >= ? AND segment < ?", tripID, stops.From, stops.To).
+		Group("seat_number").
+		Having("bool_and(status = ? OR (status = ? AND locked_until < ?))",
 			entities.SeatStatusAvailable,
 			entities.SeatStatusLocked,
-			time.Now()).
+			time.Now())
+}
+
+// GetAvailableSeats returns the seats free on the whole range, by their row
+// of its first segment
+func (r *seatRepository) GetAvailableSeats(ctx context.Context, tripID uuid.UUID, stops entities.StopRange) ([]*entities.SeatInfo, error) {
+	db := dbFromContext(ctx, r.db)
+	var seats []*entities.SeatInfo
+	err := db.
+		Where("trip_id = ? AND segment = ? AND seat_number IN (?)", tripID, stops.From, availableOn(db, tripID, stops)).
 		Order("seat_number ASC").
 		Find(&seats).Error
 	return seats, err
 }
 
-func (r *seatRepository) CountAvailableSeats(ctx context.Context, tripID uuid.UUID) (int, error) {
+func (r *seatRepository) CountAvailableSeats(ctx context.Context, tripID uuid.UUID, stops entities.StopRange) (int, error) {
+	db := dbFromContext(ctx, r.db)
 	var count int64
-	err := r.db.WithContext(ctx).Model(&entities.SeatInfo{}).
-		Where("trip_id = ? AND (status = ? OR (status = ? AND locked_until < ?))",
-			tripID,
-			entities.SeatStatusAvailable,
-			entities.SeatStatusLocked,
-			time.Now()).
-		Count(&count).Error
+	err := db.Table("(?) AS available", availableOn(db, tripID, stops)).Count(&count).Error
 	return int(count), err
 }
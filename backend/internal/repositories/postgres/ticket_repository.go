@@ -17,16 +17,16 @@ func NewTicketRepository(db *gorm.DB) *ticketRepository {
 }
 
 func (r *ticketRepository) Create(ctx context.Context, ticket *entities.Ticket) error {
-	return r.db.WithContext(ctx).Create(ticket).Error
+	return dbFromContext(ctx, r.db).Create(ticket).Error
 }
 
 func (r *ticketRepository) CreateBatch(ctx context.Context, tickets []*entities.Ticket) error {
-	return r.db.WithContext(ctx).Create(&tickets).Error
+	return dbFromContext(ctx, r.db).Create(&tickets).Error
 }
 
 func (r *ticketRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Ticket, error) {
 	var ticket entities.Ticket
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("Booking").
 		Where("id = ?", id).
 		First(&ticket).Error
@@ -38,7 +38,7 @@ func (r *ticketRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities
 
 func (r *ticketRepository) GetByCode(ctx context.Context, code string) (*entities.Ticket, error) {
 	var ticket entities.Ticket
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("Booking").
 		Preload("Booking.Trip").
 		Preload("Booking.Trip.Route").
@@ -53,23 +53,23 @@ func (r *ticketRepository) GetByCode(ctx context.Context, code string) (*entitie
 
 func (r *ticketRepository) GetByBookingID(ctx context.Context, bookingID uuid.UUID) ([]*entities.Ticket, error) {
 	var tickets []*entities.Ticket
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Where("booking_id = ?", bookingID).
 		Find(&tickets).Error
 	return tickets, err
 }
 
 func (r *ticketRepository) Update(ctx context.Context, ticket *entities.Ticket) error {
-	return r.db.WithContext(ctx).Save(ticket).Error
+	return dbFromContext(ctx, r.db).Save(ticket).Error
 }
 
 func (r *ticketRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&entities.Ticket{}, id).Error
+	return dbFromContext(ctx, r.db).Delete(&entities.Ticket{}, id).Error
 }
 
 func (r *ticketRepository) List(ctx context.Context, limit, offset int) ([]*entities.Ticket, error) {
 	var tickets []*entities.Ticket
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("Booking").
 		Limit(limit).
 		Offset(offset).
@@ -78,7 +78,7 @@ func (r *ticketRepository) List(ctx context.Context, limit, offset int) ([]*enti
 }
 
 func (r *ticketRepository) MarkAsUsed(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).
+	return dbFromContext(ctx, r.db).
 		Model(&entities.Ticket{}).
 		Where("id = ?", id).
 		Updates(map[string]interface{}{
@@ -87,7 +87,7 @@ func (r *ticketRepository) MarkAsUsed(ctx context.Context, id uuid.UUID) error {
 }
 
 func (r *ticketRepository) CheckIn(ctx context.Context, ticketCode string) error {
-	return r.db.WithContext(ctx).
+	return dbFromContext(ctx, r.db).
 		Model(&entities.Ticket{}).
 		Where("ticket_code = ?", ticketCode).
 		Updates(map[string]interface{}{
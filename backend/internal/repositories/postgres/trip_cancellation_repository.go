@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+)
+
+type tripCancellationRepository struct {
+	db *gorm.DB
+}
+
+// NewTripCancellationRepository creates a new trip cancellation repository
+func NewTripCancellationRepository(db *gorm.DB) *tripCancellationRepository {
+	return &tripCancellationRepository{db: db}
+}
+
+func (r *tripCancellationRepository) Create(ctx context.Context, cancellation *entities.TripCancellation) error {
+	return dbFromContext(ctx, r.db).Omit("Outcomes").Create(cancellation).Error
+}
+
+func (r *tripCancellationRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.TripCancellation, error) {
+	var cancellation entities.TripCancellation
+	err := dbFromContext(ctx, r.db).
+		Preload("Outcomes", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Where("id = ?", id).
+		First(&cancellation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cancellation, nil
+}
+
+func (r *tripCancellationRepository) GetByTrip(ctx context.Context, tripID uuid.UUID) (*entities.TripCancellation, error) {
+	var cancellation entities.TripCancellation
+	err := dbFromContext(ctx, r.db).
+		Preload("Outcomes", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Where("trip_id = ?", tripID).
+		First(&cancellation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cancellation, nil
+}
+
+func (r *tripCancellationRepository) CreateOutcome(ctx context.Context, outcome *entities.TripCancellationOutcome) error {
+	return dbFromContext(ctx, r.db).Create(outcome).Error
+}
+
+func (r *tripCancellationRepository) GetOutcomeByToken(ctx context.Context, token string) (*entities.TripCancellationOutcome, error) {
+	var outcome entities.TripCancellationOutcome
+	err := dbFromContext(ctx, r.db).Where("choice_token = ?", token).First(&outcome).Error
+	if err != nil {
+		return nil, err
+	}
+	return &outcome, nil
+}
+
+func (r *tripCancellationRepository) UpdateOutcome(ctx context.Context, outcome *entities.TripCancellationOutcome) error {
+	return dbFromContext(ctx, r.db).Save(outcome).Error
+}
+
+// ClaimOutcomeRefund moves the outcome from rebooked to refund_failed, the
+// state Resume retries refunds from, so a refund cut short is not lost
+func (r *tripCancellationRepository) ClaimOutcomeRefund(ctx context.Context, id uuid.UUID, reason string) (bool, error) {
+	result := dbFromContext(ctx, r.db).Model(&entities.TripCancellationOutcome{}).
+		Where("id = ? AND resolution = ?", id, entities.CancellationRebooked).
+		Updates(map[string]interface{}{
+			"resolution":          entities.CancellationRefundFailed,
+			"error":               reason,
+			"chosen_by_passenger": true,
+		})
+	return result.RowsAffected == 1, result.Error
+}
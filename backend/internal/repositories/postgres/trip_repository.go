@@ -6,9 +6,14 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// bookableTripStatuses are the statuses of trips that still sell seats
+var bookableTripStatuses = []entities.TripStatus{entities.TripStatusScheduled, entities.TripStatusDelayed}
+
 type tripRepository struct {
 	db *gorm.DB
 }
@@ -18,12 +23,26 @@ func NewTripRepository(db *gorm.DB) *tripRepository {
 }
 
 func (r *tripRepository) Create(ctx context.Context, trip *entities.Trip) error {
-	return r.db.WithContext(ctx).Create(trip).Error
+	return dbFromContext(ctx, r.db).Create(trip).Error
 }
 
 func (r *tripRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Trip, error) {
 	var trip entities.Trip
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&trip).Error
+	err := dbFromContext(ctx, r.db).Where("id = ?", id).First(&trip).Error
+	if err != nil {
+		return nil, err
+	}
+	return &trip, nil
+}
+
+// GetByIDForUpdate locks the trip row; new bookings take a key share lock on
+// it through their foreign key and wait for the transaction to end
+func (r *tripRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entities.Trip, error) {
+	var trip entities.Trip
+	err := dbFromContext(ctx, r.db).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		First(&trip).Error
 	if err != nil {
 		return nil, err
 	}
@@ -32,9 +51,12 @@ func (r *tripRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.T
 
 func (r *tripRepository) GetByIDWithDetails(ctx context.Context, id uuid.UUID) (*entities.Trip, error) {
 	var trip entities.Trip
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("Route").
+		Preload("Route.Stops", orderStops).
 		Preload("Bus").
+		Preload("Driver").
+		Preload("CoDriver").
 		Where("id = ?", id).
 		First(&trip).Error
 	if err != nil {
@@ -44,16 +66,34 @@ func (r *tripRepository) GetByIDWithDetails(ctx context.Context, id uuid.UUID) (
 }
 
 func (r *tripRepository) Update(ctx context.Context, trip *entities.Trip) error {
-	return r.db.WithContext(ctx).Save(trip).Error
+	return dbFromContext(ctx, r.db).Omit("Route", "Bus", "Driver", "CoDriver").Save(trip).Error
+}
+
+func (r *tripRepository) UpdateStatus(ctx context.Context, trip *entities.Trip, from entities.TripStatus) error {
+	result := dbFromContext(ctx, r.db).Model(&entities.Trip{}).
+		Where("id = ? AND status = ?", trip.ID, from).
+		Updates(map[string]interface{}{
+			"status":         trip.Status,
+			"departure_time": trip.DepartureTime,
+			"arrival_time":   trip.ArrivalTime,
+			"duration":       trip.Duration,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repositories.ErrTripStatusChanged
+	}
+	return nil
 }
 
 func (r *tripRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&entities.Trip{}, id).Error
+	return dbFromContext(ctx, r.db).Delete(&entities.Trip{}, id).Error
 }
 
 func (r *tripRepository) List(ctx context.Context, status entities.TripStatus, limit, offset int) ([]*entities.Trip, error) {
 	var trips []*entities.Trip
-	query := r.db.WithContext(ctx).Preload("Route").Preload("Bus")
+	query := dbFromContext(ctx, r.db).Preload("Route").Preload("Bus")
 
 	if status != "" {
 		query = query.Where("status = ?", status)
@@ -63,28 +103,68 @@ func (r *tripRepository) List(ctx context.Context, status entities.TripStatus, l
 	return trips, err
 }
 
-func (r *tripRepository) Search(ctx context.Context, fromCity, toCity string, date time.Time, limit, offset int) ([]*entities.Trip, error) {
+func (r *tripRepository) GetUpcomingTrips(ctx context.Context, limit int) ([]*entities.Trip, error) {
 	var trips []*entities.Trip
-	query := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("Route").
 		Preload("Bus").
-		Joins("JOIN routes ON routes.id = trips.route_id").
-		Where("routes.from_city ILIKE ? AND routes.to_city ILIKE ?", "%"+fromCity+"%", "%"+toCity+"%").
-		Where("DATE(trips.departure_time) = DATE(?)", date).
-		Where("trips.status = ?", entities.TripStatusScheduled)
+		Where("departure_time > ? AND status IN ?", time.Now(), bookableTripStatuses).
+		Order("departure_time ASC").
+		Limit(limit).
+		Find(&trips).Error
+	return trips, err
+}
 
-	err := query.Limit(limit).Offset(offset).Find(&trips).Error
+func (r *tripRepository) GetBusTrips(ctx context.Context, busID uuid.UUID, from, to time.Time) ([]*entities.Trip, error) {
+	var trips []*entities.Trip
+	err := dbFromContext(ctx, r.db).
+		Preload("Route").
+		Where("bus_id = ? AND departure_time < ? AND arrival_time > ?", busID, to, from).
+		Where("status <> ?", entities.TripStatusCancelled).
+		Order("departure_time ASC").
+		Find(&trips).Error
 	return trips, err
 }
 
-func (r *tripRepository) GetUpcomingTrips(ctx context.Context, limit int) ([]*entities.Trip, error) {
+func (r *tripRepository) GetDriverTrips(ctx context.Context, driverID uuid.UUID, from, to time.Time) ([]*entities.Trip, error) {
 	var trips []*entities.Trip
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Preload("Route").
 		Preload("Bus").
-		Where("departure_time > ? AND status = ?", time.Now(), entities.TripStatusScheduled).
+		Where("driver_id = ? OR co_driver_id = ?", driverID, driverID).
+		Where("departure_time < ? AND arrival_time > ?", to, from).
+		Where("status <> ?", entities.TripStatusCancelled).
+		Order("departure_time ASC").
+		Find(&trips).Error
+	return trips, err
+}
+
+func (r *tripRepository) GetTripsBetween(ctx context.Context, from, to time.Time) ([]*entities.Trip, error) {
+	var trips []*entities.Trip
+	err := dbFromContext(ctx, r.db).
+		Preload("Route").
+		Preload("Bus").
+		Where("departure_time < ? AND arrival_time > ?", to, from).
+		Where("status <> ?", entities.TripStatusCancelled).
+		Order("bus_id, departure_time ASC").
+		Find(&trips).Error
+	return trips, err
+}
+
+func (r *tripRepository) GetScheduleTrips(ctx context.Context, scheduleID uuid.UUID, from, to time.Time) ([]*entities.Trip, error) {
+	var trips []*entities.Trip
+	err := dbFromContext(ctx, r.db).
+		Where("schedule_id = ? AND departure_time >= ? AND departure_time < ?", scheduleID, from, to).
+		Order("departure_time ASC").
+		Find(&trips).Error
+	return trips, err
+}
+
+func (r *tripRepository) GetRouteTrips(ctx context.Context, routeID uuid.UUID, from time.Time) ([]*entities.Trip, error) {
+	var trips []*entities.Trip
+	err := dbFromContext(ctx, r.db).
+		Where("route_id = ? AND departure_time > ? AND status IN ?", routeID, from, bookableTripStatuses).
 		Order("departure_time ASC").
-		Limit(limit).
 		Find(&trips).Error
 	return trips, err
 }
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"gorm.io/gorm"
+)
+
+type tripScheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewTripScheduleRepository creates a new trip schedule repository
+func NewTripScheduleRepository(db *gorm.DB) *tripScheduleRepository {
+	return &tripScheduleRepository{db: db}
+}
+
+func (r *tripScheduleRepository) Create(ctx context.Context, schedule *entities.TripSchedule) error {
+	return dbFromContext(ctx, r.db).Create(schedule).Error
+}
+
+func (r *tripScheduleRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.TripSchedule, error) {
+	var schedule entities.TripSchedule
+	err := dbFromContext(ctx, r.db).
+		Preload("Route").
+		Preload("Bus").
+		Where("id = ?", id).
+		First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *tripScheduleRepository) Update(ctx context.Context, schedule *entities.TripSchedule) error {
+	return dbFromContext(ctx, r.db).Omit("Route", "Bus").Save(schedule).Error
+}
+
+func (r *tripScheduleRepository) List(ctx context.Context, activeOnly bool, limit, offset int) ([]*entities.TripSchedule, error) {
+	var schedules []*entities.TripSchedule
+	query := dbFromContext(ctx, r.db).Preload("Route").Preload("Bus")
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *tripScheduleRepository) GetActive(ctx context.Context) ([]*entities.TripSchedule, error) {
+	var schedules []*entities.TripSchedule
+	err := dbFromContext(ctx, r.db).
+		Where("is_active = ?", true).
+		Order("created_at ASC").
+		Find(&schedules).Error
+	return schedules, err
+}
@@ -0,0 +1,284 @@
+package postgres
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/yourusername/bus-booking/internal/entities"
+)
+
+// tripSortColumns maps the search orders onto the columns of searchedTrips;
+// the ties go to the earlier departure
+var tripSortColumns = map[entities.TripSearchSort]string{
+	entities.TripSortDeparture: "departure_time",
+	entities.TripSortPrice:     "price",
+	entities.TripSortDuration:  "duration",
+	entities.TripSortRating:    "rating",
+}
+
+// searchedTrips builds the CTEs shared by the search and its facets: every
+// bookable trip between the cities on the day with its free seats and adult
+// fare, flagged with the filters it passes so each facet can leave its own
+// filter out
+func searchedTrips(search *entities.TripSearch) (string, []interface{}) {
+	var args []interface{}
+	flag := func(condition string, conditionArgs ...interface{}) string {
+		args = append(args, conditionArgs...)
+		return "COALESCE(" + condition + ", FALSE)"
+	}
+
+	// Seats free on every segment, counted in one pass over the day's seats
+	availability := `SELECT trip_id, COUNT(*) AS seats FROM (
+			SELECT trip_id, seat_number FROM seats_status
+			WHERE trip_id IN (SELECT id FROM trips WHERE departure_time >= ? AND departure_time < ?)
+			GROUP BY trip_id, seat_number
+			HAVING bool_and(status = ? OR (status = ? AND locked_until < ?))
+		) free GROUP BY trip_id`
+	availabilityArgs := []interface{}{search.From, search.To, entities.SeatStatusAvailable, entities.SeatStatusLocked, time.Now()}
+
+	// The adult fare is what the search filters, sorts and shows by: the trip
+	// price under the most specific active adult rule, as FareRule.Fare has it
+	adultRule := `SELECT discount_type, discount_value FROM fare_rules
+			WHERE category = ? AND is_active
+				AND (route_id IS NULL OR route_id = trips.route_id)
+				AND (operator_name IS NULL OR operator_name = COALESCE(buses.operator_name, ''))
+			ORDER BY (route_id IS NOT NULL)::int * 2 + (operator_name IS NOT NULL)::int DESC
+			LIMIT 1`
+
+	candidates := `SELECT trips.id, trips.departure_time, trips.duration,
+			GREATEST(0, ROUND(CASE adult_rule.discount_type
+				WHEN ? THEN trips.price * (1 - adult_rule.discount_value::numeric / 100)
+				WHEN ? THEN trips.price - adult_rule.discount_value::numeric
+				ELSE trips.price END)) AS price,
+			buses.bus_type, COALESCE(buses.operator_name, '') AS operator_name, buses.amenities, buses.rating,
+			COALESCE(availability.seats, 0) AS available_seats
+		FROM trips
+		JOIN routes ON routes.id = trips.route_id
+		JOIN buses ON buses.id = trips.bus_id
+		LEFT JOIN (` + availability + `) availability ON availability.trip_id = trips.id
+		LEFT JOIN LATERAL (` + adultRule + `) adult_rule ON TRUE
+		WHERE routes.from_location_id = ? AND routes.to_location_id = ?
+			AND trips.departure_time >= ? AND trips.departure_time < ?
+			AND trips.status IN ?`
+	args = append(args, entities.FareDiscountPercentage, entities.FareDiscountFixed)
+	args = append(args, availabilityArgs...)
+	args = append(args, entities.FareCategoryAdult)
+	args = append(args, search.FromLocationID, search.ToLocationID, search.From, search.To, bookableTripStatuses)
+
+	var flags []string
+	flags = append(flags, flag("departure_time >= ? AND departure_time < ?", search.DepartFrom, search.DepartTo)+" AS in_window")
+
+	var price []string
+	var priceArgs []interface{}
+	if search.MinPrice != nil {
+		price = append(price, "price >= ?")
+		priceArgs = append(priceArgs, *search.MinPrice)
+	}
+	if search.MaxPrice != nil {
+		price = append(price, "price <= ?")
+		priceArgs = append(priceArgs, *search.MaxPrice)
+	}
+	if len(price) == 0 {
+		price = append(price, "TRUE")
+	}
+	flags = append(flags, flag(strings.Join(price, " AND "), priceArgs...)+" AS in_price")
+
+	if len(search.BusTypes) > 0 {
+		flags = append(flags, flag("bus_type IN ?", search.BusTypes)+" AS of_type")
+	} else {
+		flags = append(flags, "TRUE AS of_type")
+	}
+	if len(search.Operators) > 0 {
+		flags = append(flags, flag("operator_name IN ?", search.Operators)+" AS of_operator")
+	} else {
+		flags = append(flags, "TRUE AS of_operator")
+	}
+	if len(search.Amenities) > 0 {
+		flags = append(flags, flag("amenities @> ?", pq.StringArray(search.Amenities))+" AS has_amenities")
+	} else {
+		flags = append(flags, "TRUE AS has_amenities")
+	}
+	if search.MinSeats > 0 {
+		flags = append(flags, flag("available_seats >= ?", search.MinSeats)+" AS has_seats")
+	} else {
+		flags = append(flags, "TRUE AS has_seats")
+	}
+
+	return `WITH candidates AS (` + candidates + `),
+		flagged AS (SELECT candidates.*, ` + strings.Join(flags, ", ") + ` FROM candidates)`, args
+}
+
+// tripFilters joins the flags of every filter but the ones left out
+func tripFilters(except ...string) string {
+	var filters []string
+	for _, filter := range []string{"in_window", "in_price", "of_type", "of_operator", "has_amenities", "has_seats"} {
+		skip := false
+		for _, left := range except {
+			skip = skip || filter == left
+		}
+		if !skip {
+			filters = append(filters, filter)
+		}
+	}
+	return strings.Join(filters, " AND ")
+}
+
+func (r *tripRepository) Search(ctx context.Context, search *entities.TripSearch, limit, offset int) ([]*entities.TripSearchResult, error) {
+	cte, args := searchedTrips(search)
+
+	order := tripSortColumns[search.Sort]
+	if order == "" {
+		order = tripSortColumns[entities.TripSortDeparture]
+	}
+	if search.Descending {
+		order += " DESC NULLS LAST"
+	} else {
+		order += " ASC NULLS LAST"
+	}
+
+	query := cte + ` SELECT id, available_seats, price FROM flagged WHERE ` + tripFilters() +
+		` ORDER BY ` + order + `, departure_time ASC, id`
+	if limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, offset)
+	}
+
+	var found []struct {
+		ID             uuid.UUID
+		AvailableSeats int
+		Price          float64
+	}
+	err := dbFromContext(ctx, r.db).Raw(query, args...).Scan(&found).Error
+	if err != nil || len(found) == 0 {
+		return []*entities.TripSearchResult{}, err
+	}
+
+	ids := make([]uuid.UUID, len(found))
+	for i, trip := range found {
+		ids[i] = trip.ID
+	}
+	var trips []*entities.Trip
+	err = dbFromContext(ctx, r.db).
+		Preload("Route").
+		Preload("Bus").
+		Where("id IN ?", ids).
+		Find(&trips).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*entities.Trip, len(trips))
+	for _, trip := range trips {
+		byID[trip.ID] = trip
+	}
+
+	results := make([]*entities.TripSearchResult, 0, len(found))
+	for _, trip := range found {
+		if byID[trip.ID] != nil {
+			results = append(results, &entities.TripSearchResult{
+				Trip:           byID[trip.ID],
+				AvailableSeats: trip.AvailableSeats,
+				LowestPrice:    trip.Price,
+			})
+		}
+	}
+	return results, nil
+}
+
+func (r *tripRepository) SearchFacets(ctx context.Context, search *entities.TripSearch) (*entities.TripSearchFacets, error) {
+	cte, args := searchedTrips(search)
+
+	// The departure windows split the day at 06:00, 12:00 and 18:00 local time
+	windowAt := func(hour int) time.Time {
+		return time.Date(search.From.Year(), search.From.Month(), search.From.Day(), hour, 0, 0, 0, search.From.Location())
+	}
+	args = append(args,
+		windowAt(6), entities.DepartureWindowNight,
+		windowAt(12), entities.DepartureWindowMorning,
+		windowAt(18), entities.DepartureWindowAfternoon,
+		entities.DepartureWindowEvening,
+	)
+
+	var rows []struct {
+		Facet    string
+		Value    string
+		Count    int
+		MinPrice float64
+		MaxPrice float64
+	}
+	err := dbFromContext(ctx, r.db).Raw(cte+`
+		SELECT 'bus_type' AS facet, bus_type AS value, COUNT(*) FILTER (WHERE `+tripFilters("of_type")+`) AS count, 0 AS min_price, 0 AS max_price
+		FROM flagged GROUP BY bus_type
+		UNION ALL
+		SELECT 'operator', operator_name, COUNT(*) FILTER (WHERE `+tripFilters("of_operator")+`), 0, 0
+		FROM flagged WHERE operator_name <> '' GROUP BY operator_name
+		UNION ALL
+		SELECT 'amenity', amenity, COUNT(*) FILTER (WHERE `+tripFilters()+`), 0, 0
+		FROM flagged, unnest(amenities) AS amenity GROUP BY amenity
+		UNION ALL
+		SELECT 'departure_window', departure_window, COUNT(*) FILTER (WHERE `+tripFilters("in_window")+`), 0, 0
+		FROM (
+			SELECT flagged.*, CASE
+				WHEN departure_time < ? THEN ?
+				WHEN departure_time < ? THEN ?
+				WHEN departure_time < ? THEN ?
+				ELSE ? END AS departure_window
+			FROM flagged
+		) windows GROUP BY departure_window
+		UNION ALL
+		SELECT 'total', '', COUNT(*) FILTER (WHERE `+tripFilters()+`),
+			COALESCE(MIN(price) FILTER (WHERE `+tripFilters("in_price")+`), 0),
+			COALESCE(MAX(price) FILTER (WHERE `+tripFilters("in_price")+`), 0)
+		FROM flagged`, args...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	facets := &entities.TripSearchFacets{
+		BusTypes:         []entities.FacetCount{},
+		Operators:        []entities.FacetCount{},
+		Amenities:        []entities.FacetCount{},
+		DepartureWindows: []entities.FacetCount{},
+	}
+	for _, row := range rows {
+		count := entities.FacetCount{Value: row.Value, Count: row.Count}
+		switch row.Facet {
+		case "bus_type":
+			facets.BusTypes = append(facets.BusTypes, count)
+		case "operator":
+			facets.Operators = append(facets.Operators, count)
+		case "amenity":
+			facets.Amenities = append(facets.Amenities, count)
+		case "departure_window":
+			facets.DepartureWindows = append(facets.DepartureWindows, count)
+		case "total":
+			facets.Total = row.Count
+			facets.MinPrice = row.MinPrice
+			facets.MaxPrice = row.MaxPrice
+		}
+	}
+
+	// Values come most common first, departure windows in the order of the day
+	for _, counts := range [][]entities.FacetCount{facets.BusTypes, facets.Operators, facets.Amenities} {
+		sort.SliceStable(counts, func(i, j int) bool {
+			if counts[i].Count != counts[j].Count {
+				return counts[i].Count > counts[j].Count
+			}
+			return counts[i].Value < counts[j].Value
+		})
+	}
+	windows := map[string]int{
+		entities.DepartureWindowNight:     0,
+		entities.DepartureWindowMorning:   1,
+		entities.DepartureWindowAfternoon: 2,
+		entities.DepartureWindowEvening:   3,
+	}
+	sort.Slice(facets.DepartureWindows, func(i, j int) bool {
+		return windows[facets.DepartureWindows[i].Value] < windows[facets.DepartureWindows[j].Value]
+	})
+	return facets, nil
+}
@@ -18,12 +18,12 @@ func NewUserRepository(db *gorm.DB) *userRepository {
 }
 
 func (r *userRepository) Create(ctx context.Context, user *entities.User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	return dbFromContext(ctx, r.db).Create(user).Error
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.User, error) {
 	var user entities.User
-	err := r.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", id).First(&user).Error
+	err := dbFromContext(ctx, r.db).Where("id = ? AND deleted_at IS NULL", id).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -32,7 +32,7 @@ func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.U
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
 	var user entities.User
-	err := r.db.WithContext(ctx).Where("email = ? AND deleted_at IS NULL", email).First(&user).Error
+	err := dbFromContext(ctx, r.db).Where("email = ? AND deleted_at IS NULL", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +41,7 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entitie
 
 func (r *userRepository) GetByOAuthID(ctx context.Context, oauthID string, provider string) (*entities.User, error) {
 	var user entities.User
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Where("oauth_id = ? AND oauth_provider = ? AND deleted_at IS NULL", oauthID, provider).
 		First(&user).Error
 	if err != nil {
@@ -51,18 +51,18 @@ func (r *userRepository) GetByOAuthID(ctx context.Context, oauthID string, provi
 }
 
 func (r *userRepository) Update(ctx context.Context, user *entities.User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+	return dbFromContext(ctx, r.db).Save(user).Error
 }
 
 func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Model(&entities.User{}).
+	return dbFromContext(ctx, r.db).Model(&entities.User{}).
 		Where("id = ?", id).
 		Update("deleted_at", gorm.Expr("NOW()")).Error
 }
 
 func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*entities.User, error) {
 	var users []*entities.User
-	err := r.db.WithContext(ctx).
+	err := dbFromContext(ctx, r.db).
 		Where("deleted_at IS NULL").
 		Limit(limit).
 		Offset(offset).
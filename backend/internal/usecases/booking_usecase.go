@@ -2,7 +2,10 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,115 +16,425 @@ import (
 
 // BookingUsecase handles booking business logic
 type BookingUsecase struct {
+	tx          repositories.Transactor
+	outboxRepo  repositories.OutboxRepository
 	bookingRepo repositories.BookingRepository
 	seatRepo    repositories.SeatRepository
 	tripRepo    repositories.TripRepository
 	paymentRepo repositories.PaymentRepository
 	ticketRepo  repositories.TicketRepository
 	cache       *cache.RedisCache
-
-	seatLockDuration time.Duration
-	bookingExpiry    time.Duration
+	fares       *FareUsecase
+	promotions  *PromotionUsecase
+	loyalty     *LoyaltyUsecase
+	holds       *HoldLimitUsecase
+
+	seatLockDuration  time.Duration
+	bookingExpiry     time.Duration
+	roundTripDiscount float64
+	location          *time.Location // Business timezone trip times are shown in
 }
 
 // NewBookingUsecase creates a new booking usecase
 func NewBookingUsecase(
+	tx repositories.Transactor,
+	outboxRepo repositories.OutboxRepository,
 	bookingRepo repositories.BookingRepository,
 	seatRepo repositories.SeatRepository,
 	tripRepo repositories.TripRepository,
 	paymentRepo repositories.PaymentRepository,
 	ticketRepo repositories.TicketRepository,
 	cache *cache.RedisCache,
+	fares *FareUsecase,
+	promotions *PromotionUsecase,
+	loyalty *LoyaltyUsecase,
+	holds *HoldLimitUsecase,
 	seatLockDuration time.Duration,
 	bookingExpiry time.Duration,
+	roundTripDiscount float64,
+	location *time.Location,
 ) *BookingUsecase {
 	return &BookingUsecase{
-		bookingRepo:      bookingRepo,
-		seatRepo:         seatRepo,
-		tripRepo:         tripRepo,
-		paymentRepo:      paymentRepo,
-		ticketRepo:       ticketRepo,
-		cache:            cache,
-		seatLockDuration: seatLockDuration,
-		bookingExpiry:    bookingExpiry,
+		tx:                tx,
+		outboxRepo:        outboxRepo,
+		bookingRepo:       bookingRepo,
+		seatRepo:          seatRepo,
+		tripRepo:          tripRepo,
+		paymentRepo:       paymentRepo,
+		ticketRepo:        ticketRepo,
+		cache:             cache,
+		fares:             fares,
+		promotions:        promotions,
+		loyalty:           loyalty,
+		holds:             holds,
+		seatLockDuration:  seatLockDuration,
+		bookingExpiry:     bookingExpiry,
+		roundTripDiscount: roundTripDiscount,
+		location:          location,
 	}
 }
 
+// ErrBookingNotConfirmable is returned when a paid booking can no longer be confirmed,
+// e.g. because it was cancelled or its seats were taken after the lock expired
+var ErrBookingNotConfirmable = errors.New("booking can no longer be confirmed")
+
+// InitiateBookingInput holds the details needed to start a booking. Setting
+// ReturnTripID books a round trip: both legs are held together and paid with
+// one payment on the outbound booking. Without Passengers every seat is sold
+// as an adult fare in the contact's name. PromoCode, if set, is redeemed
+// against the whole journey. Leaving SeatNumbers empty assigns SeatCount seats
+// automatically, as does leaving ReturnSeatNumbers empty for the return trip.
+// ClientIP and DeviceID identify guests for the anti-hoarding limits.
+// PickupStopID and DropoffStopID pick where the passengers board and leave
+// the bus among the route's stops; nil means the route's origin and
+// destination. The Return fields do the same for the return trip.
+type InitiateBookingInput struct {
+	TripID              uuid.UUID
+	SeatNumbers         []string
+	SeatCount           int
+	SeatPreferences     SeatPreferences
+	PickupStopID        *uuid.UUID
+	DropoffStopID       *uuid.UUID
+	ReturnTripID        *uuid.UUID
+	ReturnSeatNumbers   []string
+	ReturnPickupStopID  *uuid.UUID
+	ReturnDropoffStopID *uuid.UUID
+	Passengers          []PassengerInput
+	PromoCode           string
+	UserID              *uuid.UUID
+	ClientIP            string
+	DeviceID            string
+	ContactName         string
+	ContactEmail        string
+	ContactPhone        string
+}
+
 // InitiateBooking starts the booking process by locking seats
-func (uc *BookingUsecase) InitiateBooking(ctx context.Context, tripID uuid.UUID, seatNumbers []string, userID *uuid.UUID) (*entities.Booking, error) {
+func (uc *BookingUsecase) InitiateBooking(ctx context.Context, input InitiateBookingInput) (*entities.Booking, error) {
 	// Validate trip exists
-	trip, err := uc.tripRepo.GetByIDWithDetails(ctx, tripID)
+	trip, err := uc.tripRepo.GetByIDWithDetails(ctx, input.TripID)
 	if err != nil {
 		return nil, fmt.Errorf("trip not found: %w", err)
 	}
 
-	if trip.Status != entities.TripStatusScheduled {
+	if !trip.IsBookable() {
 		return nil, fmt.Errorf("trip is not available for booking")
 	}
 
-	// Generate lock ID (user ID or session ID)
-	lockID := uuid.New()
-	if userID != nil {
-		lockID = *userID
+	if err := validateBoardingStops(trip.Route, input.PickupStopID, input.DropoffStopID); err != nil {
+		return nil, err
 	}
+	stops := trip.Route.StopRange(input.PickupStopID, input.DropoffStopID)
 
-	// Attempt to lock seats in both Redis and PostgreSQL
-	// 1. Redis lock for fast distributed locking
-	for _, seatNum := range seatNumbers {
-		err := uc.cache.LockSeat(ctx, tripID, seatNum, lockID, uc.seatLockDuration)
+	if len(input.SeatNumbers) == 0 {
+		allocation, err := uc.assignSeats(ctx, trip, stops, input.SeatCount, input.SeatPreferences)
 		if err != nil {
-			// Rollback Redis locks
-			for i := 0; i < len(seatNumbers); i++ {
-				_ = uc.cache.UnlockSeat(ctx, tripID, seatNumbers[i])
+			return nil, err
+		}
+		input.SeatNumbers = allocation.Seats
+	}
+
+	var returnTrip *entities.Trip
+	var returnStops entities.StopRange
+	if input.ReturnTripID != nil {
+		returnTrip, err = uc.validateReturnTrip(ctx, trip, *input.ReturnTripID)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateBoardingStops(returnTrip.Route, input.ReturnPickupStopID, input.ReturnDropoffStopID); err != nil {
+			return nil, fmt.Errorf("return trip: %w", err)
+		}
+		returnStops = returnTrip.Route.StopRange(input.ReturnPickupStopID, input.ReturnDropoffStopID)
+
+		if len(input.ReturnSeatNumbers) == 0 {
+			allocation, err := uc.assignSeats(ctx, returnTrip, returnStops, len(input.SeatNumbers), input.SeatPreferences)
+			if err != nil {
+				return nil, fmt.Errorf("return trip: %w", err)
 			}
-			return nil, fmt.Errorf("failed to lock seat %s: %w", seatNum, err)
+			input.ReturnSeatNumbers = allocation.Seats
 		}
 	}
 
-	// 2. PostgreSQL lock with SELECT FOR UPDATE
-	err = uc.seatRepo.LockSeats(ctx, tripID, seatNumbers, lockID, uc.seatLockDuration)
-	if err != nil {
-		// Rollback Redis locks
-		for _, seatNum := range seatNumbers {
-			_ = uc.cache.UnlockSeat(ctx, tripID, seatNum)
+	passengerInputs := input.Passengers
+	if len(passengerInputs) == 0 {
+		for range input.SeatNumbers {
+			passengerInputs = append(passengerInputs, PassengerInput{
+				Name:     input.ContactName,
+				Phone:    input.ContactPhone,
+				Email:    input.ContactEmail,
+				Category: entities.FareCategoryAdult,
+			})
 		}
-		return nil, fmt.Errorf("failed to lock seats in database: %w", err)
 	}
 
+	passengers, fare, err := uc.fares.PricePassengers(ctx, trip, stops, passengerInputs, input.SeatNumbers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every booking locks its seats with a fresh token, written to both Redis and
+	// PostgreSQL, so only this booking can release or confirm them. Members in
+	// higher loyalty tiers hold seats longer.
+	lockID := uuid.New()
+	holdBonus := time.Duration(0)
+	if input.UserID != nil {
+		holdBonus = uc.loyalty.HoldBonus(ctx, *input.UserID)
+	}
+	lockDuration := uc.seatLockDuration + holdBonus
+	expiresAt := time.Now().Add(uc.bookingExpiry + holdBonus)
+
 	// Create pending booking
 	booking := &entities.Booking{
-		TripID:      tripID,
-		UserID:      userID,
-		Seats:       seatNumbers,
-		TotalPrice:  trip.Price * float64(len(seatNumbers)),
-		Status:      entities.BookingStatusPending,
-		BookingCode: generateBookingCode(),
+		TripID:        trip.ID,
+		UserID:        input.UserID,
+		ContactName:   input.ContactName,
+		ContactEmail:  input.ContactEmail,
+		ContactPhone:  input.ContactPhone,
+		Seats:         input.SeatNumbers,
+		Passengers:    passengers,
+		TotalPrice:    fare,
+		Status:        entities.BookingStatusPending,
+		BookingCode:   generateBookingCode(),
+		LockID:        &lockID,
+		ClientIP:      input.ClientIP,
+		DeviceID:      input.DeviceID,
+		ExpiresAt:     &expiresAt,
+		PickupStopID:  input.PickupStopID,
+		DropoffStopID: input.DropoffStopID,
+		FromStop:      stops.From,
+		ToStop:        stops.To,
 	}
 
-	expiresAt := time.Now().Add(uc.bookingExpiry)
-	booking.ExpiresAt = &expiresAt
+	if returnTrip != nil {
+		returnPassengers, fare, err := uc.fares.PricePassengers(ctx, returnTrip, returnStops, passengerInputs, input.ReturnSeatNumbers)
+		if err != nil {
+			return nil, fmt.Errorf("return trip: %w", err)
+		}
+		discount := roundTripDiscount(fare, uc.roundTripDiscount)
+		booking.ReturnBooking = &entities.Booking{
+			TripID:         returnTrip.ID,
+			UserID:         input.UserID,
+			ContactName:    input.ContactName,
+			ContactEmail:   input.ContactEmail,
+			ContactPhone:   input.ContactPhone,
+			Seats:          input.ReturnSeatNumbers,
+			Passengers:     returnPassengers,
+			TotalPrice:     fare - discount,
+			DiscountAmount: discount,
+			Status:         entities.BookingStatusPending,
+			BookingCode:    generateBookingCode(),
+			LockID:         &lockID,
+			ExpiresAt:      &expiresAt,
+			PickupStopID:   input.ReturnPickupStopID,
+			DropoffStopID:  input.ReturnDropoffStopID,
+			FromStop:       returnStops.From,
+			ToStop:         returnStops.To,
+		}
+	}
+	legs := booking.Legs()
 
-	err = uc.bookingRepo.Create(ctx, booking)
+	// Enforce the anti-hoarding limits before any seat is touched
+	if err := uc.holds.Reserve(ctx, booking); err != nil {
+		return nil, err
+	}
+
+	// Attempt to lock seats in both Redis and PostgreSQL
+	// 1. Redis lock for fast distributed locking; all legs or none
+	for i, leg := range legs {
+		if err := uc.cache.LockSeats(ctx, leg.TripID, leg.Seats, leg.StopRange(), lockID, lockDuration); err != nil {
+			for _, locked := range legs[:i] {
+				uc.unlockSeatsInCache(ctx, locked)
+			}
+			_ = uc.holds.Release(ctx, booking)
+			return nil, fmt.Errorf("failed to lock seats: %w", err)
+		}
+	}
+
+	// 2. PostgreSQL lock with SELECT FOR UPDATE, committed together with the bookings and their events
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, leg := range legs {
+			if err := uc.seatRepo.LockSeats(ctx, leg.TripID, leg.Seats, leg.StopRange(), lockID, lockDuration); err != nil {
+				return fmt.Errorf("failed to lock seats in database: %w", err)
+			}
+		}
+
+		// The return leg is created separately so it can reference the outbound ID
+		returnLeg := booking.ReturnBooking
+		booking.ReturnBooking = nil
+		if err := uc.bookingRepo.Create(ctx, booking); err != nil {
+			return fmt.Errorf("failed to create booking: %w", err)
+		}
+		if returnLeg != nil {
+			returnLeg.ParentBookingID = &booking.ID
+			if err := uc.bookingRepo.Create(ctx, returnLeg); err != nil {
+				return fmt.Errorf("failed to create return booking: %w", err)
+			}
+			booking.ReturnBooking = returnLeg
+		}
+
+		if input.PromoCode != "" {
+			booking.Trip = trip
+			if returnLeg != nil {
+				returnLeg.Trip = returnTrip
+			}
+			if err := uc.promotions.Apply(ctx, input.PromoCode, booking); err != nil {
+				return err
+			}
+		}
+
+		for _, leg := range legs {
+			if err := recordSeatsEvent(ctx, uc.outboxRepo, entities.EventBookingCreated, leg, entities.SeatStatusLocked); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create booking: %w", err)
+		// Rollback Redis locks
+		for _, leg := range legs {
+			uc.unlockSeatsInCache(ctx, leg)
+		}
+		_ = uc.holds.Release(ctx, booking)
+		return nil, err
 	}
 
-	// Invalidate cache and publish seat update
-	_ = uc.cache.InvalidateTripSeats(ctx, tripID)
-	uc.publishSeatUpdates(ctx, tripID, seatNumbers, entities.SeatStatusLocked)
+	// Invalidate cache
+	for _, leg := range legs {
+		_ = uc.cache.InvalidateTripSeats(ctx, leg.TripID)
+	}
 
+	booking.InLocation(uc.location)
 	return booking, nil
 }
 
-// ConfirmBooking confirms a booking after successful payment
+// validateReturnTrip checks that the return trip goes back the way the outbound
+// trip came and leaves after it arrives
+func (uc *BookingUsecase) validateReturnTrip(ctx context.Context, outbound *entities.Trip, returnTripID uuid.UUID) (*entities.Trip, error) {
+	returnTrip, err := uc.tripRepo.GetByIDWithDetails(ctx, returnTripID)
+	if err != nil {
+		return nil, fmt.Errorf("return trip not found: %w", err)
+	}
+
+	if !returnTrip.IsBookable() {
+		return nil, fmt.Errorf("return trip is not available for booking")
+	}
+
+	if !isReverseRoute(outbound.Route, returnTrip.Route) {
+		return nil, fmt.Errorf("return trip must go from %s to %s", outbound.Route.ToCity, outbound.Route.FromCity)
+	}
+
+	if !returnTrip.DepartureTime.After(outbound.ArrivalTime) {
+		return nil, fmt.Errorf("return trip must depart after the outbound trip arrives")
+	}
+
+	return returnTrip, nil
+}
+
+// validateBoardingStops checks the passengers can board at pickupID and leave
+// at dropoffID on route, where nil means the route's origin and destination
+func validateBoardingStops(route *entities.Route, pickupID, dropoffID *uuid.UUID) error {
+	var pickup, dropoff *entities.RouteStop
+	if pickupID != nil {
+		if pickup = route.Stop(*pickupID); pickup == nil {
+			return fmt.Errorf("pickup stop is not on this route")
+		}
+		if !pickup.AllowsPickup {
+			return fmt.Errorf("passengers cannot board at %s", pickup.Name)
+		}
+	}
+	if dropoffID != nil {
+		if dropoff = route.Stop(*dropoffID); dropoff == nil {
+			return fmt.Errorf("drop-off stop is not on this route")
+		}
+		if !dropoff.AllowsDropoff {
+			return fmt.Errorf("passengers cannot leave the bus at %s", dropoff.Name)
+		}
+	}
+	if pickup != nil && dropoff != nil && pickup.Sequence >= dropoff.Sequence {
+		return fmt.Errorf("drop-off stop must come after the pickup stop")
+	}
+	return nil
+}
+
+// SuggestSeats proposes seats for a group on a trip without holding them,
+// among the seats free from the pickup stop to the drop-off stop
+func (uc *BookingUsecase) SuggestSeats(ctx context.Context, tripID uuid.UUID, pickupStopID, dropoffStopID *uuid.UUID, count int, prefs SeatPreferences) (*SeatAllocation, error) {
+	trip, err := uc.tripRepo.GetByIDWithDetails(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("trip not found: %w", err)
+	}
+	if err := validateBoardingStops(trip.Route, pickupStopID, dropoffStopID); err != nil {
+		return nil, err
+	}
+	return uc.assignSeats(ctx, trip, trip.Route.StopRange(pickupStopID, dropoffStopID), count, prefs)
+}
+
+// assignSeats picks count seats free on the whole stop range using the
+// trip's bus's seat layout
+func (uc *BookingUsecase) assignSeats(ctx context.Context, trip *entities.Trip, stops entities.StopRange, count int, prefs SeatPreferences) (*SeatAllocation, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("either seat numbers or a seat count is required")
+	}
+
+	seats, err := uc.seatRepo.GetAvailableSeats(ctx, trip.ID, stops)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load available seats: %w", err)
+	}
+
+	available := make([]string, 0, len(seats))
+	for _, seat := range seats {
+		available = append(available, seat.SeatNumber)
+	}
+
+	var layout entities.SeatLayout
+	if trip.Bus != nil {
+		layout = trip.Bus.SeatLayout
+	}
+
+	allocation, ok := allocateSeats(layout, available, count, prefs)
+	if !ok {
+		return nil, fmt.Errorf("only %d seats are available", len(available))
+	}
+	return allocation, nil
+}
+
+// unlockSeatsInCache releases the Redis locks a booking still holds on its
+// seats; locks that expired and were taken by someone else are left alone
+func (uc *BookingUsecase) unlockSeatsInCache(ctx context.Context, booking *entities.Booking) {
+	if booking.LockID == nil {
+		return
+	}
+	_, _ = uc.cache.UnlockSeats(ctx, booking.TripID, booking.Seats, booking.StopRange(), *booking.LockID)
+}
+
+// ConfirmBooking confirms a booking after successful payment. Every leg of a
+// round trip is confirmed in the same transaction, so either the whole journey
+// is booked or none of it. It is safe to call again for a confirmed booking.
 func (uc *BookingUsecase) ConfirmBooking(ctx context.Context, bookingID uuid.UUID, paymentID uuid.UUID) error {
 	booking, err := uc.bookingRepo.GetByIDWithDetails(ctx, bookingID)
 	if err != nil {
 		return fmt.Errorf("booking not found: %w", err)
 	}
 
-	if booking.Status != entities.BookingStatusPending {
-		return fmt.Errorf("booking is not in pending status")
+	legs := booking.Legs()
+	pending := make([]*entities.Booking, 0, len(legs))
+	for _, leg := range legs {
+		switch leg.Status {
+		case entities.BookingStatusConfirmed:
+		case entities.BookingStatusPending, entities.BookingStatusPaid:
+			pending = append(pending, leg)
+		default:
+			return fmt.Errorf("booking %s is %s: %w", leg.BookingCode, leg.Status, ErrBookingNotConfirmable)
+		}
+	}
+
+	if len(pending) == 0 {
+		for _, leg := range legs {
+			if err := uc.ensureTickets(ctx, leg); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
 	// Verify payment
@@ -134,40 +447,72 @@ func (uc *BookingUsecase) ConfirmBooking(ctx context.Context, bookingID uuid.UUI
 		return fmt.Errorf("payment is not completed")
 	}
 
-	// Mark seats as booked
-	err = uc.seatRepo.MarkSeatsAsBooked(ctx, booking.TripID, booking.Seats, bookingID)
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, leg := range legs {
+			if leg.Status == entities.BookingStatusConfirmed {
+				if err := uc.ensureTickets(ctx, leg); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := uc.confirmLeg(ctx, leg); err != nil {
+				return err
+			}
+		}
+
+		// Points are earned once per journey, on the amount actually paid
+		return uc.loyalty.Earn(ctx, booking)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Clear Redis locks and invalidate cache
+	for _, leg := range pending {
+		uc.unlockSeatsInCache(ctx, leg)
+		_ = uc.cache.InvalidateTripSeats(ctx, leg.TripID)
+	}
+	_ = uc.holds.Release(ctx, booking)
+
+	return nil
+}
+
+// confirmLeg books the seats of a single booking and issues its tickets; it
+// must run inside a transaction
+func (uc *BookingUsecase) confirmLeg(ctx context.Context, booking *entities.Booking) error {
+	lockedBy := uuid.Nil
+	if booking.LockID != nil {
+		lockedBy = *booking.LockID
+	}
+
+	// Mark seats as booked, provided nobody else took them after our lock expired
+	err := uc.seatRepo.ConfirmSeats(ctx, booking.TripID, booking.Seats, booking.StopRange(), lockedBy, booking.ID)
 	if err != nil {
+		if errors.Is(err, repositories.ErrSeatUnavailable) {
+			return fmt.Errorf("%w: %v", ErrBookingNotConfirmable, err)
+		}
 		return fmt.Errorf("failed to mark seats as booked: %w", err)
 	}
 
 	// Update booking status
 	booking.Status = entities.BookingStatusConfirmed
-	err = uc.bookingRepo.Update(ctx, booking)
-	if err != nil {
+	booking.ExpiresAt = nil
+	if err := uc.bookingRepo.Update(ctx, booking); err != nil {
 		return fmt.Errorf("failed to update booking: %w", err)
 	}
 
 	// Generate tickets
-	err = uc.generateTickets(ctx, booking)
-	if err != nil {
-		return fmt.Errorf("failed to generate tickets: %w", err)
-	}
-
-	// Clear Redis locks and invalidate cache
-	for _, seatNum := range booking.Seats {
-		_ = uc.cache.UnlockSeat(ctx, booking.TripID, seatNum)
+	if err := uc.ensureTickets(ctx, booking); err != nil {
+		return err
 	}
-	_ = uc.cache.InvalidateTripSeats(ctx, booking.TripID)
 
-	// Publish seat updates
-	uc.publishSeatUpdates(ctx, booking.TripID, booking.Seats, entities.SeatStatusBooked)
-
-	return nil
+	return recordSeatsEvent(ctx, uc.outboxRepo, entities.EventBookingConfirmed, booking, entities.SeatStatusBooked)
 }
 
-// CancelBooking cancels a booking and releases seats
+// CancelBooking cancels a booking and releases seats. Cancelling either leg of
+// a round trip cancels the whole journey, since it was priced and paid as one.
 func (uc *BookingUsecase) CancelBooking(ctx context.Context, bookingID uuid.UUID) error {
-	booking, err := uc.bookingRepo.GetByID(ctx, bookingID)
+	booking, err := uc.getJourney(ctx, bookingID)
 	if err != nil {
 		return fmt.Errorf("booking not found: %w", err)
 	}
@@ -176,29 +521,70 @@ func (uc *BookingUsecase) CancelBooking(ctx context.Context, bookingID uuid.UUID
 		return fmt.Errorf("booking is already cancelled or expired")
 	}
 
-	// Release seats
-	err = uc.seatRepo.ReleaseSeats(ctx, bookingID)
+	return uc.releaseBooking(ctx, booking, entities.BookingStatusCancelled)
+}
+
+// getJourney loads a booking with its legs; for a return leg it loads the
+// outbound booking it belongs to
+func (uc *BookingUsecase) getJourney(ctx context.Context, bookingID uuid.UUID) (*entities.Booking, error) {
+	booking, err := uc.bookingRepo.GetByIDWithDetails(ctx, bookingID)
 	if err != nil {
-		return fmt.Errorf("failed to release seats: %w", err)
+		return nil, err
 	}
+	if booking.IsReturnLeg() {
+		return uc.bookingRepo.GetByIDWithDetails(ctx, *booking.ParentBookingID)
+	}
+	return booking, nil
+}
 
-	// Update booking status
-	now := time.Now()
-	booking.Status = entities.BookingStatusCancelled
-	booking.CancelledAt = &now
-	err = uc.bookingRepo.Update(ctx, booking)
+// releaseBooking frees every seat held by the booking's legs, gives back its
+// promo code and loyalty points and moves the legs to the given status
+func (uc *BookingUsecase) releaseBooking(ctx context.Context, booking *entities.Booking, status entities.BookingStatus) error {
+	legs := booking.Legs()
+
+	err := uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.promotions.Release(ctx, booking.ID); err != nil {
+			return err
+		}
+		if err := uc.loyalty.ReleaseBooking(ctx, booking.ID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, leg := range legs {
+			// Release seats: a pending leg still holds them under its lock token
+			if leg.Status == entities.BookingStatusPending && leg.LockID != nil {
+				if err := uc.seatRepo.UnlockSeats(ctx, leg.TripID, leg.Seats, leg.StopRange(), *leg.LockID); err != nil {
+					return fmt.Errorf("failed to unlock seats: %w", err)
+				}
+			}
+			if err := uc.seatRepo.ReleaseSeats(ctx, leg.ID); err != nil {
+				return fmt.Errorf("failed to release seats: %w", err)
+			}
+
+			// Update booking status
+			leg.Status = status
+			leg.CancelledAt = &now
+			if err := uc.bookingRepo.Update(ctx, leg); err != nil {
+				return fmt.Errorf("failed to update booking: %w", err)
+			}
+
+			if err := recordSeatsEvent(ctx, uc.outboxRepo, entities.EventSeatsReleased, leg, entities.SeatStatusAvailable); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update booking: %w", err)
+		return err
 	}
 
 	// Clear Redis locks
-	for _, seatNum := range booking.Seats {
-		_ = uc.cache.UnlockSeat(ctx, booking.TripID, seatNum)
+	for _, leg := range legs {
+		uc.unlockSeatsInCache(ctx, leg)
+		_ = uc.cache.InvalidateTripSeats(ctx, leg.TripID)
 	}
-	_ = uc.cache.InvalidateTripSeats(ctx, booking.TripID)
-
-	// Publish updates
-	uc.publishSeatUpdates(ctx, booking.TripID, booking.Seats, entities.SeatStatusAvailable)
+	_ = uc.holds.Release(ctx, booking)
 
 	return nil
 }
@@ -209,6 +595,7 @@ func (uc *BookingUsecase) GetBookingByID(ctx context.Context, bookingID uuid.UUI
 	if err != nil {
 		return nil, fmt.Errorf("booking not found: %w", err)
 	}
+	booking.InLocation(uc.location)
 	return booking, nil
 }
 
@@ -219,27 +606,53 @@ func (uc *BookingUsecase) GetUserBookings(ctx context.Context, userID uuid.UUID,
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user bookings: %w", err)
 	}
+	for _, booking := range bookings {
+		booking.InLocation(uc.location)
+	}
 	return bookings, nil
 }
 
-// GetAvailableSeats retrieves available seats for a trip (with caching)
-func (uc *BookingUsecase) GetAvailableSeats(ctx context.Context, tripID uuid.UUID) ([]*entities.SeatInfo, error) {
+// GetAvailableSeats retrieves the seats of a trip from the pickup stop to the
+// drop-off stop (with caching), where nil means the origin or destination.
+// Each seat is as taken as the most taken of its segments in the range.
+func (uc *BookingUsecase) GetAvailableSeats(ctx context.Context, tripID uuid.UUID, pickupStopID, dropoffStopID *uuid.UUID) ([]*entities.SeatInfo, error) {
+	var stops *entities.StopRange
+	if pickupStopID != nil || dropoffStopID != nil {
+		trip, err := uc.tripRepo.GetByIDWithDetails(ctx, tripID)
+		if err != nil {
+			return nil, fmt.Errorf("trip not found: %w", err)
+		}
+		if err := validateBoardingStops(trip.Route, pickupStopID, dropoffStopID); err != nil {
+			return nil, err
+		}
+		r := trip.Route.StopRange(pickupStopID, dropoffStopID)
+		stops = &r
+	}
+
 	// Try cache first
 	seats, err := uc.cache.GetTripSeats(ctx, tripID)
-	if err == nil && seats != nil {
-		return seats, nil
-	}
+	if err != nil || seats == nil {
+		// Cache miss - fetch from database
+		seats, err = uc.seatRepo.GetAllByTrip(ctx, tripID)
+		if err != nil {
+			return nil, err
+		}
 
-	// Cache miss - fetch from database
-	seats, err = uc.seatRepo.GetAllByTrip(ctx, tripID)
-	if err != nil {
-		return nil, err
+		// Cache for 30 seconds
+		_ = uc.cache.CacheTripSeats(ctx, tripID, seats, 30*time.Second)
 	}
 
-	// Cache for 30 seconds
-	_ = uc.cache.CacheTripSeats(ctx, tripID, seats, 30*time.Second)
-
-	return seats, nil
+	if stops == nil {
+		// The whole trip, up to the last segment it has rows for
+		whole := entities.StopRange{To: 1}
+		for _, seat := range seats {
+			if seat.Segment >= whole.To {
+				whole.To = seat.Segment + 1
+			}
+		}
+		stops = &whole
+	}
+	return seatsOnRange(seats, *stops, time.Now()), nil
 }
 
 // Helper functions
@@ -247,35 +660,190 @@ func generateBookingCode() string {
 	return fmt.Sprintf("BK%d%s", time.Now().Unix(), uuid.New().String()[:8])
 }
 
+// roundTripDiscount is the discount on a return-leg fare, rounded to whole dong
+func roundTripDiscount(fare, rate float64) float64 {
+	if rate <= 0 {
+		return 0
+	}
+	if rate > 1 {
+		rate = 1
+	}
+	return math.Round(fare * rate)
+}
+
+// isReverseRoute reports whether back runs between the same locations as out, in the opposite direction
+func isReverseRoute(out, back *entities.Route) bool {
+	if out == nil || back == nil {
+		return false
+	}
+	return out.FromLocationID == back.ToLocationID && out.ToLocationID == back.FromLocationID
+}
+
+// ensureTickets creates tickets for a confirmed booking unless they already exist
+func (uc *BookingUsecase) ensureTickets(ctx context.Context, booking *entities.Booking) error {
+	existing, err := uc.ticketRepo.GetByBookingID(ctx, booking.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load tickets: %w", err)
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+
+	if err := uc.generateTickets(ctx, booking); err != nil {
+		return fmt.Errorf("failed to generate tickets: %w", err)
+	}
+	return nil
+}
+
 func (uc *BookingUsecase) generateTickets(ctx context.Context, booking *entities.Booking) error {
-	tickets := make([]*entities.Ticket, len(booking.Seats))
+	tickets := make([]*entities.Ticket, 0, len(booking.Seats))
+
+	if len(booking.Passengers) > 0 {
+		// One ticket per passenger, including lap infants
+		for _, passenger := range booking.Passengers {
+			tickets = append(tickets, &entities.Ticket{
+				BookingID:      booking.ID,
+				PassengerName:  passenger.Name,
+				PassengerPhone: firstNonEmpty(passenger.Phone, booking.ContactPhone),
+				PassengerEmail: firstNonEmpty(passenger.Email, booking.ContactEmail),
+				SeatNumber:     passenger.SeatNumber,
+				FareCategory:   passenger.Category,
+				Fare:           passenger.Fare,
+				TicketCode:     generateTicketCode(),
+			})
+		}
+		return uc.ticketRepo.CreateBatch(ctx, tickets)
+	}
 
-	for i, seatNum := range booking.Seats {
-		tickets[i] = &entities.Ticket{
+	// Bookings made before fare categories: one adult ticket per seat
+	for _, seatNum := range booking.Seats {
+		tickets = append(tickets, &entities.Ticket{
 			BookingID:      booking.ID,
 			PassengerName:  booking.ContactName,
 			PassengerPhone: booking.ContactPhone,
 			PassengerEmail: booking.ContactEmail,
 			SeatNumber:     seatNum,
-			TicketCode:     fmt.Sprintf("TK%d%s", time.Now().Unix(), uuid.New().String()[:8]),
-		}
+			FareCategory:   entities.FareCategoryAdult,
+			Fare:           booking.TotalPrice / float64(len(booking.Seats)),
+			TicketCode:     generateTicketCode(),
+		})
 	}
 
 	return uc.ticketRepo.CreateBatch(ctx, tickets)
 }
 
-func (uc *BookingUsecase) publishSeatUpdates(ctx context.Context, tripID uuid.UUID, seatNumbers []string, status entities.SeatStatus) {
-	for _, seatNum := range seatNumbers {
-		seat := &entities.SeatInfo{
-			TripID:     tripID,
-			SeatNumber: seatNum,
-			Status:     status,
+func generateTicketCode() string {
+	return fmt.Sprintf("TK%d%s", time.Now().Unix(), uuid.New().String()[:8])
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ManifestEntry is one passenger on a trip manifest
+type ManifestEntry struct {
+	BookingCode    string                `json:"booking_code"`
+	TicketCode     string                `json:"ticket_code,omitempty"` // Empty until the booking is confirmed
+	PassengerName  string                `json:"passenger_name"`
+	PassengerPhone string                `json:"passenger_phone"`
+	SeatNumber     string                `json:"seat_number"`
+	FareCategory   entities.FareCategory `json:"fare_category"`
+	Fare           float64               `json:"fare"`
+	IsCheckedIn    bool                  `json:"is_checked_in"`
+
+	// Where the passenger boards and leaves; nil means the route's origin or destination
+	PickupStop  *entities.RouteStop `json:"pickup_stop,omitempty"`
+	DropoffStop *entities.RouteStop `json:"dropoff_stop,omitempty"`
+}
+
+// TripManifest lists everyone travelling on a trip
+type TripManifest struct {
+	TripID     uuid.UUID                     `json:"trip_id"`
+	Passengers []*ManifestEntry              `json:"passengers"`
+	Categories map[entities.FareCategory]int `json:"categories"`
+	SeatsTaken int                           `json:"seats_taken"`
+}
+
+// GetTripManifest builds the passenger manifest of a trip from its paid and confirmed bookings
+func (uc *BookingUsecase) GetTripManifest(ctx context.Context, tripID uuid.UUID) (*TripManifest, error) {
+	bookings, err := uc.bookingRepo.GetTripBookings(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trip bookings: %w", err)
+	}
+
+	manifest := &TripManifest{
+		TripID:     tripID,
+		Passengers: make([]*ManifestEntry, 0),
+		Categories: make(map[entities.FareCategory]int),
+	}
+	add := func(booking *entities.Booking, entry *ManifestEntry) {
+		entry.PickupStop, entry.DropoffStop = booking.PickupStop, booking.DropoffStop
+		manifest.Passengers = append(manifest.Passengers, entry)
+		manifest.Categories[entry.FareCategory]++
+		if entry.SeatNumber != "" {
+			manifest.SeatsTaken++
 		}
-		_ = uc.cache.PublishSeatUpdate(ctx, tripID, seat)
 	}
+
+	for _, booking := range bookings {
+		switch {
+		case len(booking.Tickets) > 0:
+			for _, ticket := range booking.Tickets {
+				add(booking, &ManifestEntry{
+					BookingCode:    booking.BookingCode,
+					TicketCode:     ticket.TicketCode,
+					PassengerName:  ticket.PassengerName,
+					PassengerPhone: ticket.PassengerPhone,
+					SeatNumber:     ticket.SeatNumber,
+					FareCategory:   ticket.FareCategory,
+					Fare:           ticket.Fare,
+					IsCheckedIn:    ticket.IsCheckedIn,
+				})
+			}
+		case len(booking.Passengers) > 0:
+			for _, passenger := range booking.Passengers {
+				add(booking, &ManifestEntry{
+					BookingCode:    booking.BookingCode,
+					PassengerName:  passenger.Name,
+					PassengerPhone: firstNonEmpty(passenger.Phone, booking.ContactPhone),
+					SeatNumber:     passenger.SeatNumber,
+					FareCategory:   passenger.Category,
+					Fare:           passenger.Fare,
+				})
+			}
+		default:
+			for _, seatNum := range booking.Seats {
+				add(booking, &ManifestEntry{
+					BookingCode:    booking.BookingCode,
+					PassengerName:  booking.ContactName,
+					PassengerPhone: booking.ContactPhone,
+					SeatNumber:     seatNum,
+					FareCategory:   entities.FareCategoryAdult,
+					Fare:           booking.TotalPrice / float64(len(booking.Seats)),
+				})
+			}
+		}
+	}
+
+	// By seat, with passengers who have no seat last
+	sort.SliceStable(manifest.Passengers, func(i, j int) bool {
+		a, b := manifest.Passengers[i].SeatNumber, manifest.Passengers[j].SeatNumber
+		if (a == "") != (b == "") {
+			return b == ""
+		}
+		return a < b
+	})
+
+	return manifest, nil
 }
 
-// ExpireOldBookings is a cleanup job that expires pending bookings
+// ExpireOldBookings is a cleanup job that expires pending bookings. Each
+// journey that lapsed unpaid counts towards a hold cooldown for whoever made it.
 func (uc *BookingUsecase) ExpireOldBookings(ctx context.Context) error {
 	expiredBookings, err := uc.bookingRepo.GetExpiredBookings(ctx)
 	if err != nil {
@@ -283,7 +851,10 @@ func (uc *BookingUsecase) ExpireOldBookings(ctx context.Context) error {
 	}
 
 	for _, booking := range expiredBookings {
-		_ = uc.CancelBooking(ctx, booking.ID)
+		if err := uc.CancelBooking(ctx, booking.ID); err != nil || booking.IsReturnLeg() {
+			continue
+		}
+		_ = uc.holds.RecordExpiry(ctx, booking)
 	}
 
 	return nil
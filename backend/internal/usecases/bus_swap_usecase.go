@@ -0,0 +1,521 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/infrastructure"
+	"github.com/yourusername/bus-booking/internal/repositories"
+	"github.com/yourusername/bus-booking/internal/repositories/cache"
+)
+
+// BusSwapUsecase replaces the bus of a trip, carrying its passengers over to the new bus
+type BusSwapUsecase struct {
+	tx           repositories.Transactor
+	outboxRepo   repositories.OutboxRepository
+	tripRepo     repositories.TripRepository
+	busRepo      repositories.BusRepository
+	seatRepo     repositories.SeatRepository
+	bookingRepo  repositories.BookingRepository
+	ticketRepo   repositories.TicketRepository
+	busSwapRepo  repositories.BusSwapRepository
+	driverRepo   repositories.DriverRepository
+	cache        *cache.RedisCache
+	pdfGenerator *infrastructure.PDFGenerator
+	emailService *infrastructure.EmailService
+	conflicts    *ScheduleConflictUsecase
+}
+
+func NewBusSwapUsecase(
+	tx repositories.Transactor,
+	outboxRepo repositories.OutboxRepository,
+	tripRepo repositories.TripRepository,
+	busRepo repositories.BusRepository,
+	seatRepo repositories.SeatRepository,
+	bookingRepo repositories.BookingRepository,
+	ticketRepo repositories.TicketRepository,
+	busSwapRepo repositories.BusSwapRepository,
+	driverRepo repositories.DriverRepository,
+	cache *cache.RedisCache,
+	pdfGenerator *infrastructure.PDFGenerator,
+	emailService *infrastructure.EmailService,
+	conflicts *ScheduleConflictUsecase,
+) *BusSwapUsecase {
+	return &BusSwapUsecase{
+		tx:           tx,
+		outboxRepo:   outboxRepo,
+		tripRepo:     tripRepo,
+		busRepo:      busRepo,
+		seatRepo:     seatRepo,
+		bookingRepo:  bookingRepo,
+		ticketRepo:   ticketRepo,
+		busSwapRepo:  busSwapRepo,
+		driverRepo:   driverRepo,
+		cache:        cache,
+		pdfGenerator: pdfGenerator,
+		emailService: emailService,
+		conflicts:    conflicts,
+	}
+}
+
+// ReplaceBusInput describes a bus swap
+type ReplaceBusInput struct {
+	TripID    uuid.UUID
+	NewBusID  uuid.UUID
+	Reason    string
+	SwappedBy *uuid.UUID
+}
+
+// ReplaceBus moves a trip onto another bus. The trip's seats are rebuilt from
+// the new bus's layout and every live booking is carried over seat by seat:
+// to the seat with the same number if the new bus has it, otherwise to the
+// nearest free seat by position. Seats that cannot be carried over are
+// recorded as unseated, with their tickets flagged for staff to assign a seat.
+// Affected customers are emailed.
+func (uc *BusSwapUsecase) ReplaceBus(ctx context.Context, input ReplaceBusInput) (*entities.BusSwap, error) {
+	trip, err := uc.tripRepo.GetByID(ctx, input.TripID)
+	if err != nil {
+		return nil, fmt.Errorf("trip not found: %w", err)
+	}
+	if trip.Status != entities.TripStatusScheduled && trip.Status != entities.TripStatusDelayed {
+		return nil, fmt.Errorf("cannot replace the bus of a %s trip", trip.Status)
+	}
+	if trip.BusID == input.NewBusID {
+		return nil, fmt.Errorf("trip already runs with this bus")
+	}
+
+	oldBus, err := uc.busRepo.GetByID(ctx, trip.BusID)
+	if err != nil {
+		return nil, fmt.Errorf("current bus not found: %w", err)
+	}
+	newBus, err := uc.busRepo.GetByID(ctx, input.NewBusID)
+	if err != nil {
+		return nil, fmt.Errorf("bus not found: %w", err)
+	}
+	if newBus.Status != entities.BusStatusActive {
+		return nil, fmt.Errorf("bus is not active")
+	}
+	moved := *trip
+	moved.BusID = newBus.ID
+	moved.Bus = newBus
+	if err := assignDrivers(ctx, uc.driverRepo, &moved, newBus); err != nil {
+		return nil, err
+	}
+	if err := uc.conflicts.CheckTrip(ctx, &moved); err != nil {
+		return nil, err
+	}
+
+	swap := &entities.BusSwap{
+		TripID:    trip.ID,
+		OldBusID:  oldBus.ID,
+		NewBusID:  newBus.ID,
+		Reason:    input.Reason,
+		SwappedBy: input.SwappedBy,
+		Moves:     entities.SeatMoves{},
+	}
+
+	var carried []*entities.Booking
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		// No booking can be made on the trip while its seats are rebuilt
+		locked, err := uc.tripRepo.GetByIDForUpdate(ctx, trip.ID)
+		if err != nil {
+			return fmt.Errorf("trip not found: %w", err)
+		}
+		if locked.BusID != trip.BusID || locked.Status != trip.Status {
+			return fmt.Errorf("trip was changed meanwhile, try again")
+		}
+
+		bookings, err := uc.bookingRepo.GetTripBookingsByStatus(ctx, trip.ID, []entities.BookingStatus{
+			entities.BookingStatusPending,
+			entities.BookingStatusPaid,
+			entities.BookingStatusConfirmed,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load bookings: %w", err)
+		}
+
+		var live []*entities.Booking
+		var occupied []string
+		// Bookings on separate segments of the trip can share a seat, which
+		// then moves once for all of them
+		seen := make(map[string]bool)
+		for _, booking := range bookings {
+			if booking.IsExpired() {
+				continue
+			}
+			live = append(live, booking)
+			for _, seatNumber := range booking.Seats {
+				if !seen[seatNumber] {
+					seen[seatNumber] = true
+					occupied = append(occupied, seatNumber)
+				}
+			}
+		}
+		segments, err := uc.seatRepo.CountSegments(ctx, trip.ID)
+		if err != nil {
+			return fmt.Errorf("failed to count segments: %w", err)
+		}
+
+		newSeats := generateSeatNumbers(newBus.SeatLayout)
+		onNewBus := make(map[string]bool, len(newSeats))
+		for _, seatNumber := range newSeats {
+			onNewBus[seatNumber] = true
+		}
+
+		// Seats blocked for this trip stay blocked where the new bus has them;
+		// the old bus's own blocks give way to the new bus's
+		seats, err := uc.seatRepo.GetAllByTrip(ctx, trip.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load seats: %w", err)
+		}
+		oldDefaults := make(map[string]bool)
+		for _, block := range oldBus.BlockedSeats {
+			oldDefaults[block.SeatNumber+"|"+block.Reason] = true
+		}
+		blocked := make(map[string]bool)
+		for _, block := range newBus.BlockedSeats {
+			blocked[block.SeatNumber] = true
+		}
+		var tripBlocks []*entities.SeatInfo
+		for _, seat := range seats {
+			if seat.Segment != 0 || seat.Status != entities.SeatStatusBlocked || !onNewBus[seat.SeatNumber] || blocked[seat.SeatNumber] {
+				continue
+			}
+			if seat.BlockedBy == nil && oldDefaults[seat.SeatNumber+"|"+seat.BlockReason] {
+				continue
+			}
+			blocked[seat.SeatNumber] = true
+			tripBlocks = append(tripBlocks, seat)
+		}
+
+		free := make([]string, 0, len(newSeats))
+		for _, seatNumber := range newSeats {
+			if !blocked[seatNumber] {
+				free = append(free, seatNumber)
+			}
+		}
+		mapping := mapSeats(oldBus.SeatLayout, newBus.SeatLayout, occupied, free)
+
+		// Rebuild the trip's seats for the new bus
+		if err := uc.seatRepo.DeleteByTrip(ctx, trip.ID); err != nil {
+			return fmt.Errorf("failed to remove old seats: %w", err)
+		}
+		if err := uc.seatRepo.InitializeSeatsForTrip(ctx, trip.ID, newSeats, segments); err != nil {
+			return fmt.Errorf("failed to initialize seats: %w", err)
+		}
+		if err := blockBusDefaultSeats(ctx, uc.seatRepo, trip.ID, newBus); err != nil {
+			return fmt.Errorf("failed to block seats: %w", err)
+		}
+		if err := reblockSeats(ctx, uc.seatRepo, trip.ID, tripBlocks); err != nil {
+			return fmt.Errorf("failed to block seats: %w", err)
+		}
+
+		for _, booking := range live {
+			if err := uc.moveBooking(ctx, booking, mapping, swap); err != nil {
+				return fmt.Errorf("failed to move booking %s: %w", booking.BookingCode, err)
+			}
+		}
+		carried = live
+
+		trip.BusID = newBus.ID
+		if err := uc.tripRepo.Update(ctx, trip); err != nil {
+			return fmt.Errorf("failed to update trip: %w", err)
+		}
+		if err := uc.busSwapRepo.Create(ctx, swap); err != nil {
+			return fmt.Errorf("failed to record bus swap: %w", err)
+		}
+		return recordEvent(ctx, uc.outboxRepo, entities.AggregateTrip, trip.ID, entities.EventTripBusReplaced, entities.BusSwapEventPayload{
+			TripID:   trip.ID,
+			OldBusID: oldBus.ID,
+			NewBusID: newBus.ID,
+			Moves:    swap.Moves,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.moveCacheLocks(ctx, trip.ID, carried, swap.Moves)
+	_ = uc.cache.InvalidateTripSeats(ctx, trip.ID)
+	uc.notify(ctx, trip.ID, carried, swap.Moves)
+
+	return swap, nil
+}
+
+// ListSwaps returns the bus swaps of a trip, newest first
+func (uc *BusSwapUsecase) ListSwaps(ctx context.Context, tripID uuid.UUID) ([]*entities.BusSwap, error) {
+	return uc.busSwapRepo.ListByTrip(ctx, tripID)
+}
+
+// moveBooking puts a booking on its mapped seats of the new bus, recording
+// the moves in the swap. Passengers left without a seat lose their old seat
+// number, which someone else may now sit in, and their tickets are flagged
+// for staff to assign a seat.
+func (uc *BusSwapUsecase) moveBooking(ctx context.Context, booking *entities.Booking, mapping map[string]string, swap *entities.BusSwap) error {
+	passengerBySeat := make(map[string]string)
+	for _, passenger := range booking.Passengers {
+		if passenger.SeatNumber != "" {
+			passengerBySeat[passenger.SeatNumber] = passenger.Name
+		}
+	}
+
+	renamed := make(map[string]string)
+	unseated := make(map[string]bool)
+	var seated []string
+	for i, oldSeat := range booking.Seats {
+		move := entities.SeatMove{
+			BookingID:     booking.ID,
+			BookingCode:   booking.BookingCode,
+			PassengerName: passengerBySeat[oldSeat],
+			OldSeat:       oldSeat,
+		}
+		newSeat, ok := mapping[oldSeat]
+		switch {
+		case !ok:
+			move.Method = entities.SeatMoveUnseated
+			unseated[oldSeat] = true
+			swap.Unseated++
+		case newSeat == oldSeat:
+			move.NewSeat = newSeat
+			move.Method = entities.SeatMoveByLabel
+			seated = append(seated, newSeat)
+		default:
+			move.NewSeat = newSeat
+			move.Method = entities.SeatMoveByPosition
+			booking.Seats[i] = newSeat
+			renamed[oldSeat] = newSeat
+			seated = append(seated, newSeat)
+		}
+		swap.Moves = append(swap.Moves, move)
+	}
+
+	if len(seated) > 0 {
+		var err error
+		if booking.Status == entities.BookingStatusConfirmed {
+			err = uc.seatRepo.MarkSeatsAsBooked(ctx, booking.TripID, seated, booking.StopRange(), booking.ID)
+		} else if booking.LockID != nil {
+			err = uc.seatRepo.LockSeats(ctx, booking.TripID, seated, booking.StopRange(), *booking.LockID, bookingLockDuration(booking))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(renamed) == 0 && len(unseated) == 0 {
+		return nil
+	}
+
+	booking.Seats = seated
+	for i := range booking.Passengers {
+		if newSeat, ok := renamed[booking.Passengers[i].SeatNumber]; ok {
+			booking.Passengers[i].SeatNumber = newSeat
+		} else if unseated[booking.Passengers[i].SeatNumber] {
+			booking.Passengers[i].SeatNumber = ""
+		}
+	}
+	if err := uc.bookingRepo.Update(ctx, booking); err != nil {
+		return err
+	}
+
+	tickets, err := uc.ticketRepo.GetByBookingID(ctx, booking.ID)
+	if err != nil {
+		return err
+	}
+	for _, ticket := range tickets {
+		if newSeat, ok := renamed[ticket.SeatNumber]; ok {
+			ticket.SeatNumber = newSeat
+		} else if unseated[ticket.SeatNumber] {
+			ticket.SeatNumber = ""
+			ticket.NeedsSeat = true
+		} else {
+			continue
+		}
+		ticket.PDFPath = "" // Regenerated with the new seat
+		if err := uc.ticketRepo.Update(ctx, ticket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveCacheLocks carries the Redis seat locks of unpaid bookings over to their
+// new seats. Failures are only logged: the seat reconciler restores missing locks.
+func (uc *BusSwapUsecase) moveCacheLocks(ctx context.Context, tripID uuid.UUID, bookings []*entities.Booking, moves entities.SeatMoves) {
+	for _, booking := range bookings {
+		if booking.Status == entities.BookingStatusConfirmed || booking.LockID == nil {
+			continue
+		}
+
+		var oldSeats, newSeats []string
+		for _, move := range moves {
+			if move.BookingID != booking.ID || move.OldSeat == move.NewSeat {
+				continue
+			}
+			oldSeats = append(oldSeats, move.OldSeat)
+			if move.NewSeat != "" {
+				newSeats = append(newSeats, move.NewSeat)
+			}
+		}
+
+		if _, err := uc.cache.UnlockSeats(ctx, tripID, oldSeats, booking.StopRange(), *booking.LockID); err != nil {
+			log.Printf("Failed to release old seat locks of booking %s: %v", booking.BookingCode, err)
+		}
+		if len(newSeats) == 0 {
+			continue
+		}
+		if err := uc.cache.LockSeats(ctx, tripID, newSeats, booking.StopRange(), *booking.LockID, bookingLockDuration(booking)); err != nil {
+			log.Printf("Failed to lock new seats of booking %s: %v", booking.BookingCode, err)
+		}
+	}
+}
+
+// notify emails the customers of the trip where they now sit, with regenerated e-tickets
+// for confirmed bookings. It runs after the swap is committed, so failures
+// are logged rather than returned.
+func (uc *BusSwapUsecase) notify(ctx context.Context, tripID uuid.UUID, bookings []*entities.Booking, moves entities.SeatMoves) {
+	if len(bookings) == 0 {
+		return
+	}
+
+	trip, err := uc.tripRepo.GetByIDWithDetails(ctx, tripID)
+	if err != nil {
+		log.Printf("Failed to load trip %s for bus swap notifications: %v", tripID, err)
+		return
+	}
+
+	for _, booking := range bookings {
+		var changes []string
+		for _, move := range moves {
+			if move.BookingID != booking.ID {
+				continue
+			}
+			who := move.PassengerName
+			if who == "" {
+				who = "Seat " + move.OldSeat
+			}
+			switch move.Method {
+			case entities.SeatMoveUnseated:
+				changes = append(changes, fmt.Sprintf("%s: seat %s is not available on the new bus, our staff will contact you", who, move.OldSeat))
+			case entities.SeatMoveByLabel:
+				changes = append(changes, fmt.Sprintf("%s: keeps seat %s", who, move.NewSeat))
+			default:
+				changes = append(changes, fmt.Sprintf("%s: moved from seat %s to %s", who, move.OldSeat, move.NewSeat))
+			}
+		}
+
+		var attachments []string
+		if booking.Status == entities.BookingStatusConfirmed {
+			attachments = regenerateTickets(ctx, uc.ticketRepo, uc.pdfGenerator, uc.conflicts.location, trip, booking)
+		}
+
+		if err := uc.emailService.SendSeatChange(booking.ContactEmail, booking.BookingCode, changes, attachments...); err != nil {
+			log.Printf("Failed to send bus change email for booking %s: %v", booking.BookingCode, err)
+		}
+	}
+}
+
+// regenerateTickets renders the booking's e-tickets that lost their PDF, with
+// the departure in the business timezone location, and returns the paths of
+// all of them
+func regenerateTickets(ctx context.Context, ticketRepo repositories.TicketRepository, pdfGenerator *infrastructure.PDFGenerator, location *time.Location, trip *entities.Trip, booking *entities.Booking) []string {
+	tickets, err := ticketRepo.GetByBookingID(ctx, booking.ID)
+	if err != nil || trip.Route == nil {
+		log.Printf("Failed to load tickets of booking %s: %v", booking.BookingCode, err)
+		return nil
+	}
+
+	departure := trip.DepartureTime.In(location).Format("02/01/2006 15:04")
+	pickup, dropoff := boardingPoints(trip, booking)
+	var paths []string
+	for _, ticket := range tickets {
+		if ticket.NeedsSeat {
+			continue // No valid ticket until staff assign a seat
+		}
+		if ticket.PDFPath == "" {
+			pdfPath, err := pdfGenerator.GenerateTicket(
+				ticket.TicketCode,
+				ticket.PassengerName,
+				string(ticket.FareCategory),
+				trip.Route.FromCity,
+				trip.Route.ToCity,
+				pickup,
+				dropoff,
+				ticket.SeatNumber,
+				departure,
+			)
+			if err != nil {
+				log.Printf("Failed to regenerate ticket %s: %v", ticket.TicketCode, err)
+				continue
+			}
+			ticket.PDFPath = pdfPath
+			if err := ticketRepo.Update(ctx, ticket); err != nil {
+				log.Printf("Failed to save ticket %s: %v", ticket.TicketCode, err)
+			}
+		}
+		paths = append(paths, ticket.PDFPath)
+	}
+	return paths
+}
+
+// bookingLockDuration is how long a booking's seats stay held: until the
+// booking expires, but at least a minute so a paid booking can still confirm
+func bookingLockDuration(booking *entities.Booking) time.Duration {
+	remaining := time.Minute
+	if booking.ExpiresAt != nil {
+		remaining = max(remaining, time.Until(*booking.ExpiresAt))
+	}
+	return remaining
+}
+
+// mapSeats assigns the occupied seats of the old layout to free seats of the
+// new one: first to the seat with the same number, then to the nearest free
+// seat by position. Occupied seats missing from the result could not be placed.
+func mapSeats(oldLayout, newLayout entities.SeatLayout, occupied, free []string) map[string]string {
+	mapping := make(map[string]string, len(occupied))
+	freeSet := make(map[string]bool, len(free))
+	for _, seatNumber := range free {
+		freeSet[seatNumber] = true
+	}
+
+	var remaining []string
+	for _, seatNumber := range occupied {
+		if freeSet[seatNumber] {
+			mapping[seatNumber] = seatNumber
+			delete(freeSet, seatNumber)
+		} else {
+			remaining = append(remaining, seatNumber)
+		}
+	}
+
+	oldPositions := oldLayout.Positions()
+	newPositions := newLayout.Positions()
+	sort.Slice(remaining, func(i, j int) bool { return seatNumberLess(remaining[i], remaining[j]) })
+	for _, seatNumber := range remaining {
+		from, ok := oldPositions[seatNumber]
+		if !ok {
+			continue
+		}
+
+		best, bestDistance := "", 0.0
+		for candidate := range freeSet {
+			to, ok := newPositions[candidate]
+			if !ok {
+				continue
+			}
+			distance := seatDistance(from, to)
+			if best == "" || distance < bestDistance || (distance == bestDistance && seatNumberLess(candidate, best)) {
+				best, bestDistance = candidate, distance
+			}
+		}
+		if best != "" {
+			mapping[seatNumber] = best
+			delete(freeSet, best)
+		}
+	}
+	return mapping
+}
@@ -28,6 +28,12 @@ func (uc *BusUsecase) CreateBus(ctx context.Context, bus *entities.Bus) error {
 	if err := validateSeatLayout(&bus.SeatLayout); err != nil {
 		return fmt.Errorf("invalid seat layout: %w", err)
 	}
+	if err := validateBlockedSeats(bus.SeatLayout, bus.BlockedSeats); err != nil {
+		return err
+	}
+	if err := validateBusRating(bus.Rating); err != nil {
+		return err
+	}
 
 	return uc.busRepo.Create(ctx, bus)
 }
@@ -47,10 +53,34 @@ func (uc *BusUsecase) UpdateBus(ctx context.Context, bus *entities.Bus) error {
 	if err := validateSeatLayout(&bus.SeatLayout); err != nil {
 		return fmt.Errorf("invalid seat layout: %w", err)
 	}
+	if err := validateBlockedSeats(bus.SeatLayout, bus.BlockedSeats); err != nil {
+		return err
+	}
+	if err := validateBusRating(bus.Rating); err != nil {
+		return err
+	}
 
 	return uc.busRepo.Update(ctx, bus)
 }
 
+// SetBlockedSeats replaces the seats blocked by default on new trips of a bus;
+// trips that already exist keep their seats as they are
+func (uc *BusUsecase) SetBlockedSeats(ctx context.Context, id uuid.UUID, blocks entities.SeatBlocks) (*entities.Bus, error) {
+	bus, err := uc.busRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("bus not found: %w", err)
+	}
+	if err := validateBlockedSeats(bus.SeatLayout, blocks); err != nil {
+		return nil, err
+	}
+
+	bus.BlockedSeats = blocks
+	if err := uc.busRepo.Update(ctx, bus); err != nil {
+		return nil, err
+	}
+	return bus, nil
+}
+
 func (uc *BusUsecase) DeleteBus(ctx context.Context, id uuid.UUID) error {
 	return uc.busRepo.Delete(ctx, id)
 }
@@ -75,3 +105,33 @@ func validateSeatLayout(layout *entities.SeatLayout) error {
 	}
 	return nil
 }
+
+func validateBlockedSeats(layout entities.SeatLayout, blocks entities.SeatBlocks) error {
+	seats := make(map[string]bool)
+	for _, seat := range generateSeatNumbers(layout) {
+		seats[seat] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, block := range blocks {
+		if !seats[block.SeatNumber] {
+			return fmt.Errorf("blocked seat %s is not in the seat layout", block.SeatNumber)
+		}
+		if seen[block.SeatNumber] {
+			return fmt.Errorf("seat %s is blocked more than once", block.SeatNumber)
+		}
+		if block.Reason == "" {
+			return fmt.Errorf("blocked seat %s needs a reason", block.SeatNumber)
+		}
+		seen[block.SeatNumber] = true
+	}
+	return nil
+}
+
+// validateBusRating checks a rating, when set, is on the 1 to 5 scale
+func validateBusRating(rating *float64) error {
+	if rating != nil && (*rating < 1 || *rating > 5) {
+		return fmt.Errorf("rating must be between 1 and 5")
+	}
+	return nil
+}
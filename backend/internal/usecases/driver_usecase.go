@@ -0,0 +1,193 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+)
+
+// DriverUsecase manages the drivers of operators and their timetables
+type DriverUsecase struct {
+	driverRepo repositories.DriverRepository
+	tripRepo   repositories.TripRepository
+}
+
+func NewDriverUsecase(driverRepo repositories.DriverRepository, tripRepo repositories.TripRepository) *DriverUsecase {
+	return &DriverUsecase{
+		driverRepo: driverRepo,
+		tripRepo:   tripRepo,
+	}
+}
+
+// DriverTimetableEntry is a trip in a driver's timetable
+type DriverTimetableEntry struct {
+	Trip        *entities.Trip      `json:"trip"`
+	Role        entities.DriverRole `json:"role"`
+	DrivingTime int                 `json:"driving_time"` // Minutes at the wheel
+}
+
+// DriverTimetable lists a driver's trips in a period
+type DriverTimetable struct {
+	Driver           *entities.Driver       `json:"driver"`
+	From             time.Time              `json:"from"`
+	To               time.Time              `json:"to"`
+	Trips            []DriverTimetableEntry `json:"trips"`
+	TotalDrivingTime int                    `json:"total_driving_time"` // Minutes at the wheel
+}
+
+func (uc *DriverUsecase) CreateDriver(ctx context.Context, driver *entities.Driver) error {
+	if err := validateDriver(driver); err != nil {
+		return err
+	}
+	existing, err := uc.driverRepo.GetByLicenceNumber(ctx, driver.LicenceNumber)
+	if err == nil && existing != nil {
+		return fmt.Errorf("driver with licence number %s already exists", driver.LicenceNumber)
+	}
+
+	driver.Status = entities.DriverStatusActive
+	return uc.driverRepo.Create(ctx, driver)
+}
+
+func (uc *DriverUsecase) GetDriver(ctx context.Context, id uuid.UUID) (*entities.Driver, error) {
+	return uc.driverRepo.GetByID(ctx, id)
+}
+
+func (uc *DriverUsecase) ListDrivers(ctx context.Context, operatorName string, status entities.DriverStatus, page, limit int) ([]*entities.Driver, error) {
+	offset := (page - 1) * limit
+	return uc.driverRepo.List(ctx, operatorName, status, limit, offset)
+}
+
+// UpdateDriver changes a driver's details. Trips the driver is already on are
+// not checked again; the schedule conflicts report shows any that no longer fit.
+func (uc *DriverUsecase) UpdateDriver(ctx context.Context, driver *entities.Driver) error {
+	existing, err := uc.driverRepo.GetByID(ctx, driver.ID)
+	if err != nil {
+		return fmt.Errorf("driver not found: %w", err)
+	}
+	if err := validateDriver(driver); err != nil {
+		return err
+	}
+	if driver.LicenceNumber != existing.LicenceNumber {
+		other, err := uc.driverRepo.GetByLicenceNumber(ctx, driver.LicenceNumber)
+		if err == nil && other != nil {
+			return fmt.Errorf("driver with licence number %s already exists", driver.LicenceNumber)
+		}
+	}
+	if driver.Status == "" {
+		driver.Status = existing.Status
+	}
+	if driver.Status != entities.DriverStatusActive && driver.Status != entities.DriverStatusInactive {
+		return fmt.Errorf("invalid driver status: %s", driver.Status)
+	}
+
+	driver.CreatedAt = existing.CreatedAt
+	return uc.driverRepo.Update(ctx, driver)
+}
+
+// DeactivateDriver stops a driver from being given new trips; trips the
+// driver is already on keep them
+func (uc *DriverUsecase) DeactivateDriver(ctx context.Context, id uuid.UUID) (*entities.Driver, error) {
+	driver, err := uc.driverRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("driver not found: %w", err)
+	}
+
+	driver.Status = entities.DriverStatusInactive
+	if err := uc.driverRepo.Update(ctx, driver); err != nil {
+		return nil, err
+	}
+	return driver, nil
+}
+
+// GetTimetable returns the trips a driver is on between from and to, with the
+// time at the wheel on each
+func (uc *DriverUsecase) GetTimetable(ctx context.Context, driverID uuid.UUID, from, to time.Time) (*DriverTimetable, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("to must be after from")
+	}
+	driver, err := uc.driverRepo.GetByID(ctx, driverID)
+	if err != nil {
+		return nil, fmt.Errorf("driver not found: %w", err)
+	}
+
+	trips, err := uc.tripRepo.GetDriverTrips(ctx, driverID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trips: %w", err)
+	}
+
+	timetable := &DriverTimetable{Driver: driver, From: from, To: to, Trips: []DriverTimetableEntry{}}
+	for _, trip := range trips {
+		minutes := int(trip.DrivingTime().Minutes())
+		timetable.Trips = append(timetable.Trips, DriverTimetableEntry{
+			Trip:        trip,
+			Role:        trip.DriverRole(driverID),
+			DrivingTime: minutes,
+		})
+		timetable.TotalDrivingTime += minutes
+	}
+	return timetable, nil
+}
+
+func validateDriver(driver *entities.Driver) error {
+	driver.FullName = strings.TrimSpace(driver.FullName)
+	driver.OperatorName = strings.TrimSpace(driver.OperatorName)
+	driver.LicenceNumber = strings.TrimSpace(driver.LicenceNumber)
+
+	if driver.FullName == "" {
+		return fmt.Errorf("full name is required")
+	}
+	if driver.OperatorName == "" {
+		return fmt.Errorf("operator name is required")
+	}
+	if driver.Phone == "" {
+		return fmt.Errorf("phone is required")
+	}
+	if driver.LicenceNumber == "" {
+		return fmt.Errorf("licence number is required")
+	}
+	if !driver.LicenceClass.IsValid() {
+		return fmt.Errorf("licence class must be %s or %s to drive buses", entities.LicenceClassD, entities.LicenceClassE)
+	}
+	if driver.LicenceExpiry.IsZero() {
+		return fmt.Errorf("licence expiry is required")
+	}
+	return nil
+}
+
+// assignDrivers checks the drivers set on a trip can be given it and copies
+// the main driver's name and phone onto the trip. Clashes with their other
+// trips and driving limits are left to ScheduleConflictUsecase.
+func assignDrivers(ctx context.Context, driverRepo repositories.DriverRepository, trip *entities.Trip, bus *entities.Bus) error {
+	if trip.DriverID == nil {
+		if trip.CoDriverID != nil {
+			return fmt.Errorf("a co-driver needs a main driver")
+		}
+		trip.DriverName, trip.DriverPhone = "", ""
+		return nil
+	}
+	if trip.CoDriverID != nil && *trip.CoDriverID == *trip.DriverID {
+		return fmt.Errorf("the co-driver must be another driver than the main driver")
+	}
+
+	for _, driverID := range tripDriverIDs(trip) {
+		driver, err := driverRepo.GetByID(ctx, driverID)
+		if err != nil {
+			return fmt.Errorf("driver %s not found: %w", driverID, err)
+		}
+		if driver.Status != entities.DriverStatusActive {
+			return fmt.Errorf("driver %s is not active", driver.FullName)
+		}
+		if bus.OperatorName != "" && !strings.EqualFold(driver.OperatorName, bus.OperatorName) {
+			return fmt.Errorf("driver %s works for %s, not for %s", driver.FullName, driver.OperatorName, bus.OperatorName)
+		}
+		if driverID == *trip.DriverID {
+			trip.DriverName, trip.DriverPhone = driver.FullName, driver.Phone
+		}
+	}
+	return nil
+}
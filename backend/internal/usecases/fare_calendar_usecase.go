@@ -0,0 +1,179 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+	"github.com/yourusername/bus-booking/internal/repositories/cache"
+)
+
+// ErrInvalidFareCalendar is returned for fare calendar requests with a malformed
+// window or cities that match no location
+var ErrInvalidFareCalendar = errors.New("invalid fare calendar request")
+
+// maxFareCalendarDays bounds the window of one fare calendar, a month at most
+const maxFareCalendarDays = 31
+
+// fareCalendarTTL bounds how long a cached day can miss a new or rescheduled
+// trip or a fare rule change; seat changes drop the days of their trip at once
+const fareCalendarTTL = 10 * time.Minute
+
+// FareCalendarUsecase sums up the departures and lowest fares between two
+// cities day by day, for customers flexible on when they travel
+type FareCalendarUsecase struct {
+	tripRepo  repositories.TripRepository
+	locations *LocationUsecase
+	cache     *cache.RedisCache
+	location  *time.Location // Business timezone the days are taken in
+}
+
+func NewFareCalendarUsecase(
+	tripRepo repositories.TripRepository,
+	locations *LocationUsecase,
+	cache *cache.RedisCache,
+	location *time.Location,
+) *FareCalendarUsecase {
+	return &FareCalendarUsecase{
+		tripRepo:  tripRepo,
+		locations: locations,
+		cache:     cache,
+		location:  location,
+	}
+}
+
+// FareCalendar is the fare calendar between two locations
+type FareCalendar struct {
+	From *entities.Location          `json:"from"` // Locations the cities were taken to mean
+	To   *entities.Location          `json:"to"`
+	Days []*entities.FareCalendarDay `json:"days"`
+}
+
+// GetFareCalendar returns a day for every date from start to end, both
+// included, with the departures between the locations the cities resolve to
+// and the lowest adult fare. Days already gone are left out. Days come from
+// the cache where they can and the rest are counted in one search over the
+// trips and their seats.
+func (uc *FareCalendarUsecase) GetFareCalendar(ctx context.Context, fromCity, toCity string, start, end time.Time) (*FareCalendar, error) {
+	first, _ := dayRange(start, uc.location)
+	last, _ := dayRange(end, uc.location)
+	if last.Before(first) {
+		return nil, fmt.Errorf("%w: end date must not be before start date", ErrInvalidFareCalendar)
+	}
+	if first.AddDate(0, 0, maxFareCalendarDays).Before(last.AddDate(0, 0, 1)) {
+		return nil, fmt.Errorf("%w: window must be at most %d days", ErrInvalidFareCalendar, maxFareCalendarDays)
+	}
+	if today, _ := dayRange(time.Now().In(uc.location), uc.location); first.Before(today) {
+		first = today
+	}
+
+	calendar := &FareCalendar{Days: []*entities.FareCalendarDay{}}
+	var err error
+	for _, side := range []struct {
+		location **entities.Location
+		city     string
+	}{{&calendar.From, fromCity}, {&calendar.To, toCity}} {
+		*side.location, err = uc.locations.ResolveLocation(ctx, side.city)
+		if errors.Is(err, ErrLocationNotFound) {
+			return nil, fmt.Errorf("%w: no city matches %q", ErrInvalidFareCalendar, side.city)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var dates []string
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		dates = append(dates, day.Format("2006-01-02"))
+	}
+	if len(dates) == 0 {
+		return calendar, nil
+	}
+
+	cached, err := uc.cache.GetFareCalendarDays(ctx, calendar.From.ID, calendar.To.ID, dates)
+	if err != nil {
+		cached = map[string]*entities.FareCalendarDay{}
+	}
+
+	var missing []string
+	for _, date := range dates {
+		if cached[date] == nil {
+			missing = append(missing, date)
+		}
+	}
+	if len(missing) > 0 {
+		counted, tripIDs, err := uc.countDays(ctx, calendar.From.ID, calendar.To.ID, missing)
+		if err != nil {
+			return nil, err
+		}
+		_ = uc.cache.CacheFareCalendarDays(ctx, calendar.From.ID, calendar.To.ID, counted, tripIDs, fareCalendarTTL)
+		for _, day := range counted {
+			cached[day.Date] = day
+		}
+	}
+
+	for _, date := range dates {
+		calendar.Days = append(calendar.Days, cached[date])
+	}
+	return calendar, nil
+}
+
+// countDays counts the departures and lowest fares of the dates, which come in
+// order, with the trips counted on each
+func (uc *FareCalendarUsecase) countDays(ctx context.Context, fromLocationID, toLocationID uuid.UUID, dates []string) ([]*entities.FareCalendarDay, map[string][]uuid.UUID, error) {
+	days := make(map[string]*entities.FareCalendarDay, len(dates))
+	counted := make([]*entities.FareCalendarDay, len(dates))
+	for i, date := range dates {
+		counted[i] = &entities.FareCalendarDay{Date: date}
+		days[date] = counted[i]
+	}
+
+	// The search spans the first to the last missing day; trips on days in
+	// between that were cached are counted but not used
+	first, err := time.ParseInLocation("2006-01-02", dates[0], uc.location)
+	if err != nil {
+		return nil, nil, err
+	}
+	last, err := time.ParseInLocation("2006-01-02", dates[len(dates)-1], uc.location)
+	if err != nil {
+		return nil, nil, err
+	}
+	from, _ := dayRange(first, uc.location)
+	_, to := dayRange(last, uc.location)
+
+	trips, err := uc.tripRepo.Search(ctx, &entities.TripSearch{
+		FromLocationID: fromLocationID,
+		ToLocationID:   toLocationID,
+		From:           from,
+		To:             to,
+		DepartFrom:     from,
+		DepartTo:       to,
+		Sort:           entities.TripSortDeparture,
+	}, 0, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search trips: %w", err)
+	}
+
+	tripIDs := make(map[string][]uuid.UUID, len(dates))
+	for _, trip := range trips {
+		date := trip.DepartureTime.In(uc.location).Format("2006-01-02")
+		day := days[date]
+		if day == nil {
+			continue
+		}
+		tripIDs[date] = append(tripIDs[date], trip.ID)
+		day.Departures++
+		if trip.AvailableSeats == 0 {
+			continue
+		}
+		day.Available++
+		if fare := trip.LowestPrice; day.LowestPrice == nil || fare < *day.LowestPrice {
+			day.LowestPrice = &fare
+		}
+	}
+	return counted, tripIDs, nil
+}
@@ -0,0 +1,240 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+)
+
+// FareUsecase manages fare category rules and prices passengers with them
+type FareUsecase struct {
+	fareRuleRepo repositories.FareRuleRepository
+	tripRepo     repositories.TripRepository
+}
+
+func NewFareUsecase(fareRuleRepo repositories.FareRuleRepository, tripRepo repositories.TripRepository) *FareUsecase {
+	return &FareUsecase{
+		fareRuleRepo: fareRuleRepo,
+		tripRepo:     tripRepo,
+	}
+}
+
+// PassengerInput is a passenger as entered at booking time
+type PassengerInput struct {
+	Name     string
+	Phone    string
+	Email    string
+	IDCard   string
+	Age      *int
+	Category entities.FareCategory
+}
+
+// TripFare is the price of one fare category on a trip
+type TripFare struct {
+	Category     entities.FareCategory `json:"category"`
+	Fare         float64               `json:"fare"`
+	MinAge       *int                  `json:"min_age,omitempty"`
+	MaxAge       *int                  `json:"max_age,omitempty"`
+	RequiresID   bool                  `json:"requires_id"`
+	RequiresSeat bool                  `json:"requires_seat"`
+}
+
+func (uc *FareUsecase) CreateRule(ctx context.Context, rule *entities.FareRule) error {
+	if err := validateFareRule(rule); err != nil {
+		return err
+	}
+	return uc.fareRuleRepo.Create(ctx, rule)
+}
+
+func (uc *FareUsecase) GetRuleByID(ctx context.Context, id uuid.UUID) (*entities.FareRule, error) {
+	return uc.fareRuleRepo.GetByID(ctx, id)
+}
+
+func (uc *FareUsecase) UpdateRule(ctx context.Context, rule *entities.FareRule) error {
+	// Validate rule exists
+	_, err := uc.fareRuleRepo.GetByID(ctx, rule.ID)
+	if err != nil {
+		return fmt.Errorf("fare rule not found: %w", err)
+	}
+
+	if err := validateFareRule(rule); err != nil {
+		return err
+	}
+	return uc.fareRuleRepo.Update(ctx, rule)
+}
+
+func (uc *FareUsecase) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	return uc.fareRuleRepo.Delete(ctx, id)
+}
+
+func (uc *FareUsecase) ListRules(ctx context.Context, category entities.FareCategory, page, limit int) ([]*entities.FareRule, error) {
+	offset := (page - 1) * limit
+	return uc.fareRuleRepo.List(ctx, category, limit, offset)
+}
+
+// GetTripFares lists the fare of every category offered on a trip from the
+// pickup stop to the drop-off stop, where nil means the origin or destination
+func (uc *FareUsecase) GetTripFares(ctx context.Context, tripID uuid.UUID, pickupStopID, dropoffStopID *uuid.UUID) ([]*TripFare, error) {
+	trip, err := uc.tripRepo.GetByIDWithDetails(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("trip not found: %w", err)
+	}
+	if err := validateBoardingStops(trip.Route, pickupStopID, dropoffStopID); err != nil {
+		return nil, err
+	}
+	price := trip.PriceFor(trip.Route.StopRange(pickupStopID, dropoffStopID))
+
+	rules, err := uc.rulesForTrip(ctx, trip)
+	if err != nil {
+		return nil, err
+	}
+
+	fares := make([]*TripFare, 0, len(rules))
+	for category, rule := range rules {
+		fares = append(fares, &TripFare{
+			Category:     category,
+			Fare:         rule.Fare(price),
+			MinAge:       rule.MinAge,
+			MaxAge:       rule.MaxAge,
+			RequiresID:   rule.RequiresID,
+			RequiresSeat: rule.RequiresSeat,
+		})
+	}
+
+	sort.Slice(fares, func(i, j int) bool { return fares[i].Fare > fares[j].Fare })
+	return fares, nil
+}
+
+// PricePassengers checks every passenger against the rules of their fare
+// category on the trip, gives each passenger who needs a seat the next of the
+// selected seats, and returns the priced passengers with their total for
+// travelling the stop range
+func (uc *FareUsecase) PricePassengers(ctx context.Context, trip *entities.Trip, stops entities.StopRange, passengers []PassengerInput, seatNumbers []string) (entities.Passengers, float64, error) {
+	rules, err := uc.rulesForTrip(ctx, trip)
+	if err != nil {
+		return nil, 0, err
+	}
+	price := trip.PriceFor(stops)
+
+	priced := make(entities.Passengers, 0, len(passengers))
+	seated, lap := 0, 0
+	total := 0.0
+	for _, p := range passengers {
+		category := p.Category
+		if category == "" {
+			category = entities.FareCategoryAdult
+		}
+
+		rule, ok := rules[category]
+		if !ok {
+			return nil, 0, fmt.Errorf("fare category %s is not offered on this trip", category)
+		}
+
+		if rule.MinAge != nil || rule.MaxAge != nil {
+			if p.Age == nil {
+				return nil, 0, fmt.Errorf("age is required for %s fare of %s", category, p.Name)
+			}
+			if !rule.AcceptsAge(*p.Age) {
+				return nil, 0, fmt.Errorf("%s does not meet the age requirement of the %s fare", p.Name, category)
+			}
+		}
+		if rule.RequiresID && p.IDCard == "" {
+			return nil, 0, fmt.Errorf("an ID number is required for %s fare of %s", category, p.Name)
+		}
+
+		info := entities.PassengerInfo{
+			Name:     p.Name,
+			Phone:    p.Phone,
+			Email:    p.Email,
+			IDCard:   p.IDCard,
+			Age:      p.Age,
+			Category: category,
+			Fare:     rule.Fare(price),
+		}
+		if rule.RequiresSeat {
+			if seated < len(seatNumbers) {
+				info.SeatNumber = seatNumbers[seated]
+			}
+			seated++
+		} else {
+			lap++
+		}
+
+		priced = append(priced, info)
+		total += info.Fare
+	}
+
+	if seated != len(seatNumbers) {
+		return nil, 0, fmt.Errorf("%d seats selected for %d passengers who need a seat", len(seatNumbers), seated)
+	}
+	if lap > seated {
+		// Every lap passenger rides with a seated passenger
+		return nil, 0, fmt.Errorf("each passenger without a seat must travel with a seated passenger")
+	}
+
+	return priced, total, nil
+}
+
+// rulesForTrip picks, per category, the most specific active rule for the
+// trip's route and operator. Adults pay the full trip price unless a rule says otherwise.
+func (uc *FareUsecase) rulesForTrip(ctx context.Context, trip *entities.Trip) (map[entities.FareCategory]*entities.FareRule, error) {
+	operatorName := ""
+	if trip.Bus != nil {
+		operatorName = trip.Bus.OperatorName
+	}
+
+	rules, err := uc.fareRuleRepo.GetApplicable(ctx, trip.RouteID, operatorName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fare rules: %w", err)
+	}
+
+	byCategory := map[entities.FareCategory]*entities.FareRule{
+		entities.FareCategoryAdult: {
+			Category:     entities.FareCategoryAdult,
+			DiscountType: entities.FareDiscountPercentage,
+			RequiresSeat: true,
+		},
+	}
+	specificity := map[entities.FareCategory]int{entities.FareCategoryAdult: -1}
+	for _, rule := range rules {
+		if current, ok := specificity[rule.Category]; ok && current >= rule.Specificity() {
+			continue
+		}
+		byCategory[rule.Category] = rule
+		specificity[rule.Category] = rule.Specificity()
+	}
+
+	return byCategory, nil
+}
+
+func validateFareRule(rule *entities.FareRule) error {
+	if !rule.Category.IsValid() {
+		return fmt.Errorf("invalid fare category: %s", rule.Category)
+	}
+
+	switch rule.DiscountType {
+	case entities.FareDiscountPercentage:
+		if rule.DiscountValue < 0 || rule.DiscountValue > 100 {
+			return fmt.Errorf("percentage discount must be between 0 and 100")
+		}
+	case entities.FareDiscountFixed:
+		if rule.DiscountValue < 0 {
+			return fmt.Errorf("fixed discount must not be negative")
+		}
+	default:
+		return fmt.Errorf("invalid discount type: %s", rule.DiscountType)
+	}
+
+	if rule.MinAge != nil && rule.MaxAge != nil && *rule.MinAge > *rule.MaxAge {
+		return fmt.Errorf("min_age must not exceed max_age")
+	}
+	if rule.Category == entities.FareCategoryAdult && !rule.RequiresSeat {
+		return fmt.Errorf("adult fares always need a seat")
+	}
+
+	return nil
+}
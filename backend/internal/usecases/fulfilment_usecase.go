@@ -0,0 +1,394 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/infrastructure"
+	"github.com/yourusername/bus-booking/internal/infrastructure/payment"
+	"github.com/yourusername/bus-booking/internal/repositories"
+)
+
+const (
+	// fulfilmentLease is how long a worker owns a fulfilment before another may retry it
+	fulfilmentLease       = 5 * time.Minute
+	fulfilmentBatchSize   = 20
+	fulfilmentMaxAttempts = 8
+	fulfilmentRetryBase   = 30 * time.Second
+	fulfilmentRetryMax    = 30 * time.Minute
+)
+
+// FulfilmentUsecase runs the post-payment saga: confirm the booking (which marks
+// the seats booked and creates tickets), render ticket PDFs and email them.
+// Progress is checkpointed after every step so retries resume where they stopped;
+// if the booking can no longer be confirmed the payment is refunded instead.
+type FulfilmentUsecase struct {
+	tx             repositories.Transactor
+	outboxRepo     repositories.OutboxRepository
+	fulfilmentRepo repositories.FulfilmentRepository
+	bookingRepo    repositories.BookingRepository
+	paymentRepo    repositories.PaymentRepository
+	seatRepo       repositories.SeatRepository
+	ticketRepo     repositories.TicketRepository
+	bookingUsecase *BookingUsecase
+	gateways       map[entities.PaymentGateway]payment.Gateway
+	pdfGenerator   *infrastructure.PDFGenerator
+	emailService   *infrastructure.EmailService
+}
+
+// NewFulfilmentUsecase creates a new fulfilment usecase
+func NewFulfilmentUsecase(
+	tx repositories.Transactor,
+	outboxRepo repositories.OutboxRepository,
+	fulfilmentRepo repositories.FulfilmentRepository,
+	bookingRepo repositories.BookingRepository,
+	paymentRepo repositories.PaymentRepository,
+	seatRepo repositories.SeatRepository,
+	ticketRepo repositories.TicketRepository,
+	bookingUsecase *BookingUsecase,
+	gateways map[entities.PaymentGateway]payment.Gateway,
+	pdfGenerator *infrastructure.PDFGenerator,
+	emailService *infrastructure.EmailService,
+) *FulfilmentUsecase {
+	return &FulfilmentUsecase{
+		tx:             tx,
+		outboxRepo:     outboxRepo,
+		fulfilmentRepo: fulfilmentRepo,
+		bookingRepo:    bookingRepo,
+		paymentRepo:    paymentRepo,
+		seatRepo:       seatRepo,
+		ticketRepo:     ticketRepo,
+		bookingUsecase: bookingUsecase,
+		gateways:       gateways,
+		pdfGenerator:   pdfGenerator,
+		emailService:   emailService,
+	}
+}
+
+// Start records a fulfilment for a completed payment and runs it in the background.
+// Calling it again for the same booking is a no-op.
+func (uc *FulfilmentUsecase) Start(ctx context.Context, pmt *entities.Payment) error {
+	nextAttempt := time.Now().Add(fulfilmentLease)
+	fulfilment := &entities.Fulfilment{
+		BookingID:     pmt.BookingID,
+		PaymentID:     pmt.ID,
+		Status:        entities.FulfilmentStatusPending,
+		Step:          entities.FulfilmentStepConfirm,
+		NextAttemptAt: &nextAttempt,
+	}
+
+	created, err := uc.fulfilmentRepo.CreateIfNotExists(ctx, fulfilment)
+	if err != nil {
+		return fmt.Errorf("failed to start fulfilment: %w", err)
+	}
+	if !created {
+		return nil
+	}
+
+	// The creator holds the lease; if this run dies the retry job picks it up
+	go func() {
+		runCtx, cancel := context.WithTimeout(context.Background(), fulfilmentLease)
+		defer cancel()
+		uc.process(runCtx, fulfilment)
+	}()
+
+	return nil
+}
+
+// ProcessDue is a background job that retries fulfilments whose next attempt is due
+func (uc *FulfilmentUsecase) ProcessDue(ctx context.Context) error {
+	fulfilments, err := uc.fulfilmentRepo.ClaimDue(ctx, fulfilmentLease, fulfilmentBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to claim fulfilments: %w", err)
+	}
+
+	for _, fulfilment := range fulfilments {
+		uc.process(ctx, fulfilment)
+	}
+
+	return nil
+}
+
+// process runs the remaining steps and records the outcome or schedules a retry
+func (uc *FulfilmentUsecase) process(ctx context.Context, fulfilment *entities.Fulfilment) {
+	err := uc.advance(ctx, fulfilment)
+
+	now := time.Now()
+	if err == nil {
+		if fulfilment.Status == entities.FulfilmentStatusPending {
+			fulfilment.Status = entities.FulfilmentStatusCompleted
+		}
+		fulfilment.LastError = nil
+		fulfilment.NextAttemptAt = nil
+		fulfilment.CompletedAt = &now
+	} else {
+		fulfilment.Attempts++
+		message := err.Error()
+		fulfilment.LastError = &message
+
+		if fulfilment.Attempts >= fulfilmentMaxAttempts {
+			fulfilment.Status = entities.FulfilmentStatusFailed
+			fulfilment.NextAttemptAt = nil
+			log.Printf("Fulfilment for booking %s failed permanently at step %s: %v", fulfilment.BookingID, fulfilment.Step, err)
+		} else {
+			nextAttempt := now.Add(fulfilmentRetryDelay(fulfilment.Attempts))
+			fulfilment.NextAttemptAt = &nextAttempt
+		}
+	}
+
+	if err := uc.fulfilmentRepo.Update(ctx, fulfilment); err != nil {
+		log.Printf("Failed to save fulfilment for booking %s: %v", fulfilment.BookingID, err)
+	}
+}
+
+// advance runs steps until the saga is done, checkpointing after each one
+func (uc *FulfilmentUsecase) advance(ctx context.Context, fulfilment *entities.Fulfilment) error {
+	for {
+		switch fulfilment.Step {
+		case entities.FulfilmentStepConfirm:
+			err := uc.bookingUsecase.ConfirmBooking(ctx, fulfilment.BookingID, fulfilment.PaymentID)
+			switch {
+			case errors.Is(err, ErrBookingNotConfirmable):
+				fulfilment.Step = entities.FulfilmentStepCompensate
+			case err != nil:
+				return err
+			default:
+				fulfilment.Step = entities.FulfilmentStepDocuments
+			}
+
+		case entities.FulfilmentStepDocuments:
+			if err := uc.generateDocuments(ctx, fulfilment); err != nil {
+				return err
+			}
+			fulfilment.Step = entities.FulfilmentStepEmail
+
+		case entities.FulfilmentStepEmail:
+			if err := uc.sendTickets(ctx, fulfilment); err != nil {
+				return err
+			}
+			fulfilment.Step = entities.FulfilmentStepDone
+
+		case entities.FulfilmentStepCompensate:
+			if err := uc.compensate(ctx, fulfilment); err != nil {
+				return err
+			}
+			fulfilment.Status = entities.FulfilmentStatusCompensated
+			fulfilment.Step = entities.FulfilmentStepDone
+
+		case entities.FulfilmentStepDone:
+			return nil
+
+		default:
+			return fmt.Errorf("unknown fulfilment step: %s", fulfilment.Step)
+		}
+
+		if err := uc.fulfilmentRepo.Update(ctx, fulfilment); err != nil {
+			return fmt.Errorf("failed to save fulfilment progress: %w", err)
+		}
+	}
+}
+
+// generateDocuments renders a PDF for every ticket that does not have one yet
+func (uc *FulfilmentUsecase) generateDocuments(ctx context.Context, fulfilment *entities.Fulfilment) error {
+	booking, err := uc.bookingRepo.GetByIDWithDetails(ctx, fulfilment.BookingID)
+	if err != nil {
+		return fmt.Errorf("booking not found: %w", err)
+	}
+
+	for _, leg := range booking.Legs() {
+		if err := uc.generateLegDocuments(ctx, leg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (uc *FulfilmentUsecase) generateLegDocuments(ctx context.Context, booking *entities.Booking) error {
+	if booking.Trip == nil || booking.Trip.Route == nil {
+		return fmt.Errorf("trip details missing for booking %s", booking.BookingCode)
+	}
+
+	departure := booking.Trip.DepartureTime.In(uc.bookingUsecase.location).Format("02/01/2006 15:04")
+	pickup, dropoff := boardingPoints(booking.Trip, booking)
+	for i := range booking.Tickets {
+		ticket := &booking.Tickets[i]
+		if ticket.PDFPath != "" {
+			continue
+		}
+
+		pdfPath, err := uc.pdfGenerator.GenerateTicket(
+			ticket.TicketCode,
+			ticket.PassengerName,
+			string(ticket.FareCategory),
+			booking.Trip.Route.FromCity,
+			booking.Trip.Route.ToCity,
+			pickup,
+			dropoff,
+			ticket.SeatNumber,
+			departure,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to generate ticket %s: %w", ticket.TicketCode, err)
+		}
+
+		ticket.PDFPath = pdfPath
+		if err := uc.ticketRepo.Update(ctx, ticket); err != nil {
+			return fmt.Errorf("failed to save ticket %s: %w", ticket.TicketCode, err)
+		}
+	}
+
+	return nil
+}
+
+// boardingPoints describes the stops a booking's passengers board and leave
+// the trip at, with when the bus gets there. Either is empty when it is the
+// route's origin or destination.
+func boardingPoints(trip *entities.Trip, booking *entities.Booking) (pickup, dropoff string) {
+	describe := func(stop *entities.RouteStop, id *uuid.UUID) string {
+		if stop == nil && id != nil && trip.Route != nil {
+			stop = trip.Route.Stop(*id)
+		}
+		if stop == nil {
+			return ""
+		}
+		return fmt.Sprintf("%s (%s)", stop.Label(), stop.TimeAt(trip.DepartureTime).Format("02/01/2006 15:04"))
+	}
+	return describe(booking.PickupStop, booking.PickupStopID), describe(booking.DropoffStop, booking.DropoffStopID)
+}
+
+// sendTickets emails the booking confirmation with all ticket PDFs attached
+func (uc *FulfilmentUsecase) sendTickets(ctx context.Context, fulfilment *entities.Fulfilment) error {
+	booking, err := uc.bookingRepo.GetByIDWithDetails(ctx, fulfilment.BookingID)
+	if err != nil {
+		return fmt.Errorf("booking not found: %w", err)
+	}
+
+	to := booking.ContactEmail
+	if to == "" && booking.User != nil {
+		to = booking.User.Email
+	}
+	if to == "" {
+		// Nothing to retry: the customer can still download tickets from the booking page
+		log.Printf("Booking %s has no contact email, skipping ticket email", booking.BookingCode)
+		return nil
+	}
+
+	var attachments []string
+	for _, leg := range booking.Legs() {
+		for _, ticket := range leg.Tickets {
+			attachments = append(attachments, ticket.PDFPath)
+		}
+	}
+
+	if err := uc.emailService.SendBookingConfirmation(to, booking.BookingCode, attachments...); err != nil {
+		return fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+	return nil
+}
+
+// compensate refunds the payment and frees anything the booking still holds
+func (uc *FulfilmentUsecase) compensate(ctx context.Context, fulfilment *entities.Fulfilment) error {
+	pmt, err := uc.paymentRepo.GetByID(ctx, fulfilment.PaymentID)
+	if err != nil {
+		return fmt.Errorf("payment not found: %w", err)
+	}
+
+	// The claim is committed before the gateway is called, so a retry after a
+	// failure further on refunds under the same idempotency key
+	refundDue, err := claimRefund(ctx, uc.paymentRepo, pmt)
+	if err != nil {
+		return err
+	}
+	if refundDue {
+		if err := refundAtGateway(ctx, uc.gateways, pmt); err != nil {
+			return err
+		}
+	}
+
+	booking, err := uc.bookingRepo.GetByIDWithDetails(ctx, fulfilment.BookingID)
+	if err != nil {
+		return fmt.Errorf("booking not found: %w", err)
+	}
+	legs := booking.Legs()
+
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if refundDue {
+			if err := markPaymentRefunded(ctx, uc.paymentRepo, uc.outboxRepo, pmt); err != nil {
+				return err
+			}
+		}
+
+		if err := uc.bookingUsecase.promotions.Release(ctx, booking.ID); err != nil {
+			return err
+		}
+		if err := uc.bookingUsecase.loyalty.ReleaseBooking(ctx, booking.ID); err != nil {
+			return err
+		}
+
+		for _, leg := range legs {
+			// Only seats booked under this booking are released; seats that were lost
+			// now belong to another customer and must be left alone
+			seats, err := uc.seatRepo.GetAllByTrip(ctx, leg.TripID)
+			if err != nil {
+				return fmt.Errorf("failed to load seats: %w", err)
+			}
+			held := make(map[string]bool)
+			for _, seat := range seats {
+				if seat.BookingID != nil && *seat.BookingID == leg.ID {
+					held[seat.SeatNumber] = true
+				}
+			}
+			if err := uc.seatRepo.ReleaseSeats(ctx, leg.ID); err != nil {
+				return fmt.Errorf("failed to release seats: %w", err)
+			}
+
+			if err := uc.bookingRepo.UpdateStatus(ctx, leg.ID, entities.BookingStatusRefunded); err != nil {
+				return fmt.Errorf("failed to update booking: %w", err)
+			}
+
+			released := *leg
+			released.Status = entities.BookingStatusRefunded
+			released.Seats = nil
+			for _, seatNumber := range leg.Seats {
+				if held[seatNumber] {
+					released.Seats = append(released.Seats, seatNumber)
+				}
+			}
+			if len(released.Seats) == 0 {
+				continue
+			}
+			if err := recordSeatsEvent(ctx, uc.outboxRepo, entities.EventSeatsReleased, &released, entities.SeatStatusAvailable); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, leg := range legs {
+		_ = uc.bookingUsecase.cache.InvalidateTripSeats(ctx, leg.TripID)
+	}
+	_ = uc.bookingUsecase.holds.Release(ctx, booking)
+
+	return nil
+}
+
+// fulfilmentRetryDelay returns an exponential backoff capped at fulfilmentRetryMax
+func fulfilmentRetryDelay(attempt int) time.Duration {
+	delay := fulfilmentRetryBase
+	for i := 1; i < attempt && delay < fulfilmentRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > fulfilmentRetryMax {
+		delay = fulfilmentRetryMax
+	}
+	return delay
+}
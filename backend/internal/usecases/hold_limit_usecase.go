@@ -0,0 +1,205 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+	"github.com/yourusername/bus-booking/internal/repositories/cache"
+)
+
+// HoldLimitConfig holds the anti-hoarding limits on seat holds; a zero limit
+// is switched off. Accounts and devices share limits, while an IP address
+// gets its own since many customers can share one.
+type HoldLimitConfig struct {
+	MaxSeatsPerBooking int // Per leg
+	MaxPendingBookings int // Unpaid bookings at once, per account or device
+	MaxPendingPerIP    int
+	MaxTripSeats       int // Seats held on one trip at once, per account or device
+	MaxTripSeatsPerIP  int
+
+	// ExpiryThreshold holds lapsing unpaid within ExpiryWindow put an identity
+	// on Cooldown, during which it cannot hold seats
+	ExpiryThreshold int
+	ExpiryWindow    time.Duration
+	Cooldown        time.Duration
+}
+
+// HoldLimitUsecase enforces the anti-hoarding limits on seat holds in Redis
+// and keeps a record of refused holds for fraud review
+type HoldLimitUsecase struct {
+	cache         *cache.RedisCache
+	violationRepo repositories.HoldViolationRepository
+	config        HoldLimitConfig
+}
+
+func NewHoldLimitUsecase(cache *cache.RedisCache, violationRepo repositories.HoldViolationRepository, config HoldLimitConfig) *HoldLimitUsecase {
+	return &HoldLimitUsecase{
+		cache:         cache,
+		violationRepo: violationRepo,
+		config:        config,
+	}
+}
+
+// ErrHoldLimitExceeded is wrapped by every HoldLimitError
+var ErrHoldLimitExceeded = errors.New("seat hold limit exceeded")
+
+// HoldLimitError is returned when a seat hold is refused by an anti-hoarding limit
+type HoldLimitError struct {
+	Policy     entities.HoldPolicy
+	Limit      int
+	RetryAfter time.Duration // How long a cooldown still lasts
+}
+
+func (e *HoldLimitError) Error() string {
+	switch e.Policy {
+	case entities.HoldPolicySeatsPerBooking:
+		return fmt.Sprintf("at most %d seats can be held in one booking", e.Limit)
+	case entities.HoldPolicyPendingBookings:
+		return fmt.Sprintf("too many unpaid bookings: pay for or cancel one of your %d pending bookings first", e.Limit)
+	case entities.HoldPolicyTripSeats:
+		return fmt.Sprintf("at most %d seats can be held on one trip at a time", e.Limit)
+	case entities.HoldPolicyCooldown:
+		return fmt.Sprintf("too many bookings expired unpaid, try again in %s", e.RetryAfter.Round(time.Second))
+	}
+	return ErrHoldLimitExceeded.Error()
+}
+
+func (e *HoldLimitError) Unwrap() error {
+	return ErrHoldLimitExceeded
+}
+
+// Reserve checks a new pending booking against the limits for everyone behind
+// it and counts its seats against them until it expires. The booking must
+// have its legs, booking code and expiry set but need not be saved yet.
+func (uc *HoldLimitUsecase) Reserve(ctx context.Context, booking *entities.Booking) error {
+	seatsPerTrip := make(map[uuid.UUID]int)
+	for _, leg := range booking.Legs() {
+		if limit := uc.config.MaxSeatsPerBooking; limit > 0 && len(leg.Seats) > limit {
+			violation := &HoldLimitError{Policy: entities.HoldPolicySeatsPerBooking, Limit: limit}
+			uc.recordViolation(ctx, booking, violation, holdIdentityKey(booking))
+			return violation
+		}
+		seatsPerTrip[leg.TripID] += len(leg.Seats)
+	}
+
+	rejection, err := uc.cache.ReserveHold(ctx, booking.BookingCode, uc.identities(booking), seatsPerTrip, *booking.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to check seat hold limits: %w", err)
+	}
+	if rejection == nil {
+		return nil
+	}
+
+	violation := &HoldLimitError{Policy: rejection.Policy, Limit: rejection.Limit, RetryAfter: rejection.RetryAfter}
+	uc.recordViolation(ctx, booking, violation, rejection.Identity)
+	return violation
+}
+
+// Release stops counting a booking's hold once it is paid, cancelled or expired
+func (uc *HoldLimitUsecase) Release(ctx context.Context, booking *entities.Booking) error {
+	var tripIDs []uuid.UUID
+	for _, leg := range booking.Legs() {
+		tripIDs = append(tripIDs, leg.TripID)
+	}
+	return uc.cache.ReleaseHold(ctx, booking.BookingCode, identityKeys(uc.identities(booking)), tripIDs)
+}
+
+// RecordExpiry counts a booking that lapsed unpaid towards the cooldown of
+// everyone behind it
+func (uc *HoldLimitUsecase) RecordExpiry(ctx context.Context, booking *entities.Booking) error {
+	keys := identityKeys(uc.identities(booking))
+	started, err := uc.cache.RecordHoldExpiry(ctx, keys, uc.config.ExpiryThreshold, uc.config.ExpiryWindow, uc.config.Cooldown)
+	if err != nil {
+		return err
+	}
+	if started > 0 {
+		log.Printf("Seat hold cooldown started after booking %s expired unpaid (%v)", booking.BookingCode, keys)
+	}
+	return nil
+}
+
+// ListViolations returns refused holds newest first, optionally of one policy
+func (uc *HoldLimitUsecase) ListViolations(ctx context.Context, policy entities.HoldPolicy, page, limit int) ([]*entities.HoldViolation, error) {
+	offset := (page - 1) * limit
+	return uc.violationRepo.List(ctx, policy, limit, offset)
+}
+
+// identities lists who is behind a booking: the account, the device and the IP
+// address, as far as they are known. The device ID is made up by the client,
+// so it is only counted on top of a known IP address: a fresh one never frees
+// a guest from the limits and cooldown of their address.
+func (uc *HoldLimitUsecase) identities(booking *entities.Booking) []cache.HoldIdentity {
+	var identities []cache.HoldIdentity
+	if booking.UserID != nil {
+		identities = append(identities, cache.HoldIdentity{
+			Key:          "user:" + booking.UserID.String(),
+			MaxPending:   uc.config.MaxPendingBookings,
+			MaxTripSeats: uc.config.MaxTripSeats,
+		})
+	}
+	if booking.DeviceID != "" && booking.ClientIP != "" {
+		identities = append(identities, cache.HoldIdentity{
+			Key:          "device:" + booking.DeviceID,
+			MaxPending:   uc.config.MaxPendingBookings,
+			MaxTripSeats: uc.config.MaxTripSeats,
+		})
+	}
+	if booking.ClientIP != "" {
+		identities = append(identities, cache.HoldIdentity{
+			Key:          "ip:" + booking.ClientIP,
+			MaxPending:   uc.config.MaxPendingPerIP,
+			MaxTripSeats: uc.config.MaxTripSeatsPerIP,
+		})
+	}
+	return identities
+}
+
+func identityKeys(identities []cache.HoldIdentity) []string {
+	keys := make([]string, 0, len(identities))
+	for _, identity := range identities {
+		keys = append(keys, identity.Key)
+	}
+	return keys
+}
+
+// holdIdentityKey names the most specific identity behind a booking
+func holdIdentityKey(booking *entities.Booking) string {
+	switch {
+	case booking.UserID != nil:
+		return "user:" + booking.UserID.String()
+	case booking.DeviceID != "" && booking.ClientIP != "":
+		return "device:" + booking.DeviceID
+	default:
+		return "ip:" + booking.ClientIP
+	}
+}
+
+// recordViolation saves a refused hold for fraud review; failing to do so
+// must not change the answer the customer gets
+func (uc *HoldLimitUsecase) recordViolation(ctx context.Context, booking *entities.Booking, violation *HoldLimitError, identity string) {
+	seatCount := 0
+	for _, leg := range booking.Legs() {
+		seatCount += len(leg.Seats)
+	}
+
+	err := uc.violationRepo.Create(ctx, &entities.HoldViolation{
+		Policy:     violation.Policy,
+		Identity:   identity,
+		UserID:     booking.UserID,
+		ClientIP:   booking.ClientIP,
+		DeviceID:   booking.DeviceID,
+		TripID:     booking.TripID,
+		SeatCount:  seatCount,
+		Limit:      violation.Limit,
+		RetryAfter: int(violation.RetryAfter.Seconds()),
+	})
+	if err != nil {
+		log.Printf("Failed to record %s hold violation for %s: %v", violation.Policy, identity, err)
+	}
+}
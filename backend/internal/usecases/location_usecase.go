@@ -0,0 +1,209 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+)
+
+// ErrLocationNotFound is returned when no location of the catalog matches a name
+var ErrLocationNotFound = errors.New("location not found")
+
+// LocationUsecase manages the catalog of cities and towns routes run between
+// and matches what customers type against it
+type LocationUsecase struct {
+	tx           repositories.Transactor
+	locationRepo repositories.LocationRepository
+	routeRepo    repositories.RouteRepository
+}
+
+func NewLocationUsecase(tx repositories.Transactor, locationRepo repositories.LocationRepository, routeRepo repositories.RouteRepository) *LocationUsecase {
+	return &LocationUsecase{
+		tx:           tx,
+		locationRepo: locationRepo,
+		routeRepo:    routeRepo,
+	}
+}
+
+// LocationInput is a location of the catalog as an admin enters it
+type LocationInput struct {
+	Name     string
+	Province string
+	Aliases  []string // Other names and abbreviations, e.g. "Sài Gòn", "HCM"
+	IsActive *bool    // Unchanged when nil; new locations are active
+}
+
+// CreateLocation adds a location with its aliases
+func (uc *LocationUsecase) CreateLocation(ctx context.Context, input LocationInput) (*entities.Location, error) {
+	location := &entities.Location{IsActive: true}
+	if err := uc.applyInput(ctx, location, input); err != nil {
+		return nil, err
+	}
+	if err := uc.locationRepo.Create(ctx, location); err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+func (uc *LocationUsecase) GetLocation(ctx context.Context, id uuid.UUID) (*entities.Location, error) {
+	return uc.locationRepo.GetByID(ctx, id)
+}
+
+func (uc *LocationUsecase) ListLocations(ctx context.Context, page, limit int) ([]*entities.Location, error) {
+	offset := (page - 1) * limit
+	return uc.locationRepo.List(ctx, limit, offset)
+}
+
+// UpdateLocation changes a location's name, province and status and makes
+// its aliases the given ones. Routes from or to it take the new name.
+func (uc *LocationUsecase) UpdateLocation(ctx context.Context, id uuid.UUID, input LocationInput) (*entities.Location, error) {
+	location, err := uc.locationRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("location not found: %w", err)
+	}
+	if err := uc.applyInput(ctx, location, input); err != nil {
+		return nil, err
+	}
+
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.locationRepo.Update(ctx, location); err != nil {
+			return err
+		}
+		if err := uc.routeRepo.RenameLocation(ctx, location.ID, location.Name); err != nil {
+			return err
+		}
+		return uc.locationRepo.ReplaceAliases(ctx, location.ID, location.Aliases)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+// DeleteLocation removes a location no route runs from or to
+func (uc *LocationUsecase) DeleteLocation(ctx context.Context, id uuid.UUID) error {
+	if _, err := uc.locationRepo.GetByID(ctx, id); err != nil {
+		return fmt.Errorf("location not found: %w", err)
+	}
+	if err := uc.locationRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("location is still used by routes, deactivate it instead: %w", err)
+	}
+	return nil
+}
+
+// applyInput checks a location's name and aliases, folds them and sets them
+// on location. No two names of the catalog may fold the same, so a folded
+// name always points to one location; aliases folding like the location's
+// own name or like each other are dropped.
+func (uc *LocationUsecase) applyInput(ctx context.Context, location *entities.Location, input LocationInput) error {
+	location.Name = strings.TrimSpace(input.Name)
+	location.SearchName = entities.FoldLocationName(location.Name)
+	if location.SearchName == "" {
+		return fmt.Errorf("location name is required")
+	}
+	if err := uc.checkNameFree(ctx, location.ID, location.Name, location.SearchName); err != nil {
+		return err
+	}
+	location.Province = strings.TrimSpace(input.Province)
+	if input.IsActive != nil {
+		location.IsActive = *input.IsActive
+	}
+
+	seen := map[string]bool{location.SearchName: true}
+	location.Aliases = make([]entities.LocationAlias, 0, len(input.Aliases))
+	for _, name := range input.Aliases {
+		alias := entities.LocationAlias{Name: strings.TrimSpace(name)}
+		alias.SearchName = entities.FoldLocationName(alias.Name)
+		if alias.SearchName == "" || seen[alias.SearchName] {
+			continue
+		}
+		seen[alias.SearchName] = true
+		if err := uc.checkNameFree(ctx, location.ID, alias.Name, alias.SearchName); err != nil {
+			return err
+		}
+		alias.LocationID = location.ID
+		location.Aliases = append(location.Aliases, alias)
+	}
+	return nil
+}
+
+// checkNameFree refuses a name that already names another location
+func (uc *LocationUsecase) checkNameFree(ctx context.Context, locationID uuid.UUID, name, searchName string) error {
+	other, err := uc.locationRepo.GetBySearchName(ctx, searchName)
+	if err == nil && other != nil && other.ID != locationID {
+		return fmt.Errorf("%q already names location %s", name, other.Name)
+	}
+	return nil
+}
+
+// Autocomplete suggests locations for what a customer has typed so far,
+// however they wrote diacritics, best match first
+func (uc *LocationUsecase) Autocomplete(ctx context.Context, query string, limit int) ([]*entities.LocationMatch, error) {
+	searchName := entities.FoldLocationName(query)
+	if searchName == "" {
+		return []*entities.LocationMatch{}, nil
+	}
+	return uc.locationRepo.Match(ctx, searchName, limit)
+}
+
+// ResolveLocation finds the location a customer means by a city name: the
+// one it names, or else the best fuzzy match
+func (uc *LocationUsecase) ResolveLocation(ctx context.Context, name string) (*entities.Location, error) {
+	searchName := entities.FoldLocationName(name)
+	if searchName == "" {
+		return nil, fmt.Errorf("%w: %q", ErrLocationNotFound, name)
+	}
+	if location, err := uc.locationRepo.GetBySearchName(ctx, searchName); err == nil && location.IsActive {
+		return location, nil
+	}
+
+	matches, err := uc.locationRepo.Match(ctx, searchName, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrLocationNotFound, name)
+	}
+	return matches[0].Location, nil
+}
+
+// routeLocations resolves the ends of a route to locations of the catalog and
+// names the route's cities after them. Given IDs are used as they are;
+// otherwise the cities must name a location exactly, by its name or an alias.
+func (uc *LocationUsecase) routeLocations(ctx context.Context, route *entities.Route) error {
+	end := func(id *uuid.UUID, city *string, which string) error {
+		var location *entities.Location
+		var err error
+		if *id != uuid.Nil {
+			if location, err = uc.locationRepo.GetByID(ctx, *id); err != nil {
+				return fmt.Errorf("%s location not found: %w", which, err)
+			}
+		} else {
+			searchName := entities.FoldLocationName(*city)
+			if searchName == "" {
+				return fmt.Errorf("%s_location_id is required", which)
+			}
+			if location, err = uc.locationRepo.GetBySearchName(ctx, searchName); err != nil {
+				return fmt.Errorf("%w: no location is named %q", ErrLocationNotFound, *city)
+			}
+		}
+		*id, *city = location.ID, location.Name
+		return nil
+	}
+
+	if err := end(&route.FromLocationID, &route.FromCity, "from"); err != nil {
+		return err
+	}
+	if err := end(&route.ToLocationID, &route.ToCity, "to"); err != nil {
+		return err
+	}
+	if route.FromLocationID == route.ToLocationID {
+		return fmt.Errorf("a route must run between two different locations")
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+)
+
+// LoyaltyConfig sets how points are earned, spent and expire, and what each tier needs and gets
+type LoyaltyConfig struct {
+	VNDPerPoint     float64 // Amount paid to earn one point
+	PointValue      float64 // Discount in VND for one redeemed point
+	ExpiryMonths    int     // Points lapse this many months after they were credited
+	SilverPoints    int     // Points earned over the qualifying period to reach silver
+	GoldPoints      int     // Points earned over the qualifying period to reach gold
+	SilverHoldBonus time.Duration
+	GoldHoldBonus   time.Duration
+}
+
+// tierQualifyingMonths is the window over which earned points count towards a tier
+const tierQualifyingMonths = 12
+
+// ErrInsufficientPoints is returned when a member redeems more points than they hold
+var ErrInsufficientPoints = errors.New("not enough loyalty points")
+
+// LoyaltyUsecase keeps the double-entry loyalty points ledger
+type LoyaltyUsecase struct {
+	tx          repositories.Transactor
+	loyaltyRepo repositories.LoyaltyRepository
+	bookingRepo repositories.BookingRepository
+	config      LoyaltyConfig
+}
+
+func NewLoyaltyUsecase(tx repositories.Transactor, loyaltyRepo repositories.LoyaltyRepository, bookingRepo repositories.BookingRepository, config LoyaltyConfig) *LoyaltyUsecase {
+	return &LoyaltyUsecase{
+		tx:          tx,
+		loyaltyRepo: loyaltyRepo,
+		bookingRepo: bookingRepo,
+		config:      config,
+	}
+}
+
+// LoyaltyPerks are the benefits of a tier
+type LoyaltyPerks struct {
+	SeatHoldBonusMinutes int `json:"seat_hold_bonus_minutes"`
+}
+
+// LoyaltyStatement is a member's balance, tier and ledger history
+type LoyaltyStatement struct {
+	Balance          int                            `json:"balance"`
+	BalanceValue     float64                        `json:"balance_value"` // VND the balance is worth at payment
+	Tier             entities.LoyaltyTier           `json:"tier"`
+	TierPoints       int                            `json:"tier_points"` // Earned over the qualifying period
+	NextTier         entities.LoyaltyTier           `json:"next_tier,omitempty"`
+	PointsToNextTier int                            `json:"points_to_next_tier,omitempty"`
+	Perks            LoyaltyPerks                   `json:"perks"`
+	Transactions     []*entities.LoyaltyTransaction `json:"transactions"`
+}
+
+// Earn credits points for a confirmed booking paid by a member; it must run
+// inside a transaction and does nothing if the booking already earned points
+func (uc *LoyaltyUsecase) Earn(ctx context.Context, booking *entities.Booking) error {
+	if booking.UserID == nil || uc.config.VNDPerPoint <= 0 {
+		return nil
+	}
+
+	points := int(math.Floor(booking.AmountDue() / uc.config.VNDPerPoint))
+	if points <= 0 {
+		return nil
+	}
+
+	if _, err := uc.loyaltyRepo.LockAccount(ctx, *booking.UserID); err != nil {
+		return fmt.Errorf("failed to lock loyalty account: %w", err)
+	}
+
+	posted, err := uc.bookingTransactions(ctx, booking.ID)
+	if err != nil {
+		return err
+	}
+	if posted[entities.LoyaltyTxnEarn] != nil {
+		return nil
+	}
+
+	expiresAt := uc.expiryFrom(time.Now())
+	txn := entities.NewLoyaltyTransaction(*booking.UserID, &booking.ID, entities.LoyaltyTxnEarn, points,
+		entities.LedgerAccountIssued, &expiresAt, fmt.Sprintf("Booking %s", booking.BookingCode))
+	return uc.post(ctx, txn)
+}
+
+// Redeem spends a member's points as a discount on a pending booking; it must
+// run inside a transaction
+func (uc *LoyaltyUsecase) Redeem(ctx context.Context, booking *entities.Booking, points int) error {
+	if booking.UserID == nil {
+		return fmt.Errorf("sign in to redeem loyalty points")
+	}
+	if booking.PointsRedeemed > 0 {
+		return fmt.Errorf("loyalty points are already redeemed on this booking")
+	}
+	if points <= 0 || uc.config.PointValue <= 0 {
+		return fmt.Errorf("invalid number of points")
+	}
+
+	maxPoints := int(math.Floor(booking.AmountDue() / uc.config.PointValue))
+	if points > maxPoints {
+		return fmt.Errorf("at most %d points can be redeemed on this booking", maxPoints)
+	}
+
+	account, err := uc.loyaltyRepo.LockAccount(ctx, *booking.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to lock loyalty account: %w", err)
+	}
+	if account.Balance < points {
+		return fmt.Errorf("%w: %d available", ErrInsufficientPoints, account.Balance)
+	}
+
+	// Points given back after a refused payment can be redeemed on the booking
+	// again, as its next attempt
+	posted, err := uc.bookingTransactions(ctx, booking.ID)
+	if err != nil {
+		return err
+	}
+	attempt := 0
+	if redeem := posted[entities.LoyaltyTxnRedeem]; redeem != nil {
+		if returned := posted[entities.LoyaltyTxnRedeemReversal]; returned == nil || returned.Attempt < redeem.Attempt {
+			return repositories.ErrPointsAlreadyRedeemed
+		}
+		attempt = redeem.Attempt + 1
+	}
+
+	// The booking was loaded before the transaction; claiming it here stops a
+	// concurrent redemption from posting a second debit
+	discount := float64(points) * uc.config.PointValue
+	if err := uc.bookingRepo.UpdatePointsRedemption(ctx, booking.ID, points, discount); err != nil {
+		if errors.Is(err, repositories.ErrPointsAlreadyRedeemed) {
+			return err
+		}
+		return fmt.Errorf("failed to update booking: %w", err)
+	}
+
+	txn := entities.NewLoyaltyTransaction(*booking.UserID, &booking.ID, entities.LoyaltyTxnRedeem, -points,
+		entities.LedgerAccountRedeemed, nil, fmt.Sprintf("Redeemed on booking %s", booking.BookingCode))
+	txn.Attempt = attempt
+	if err := uc.post(ctx, txn); err != nil {
+		return err
+	}
+	booking.PointsRedeemed = points
+	booking.PointsDiscount = discount
+
+	return nil
+}
+
+// ReleaseBooking undoes the ledger effects of a booking that was cancelled,
+// expired or refunded: redeemed points are returned and earned points are
+// taken back, as far as the member has not spent them. It must run inside a
+// transaction and is safe to repeat.
+func (uc *LoyaltyUsecase) ReleaseBooking(ctx context.Context, bookingID uuid.UUID) error {
+	posted, err := uc.bookingTransactions(ctx, bookingID)
+	if err != nil {
+		return err
+	}
+
+	earn, redeem := posted[entities.LoyaltyTxnEarn], posted[entities.LoyaltyTxnRedeem]
+	if earn == nil && redeem == nil {
+		return nil
+	}
+
+	var userID uuid.UUID
+	if earn != nil {
+		userID = earn.UserID
+	} else {
+		userID = redeem.UserID
+	}
+	account, err := uc.loyaltyRepo.LockAccount(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to lock loyalty account: %w", err)
+	}
+
+	// Re-read under the account lock so a concurrent release cannot post twice
+	posted, err = uc.bookingTransactions(ctx, bookingID)
+	if err != nil {
+		return err
+	}
+	earn, redeem = posted[entities.LoyaltyTxnEarn], posted[entities.LoyaltyTxnRedeem]
+	balance := account.Balance
+
+	if returned := posted[entities.LoyaltyTxnRedeemReversal]; redeem != nil && (returned == nil || returned.Attempt < redeem.Attempt) {
+		// Returned points get a fresh expiry, as if earned today
+		expiresAt := uc.expiryFrom(time.Now())
+		txn := entities.NewLoyaltyTransaction(userID, &bookingID, entities.LoyaltyTxnRedeemReversal, -redeem.Points,
+			entities.LedgerAccountRedeemed, &expiresAt, "Returned: "+redeem.Description)
+		txn.Attempt = redeem.Attempt
+		if err := uc.post(ctx, txn); err != nil {
+			return err
+		}
+		balance += txn.Points
+	}
+
+	if earn != nil && posted[entities.LoyaltyTxnEarnReversal] == nil {
+		// Points already spent stay spent; the balance never goes below zero
+		points := earn.Points
+		description := "Reversed: " + earn.Description
+		if points > balance {
+			points = max(balance, 0)
+			description += fmt.Sprintf(" (%d points already spent)", earn.Points-points)
+		}
+		txn := entities.NewLoyaltyTransaction(userID, &bookingID, entities.LoyaltyTxnEarnReversal, -points,
+			entities.LedgerAccountIssued, nil, description)
+		if err := uc.post(ctx, txn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// HoldBonus is the extra seat hold time a member's tier entitles them to
+func (uc *LoyaltyUsecase) HoldBonus(ctx context.Context, userID uuid.UUID) time.Duration {
+	tier, _, err := uc.tierFor(ctx, userID)
+	if err != nil {
+		return 0
+	}
+	return uc.perks(tier).holdBonus
+}
+
+// GetStatement returns a member's balance, tier and a page of their ledger history
+func (uc *LoyaltyUsecase) GetStatement(ctx context.Context, userID uuid.UUID, page, limit int) (*LoyaltyStatement, error) {
+	account, err := uc.loyaltyRepo.GetAccount(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load loyalty account: %w", err)
+	}
+
+	tier, tierPoints, err := uc.tierFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := (page - 1) * limit
+	txns, err := uc.loyaltyRepo.ListTransactions(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load loyalty transactions: %w", err)
+	}
+
+	statement := &LoyaltyStatement{
+		Balance:      account.Balance,
+		BalanceValue: math.Max(0, float64(account.Balance)*uc.config.PointValue),
+		Tier:         tier,
+		TierPoints:   tierPoints,
+		Perks:        LoyaltyPerks{SeatHoldBonusMinutes: int(uc.perks(tier).holdBonus.Minutes())},
+		Transactions: txns,
+	}
+
+	switch tier {
+	case entities.LoyaltyTierMember:
+		statement.NextTier = entities.LoyaltyTierSilver
+		statement.PointsToNextTier = uc.config.SilverPoints - tierPoints
+	case entities.LoyaltyTierSilver:
+		statement.NextTier = entities.LoyaltyTierGold
+		statement.PointsToNextTier = uc.config.GoldPoints - tierPoints
+	}
+
+	return statement, nil
+}
+
+// ExpirePoints posts an expiry for every member holding points past their
+// expiry date; it is the body of a scheduled job
+func (uc *LoyaltyUsecase) ExpirePoints(ctx context.Context) error {
+	now := time.Now()
+	userIDs, err := uc.loyaltyRepo.ListUsersWithExpiredPoints(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to find expired points: %w", err)
+	}
+
+	failed := 0
+	for _, userID := range userIDs {
+		err := uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			if _, err := uc.loyaltyRepo.LockAccount(ctx, userID); err != nil {
+				return err
+			}
+
+			// Recomputed under the lock, in case the member spent points meanwhile
+			points, err := uc.loyaltyRepo.ExpiredPoints(ctx, userID, now)
+			if err != nil || points == 0 {
+				return err
+			}
+
+			txn := entities.NewLoyaltyTransaction(userID, nil, entities.LoyaltyTxnExpire, -points,
+				entities.LedgerAccountExpired, nil, fmt.Sprintf("%d points expired", points))
+			return uc.post(ctx, txn)
+		})
+		if err != nil {
+			log.Printf("Failed to expire loyalty points of user %s: %v", userID, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to expire points of %d members", failed)
+	}
+	return nil
+}
+
+// tierFor works out a member's tier from the points earned, net of
+// reversals, over the qualifying period
+func (uc *LoyaltyUsecase) tierFor(ctx context.Context, userID uuid.UUID) (entities.LoyaltyTier, int, error) {
+	since := time.Now().AddDate(0, -tierQualifyingMonths, 0)
+	points, err := uc.loyaltyRepo.SumPointsSince(ctx, userID, []entities.LoyaltyTransactionType{
+		entities.LoyaltyTxnEarn,
+		entities.LoyaltyTxnEarnReversal,
+	}, since)
+	if err != nil {
+		return entities.LoyaltyTierMember, 0, fmt.Errorf("failed to load tier points: %w", err)
+	}
+
+	switch {
+	case uc.config.GoldPoints > 0 && points >= uc.config.GoldPoints:
+		return entities.LoyaltyTierGold, points, nil
+	case uc.config.SilverPoints > 0 && points >= uc.config.SilverPoints:
+		return entities.LoyaltyTierSilver, points, nil
+	}
+	return entities.LoyaltyTierMember, points, nil
+}
+
+type tierPerks struct {
+	holdBonus time.Duration
+}
+
+func (uc *LoyaltyUsecase) perks(tier entities.LoyaltyTier) tierPerks {
+	switch tier {
+	case entities.LoyaltyTierGold:
+		return tierPerks{holdBonus: uc.config.GoldHoldBonus}
+	case entities.LoyaltyTierSilver:
+		return tierPerks{holdBonus: uc.config.SilverHoldBonus}
+	}
+	return tierPerks{}
+}
+
+func (uc *LoyaltyUsecase) expiryFrom(t time.Time) time.Time {
+	return t.AddDate(0, uc.config.ExpiryMonths, 0)
+}
+
+func (uc *LoyaltyUsecase) post(ctx context.Context, txn *entities.LoyaltyTransaction) error {
+	if err := uc.loyaltyRepo.Post(ctx, txn); err != nil {
+		return fmt.Errorf("failed to post loyalty %s: %w", txn.Type, err)
+	}
+	return nil
+}
+
+// bookingTransactions indexes a booking's ledger transactions by type, keeping
+// the latest attempt of each
+func (uc *LoyaltyUsecase) bookingTransactions(ctx context.Context, bookingID uuid.UUID) (map[entities.LoyaltyTransactionType]*entities.LoyaltyTransaction, error) {
+	txns, err := uc.loyaltyRepo.GetBookingTransactions(ctx, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load loyalty transactions: %w", err)
+	}
+
+	byType := make(map[entities.LoyaltyTransactionType]*entities.LoyaltyTransaction, len(txns))
+	for _, txn := range txns {
+		if latest := byType[txn.Type]; latest == nil || txn.Attempt >= latest.Attempt {
+			byType[txn.Type] = txn
+		}
+	}
+	return byType, nil
+}
@@ -0,0 +1,202 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+	"github.com/yourusername/bus-booking/internal/repositories/cache"
+)
+
+const (
+	outboxBatchSize = 100
+	// outboxClaimLease keeps other relays off a claimed batch; it must outlast
+	// publishing the batch, or another relay may publish it again out of order
+	outboxClaimLease = 2 * time.Minute
+)
+
+// OutboxUsecase relays events from the outbox table to Redis Streams.
+// Delivery is at-least-once: an event is marked published only after the
+// stream accepted it, so consumers must deduplicate on event_id.
+type OutboxUsecase struct {
+	tx         repositories.Transactor
+	outboxRepo repositories.OutboxRepository
+	cache      *cache.RedisCache
+
+	streamMaxLen int64
+	retention    time.Duration
+}
+
+// NewOutboxUsecase creates a new outbox relay
+func NewOutboxUsecase(
+	tx repositories.Transactor,
+	outboxRepo repositories.OutboxRepository,
+	cache *cache.RedisCache,
+	streamMaxLen int64,
+	retention time.Duration,
+) *OutboxUsecase {
+	return &OutboxUsecase{
+		tx:           tx,
+		outboxRepo:   outboxRepo,
+		cache:        cache,
+		streamMaxLen: streamMaxLen,
+		retention:    retention,
+	}
+}
+
+// RelayPending publishes a batch of unpublished events in sequence order and
+// returns how many were published. Once an event fails, later events of the
+// same aggregate are held back so per-aggregate order is preserved. The batch
+// is claimed in a transaction of its own, so no transaction or row lock is
+// held while Redis is called.
+func (uc *OutboxUsecase) RelayPending(ctx context.Context) (int, error) {
+	var events []*entities.OutboxEvent
+	err := uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		locked, err := uc.outboxRepo.TryLockRelay(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to lock outbox relay: %w", err)
+		}
+		if !locked {
+			// Another instance is claiming
+			return nil
+		}
+
+		events, err = uc.outboxRepo.ClaimUnpublished(ctx, outboxClaimLease, outboxBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to claim outbox events: %w", err)
+		}
+		return nil
+	})
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	claimedIDs := make([]int64, 0, len(events))
+	for _, event := range events {
+		claimedIDs = append(claimedIDs, event.ID)
+	}
+	// Events left unpublished go to the next run; if this fails they do once
+	// the lease runs out
+	defer func() {
+		if err := uc.outboxRepo.ReleaseClaims(ctx, claimedIDs); err != nil {
+			log.Printf("Failed to release outbox claims: %v", err)
+		}
+	}()
+
+	blocked := make(map[uuid.UUID]bool)
+	publishedIDs := make([]int64, 0, len(events))
+	for _, event := range events {
+		if blocked[event.AggregateID] {
+			continue
+		}
+
+		if err := uc.publish(ctx, event); err != nil {
+			blocked[event.AggregateID] = true
+			if err := uc.outboxRepo.MarkFailed(ctx, event.ID, err.Error()); err != nil {
+				return 0, fmt.Errorf("failed to record outbox failure: %w", err)
+			}
+			continue
+		}
+		publishedIDs = append(publishedIDs, event.ID)
+	}
+
+	if err := uc.outboxRepo.MarkPublished(ctx, publishedIDs); err != nil {
+		return 0, fmt.Errorf("failed to mark events published: %w", err)
+	}
+	return len(publishedIDs), nil
+}
+
+// PurgePublished deletes published events older than the retention window
+func (uc *OutboxUsecase) PurgePublished(ctx context.Context) (int64, error) {
+	return uc.outboxRepo.DeletePublishedBefore(ctx, time.Now().Add(-uc.retention))
+}
+
+// publish appends the event to its stream and fans seat changes out to the
+// per-trip pub/sub channel used by the WebSocket hub
+func (uc *OutboxUsecase) publish(ctx context.Context, event *entities.OutboxEvent) error {
+	if err := uc.cache.PublishEvent(ctx, event, uc.streamMaxLen); err != nil {
+		return fmt.Errorf("failed to publish to stream: %w", err)
+	}
+
+	switch event.EventType {
+	case entities.EventBookingCreated, entities.EventBookingConfirmed, entities.EventSeatsReleased:
+		var payload entities.SeatsEventPayload
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return fmt.Errorf("invalid seats payload: %w", err)
+		}
+		stops := payload.StopRange
+		if stops.To <= stops.From {
+			stops.To = stops.From + 1 // Recorded before seats were sold per segment
+		}
+		for _, seatNum := range payload.Seats {
+			for segment := stops.From; segment < stops.To; segment++ {
+				seat := &entities.SeatInfo{
+					TripID:     payload.TripID,
+					SeatNumber: seatNum,
+					Segment:    segment,
+					Status:     payload.SeatStatus,
+				}
+				if err := uc.cache.PublishSeatUpdate(ctx, payload.TripID, seat); err != nil {
+					return fmt.Errorf("failed to publish seat update: %w", err)
+				}
+			}
+		}
+	case entities.EventSeatsBlocked, entities.EventSeatsUnblocked:
+		var payload entities.SeatBlockEventPayload
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return fmt.Errorf("invalid seat block payload: %w", err)
+		}
+		for _, seatNum := range payload.Seats {
+			seat := &entities.SeatInfo{
+				TripID:       payload.TripID,
+				SeatNumber:   seatNum,
+				Status:       payload.SeatStatus,
+				BlockReason:  payload.Reason,
+				BlockedUntil: payload.BlockedUntil,
+			}
+			if err := uc.cache.PublishSeatUpdate(ctx, payload.TripID, seat); err != nil {
+				return fmt.Errorf("failed to publish seat update: %w", err)
+			}
+		}
+	case entities.EventTripDelayed, entities.EventTripStatusChanged, entities.EventTripCancelled:
+		var payload entities.TripEventPayload
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return fmt.Errorf("invalid trip payload: %w", err)
+		}
+		if err := uc.cache.PublishTripEvent(ctx, payload.TripID, &payload); err != nil {
+			return fmt.Errorf("failed to publish trip update: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// recordEvent stores a domain event in the outbox; call it with the context of
+// the transaction that makes the corresponding state change
+func recordEvent(ctx context.Context, outboxRepo repositories.OutboxRepository, aggregateType string, aggregateID uuid.UUID, eventType entities.EventType, payload interface{}) error {
+	event, err := entities.NewOutboxEvent(aggregateType, aggregateID, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	if err := outboxRepo.Add(ctx, event); err != nil {
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// recordSeatsEvent stores a seat change event for a booking
+func recordSeatsEvent(ctx context.Context, outboxRepo repositories.OutboxRepository, eventType entities.EventType, booking *entities.Booking, seatStatus entities.SeatStatus) error {
+	return recordEvent(ctx, outboxRepo, entities.AggregateBooking, booking.ID, eventType, entities.SeatsEventPayload{
+		BookingID:     booking.ID,
+		TripID:        booking.TripID,
+		Seats:         booking.Seats,
+		StopRange:     booking.StopRange(),
+		SeatStatus:    seatStatus,
+		BookingStatus: booking.Status,
+	})
+}
@@ -3,6 +3,8 @@ package usecases
 import (
 	"context"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,33 +14,67 @@ import (
 )
 
 type PaymentUsecase struct {
+	tx          repositories.Transactor
+	outboxRepo  repositories.OutboxRepository
 	paymentRepo repositories.PaymentRepository
 	bookingRepo repositories.BookingRepository
 	gateways    map[entities.PaymentGateway]payment.Gateway
+	fulfilment  *FulfilmentUsecase
+	promotions  *PromotionUsecase
+	loyalty     *LoyaltyUsecase
 }
 
 func NewPaymentUsecase(
+	tx repositories.Transactor,
+	outboxRepo repositories.OutboxRepository,
 	paymentRepo repositories.PaymentRepository,
 	bookingRepo repositories.BookingRepository,
 	gateways map[entities.PaymentGateway]payment.Gateway,
+	fulfilment *FulfilmentUsecase,
+	promotions *PromotionUsecase,
+	loyalty *LoyaltyUsecase,
 ) *PaymentUsecase {
 	return &PaymentUsecase{
+		tx:          tx,
+		outboxRepo:  outboxRepo,
 		paymentRepo: paymentRepo,
 		bookingRepo: bookingRepo,
 		gateways:    gateways,
+		fulfilment:  fulfilment,
+		promotions:  promotions,
+		loyalty:     loyalty,
 	}
 }
 
+// CreatePaymentInput holds the details needed to pay for a booking. PromoCode
+// applies a code if none was applied when the booking was made; RedeemPoints
+// spends the paying member's loyalty points on what is left.
+type CreatePaymentInput struct {
+	BookingID    uuid.UUID
+	Gateway      entities.PaymentGateway
+	PromoCode    string
+	RedeemPoints int
+	UserID       *uuid.UUID // The paying user, if signed in
+}
+
 // CreatePayment initiates a payment for a booking
-func (uc *PaymentUsecase) CreatePayment(ctx context.Context, bookingID uuid.UUID, gateway entities.PaymentGateway) (*entities.Payment, string, error) {
+func (uc *PaymentUsecase) CreatePayment(ctx context.Context, input CreatePaymentInput) (*entities.Payment, string, error) {
+	bookingID, gateway, promoCode := input.BookingID, input.Gateway, input.PromoCode
+
 	// Get booking details
 	booking, err := uc.bookingRepo.GetByIDWithDetails(ctx, bookingID)
 	if err != nil {
 		return nil, "", fmt.Errorf("booking not found: %w", err)
 	}
 
-	if booking.Status != entities.BookingStatusPending {
-		return nil, "", fmt.Errorf("booking is not in pending status")
+	if booking.IsReturnLeg() {
+		return nil, "", fmt.Errorf("round trips are paid on the outbound booking")
+	}
+
+	for _, leg := range booking.Legs() {
+		if leg.Status != entities.BookingStatusPending {
+			return nil, "", fmt.Errorf("booking is not in pending status")
+		}
 	}
 
 	// Check if payment already exists
@@ -53,13 +89,49 @@ func (uc *PaymentUsecase) CreatePayment(ctx context.Context, bookingID uuid.UUID
 		return nil, "", fmt.Errorf("unsupported payment gateway: %s", gateway)
 	}
 
+	applyPromo := false
+	if promoCode != "" {
+		if booking.PromoCode == "" {
+			applyPromo = true
+		} else if !strings.EqualFold(booking.PromoCode, strings.TrimSpace(promoCode)) {
+			return nil, "", fmt.Errorf("promo code %s is already applied to this booking", booking.PromoCode)
+		}
+	}
+
+	if input.RedeemPoints > 0 {
+		if input.UserID == nil || booking.UserID == nil || *input.UserID != *booking.UserID {
+			return nil, "", fmt.Errorf("loyalty points can only be redeemed by the member who made the booking")
+		}
+	}
+
+	if applyPromo || input.RedeemPoints > 0 {
+		// Points are spent on what is left after the promo code
+		err := uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			if applyPromo {
+				if err := uc.promotions.Apply(ctx, promoCode, booking); err != nil {
+					return err
+				}
+			}
+			if input.RedeemPoints > 0 {
+				return uc.loyalty.Redeem(ctx, booking, input.RedeemPoints)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
 	// Generate idempotency key
 	idempotencyKey := fmt.Sprintf("%s_%s_%d", bookingID, gateway, time.Now().Unix())
 
+	// One payment covers every leg of a round trip
+	amount := booking.AmountDue()
+
 	// Create payment request
 	req := payment.PaymentRequest{
 		BookingID:   bookingID,
-		Amount:      booking.TotalPrice,
+		Amount:      amount,
 		Currency:    "VND",
 		Description: fmt.Sprintf("Thanh toán vé xe - %s", booking.BookingCode),
 		ReturnURL:   fmt.Sprintf("https://vietbusbooking.com/booking/%s/payment-success", bookingID),
@@ -75,6 +147,7 @@ func (uc *PaymentUsecase) CreatePayment(ctx context.Context, bookingID uuid.UUID
 	// Call gateway to create payment
 	resp, err := gw.CreatePayment(ctx, req)
 	if err != nil {
+		uc.undoDiscounts(ctx, booking, applyPromo, input.RedeemPoints > 0)
 		return nil, "", fmt.Errorf("failed to create payment: %w", err)
 	}
 
@@ -83,7 +156,7 @@ func (uc *PaymentUsecase) CreatePayment(ctx context.Context, bookingID uuid.UUID
 		BookingID:        bookingID,
 		Gateway:          gateway,
 		GatewayPaymentID: resp.GatewayPaymentID,
-		Amount:           booking.TotalPrice,
+		Amount:           amount,
 		Currency:         "VND",
 		Status:           entities.PaymentStatusPending,
 		IdempotencyKey:   idempotencyKey,
@@ -96,6 +169,37 @@ func (uc *PaymentUsecase) CreatePayment(ctx context.Context, bookingID uuid.UUID
 	return pmt, resp.PaymentURL, nil
 }
 
+// undoDiscounts gives back the promo code and loyalty points applied by a
+// payment the gateway refused and takes their discounts off the booking, so
+// nothing stays spent on a payment that was never made
+func (uc *PaymentUsecase) undoDiscounts(ctx context.Context, booking *entities.Booking, promo, points bool) {
+	if !promo && !points {
+		return
+	}
+	err := uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if promo {
+			if err := uc.promotions.Release(ctx, booking.ID); err != nil {
+				return err
+			}
+			if err := uc.bookingRepo.UpdatePromo(ctx, booking.ID, "", 0); err != nil {
+				return err
+			}
+		}
+		if points {
+			if err := uc.loyalty.ReleaseBooking(ctx, booking.ID); err != nil {
+				return err
+			}
+			if err := uc.bookingRepo.ClearPointsRedemption(ctx, booking.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to undo discounts of booking %s after the gateway refused its payment: %v", booking.BookingCode, err)
+	}
+}
+
 // HandleWebhook processes payment webhook notifications
 func (uc *PaymentUsecase) HandleWebhook(ctx context.Context, gateway entities.PaymentGateway, signature string, payload []byte) error {
 	// Get gateway
@@ -121,8 +225,12 @@ func (uc *PaymentUsecase) HandleWebhook(ctx context.Context, gateway entities.Pa
 		return fmt.Errorf("payment not found: %w", err)
 	}
 
-	// Check idempotency - if already processed, return success
+	// Check idempotency - if already processed, only make sure fulfilment was started
 	if pmt.Status == entities.PaymentStatusCompleted {
+		return uc.fulfilment.Start(ctx, pmt)
+	}
+	// A late or repeated notice must not bring a refunded payment back
+	if pmt.Status == entities.PaymentStatusRefunding || pmt.Status == entities.PaymentStatusRefunded {
 		return nil
 	}
 
@@ -143,15 +251,28 @@ func (uc *PaymentUsecase) HandleWebhook(ctx context.Context, gateway entities.Pa
 		return fmt.Errorf("unknown payment status: %s", webhookData.Status)
 	}
 
-	if err := uc.paymentRepo.Update(ctx, pmt); err != nil {
-		return fmt.Errorf("failed to update payment: %w", err)
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.paymentRepo.Update(ctx, pmt); err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+
+		if pmt.Status != entities.PaymentStatusCompleted {
+			return nil
+		}
+
+		// Update booking status if payment succeeded
+		if err := updateJourneyStatus(ctx, uc.bookingRepo, pmt.BookingID, entities.BookingStatusPaid); err != nil {
+			return err
+		}
+		return recordPaymentEvent(ctx, uc.outboxRepo, entities.EventPaymentCompleted, pmt)
+	})
+	if err != nil {
+		return err
 	}
 
-	// Update booking status if payment succeeded
+	// Hand over to fulfilment once the payment is committed
 	if pmt.Status == entities.PaymentStatusCompleted {
-		if err := uc.bookingRepo.UpdateStatus(ctx, pmt.BookingID, entities.BookingStatusPaid); err != nil {
-			return fmt.Errorf("failed to update booking: %w", err)
-		}
+		return uc.fulfilment.Start(ctx, pmt)
 	}
 
 	return nil
@@ -198,30 +319,109 @@ func (uc *PaymentUsecase) RefundPayment(ctx context.Context, paymentID uuid.UUID
 		return err
 	}
 
-	if pmt.Status != entities.PaymentStatusCompleted {
+	due, err := claimRefund(ctx, uc.paymentRepo, pmt)
+	if err != nil {
+		return err
+	}
+	if !due {
 		return fmt.Errorf("payment must be completed to refund")
 	}
 
-	// Call gateway to refund
-	gw, ok := uc.gateways[pmt.Gateway]
+	if err := refundAtGateway(ctx, uc.gateways, pmt); err != nil {
+		return err
+	}
+
+	return uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := markPaymentRefunded(ctx, uc.paymentRepo, uc.outboxRepo, pmt); err != nil {
+			return err
+		}
+
+		if err := uc.promotions.Release(ctx, pmt.BookingID); err != nil {
+			return err
+		}
+		if err := uc.loyalty.ReleaseBooking(ctx, pmt.BookingID); err != nil {
+			return err
+		}
+
+		// Update booking status
+		return updateJourneyStatus(ctx, uc.bookingRepo, pmt.BookingID, entities.BookingStatusRefunded)
+	})
+}
+
+// updateJourneyStatus sets the status of a paid-for booking and of its return leg, if any
+func updateJourneyStatus(ctx context.Context, bookingRepo repositories.BookingRepository, bookingID uuid.UUID, status entities.BookingStatus) error {
+	booking, err := bookingRepo.GetByIDWithDetails(ctx, bookingID)
+	if err != nil {
+		return fmt.Errorf("booking not found: %w", err)
+	}
+
+	for _, leg := range booking.Legs() {
+		if err := bookingRepo.UpdateStatus(ctx, leg.ID, status); err != nil {
+			return fmt.Errorf("failed to update booking: %w", err)
+		}
+	}
+	return nil
+}
+
+// claimRefund records that a payment is being refunded before the gateway is
+// asked to, by moving it from completed to refunding. It reports whether a
+// gateway refund is due: for a payment it claimed, and for one already
+// refunding whose refund stopped before it was recorded.
+func claimRefund(ctx context.Context, paymentRepo repositories.PaymentRepository, pmt *entities.Payment) (bool, error) {
+	if pmt.Status == entities.PaymentStatusCompleted {
+		claimed, err := paymentRepo.TransitionStatus(ctx, pmt.ID, entities.PaymentStatusCompleted, entities.PaymentStatusRefunding)
+		if err != nil {
+			return false, fmt.Errorf("failed to claim payment for refund: %w", err)
+		}
+		if claimed {
+			pmt.Status = entities.PaymentStatusRefunding
+			return true, nil
+		}
+		// Changed since it was loaded
+		current, err := paymentRepo.GetByID(ctx, pmt.ID)
+		if err != nil {
+			return false, err
+		}
+		*pmt = *current
+	}
+	return pmt.Status == entities.PaymentStatusRefunding, nil
+}
+
+// refundAtGateway asks the payment's gateway to refund the full amount. The
+// payment ID is the idempotency key, so however often a refund is retried the
+// gateway pays it out once.
+func refundAtGateway(ctx context.Context, gateways map[entities.PaymentGateway]payment.Gateway, pmt *entities.Payment) error {
+	gw, ok := gateways[pmt.Gateway]
 	if !ok {
 		return fmt.Errorf("unsupported gateway")
 	}
 
-	if err := gw.RefundPayment(ctx, pmt.GatewayPaymentID, pmt.Amount); err != nil {
+	if err := gw.RefundPayment(ctx, pmt.GatewayPaymentID, pmt.Amount, pmt.ID.String()); err != nil {
 		return fmt.Errorf("refund failed: %w", err)
 	}
+	return nil
+}
 
-	// Update payment status
-	pmt.Status = entities.PaymentStatusRefunded
-	if err := uc.paymentRepo.Update(ctx, pmt); err != nil {
-		return err
+// markPaymentRefunded records a refund that the gateway has accepted; a refund
+// recorded already is left as it is
+func markPaymentRefunded(ctx context.Context, paymentRepo repositories.PaymentRepository, outboxRepo repositories.OutboxRepository, pmt *entities.Payment) error {
+	marked, err := paymentRepo.TransitionStatus(ctx, pmt.ID, entities.PaymentStatusRefunding, entities.PaymentStatusRefunded)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
 	}
-
-	// Update booking status
-	if err := uc.bookingRepo.UpdateStatus(ctx, pmt.BookingID, entities.BookingStatusRefunded); err != nil {
-		return err
+	pmt.Status = entities.PaymentStatusRefunded
+	if !marked {
+		return nil
 	}
+	return recordPaymentEvent(ctx, outboxRepo, entities.EventPaymentRefunded, pmt)
+}
 
-	return nil
+// recordPaymentEvent stores a payment event in the outbox
+func recordPaymentEvent(ctx context.Context, outboxRepo repositories.OutboxRepository, eventType entities.EventType, pmt *entities.Payment) error {
+	return recordEvent(ctx, outboxRepo, entities.AggregatePayment, pmt.ID, eventType, entities.PaymentEventPayload{
+		PaymentID: pmt.ID,
+		BookingID: pmt.BookingID,
+		Amount:    pmt.Amount,
+		Status:    pmt.Status,
+	})
 }
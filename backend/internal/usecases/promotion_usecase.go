@@ -0,0 +1,334 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+)
+
+// PromotionUsecase manages promotions and redeems their codes against bookings
+type PromotionUsecase struct {
+	promotionRepo repositories.PromotionRepository
+	bookingRepo   repositories.BookingRepository
+}
+
+func NewPromotionUsecase(promotionRepo repositories.PromotionRepository, bookingRepo repositories.BookingRepository) *PromotionUsecase {
+	return &PromotionUsecase{
+		promotionRepo: promotionRepo,
+		bookingRepo:   bookingRepo,
+	}
+}
+
+var (
+	// ErrPromoCodeInvalid is returned for unknown, disabled or out-of-date codes
+	ErrPromoCodeInvalid = errors.New("promo code is not valid")
+	// ErrPromoCodeNotApplicable is returned when the booking does not meet the promotion's conditions
+	ErrPromoCodeNotApplicable = errors.New("promo code does not apply to this booking")
+	// ErrPromoCodeExhausted is returned when a usage limit has been reached
+	ErrPromoCodeExhausted = errors.New("promo code has reached its usage limit")
+)
+
+const (
+	// voucherAlphabet leaves out 0/O and 1/I, which are easily confused; its 32
+	// characters map evenly onto random bytes
+	voucherAlphabet   = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	voucherCodeLength = 10
+	maxVoucherBatch   = 1000
+)
+
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,50}$`)
+
+func (uc *PromotionUsecase) CreatePromotion(ctx context.Context, promotion *entities.Promotion) error {
+	if err := validatePromotion(promotion); err != nil {
+		return err
+	}
+	promotion.TimesUsed = 0
+	return uc.promotionRepo.Create(ctx, promotion)
+}
+
+func (uc *PromotionUsecase) GetPromotion(ctx context.Context, id uuid.UUID) (*entities.Promotion, error) {
+	return uc.promotionRepo.GetByID(ctx, id)
+}
+
+func (uc *PromotionUsecase) UpdatePromotion(ctx context.Context, promotion *entities.Promotion) error {
+	// Validate promotion exists
+	existing, err := uc.promotionRepo.GetByID(ctx, promotion.ID)
+	if err != nil {
+		return fmt.Errorf("promotion not found: %w", err)
+	}
+
+	if err := validatePromotion(promotion); err != nil {
+		return err
+	}
+	promotion.TimesUsed = existing.TimesUsed
+	promotion.CreatedAt = existing.CreatedAt
+	return uc.promotionRepo.Update(ctx, promotion)
+}
+
+func (uc *PromotionUsecase) ListPromotions(ctx context.Context, activeOnly bool, page, limit int) ([]*entities.Promotion, error) {
+	offset := (page - 1) * limit
+	return uc.promotionRepo.List(ctx, activeOnly, limit, offset)
+}
+
+// CreateCode adds a customer-facing code to a promotion, e.g. "TET2025"
+func (uc *PromotionUsecase) CreateCode(ctx context.Context, promotionID uuid.UUID, code string, usageLimit *int) (*entities.PromoCode, error) {
+	if _, err := uc.promotionRepo.GetByID(ctx, promotionID); err != nil {
+		return nil, fmt.Errorf("promotion not found: %w", err)
+	}
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !promoCodePattern.MatchString(code) {
+		return nil, fmt.Errorf("code must be 3-50 letters, digits, dashes or underscores")
+	}
+	if usageLimit != nil && *usageLimit < 1 {
+		return nil, fmt.Errorf("usage_limit must be at least 1")
+	}
+
+	promoCode := &entities.PromoCode{
+		PromotionID: promotionID,
+		Code:        code,
+		UsageLimit:  usageLimit,
+		IsActive:    true,
+	}
+	if err := uc.promotionRepo.CreateCodes(ctx, []*entities.PromoCode{promoCode}); err != nil {
+		return nil, fmt.Errorf("failed to create code: %w", err)
+	}
+	return promoCode, nil
+}
+
+// GenerateVouchers creates a batch of random single-use codes for a promotion
+func (uc *PromotionUsecase) GenerateVouchers(ctx context.Context, promotionID uuid.UUID, count int, prefix string) ([]*entities.PromoCode, error) {
+	if _, err := uc.promotionRepo.GetByID(ctx, promotionID); err != nil {
+		return nil, fmt.Errorf("promotion not found: %w", err)
+	}
+
+	if count < 1 || count > maxVoucherBatch {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxVoucherBatch)
+	}
+	prefix = strings.ToUpper(strings.TrimSpace(prefix))
+	if prefix != "" && !promoCodePattern.MatchString(prefix+strings.Repeat("X", voucherCodeLength)) {
+		return nil, fmt.Errorf("prefix must be letters, digits, dashes or underscores")
+	}
+
+	batchID := uuid.New()
+	singleUse := 1
+	seen := make(map[string]bool, count)
+	codes := make([]*entities.PromoCode, 0, count)
+	for len(codes) < count {
+		code, err := randomVoucherCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate code: %w", err)
+		}
+		code = prefix + code
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		codes = append(codes, &entities.PromoCode{
+			PromotionID: promotionID,
+			Code:        code,
+			UsageLimit:  &singleUse,
+			BatchID:     &batchID,
+			IsActive:    true,
+		})
+	}
+
+	if err := uc.promotionRepo.CreateCodes(ctx, codes); err != nil {
+		return nil, fmt.Errorf("failed to create vouchers: %w", err)
+	}
+	return codes, nil
+}
+
+func (uc *PromotionUsecase) ListCodes(ctx context.Context, promotionID uuid.UUID, page, limit int) ([]*entities.PromoCode, error) {
+	offset := (page - 1) * limit
+	return uc.promotionRepo.ListCodes(ctx, promotionID, limit, offset)
+}
+
+// Apply redeems a promo code for a booking and all of its legs, whose trips
+// must be loaded with route and bus. It must run inside a transaction: the
+// usage counters, the redemption and the booking's discount are committed
+// together, or not at all.
+func (uc *PromotionUsecase) Apply(ctx context.Context, code string, booking *entities.Booking) error {
+	promoCode, err := uc.promotionRepo.GetCodeByCode(ctx, strings.TrimSpace(code))
+	if err != nil {
+		return ErrPromoCodeInvalid
+	}
+
+	promotion := promoCode.Promotion
+	if !promoCode.IsActive || promotion == nil || !promotion.IsRunning(time.Now()) {
+		return ErrPromoCodeInvalid
+	}
+
+	if err := uc.checkConditions(ctx, promotion, booking); err != nil {
+		return err
+	}
+
+	subtotal := booking.Subtotal()
+	if subtotal < promotion.MinSpend {
+		return fmt.Errorf("%w: minimum spend is %.0f VND", ErrPromoCodeNotApplicable, promotion.MinSpend)
+	}
+	discount := promotion.Discount(subtotal)
+
+	ok, err := uc.promotionRepo.IncrementUsage(ctx, promoCode.ID, promotion.ID)
+	if err != nil {
+		return fmt.Errorf("failed to redeem promo code: %w", err)
+	}
+	if !ok {
+		return ErrPromoCodeExhausted
+	}
+
+	// IncrementUsage holds the promotion row lock from here on, so concurrent
+	// redemptions by the same customer are counted one after another
+	if promotion.PerUserLimit != nil {
+		used, err := uc.promotionRepo.CountCustomerRedemptions(ctx, promotion.ID, booking.UserID, booking.ContactEmail)
+		if err != nil {
+			return fmt.Errorf("failed to check promo code usage: %w", err)
+		}
+		if used >= int64(*promotion.PerUserLimit) {
+			return fmt.Errorf("%w: already used the maximum number of times", ErrPromoCodeExhausted)
+		}
+	}
+
+	err = uc.promotionRepo.CreateRedemption(ctx, &entities.PromoRedemption{
+		PromotionID:    promotion.ID,
+		PromoCodeID:    promoCode.ID,
+		BookingID:      booking.ID,
+		UserID:         booking.UserID,
+		ContactEmail:   booking.ContactEmail,
+		DiscountAmount: discount,
+		Status:         entities.RedemptionStatusActive,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record redemption: %w", err)
+	}
+
+	if err := uc.bookingRepo.UpdatePromo(ctx, booking.ID, promoCode.Code, discount); err != nil {
+		return fmt.Errorf("failed to update booking: %w", err)
+	}
+	booking.PromoCode = promoCode.Code
+	booking.PromoDiscount = discount
+
+	return nil
+}
+
+// Release gives back the use of a promo code redeemed for a booking that was
+// cancelled, expired or refunded; bookings without one are ignored
+func (uc *PromotionUsecase) Release(ctx context.Context, bookingID uuid.UUID) error {
+	if _, err := uc.promotionRepo.ReleaseRedemption(ctx, bookingID); err != nil {
+		return fmt.Errorf("failed to release promo code: %w", err)
+	}
+	return nil
+}
+
+// checkConditions verifies the promotion's route, operator, departure date and
+// first-time customer restrictions against every leg of the booking
+func (uc *PromotionUsecase) checkConditions(ctx context.Context, promotion *entities.Promotion, booking *entities.Booking) error {
+	for _, leg := range booking.Legs() {
+		trip := leg.Trip
+		if trip == nil {
+			return fmt.Errorf("trip details missing for booking %s", leg.BookingCode)
+		}
+
+		if len(promotion.RouteIDs) > 0 && !containsFold(promotion.RouteIDs, trip.RouteID.String()) {
+			return fmt.Errorf("%w: not valid on this route", ErrPromoCodeNotApplicable)
+		}
+		if len(promotion.OperatorNames) > 0 && (trip.Bus == nil || !containsFold(promotion.OperatorNames, trip.Bus.OperatorName)) {
+			return fmt.Errorf("%w: not valid with this operator", ErrPromoCodeNotApplicable)
+		}
+		if promotion.DepartureFrom != nil && trip.DepartureTime.Before(*promotion.DepartureFrom) {
+			return fmt.Errorf("%w: not valid for this departure date", ErrPromoCodeNotApplicable)
+		}
+		if promotion.DepartureTo != nil && trip.DepartureTime.After(*promotion.DepartureTo) {
+			return fmt.Errorf("%w: not valid for this departure date", ErrPromoCodeNotApplicable)
+		}
+	}
+
+	if promotion.FirstTimeOnly {
+		previous, err := uc.bookingRepo.CountCustomerBookings(ctx, booking.UserID, booking.ContactEmail, []entities.BookingStatus{
+			entities.BookingStatusPaid,
+			entities.BookingStatusConfirmed,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to check booking history: %w", err)
+		}
+		if previous > 0 {
+			return fmt.Errorf("%w: only valid on a first booking", ErrPromoCodeNotApplicable)
+		}
+	}
+
+	return nil
+}
+
+func validatePromotion(promotion *entities.Promotion) error {
+	if strings.TrimSpace(promotion.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch promotion.DiscountType {
+	case entities.PromotionDiscountPercentage:
+		if promotion.DiscountValue <= 0 || promotion.DiscountValue > 100 {
+			return fmt.Errorf("percentage discount must be between 0 and 100")
+		}
+	case entities.PromotionDiscountFixed:
+		if promotion.DiscountValue <= 0 {
+			return fmt.Errorf("fixed discount must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid discount type: %s", promotion.DiscountType)
+	}
+
+	if promotion.MinSpend < 0 {
+		return fmt.Errorf("min_spend must not be negative")
+	}
+	if promotion.MaxDiscount != nil && *promotion.MaxDiscount <= 0 {
+		return fmt.Errorf("max_discount must be positive")
+	}
+	if promotion.StartsAt != nil && promotion.EndsAt != nil && !promotion.EndsAt.After(*promotion.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	if promotion.DepartureFrom != nil && promotion.DepartureTo != nil && promotion.DepartureTo.Before(*promotion.DepartureFrom) {
+		return fmt.Errorf("departure_to must not be before departure_from")
+	}
+	if promotion.UsageLimit != nil && *promotion.UsageLimit < 1 {
+		return fmt.Errorf("usage_limit must be at least 1")
+	}
+	if promotion.PerUserLimit != nil && *promotion.PerUserLimit < 1 {
+		return fmt.Errorf("per_user_limit must be at least 1")
+	}
+	for _, routeID := range promotion.RouteIDs {
+		if _, err := uuid.Parse(routeID); err != nil {
+			return fmt.Errorf("invalid route ID: %s", routeID)
+		}
+	}
+
+	return nil
+}
+
+func randomVoucherCode() (string, error) {
+	buf := make([]byte, voucherCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = voucherAlphabet[int(b)%len(voucherAlphabet)]
+	}
+	return string(buf), nil
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
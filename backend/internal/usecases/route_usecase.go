@@ -3,6 +3,8 @@ package usecases
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/yourusername/bus-booking/internal/entities"
@@ -10,17 +12,33 @@ import (
 )
 
 type RouteUsecase struct {
+	tx        repositories.Transactor
 	routeRepo repositories.RouteRepository
+	tripRepo  repositories.TripRepository
+	seatRepo  repositories.SeatRepository
+	locations *LocationUsecase
 }
 
-func NewRouteUsecase(routeRepo repositories.RouteRepository) *RouteUsecase {
-	return &RouteUsecase{routeRepo: routeRepo}
+func NewRouteUsecase(
+	tx repositories.Transactor,
+	routeRepo repositories.RouteRepository,
+	tripRepo repositories.TripRepository,
+	seatRepo repositories.SeatRepository,
+	locations *LocationUsecase,
+) *RouteUsecase {
+	return &RouteUsecase{
+		tx:        tx,
+		routeRepo: routeRepo,
+		tripRepo:  tripRepo,
+		seatRepo:  seatRepo,
+		locations: locations,
+	}
 }
 
 func (uc *RouteUsecase) CreateRoute(ctx context.Context, route *entities.Route) error {
 	// Validate route data
-	if route.FromCity == "" || route.ToCity == "" {
-		return fmt.Errorf("from_city and to_city are required")
+	if err := uc.locations.routeLocations(ctx, route); err != nil {
+		return err
 	}
 	if route.Distance <= 0 {
 		return fmt.Errorf("distance must be positive")
@@ -28,6 +46,9 @@ func (uc *RouteUsecase) CreateRoute(ctx context.Context, route *entities.Route)
 	if route.BasePrice <= 0 {
 		return fmt.Errorf("base price must be positive")
 	}
+	if err := validateRouteStops(route, route.Stops); err != nil {
+		return err
+	}
 
 	// Generate route name if not provided
 	if route.Name == "" {
@@ -49,6 +70,9 @@ func (uc *RouteUsecase) UpdateRoute(ctx context.Context, route *entities.Route)
 	}
 
 	// Validate route data
+	if err := uc.locations.routeLocations(ctx, route); err != nil {
+		return err
+	}
 	if route.Distance <= 0 {
 		return fmt.Errorf("distance must be positive")
 	}
@@ -68,6 +92,209 @@ func (uc *RouteUsecase) ListRoutes(ctx context.Context, page, limit int) ([]*ent
 	return uc.routeRepo.List(ctx, limit, offset)
 }
 
+// SearchRoutes returns the active routes between the locations the city
+// names resolve to; an empty name matches any city
 func (uc *RouteUsecase) SearchRoutes(ctx context.Context, fromCity, toCity string) ([]*entities.Route, error) {
-	return uc.routeRepo.Search(ctx, fromCity, toCity)
+	var ends [2]*uuid.UUID
+	for i, city := range []string{fromCity, toCity} {
+		if strings.TrimSpace(city) == "" {
+			continue
+		}
+		location, err := uc.locations.ResolveLocation(ctx, city)
+		if err != nil {
+			return nil, err
+		}
+		ends[i] = &location.ID
+	}
+	return uc.routeRepo.Search(ctx, ends[0], ends[1])
+}
+
+// SetRouteStops replaces a route's stops with stops, in the order the bus
+// calls at them. Stops with an ID update the route's existing stop; stops the
+// list leaves out are deleted unless a booking boards or leaves there.
+// Adding, removing or reordering stops changes the segments seats are sold
+// by, so it is refused while an upcoming trip of the route has seats taken
+// and otherwise rebuilds the seats of those trips.
+func (uc *RouteUsecase) SetRouteStops(ctx context.Context, routeID uuid.UUID, stops []entities.RouteStop) (*entities.Route, error) {
+	route, err := uc.routeRepo.GetByID(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("route not found: %w", err)
+	}
+	if err := validateRouteStops(route, stops); err != nil {
+		return nil, err
+	}
+
+	kept := make(map[uuid.UUID]bool, len(stops))
+	for _, stop := range stops {
+		kept[stop.ID] = true
+	}
+	var removed []uuid.UUID
+	for _, stop := range route.Stops {
+		if !kept[stop.ID] {
+			removed = append(removed, stop.ID)
+		}
+	}
+
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		inUse, err := uc.routeRepo.StopsInUse(ctx, removed)
+		if err != nil {
+			return fmt.Errorf("failed to check stops in use: %w", err)
+		}
+		if len(inUse) > 0 {
+			return fmt.Errorf("stop %s cannot be removed: bookings board or leave there", route.Stop(inUse[0]).Name)
+		}
+		if err := uc.routeRepo.ReplaceStops(ctx, routeID, stops); err != nil {
+			return err
+		}
+		if sameStopOrder(route.Stops, stops) {
+			return nil
+		}
+		return uc.resegmentTrips(ctx, route, len(stops)+1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uc.routeRepo.GetByID(ctx, routeID)
+}
+
+// resegmentTrips rebuilds the seats of the route's upcoming trips with a row
+// per seat for each of segments, keeping operator blocks
+func (uc *RouteUsecase) resegmentTrips(ctx context.Context, route *entities.Route, segments int) error {
+	trips, err := uc.tripRepo.GetRouteTrips(ctx, route.ID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to load trips: %w", err)
+	}
+
+	for _, trip := range trips {
+		held, err := uc.seatRepo.CountHeldSeats(ctx, trip.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check seats of trip %s: %w", trip.ID, err)
+		}
+		if held > 0 {
+			return fmt.Errorf("stops cannot be added, removed or reordered: the trip departing %s has seats taken", trip.DepartureTime.Format(time.RFC3339))
+		}
+
+		seats, err := uc.seatRepo.GetAllByTrip(ctx, trip.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load seats of trip %s: %w", trip.ID, err)
+		}
+		var seatNumbers []string
+		var blocked []*entities.SeatInfo
+		for _, seat := range seats {
+			if seat.Segment != 0 {
+				continue
+			}
+			seatNumbers = append(seatNumbers, seat.SeatNumber)
+			if seat.Status == entities.SeatStatusBlocked {
+				blocked = append(blocked, seat)
+			}
+		}
+
+		if err := uc.seatRepo.DeleteByTrip(ctx, trip.ID); err != nil {
+			return fmt.Errorf("failed to remove seats of trip %s: %w", trip.ID, err)
+		}
+		if err := uc.seatRepo.InitializeSeatsForTrip(ctx, trip.ID, seatNumbers, segments); err != nil {
+			return fmt.Errorf("failed to initialize seats of trip %s: %w", trip.ID, err)
+		}
+		if err := reblockSeats(ctx, uc.seatRepo, trip.ID, blocked); err != nil {
+			return fmt.Errorf("failed to block seats of trip %s: %w", trip.ID, err)
+		}
+	}
+	return nil
+}
+
+// reblockSeats blocks the seats again as they were blocked, grouped so
+// seats blocked alike are one update
+func reblockSeats(ctx context.Context, seatRepo repositories.SeatRepository, tripID uuid.UUID, blocked []*entities.SeatInfo) error {
+	var keys []string
+	first := make(map[string]*entities.SeatInfo)
+	seatsByBlock := make(map[string][]string)
+	for _, seat := range blocked {
+		until, by := "", ""
+		if seat.BlockedUntil != nil {
+			until = seat.BlockedUntil.Format(time.RFC3339Nano)
+		}
+		if seat.BlockedBy != nil {
+			by = seat.BlockedBy.String()
+		}
+		key := seat.BlockReason + "|" + until + "|" + by
+		if _, ok := first[key]; !ok {
+			keys = append(keys, key)
+			first[key] = seat
+		}
+		seatsByBlock[key] = append(seatsByBlock[key], seat.SeatNumber)
+	}
+
+	for _, key := range keys {
+		seat := first[key]
+		if err := seatRepo.BlockSeats(ctx, tripID, seatsByBlock[key], seat.BlockReason, seat.BlockedUntil, seat.BlockedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameStopOrder reports whether the new stop list calls at the existing
+// stops, and only those, in the same order
+func sameStopOrder(existing, stops []entities.RouteStop) bool {
+	if len(existing) != len(stops) {
+		return false
+	}
+	for i := range stops {
+		if stops[i].ID != existing[i].ID {
+			return false
+		}
+	}
+	return true
+}
+
+// validateRouteStops checks stops form a valid list for route and numbers
+// them in order
+func validateRouteStops(route *entities.Route, stops []entities.RouteStop) error {
+	seen := make(map[uuid.UUID]bool, len(stops))
+	previousOffset := 0
+	previousDistance := 0.0
+	for i := range stops {
+		stop := &stops[i]
+		stop.Name = strings.TrimSpace(stop.Name)
+		stop.City = strings.TrimSpace(stop.City)
+		stop.Address = strings.TrimSpace(stop.Address)
+
+		if stop.ID != uuid.Nil {
+			if route.Stop(stop.ID) == nil {
+				return fmt.Errorf("stop %s is not on this route", stop.ID)
+			}
+			if seen[stop.ID] {
+				return fmt.Errorf("stop %s is listed twice", stop.ID)
+			}
+			seen[stop.ID] = true
+		}
+		if stop.Name == "" {
+			return fmt.Errorf("stop %d: name is required", i+1)
+		}
+		if stop.City == "" {
+			return fmt.Errorf("stop %s: city is required", stop.Name)
+		}
+		if (stop.Latitude == nil) != (stop.Longitude == nil) {
+			return fmt.Errorf("stop %s: latitude and longitude must be given together", stop.Name)
+		}
+		if stop.Latitude != nil && (*stop.Latitude < -90 || *stop.Latitude > 90 || *stop.Longitude < -180 || *stop.Longitude > 180) {
+			return fmt.Errorf("stop %s: coordinates are out of range", stop.Name)
+		}
+		if stop.Offset < previousOffset {
+			return fmt.Errorf("stop %s: offset must not be before the previous stop's", stop.Name)
+		}
+		if stop.Distance < previousDistance || stop.Distance > route.Distance {
+			return fmt.Errorf("stop %s: distance must be between the previous stop's and the route's", stop.Name)
+		}
+		if !stop.AllowsPickup && !stop.AllowsDropoff {
+			return fmt.Errorf("stop %s must allow pickup, drop-off or both", stop.Name)
+		}
+
+		previousOffset = stop.Offset
+		previousDistance = stop.Distance
+		stop.Sequence = i + 1
+		stop.RouteID = route.ID
+	}
+	return nil
 }
@@ -0,0 +1,453 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+)
+
+// scheduleLookaround is how far before and after a trip other trips are looked
+// at when checking turnaround and continuity
+const scheduleLookaround = 48 * time.Hour
+
+// drivingWeek is the span of the weekly driving limit
+const drivingWeek = 7 * 24 * time.Hour
+
+// ErrScheduleConflict is wrapped by every ScheduleConflictError
+var ErrScheduleConflict = errors.New("trip conflicts with the schedule")
+
+// ScheduleConflictError is returned when a trip cannot be scheduled because it
+// clashes with other trips of the same bus or driver, or would break a driving limit
+type ScheduleConflictError struct {
+	Conflicts []entities.ScheduleConflict
+}
+
+func (e *ScheduleConflictError) Error() string {
+	if len(e.Conflicts) == 0 {
+		return ErrScheduleConflict.Error()
+	}
+	if len(e.Conflicts) == 1 {
+		return e.Conflicts[0].Message
+	}
+	return fmt.Sprintf("%s (and %d more conflicts)", e.Conflicts[0].Message, len(e.Conflicts)-1)
+}
+
+func (e *ScheduleConflictError) Unwrap() error {
+	return ErrScheduleConflict
+}
+
+// DrivingLimits are the hours-of-service rules for drivers; a zero limit is
+// switched off. Days and weeks are counted in the business timezone, weeks
+// from Monday, and a trip counts towards the day it departs on.
+type DrivingLimits struct {
+	MaxContinuous time.Duration // At the wheel without a break
+	MinBreak      time.Duration // Shortest stop that counts as a break
+	MaxDaily      time.Duration
+	MaxWeekly     time.Duration
+}
+
+// ScheduleConflictReport lists the conflicts between existing trips in a period
+type ScheduleConflictReport struct {
+	From         time.Time                   `json:"from"`
+	To           time.Time                   `json:"to"`
+	TripsChecked int                         `json:"trips_checked"`
+	Conflicts    []entities.ScheduleConflict `json:"conflicts"`
+}
+
+// ScheduleConflictUsecase keeps buses and drivers from being given trips they
+// cannot run: trips at the same time, trips too close together to turn around,
+// and trips departing from another city than the one they were left in.
+// Drivers must also hold a licence for the bus and stay within DrivingLimits.
+type ScheduleConflictUsecase struct {
+	tripRepo      repositories.TripRepository
+	routeRepo     repositories.RouteRepository
+	busRepo       repositories.BusRepository
+	driverRepo    repositories.DriverRepository
+	minTurnaround time.Duration
+	limits        DrivingLimits
+	location      *time.Location // Business timezone driving days and weeks are counted in
+}
+
+func NewScheduleConflictUsecase(
+	tripRepo repositories.TripRepository,
+	routeRepo repositories.RouteRepository,
+	busRepo repositories.BusRepository,
+	driverRepo repositories.DriverRepository,
+	minTurnaround time.Duration,
+	limits DrivingLimits,
+	location *time.Location,
+) *ScheduleConflictUsecase {
+	return &ScheduleConflictUsecase{
+		tripRepo:      tripRepo,
+		routeRepo:     routeRepo,
+		busRepo:       busRepo,
+		driverRepo:    driverRepo,
+		minTurnaround: minTurnaround,
+		limits:        limits,
+		location:      location,
+	}
+}
+
+// CheckTrip checks a trip, saved or not, against the other trips of its bus
+// and of its drivers, and returns a ScheduleConflictError listing every clash
+func (uc *ScheduleConflictUsecase) CheckTrip(ctx context.Context, trip *entities.Trip) error {
+	// Work on a copy so what is loaded here is not saved along with the trip
+	candidate := *trip
+	if candidate.Route == nil || candidate.Route.ID != candidate.RouteID {
+		route, err := uc.routeRepo.GetByID(ctx, candidate.RouteID)
+		if err != nil {
+			return fmt.Errorf("route not found: %w", err)
+		}
+		candidate.Route = route
+	}
+	if candidate.Bus == nil || candidate.Bus.ID != candidate.BusID {
+		bus, err := uc.busRepo.GetByID(ctx, candidate.BusID)
+		if err != nil {
+			return fmt.Errorf("bus not found: %w", err)
+		}
+		candidate.Bus = bus
+	}
+
+	others, err := uc.tripRepo.GetBusTrips(ctx, candidate.BusID,
+		candidate.DepartureTime.Add(-scheduleLookaround), candidate.ArrivalTime.Add(scheduleLookaround))
+	if err != nil {
+		return fmt.Errorf("failed to check bus schedule: %w", err)
+	}
+	conflicts := uc.tripConflicts(entities.ScheduleResourceBus, candidate.BusID, &candidate, others, uc.minTurnaround)
+
+	for _, driverID := range tripDriverIDs(&candidate) {
+		driver, err := uc.driverRepo.GetByID(ctx, driverID)
+		if err != nil {
+			return fmt.Errorf("driver not found: %w", err)
+		}
+		from, to := driverWindow(candidate.DepartureTime, candidate.ArrivalTime)
+		others, err := uc.tripRepo.GetDriverTrips(ctx, driverID, from, to)
+		if err != nil {
+			return fmt.Errorf("failed to check driver schedule: %w", err)
+		}
+		conflicts = append(conflicts, uc.driverConflicts(driver, &candidate, others)...)
+	}
+
+	if len(conflicts) > 0 {
+		return &ScheduleConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// Report finds the conflicts between trips overlapping the period from to to.
+// Each clashing pair is reported once, on the earlier trip, and each broken
+// driving limit once per driver and period.
+func (uc *ScheduleConflictUsecase) Report(ctx context.Context, from, to time.Time) (*ScheduleConflictReport, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("to must be after from")
+	}
+
+	loadFrom, loadTo := driverWindow(from, to)
+	trips, err := uc.tripRepo.GetTripsBetween(ctx, loadFrom, loadTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trips: %w", err)
+	}
+
+	// Trips just outside the period only serve as neighbours
+	inPeriod := func(trip *entities.Trip) bool {
+		return trip.DepartureTime.Before(to) && trip.ArrivalTime.After(from)
+	}
+
+	report := &ScheduleConflictReport{From: from, To: to, Conflicts: []entities.ScheduleConflict{}}
+	byBus := make(map[uuid.UUID][]*entities.Trip)
+	byDriver := make(map[uuid.UUID][]*entities.Trip)
+	var buses, drivers []uuid.UUID
+	for _, trip := range trips {
+		if inPeriod(trip) {
+			report.TripsChecked++
+		}
+		if _, ok := byBus[trip.BusID]; !ok {
+			buses = append(buses, trip.BusID)
+		}
+		byBus[trip.BusID] = append(byBus[trip.BusID], trip)
+		for _, driverID := range tripDriverIDs(trip) {
+			if _, ok := byDriver[driverID]; !ok {
+				drivers = append(drivers, driverID)
+			}
+			byDriver[driverID] = append(byDriver[driverID], trip)
+		}
+	}
+
+	for _, busID := range buses {
+		busTrips := byBus[busID]
+		report.Conflicts = append(report.Conflicts, reportConflicts(busTrips, inPeriod, func(trip *entities.Trip) []entities.ScheduleConflict {
+			return uc.tripConflicts(entities.ScheduleResourceBus, busID, trip, busTrips, uc.minTurnaround)
+		})...)
+	}
+	for _, driverID := range drivers {
+		driver, err := uc.driverRepo.GetByID(ctx, driverID)
+		if err != nil {
+			return nil, fmt.Errorf("driver %s not found: %w", driverID, err)
+		}
+		driverTrips := byDriver[driverID]
+		report.Conflicts = append(report.Conflicts, reportConflicts(driverTrips, inPeriod, func(trip *entities.Trip) []entities.ScheduleConflict {
+			return uc.driverConflicts(driver, trip, driverTrips)
+		})...)
+	}
+	return report, nil
+}
+
+// reportConflicts runs check on the trips of one resource that are in the
+// period, dropping clashes already reported on an earlier trip. trips must be
+// in order of departure.
+func reportConflicts(trips []*entities.Trip, inPeriod func(trip *entities.Trip) bool, check func(trip *entities.Trip) []entities.ScheduleConflict) []entities.ScheduleConflict {
+	order := make(map[uuid.UUID]int, len(trips))
+	for i, trip := range trips {
+		order[trip.ID] = i
+	}
+
+	var conflicts []entities.ScheduleConflict
+	seen := make(map[string]bool)
+	for i, trip := range trips {
+		if !inPeriod(trip) {
+			continue
+		}
+		for _, conflict := range check(trip) {
+			if conflict.ConflictingTripID != nil {
+				// Reported already from the other trip if it came earlier and
+				// was checked too
+				if idx, ok := order[*conflict.ConflictingTripID]; ok && idx < i && inPeriod(trips[idx]) {
+					continue
+				}
+			} else if conflict.Period != "" {
+				key := string(conflict.Kind) + "/" + conflict.Period
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts
+}
+
+// tripConflicts checks trip against others of the same resource: any of them
+// running at the same time, and the handovers from the trip before it and to
+// the trip after it, which need at least minGap between them
+func (uc *ScheduleConflictUsecase) tripConflicts(resource entities.ScheduleResource, resourceID uuid.UUID, trip *entities.Trip, others []*entities.Trip, minGap time.Duration) []entities.ScheduleConflict {
+	var conflicts []entities.ScheduleConflict
+	var before, after *entities.Trip
+	for _, other := range others {
+		if other.ID == trip.ID || other.Status == entities.TripStatusCancelled {
+			continue
+		}
+		switch {
+		case other.DepartureTime.Before(trip.ArrivalTime) && other.ArrivalTime.After(trip.DepartureTime):
+			conflicts = append(conflicts, newScheduleConflict(entities.ScheduleConflictOverlap, resource, resourceID, trip, other,
+				fmt.Sprintf("%s is already on trip %s from %s to %s", resource, other.ID,
+					other.DepartureTime.Format(time.RFC3339), other.ArrivalTime.Format(time.RFC3339))))
+		case !other.ArrivalTime.After(trip.DepartureTime):
+			if before == nil || other.ArrivalTime.After(before.ArrivalTime) {
+				before = other
+			}
+		default:
+			if after == nil || other.DepartureTime.Before(after.DepartureTime) {
+				after = other
+			}
+		}
+	}
+
+	if before != nil {
+		conflicts = append(conflicts, handoverConflicts(resource, resourceID, trip, before, trip, minGap)...)
+	}
+	if after != nil {
+		conflicts = append(conflicts, handoverConflicts(resource, resourceID, trip, trip, after, minGap)...)
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].Kind == entities.ScheduleConflictOverlap && conflicts[j].Kind != entities.ScheduleConflictOverlap
+	})
+	return conflicts
+}
+
+// handoverConflicts checks that the resource has minGap to turn around between
+// two consecutive trips and is left where the later one departs from
+func handoverConflicts(resource entities.ScheduleResource, resourceID uuid.UUID, trip, earlier, later *entities.Trip, minGap time.Duration) []entities.ScheduleConflict {
+	other := later
+	if later == trip {
+		other = earlier
+	}
+
+	var conflicts []entities.ScheduleConflict
+	if gap := later.DepartureTime.Sub(earlier.ArrivalTime); gap < minGap {
+		conflicts = append(conflicts, newScheduleConflict(entities.ScheduleConflictTurnaround, resource, resourceID, trip, other,
+			fmt.Sprintf("%s has %s between trip %s arriving and the next departure, at least %s is needed",
+				resource, gap, describeTrip(earlier), minGap)))
+	}
+	if earlier.Route != nil && later.Route != nil && earlier.Route.ToLocationID != later.Route.FromLocationID {
+		conflicts = append(conflicts, newScheduleConflict(entities.ScheduleConflictContinuity, resource, resourceID, trip, other,
+			fmt.Sprintf("%s ends trip %s in %s but trip %s departs from %s",
+				resource, describeTrip(earlier), earlier.Route.ToCity, describeTrip(later), later.Route.FromCity)))
+	}
+	return conflicts
+}
+
+// driverConflicts checks a driver's licence for the trip's bus, the trip
+// against the driver's other trips, and the driving limits
+func (uc *ScheduleConflictUsecase) driverConflicts(driver *entities.Driver, trip *entities.Trip, others []*entities.Trip) []entities.ScheduleConflict {
+	var conflicts []entities.ScheduleConflict
+	broken := func(kind entities.ScheduleConflictKind, period, message string) {
+		conflict := newScheduleConflict(kind, entities.ScheduleResourceDriver, driver.ID, trip, nil, message)
+		conflict.Period = period
+		conflicts = append(conflicts, conflict)
+	}
+
+	if trip.Bus != nil {
+		required := entities.RequiredLicenceClass(trip.Bus.SeatLayout.TotalSeats)
+		if !driver.LicenceClass.Covers(required) {
+			broken(entities.ScheduleConflictLicence, "",
+				fmt.Sprintf("driver %s holds a class %s licence but bus %s with %d seats needs class %s",
+					driver.FullName, driver.LicenceClass, trip.Bus.LicensePlate, trip.Bus.SeatLayout.TotalSeats, required))
+		}
+	}
+	if driver.LicenceExpiry.Before(trip.ArrivalTime) {
+		broken(entities.ScheduleConflictLicence, "",
+			fmt.Sprintf("licence of driver %s expires on %s, before the trip ends",
+				driver.FullName, driver.LicenceExpiry.In(uc.location).Format(entities.ScheduleDateLayout)))
+	}
+
+	conflicts = append(conflicts, uc.tripConflicts(entities.ScheduleResourceDriver, driver.ID, trip, others, uc.limits.MinBreak)...)
+
+	var rest []*entities.Trip
+	for _, other := range others {
+		if other.ID != trip.ID && other.Status != entities.TripStatusCancelled {
+			rest = append(rest, other)
+		}
+	}
+
+	if uc.limits.MaxContinuous > 0 && trip.CoDriverID == nil {
+		start, stretch := uc.continuousDriving(trip, rest)
+		if stretch > uc.limits.MaxContinuous {
+			broken(entities.ScheduleConflictContinuousDriving, start.Format(time.RFC3339),
+				fmt.Sprintf("driver %s would drive %s from %s without a break of %s, at most %s is allowed; add a co-driver or a break",
+					driver.FullName, stretch, start.In(uc.location).Format(notificationTimeLayout), uc.limits.MinBreak, uc.limits.MaxContinuous))
+		}
+	}
+
+	day := uc.drivingDay(trip.DepartureTime)
+	week := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	daily, weekly := trip.DrivingTime(), trip.DrivingTime()
+	for _, other := range rest {
+		otherDay := uc.drivingDay(other.DepartureTime)
+		if otherDay.Equal(day) {
+			daily += other.DrivingTime()
+		}
+		if !otherDay.Before(week) && otherDay.Before(week.AddDate(0, 0, 7)) {
+			weekly += other.DrivingTime()
+		}
+	}
+	if uc.limits.MaxDaily > 0 && daily > uc.limits.MaxDaily {
+		broken(entities.ScheduleConflictDailyDriving, day.Format(entities.ScheduleDateLayout),
+			fmt.Sprintf("driver %s would drive %s on %s, at most %s is allowed",
+				driver.FullName, daily, day.Format(entities.ScheduleDateLayout), uc.limits.MaxDaily))
+	}
+	if uc.limits.MaxWeekly > 0 && weekly > uc.limits.MaxWeekly {
+		broken(entities.ScheduleConflictWeeklyDriving, week.Format(entities.ScheduleDateLayout),
+			fmt.Sprintf("driver %s would drive %s in the week of %s, at most %s is allowed",
+				driver.FullName, weekly, week.Format(entities.ScheduleDateLayout), uc.limits.MaxWeekly))
+	}
+	return conflicts
+}
+
+// continuousDriving returns when the stretch of driving the trip is part of
+// starts and how long it is. Trips driven alone run into each other when less
+// than MinBreak lies between them; on trips with a co-driver the drivers take
+// turns at the wheel, so those end a stretch.
+func (uc *ScheduleConflictUsecase) continuousDriving(trip *entities.Trip, others []*entities.Trip) (time.Time, time.Duration) {
+	timeline := append([]*entities.Trip{trip}, others...)
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].DepartureTime.Before(timeline[j].DepartureTime)
+	})
+
+	at := 0
+	for i, t := range timeline {
+		if t == trip {
+			at = i
+		}
+	}
+
+	first, last := at, at
+	for first > 0 {
+		prev := timeline[first-1]
+		if prev.CoDriverID != nil || timeline[first].DepartureTime.Sub(prev.ArrivalTime) >= uc.limits.MinBreak {
+			break
+		}
+		first--
+	}
+	for last < len(timeline)-1 {
+		next := timeline[last+1]
+		if next.CoDriverID != nil || next.DepartureTime.Sub(timeline[last].ArrivalTime) >= uc.limits.MinBreak {
+			break
+		}
+		last++
+	}
+
+	var stretch time.Duration
+	for _, t := range timeline[first : last+1] {
+		stretch += t.DrivingTime()
+	}
+	return timeline[first].DepartureTime, stretch
+}
+
+// drivingDay returns midnight of the business day t falls on
+func (uc *ScheduleConflictUsecase) drivingDay(t time.Time) time.Time {
+	local := t.In(uc.location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, uc.location)
+}
+
+// driverWindow widens from to to so that it covers the neighbouring trips and
+// whole driving weeks
+func driverWindow(from, to time.Time) (time.Time, time.Time) {
+	return from.Add(-drivingWeek - scheduleLookaround), to.Add(drivingWeek + scheduleLookaround)
+}
+
+// tripDriverIDs returns the main driver and co-driver of the trip that are set
+func tripDriverIDs(trip *entities.Trip) []uuid.UUID {
+	var ids []uuid.UUID
+	if trip.DriverID != nil {
+		ids = append(ids, *trip.DriverID)
+	}
+	if trip.CoDriverID != nil {
+		ids = append(ids, *trip.CoDriverID)
+	}
+	return ids
+}
+
+// describeTrip names a trip in a conflict message; one not saved yet is "new"
+func describeTrip(trip *entities.Trip) string {
+	if trip.ID == uuid.Nil {
+		return "new"
+	}
+	return trip.ID.String()
+}
+
+// newScheduleConflict describes a clash of trip; other is the trip it clashes
+// with, if any
+func newScheduleConflict(kind entities.ScheduleConflictKind, resource entities.ScheduleResource, resourceID uuid.UUID, trip, other *entities.Trip, message string) entities.ScheduleConflict {
+	conflict := entities.ScheduleConflict{
+		Kind:       kind,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Message:    message,
+	}
+	if trip.ID != uuid.Nil {
+		tripID := trip.ID
+		conflict.TripID = &tripID
+	}
+	if other != nil {
+		otherID := other.ID
+		conflict.ConflictingTripID = &otherID
+	}
+	return conflict
+}
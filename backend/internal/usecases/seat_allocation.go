@@ -0,0 +1,196 @@
+package usecases
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yourusername/bus-booking/internal/entities"
+)
+
+// SeatPreferences steer automatic seat assignment; they are weighed against
+// keeping the group together rather than enforced
+type SeatPreferences struct {
+	Window         bool `json:"window"`
+	LowerDeck      bool `json:"lower_deck"`
+	Front          bool `json:"front"`
+	AwayFromToilet bool `json:"away_from_toilet"`
+}
+
+// SeatAllocation is the outcome of automatic seat assignment
+type SeatAllocation struct {
+	Seats    []string `json:"seats"`
+	Together bool     `json:"together"` // false if the group had to be split up, or the bus has no seat layout to tell
+}
+
+// Seat distances: how far apart two seats feel to a travelling group
+const (
+	seatDistanceSideBySide  = 1.0 // Same row, same side of the aisle, next to each other
+	seatDistanceAcrossAisle = 2.0
+	seatDistanceFacing      = 2.0 // Berths stacked on the two decks of a sleeper
+	seatDistancePerRow      = 3.0 // Directly in front or behind
+	seatDistanceOtherDeck   = 6.0
+
+	// Links up to this distance still count as sitting together
+	seatDistanceTogether = seatDistancePerRow
+)
+
+// Preference penalties per seat
+const (
+	penaltyNotWindow   = 2.0
+	penaltyUpperDeck   = 4.0
+	penaltyNearToilet  = 4.0
+	penaltyPerRowFront = 0.5
+	penaltyPerRow      = 0.01 // Without a preference, fill from the front so results are stable
+)
+
+// allocateSeats picks count seats out of the available ones, preferring a
+// tight group close to the preferences. Groups are grown from every possible
+// starting seat by adding the nearest free seat, and the cheapest group wins;
+// when the bus is fragmented this degrades to the nearest scattered seats.
+// Seats missing from the layout are allocated in seat number order.
+func allocateSeats(layout entities.SeatLayout, available []string, count int, prefs SeatPreferences) (*SeatAllocation, bool) {
+	if count <= 0 || len(available) < count {
+		return nil, false
+	}
+
+	positions := layout.Positions()
+	candidates := make([]entities.SeatPosition, 0, len(available))
+	for _, seatNum := range available {
+		if pos, ok := positions[seatNum]; ok {
+			candidates = append(candidates, pos)
+		}
+	}
+
+	if len(candidates) < count {
+		// No usable layout: neighbouring seat numbers are usually neighbouring seats
+		seats := append([]string(nil), available...)
+		sort.Slice(seats, func(i, j int) bool { return seatNumberLess(seats[i], seats[j]) })
+		return &SeatAllocation{Seats: seats[:count], Together: false}, true
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return seatNumberLess(candidates[i].SeatNumber, candidates[j].SeatNumber)
+	})
+
+	var best []entities.SeatPosition
+	bestCost, bestTogether := math.Inf(1), false
+	for seed := range candidates {
+		group, cost, together := growSeatGroup(candidates, seed, count, prefs)
+		if cost < bestCost {
+			best, bestCost, bestTogether = group, cost, together
+		}
+	}
+
+	seats := make([]string, 0, count)
+	for _, pos := range best {
+		seats = append(seats, pos.SeatNumber)
+	}
+	sort.Slice(seats, func(i, j int) bool { return seatNumberLess(seats[i], seats[j]) })
+
+	return &SeatAllocation{Seats: seats, Together: bestTogether}, true
+}
+
+// growSeatGroup builds a group from candidates[seed] by repeatedly adding the
+// free seat with the lowest distance to the group plus preference penalty
+func growSeatGroup(candidates []entities.SeatPosition, seed, count int, prefs SeatPreferences) ([]entities.SeatPosition, float64, bool) {
+	taken := make([]bool, len(candidates))
+	taken[seed] = true
+	group := []entities.SeatPosition{candidates[seed]}
+	cost := seatPenalty(candidates[seed], prefs)
+	together := true
+
+	for len(group) < count {
+		next, nextCost, nextLink := -1, math.Inf(1), 0.0
+		for i, candidate := range candidates {
+			if taken[i] {
+				continue
+			}
+
+			link := math.Inf(1)
+			for _, member := range group {
+				link = math.Min(link, seatDistance(member, candidate))
+			}
+
+			if c := link + seatPenalty(candidate, prefs); c < nextCost {
+				next, nextCost, nextLink = i, c, link
+			}
+		}
+
+		taken[next] = true
+		group = append(group, candidates[next])
+		cost += nextCost
+		if nextLink > seatDistanceTogether {
+			together = false
+		}
+	}
+
+	return group, cost, together
+}
+
+// seatDistance measures how far apart two seats are for people travelling together
+func seatDistance(a, b entities.SeatPosition) float64 {
+	rows := math.Abs(float64(a.Row - b.Row))
+	cols := math.Abs(float64(a.Column - b.Column))
+
+	if a.Floor != b.Floor {
+		if rows == 0 && cols == 0 {
+			return seatDistanceFacing
+		}
+		return seatDistanceOtherDeck + rows*seatDistancePerRow + cols
+	}
+
+	if rows == 0 {
+		if a.Side == b.Side {
+			return seatDistanceSideBySide * cols
+		}
+		// The aisle itself takes a column
+		return seatDistanceAcrossAisle + math.Max(0, cols-2)
+	}
+
+	return rows*seatDistancePerRow + cols
+}
+
+func seatPenalty(pos entities.SeatPosition, prefs SeatPreferences) float64 {
+	penalty := penaltyPerRow * float64(pos.Row)
+	if prefs.Window && !pos.Window {
+		penalty += penaltyNotWindow
+	}
+	if prefs.LowerDeck && pos.Floor > 1 {
+		penalty += penaltyUpperDeck
+	}
+	if prefs.Front {
+		penalty += penaltyPerRowFront * float64(pos.Row)
+	}
+	if prefs.AwayFromToilet && pos.NearToilet {
+		penalty += penaltyNearToilet
+	}
+	return penalty
+}
+
+// seatNumberLess orders seat numbers naturally, so "A2" comes before "A10"
+func seatNumberLess(a, b string) bool {
+	prefixA, numA := splitSeatNumber(a)
+	prefixB, numB := splitSeatNumber(b)
+	if prefixA != prefixB {
+		return prefixA < prefixB
+	}
+	if numA != numB {
+		return numA < numB
+	}
+	return a < b
+}
+
+func splitSeatNumber(seat string) (string, int) {
+	i := strings.IndexFunc(seat, unicode.IsDigit)
+	if i < 0 {
+		return seat, 0
+	}
+	n, err := strconv.Atoi(seat[i:])
+	if err != nil {
+		return seat, 0
+	}
+	return seat[:i], n
+}
@@ -0,0 +1,173 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+	"github.com/yourusername/bus-booking/internal/repositories/cache"
+)
+
+// ErrSeatNotBlockable is returned when a seat cannot be blocked because it is sold or held
+var ErrSeatNotBlockable = errors.New("seat is booked or held by a customer")
+
+// SeatBlockUsecase lets operators keep seats of a trip off sale
+type SeatBlockUsecase struct {
+	tx         repositories.Transactor
+	outboxRepo repositories.OutboxRepository
+	tripRepo   repositories.TripRepository
+	seatRepo   repositories.SeatRepository
+	cache      *cache.RedisCache
+}
+
+func NewSeatBlockUsecase(
+	tx repositories.Transactor,
+	outboxRepo repositories.OutboxRepository,
+	tripRepo repositories.TripRepository,
+	seatRepo repositories.SeatRepository,
+	cache *cache.RedisCache,
+) *SeatBlockUsecase {
+	return &SeatBlockUsecase{
+		tx:         tx,
+		outboxRepo: outboxRepo,
+		tripRepo:   tripRepo,
+		seatRepo:   seatRepo,
+		cache:      cache,
+	}
+}
+
+// BlockSeatsInput describes seats to take off sale; without Until the block
+// lasts until the seats are unblocked
+type BlockSeatsInput struct {
+	TripID      uuid.UUID
+	SeatNumbers []string
+	Reason      string
+	Until       *time.Time
+	BlockedBy   *uuid.UUID
+}
+
+func (uc *SeatBlockUsecase) BlockSeats(ctx context.Context, input BlockSeatsInput) error {
+	if len(input.SeatNumbers) == 0 {
+		return fmt.Errorf("no seats selected")
+	}
+	if input.Reason == "" {
+		return fmt.Errorf("a reason is required to block seats")
+	}
+	if input.Until != nil && !input.Until.After(time.Now()) {
+		return fmt.Errorf("block must end in the future")
+	}
+
+	trip, err := uc.tripRepo.GetByID(ctx, input.TripID)
+	if err != nil {
+		return fmt.Errorf("trip not found: %w", err)
+	}
+	if trip.Status == entities.TripStatusCompleted || trip.Status == entities.TripStatusCancelled {
+		return fmt.Errorf("cannot block seats of a %s trip", trip.Status)
+	}
+
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.seatRepo.BlockSeats(ctx, trip.ID, input.SeatNumbers, input.Reason, input.Until, input.BlockedBy); err != nil {
+			if errors.Is(err, repositories.ErrSeatUnavailable) {
+				return fmt.Errorf("%w: %v", ErrSeatNotBlockable, err)
+			}
+			return err
+		}
+
+		return recordSeatBlockEvent(ctx, uc.outboxRepo, entities.EventSeatsBlocked, entities.SeatBlockEventPayload{
+			TripID:       trip.ID,
+			Seats:        input.SeatNumbers,
+			SeatStatus:   entities.SeatStatusBlocked,
+			Reason:       input.Reason,
+			BlockedUntil: input.Until,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	_ = uc.cache.InvalidateTripSeats(ctx, trip.ID)
+	return nil
+}
+
+// UnblockSeats puts blocked seats back on sale and returns the seats that were blocked
+func (uc *SeatBlockUsecase) UnblockSeats(ctx context.Context, tripID uuid.UUID, seatNumbers []string) ([]string, error) {
+	if len(seatNumbers) == 0 {
+		return nil, fmt.Errorf("no seats selected")
+	}
+
+	var unblocked []string
+	err := uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		unblocked, err = uc.seatRepo.UnblockSeats(ctx, tripID, seatNumbers)
+		if err != nil || len(unblocked) == 0 {
+			return err
+		}
+
+		return recordSeatBlockEvent(ctx, uc.outboxRepo, entities.EventSeatsUnblocked, entities.SeatBlockEventPayload{
+			TripID:     tripID,
+			Seats:      unblocked,
+			SeatStatus: entities.SeatStatusAvailable,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(unblocked) > 0 {
+		_ = uc.cache.InvalidateTripSeats(ctx, tripID)
+	}
+	return unblocked, nil
+}
+
+// UnblockExpiredSeats puts seats whose block has run out back on sale
+func (uc *SeatBlockUsecase) UnblockExpiredSeats(ctx context.Context) error {
+	var seats []*entities.SeatInfo
+	err := uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		seats, err = uc.seatRepo.UnblockExpiredSeats(ctx)
+		if err != nil {
+			return err
+		}
+
+		seatsByTrip := make(map[uuid.UUID][]string)
+		for _, seat := range seats {
+			seatsByTrip[seat.TripID] = append(seatsByTrip[seat.TripID], seat.SeatNumber)
+		}
+		for tripID, seatNumbers := range seatsByTrip {
+			err := recordSeatBlockEvent(ctx, uc.outboxRepo, entities.EventSeatsUnblocked, entities.SeatBlockEventPayload{
+				TripID:     tripID,
+				Seats:      seatNumbers,
+				SeatStatus: entities.SeatStatusAvailable,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidated := make(map[uuid.UUID]bool)
+	for _, seat := range seats {
+		if !invalidated[seat.TripID] {
+			invalidated[seat.TripID] = true
+			_ = uc.cache.InvalidateTripSeats(ctx, seat.TripID)
+		}
+	}
+	if len(seats) > 0 {
+		log.Printf("Unblocked %d seats whose block expired", len(seats))
+	}
+	return nil
+}
+
+// recordSeatBlockEvent stores a seat block change of a trip in the outbox
+func recordSeatBlockEvent(ctx context.Context, outboxRepo repositories.OutboxRepository, eventType entities.EventType, payload entities.SeatBlockEventPayload) error {
+	return recordEvent(ctx, outboxRepo, entities.AggregateTrip, payload.TripID, eventType, payload)
+}
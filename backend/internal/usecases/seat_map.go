@@ -0,0 +1,221 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+)
+
+// seatMapTTL bounds how stale a seat map can get when an invalidation is missed
+const seatMapTTL = 30 * time.Second
+
+// GetSeatMap returns the seat map of a trip from the pickup stop to the
+// drop-off stop, where nil means the origin or destination. The map of the
+// whole trip comes from the cached read model. Given the code of a pending
+// booking on the trip, the seats it holds are marked as held by the caller.
+func (uc *BookingUsecase) GetSeatMap(ctx context.Context, tripID uuid.UUID, pickupStopID, dropoffStopID *uuid.UUID, bookingCode string) (*entities.SeatMap, error) {
+	wholeTrip := pickupStopID == nil && dropoffStopID == nil
+	var seatMap *entities.SeatMap
+	if wholeTrip {
+		seatMap, _ = uc.cache.GetSeatMap(ctx, tripID)
+	}
+	if seatMap == nil {
+		trip, err := uc.tripRepo.GetByIDWithDetails(ctx, tripID)
+		if err != nil {
+			return nil, fmt.Errorf("trip not found: %w", err)
+		}
+		if err := validateBoardingStops(trip.Route, pickupStopID, dropoffStopID); err != nil {
+			return nil, err
+		}
+		seats, err := uc.seatRepo.GetAllByTrip(ctx, tripID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load seats: %w", err)
+		}
+
+		seatMap = buildSeatMap(trip, trip.Route.StopRange(pickupStopID, dropoffStopID), seats, time.Now())
+		if wholeTrip {
+			_ = uc.cache.CacheSeatMap(ctx, seatMap, seatMapTTL)
+		}
+	}
+
+	if bookingCode == "" {
+		return seatMap, nil
+	}
+
+	// An unknown or finished booking holds nothing, so the map is simply not personalised
+	booking, err := uc.bookingRepo.GetByCode(ctx, bookingCode)
+	if err != nil || booking.TripID != tripID || booking.Status != entities.BookingStatusPending || booking.IsExpired() {
+		return seatMap, nil
+	}
+	// Outside the booking's own range a locked seat may be someone else's
+	if held := booking.StopRange(); held.From > seatMap.From || held.To < seatMap.To {
+		return seatMap, nil
+	}
+	markHeldSeats(seatMap, booking.Seats)
+	return seatMap, nil
+}
+
+// buildSeatMap lays the trip's seats on the stop range out on its bus's seat layout
+func buildSeatMap(trip *entities.Trip, stops entities.StopRange, seats []*entities.SeatInfo, now time.Time) *entities.SeatMap {
+	seats = seatsOnRange(seats, stops, now)
+	price := trip.PriceFor(stops)
+	seatMap := &entities.SeatMap{
+		TripID:      trip.ID,
+		BusID:       trip.BusID,
+		StopRange:   stops,
+		Floors:      []entities.SeatMapFloor{},
+		TotalSeats:  len(seats),
+		GeneratedAt: now,
+	}
+
+	cells := make(map[string]entities.SeatMapCell, len(seats))
+	for _, seat := range seats {
+		cell := entities.SeatMapCell{
+			Type:       entities.SeatMapCellSeat,
+			SeatNumber: seat.SeatNumber,
+			Status:     seat.Status,
+			Price:      price, // Every seat sells at the trip's fare for the range
+		}
+		switch {
+		case seat.Status == entities.SeatStatusLocked && (seat.LockedUntil == nil || !now.Before(*seat.LockedUntil)):
+			cell.Status = entities.SeatStatusAvailable
+		case seat.Status == entities.SeatStatusLocked:
+			cell.LockedUntil = seat.LockedUntil
+		case seat.Status == entities.SeatStatusBlocked:
+			cell.BlockReason = seat.BlockReason
+			cell.BlockedUntil = seat.BlockedUntil
+		}
+		if cell.Status == entities.SeatStatusAvailable {
+			seatMap.AvailableSeats++
+		}
+		cells[seat.SeatNumber] = cell
+	}
+
+	if trip.Bus != nil {
+		seatMap.Columns = trip.Bus.SeatLayout.Columns
+		for i, deck := range trip.Bus.SeatLayout.Decks() {
+			floor := entities.SeatMapFloor{Floor: i + 1, Rows: make([][]entities.SeatMapCell, len(deck))}
+			for r, row := range deck {
+				floor.Rows[r] = make([]entities.SeatMapCell, len(row))
+				for c, layoutCell := range row {
+					if !entities.IsSeatCell(layoutCell) {
+						if layoutCell == "" {
+							layoutCell = entities.SeatCellEmpty
+						}
+						floor.Rows[r][c] = entities.SeatMapCell{Type: layoutCell}
+						continue
+					}
+					cell, ok := cells[layoutCell]
+					if !ok {
+						// In the layout but not sold on this trip
+						cell = entities.SeatMapCell{Type: entities.SeatCellEmpty}
+					}
+					floor.Rows[r][c] = cell
+					delete(cells, layoutCell)
+				}
+			}
+			seatMap.Floors = append(seatMap.Floors, floor)
+		}
+	}
+
+	// Seats whose layout changed after the trip was created
+	for _, cell := range cells {
+		seatMap.Unplaced = append(seatMap.Unplaced, cell)
+	}
+	sort.Slice(seatMap.Unplaced, func(i, j int) bool {
+		return seatNumberLess(seatMap.Unplaced[i].SeatNumber, seatMap.Unplaced[j].SeatNumber)
+	})
+
+	seatMap.Version = seatMapVersion(seatMap)
+	return seatMap
+}
+
+// seatsOnRange folds the per-segment rows of a trip's seats into one row per
+// seat for the stop range, each the most taken of the seat's segments so a
+// seat shows as available only when it is free all the way
+func seatsOnRange(seats []*entities.SeatInfo, stops entities.StopRange, now time.Time) []*entities.SeatInfo {
+	rank := func(seat *entities.SeatInfo) int {
+		switch {
+		case seat.Status == entities.SeatStatusBooked:
+			return 3
+		case seat.Status == entities.SeatStatusBlocked:
+			return 2
+		case seat.Status == entities.SeatStatusLocked && seat.LockedUntil != nil && now.Before(*seat.LockedUntil):
+			return 1
+		}
+		return 0
+	}
+
+	folded := make([]*entities.SeatInfo, 0, len(seats))
+	index := make(map[string]int, len(seats))
+	for _, seat := range seats {
+		if !stops.Covers(seat.Segment) {
+			continue
+		}
+		i, ok := index[seat.SeatNumber]
+		switch {
+		case !ok:
+			index[seat.SeatNumber] = len(folded)
+			folded = append(folded, seat)
+		case rank(seat) > rank(folded[i]):
+			folded[i] = seat
+		}
+	}
+	return folded
+}
+
+// markHeldSeats flags the locked seats among seatNumbers as held by the caller.
+// Locks are only granted to one booking at a time, so a locked seat of a live
+// pending booking is that booking's.
+func markHeldSeats(seatMap *entities.SeatMap, seatNumbers []string) {
+	wanted := make(map[string]bool, len(seatNumbers))
+	for _, seatNumber := range seatNumbers {
+		wanted[seatNumber] = true
+	}
+
+	var held []string
+	mark := func(cell *entities.SeatMapCell) {
+		if cell.Type == entities.SeatMapCellSeat && cell.Status == entities.SeatStatusLocked && wanted[cell.SeatNumber] {
+			cell.HeldByMe = true
+			held = append(held, cell.SeatNumber)
+		}
+	}
+	for f := range seatMap.Floors {
+		for r := range seatMap.Floors[f].Rows {
+			for c := range seatMap.Floors[f].Rows[r] {
+				mark(&seatMap.Floors[f].Rows[r][c])
+			}
+		}
+	}
+	for i := range seatMap.Unplaced {
+		mark(&seatMap.Unplaced[i])
+	}
+
+	if len(held) > 0 {
+		sort.Strings(held)
+		sum := sha256.Sum256([]byte(strings.Join(held, ",")))
+		seatMap.Version += "-" + hex.EncodeToString(sum[:4])
+	}
+}
+
+// seatMapVersion hashes the content of the seat map, leaving out when it was generated
+func seatMapVersion(seatMap *entities.SeatMap) string {
+	data, _ := json.Marshal(struct {
+		BusID          uuid.UUID
+		Columns        int
+		Floors         []entities.SeatMapFloor
+		Unplaced       []entities.SeatMapCell
+		TotalSeats     int
+		AvailableSeats int
+	}{seatMap.BusID, seatMap.Columns, seatMap.Floors, seatMap.Unplaced, seatMap.TotalSeats, seatMap.AvailableSeats})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
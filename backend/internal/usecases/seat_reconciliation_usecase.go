@@ -0,0 +1,338 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+	"github.com/yourusername/bus-booking/internal/repositories/cache"
+)
+
+const (
+	// reconcileTripLimit caps how many upcoming trips one run checks, soonest first
+	reconcileTripLimit = 500
+	// reconcileLockGrace spares young Redis locks: InitiateBooking takes them
+	// before the booking and its database locks are committed
+	reconcileLockGrace = time.Minute
+)
+
+// SeatReconciliationUsecase compares the seat state kept in Redis, in
+// seats_status and in live bookings for upcoming trips. Discrepancies with an
+// obvious fix are repaired; the rest are flagged for an admin.
+type SeatReconciliationUsecase struct {
+	tripRepo        repositories.TripRepository
+	seatRepo        repositories.SeatRepository
+	bookingRepo     repositories.BookingRepository
+	discrepancyRepo repositories.SeatDiscrepancyRepository
+	cache           *cache.RedisCache
+
+	seatLockDuration time.Duration
+}
+
+func NewSeatReconciliationUsecase(
+	tripRepo repositories.TripRepository,
+	seatRepo repositories.SeatRepository,
+	bookingRepo repositories.BookingRepository,
+	discrepancyRepo repositories.SeatDiscrepancyRepository,
+	cache *cache.RedisCache,
+	seatLockDuration time.Duration,
+) *SeatReconciliationUsecase {
+	return &SeatReconciliationUsecase{
+		tripRepo:         tripRepo,
+		seatRepo:         seatRepo,
+		bookingRepo:      bookingRepo,
+		discrepancyRepo:  discrepancyRepo,
+		cache:            cache,
+		seatLockDuration: seatLockDuration,
+	}
+}
+
+// SeatReconciliationReport summarises one reconciliation run
+type SeatReconciliationReport struct {
+	RunID         uuid.UUID                   `json:"run_id"`
+	StartedAt     time.Time                   `json:"started_at"`
+	FinishedAt    time.Time                   `json:"finished_at"`
+	TripsChecked  int                         `json:"trips_checked"`
+	SeatsChecked  int                         `json:"seats_checked"`
+	Repaired      int                         `json:"repaired"`
+	Flagged       int                         `json:"flagged"`
+	Discrepancies []*entities.SeatDiscrepancy `json:"discrepancies"`
+}
+
+// Reconcile checks every upcoming trip. A trip that cannot be checked is
+// logged and skipped so it does not hold up the others.
+func (uc *SeatReconciliationUsecase) Reconcile(ctx context.Context) (*SeatReconciliationReport, error) {
+	report := &SeatReconciliationReport{
+		RunID:         uuid.New(),
+		StartedAt:     time.Now(),
+		Discrepancies: []*entities.SeatDiscrepancy{},
+	}
+
+	trips, err := uc.tripRepo.GetUpcomingTrips(ctx, reconcileTripLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list upcoming trips: %w", err)
+	}
+
+	for _, trip := range trips {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := uc.reconcileTrip(ctx, report, trip.ID); err != nil {
+			log.Printf("Failed to reconcile seats of trip %s: %v", trip.ID, err)
+			continue
+		}
+		report.TripsChecked++
+	}
+
+	report.FinishedAt = time.Now()
+	if report.Repaired > 0 || report.Flagged > 0 {
+		log.Printf("Seat reconciliation %s: %d trips, %d repaired, %d flagged", report.RunID, report.TripsChecked, report.Repaired, report.Flagged)
+	}
+	return report, nil
+}
+
+// reconcileTrip checks one trip's seats, its confirmed bookings and its Redis locks
+func (uc *SeatReconciliationUsecase) reconcileTrip(ctx context.Context, report *SeatReconciliationReport, tripID uuid.UUID) error {
+	seats, err := uc.seatRepo.GetAllByTrip(ctx, tripID)
+	if err != nil {
+		return err
+	}
+	bookings, err := uc.bookingRepo.GetTripBookingsByStatus(ctx, tripID, []entities.BookingStatus{
+		entities.BookingStatusPending,
+		entities.BookingStatusPaid,
+		entities.BookingStatusConfirmed,
+	})
+	if err != nil {
+		return err
+	}
+	locks, err := uc.cache.GetSeatLocks(ctx, tripID)
+	if err != nil {
+		return err
+	}
+	report.SeatsChecked += len(seats)
+
+	seatsByKey := make(map[entities.SeatSegment]*entities.SeatInfo, len(seats))
+	for _, seat := range seats {
+		seatsByKey[seat.Key()] = seat
+	}
+	bookingsByID := make(map[uuid.UUID]*entities.Booking, len(bookings))
+	pendingByToken := make(map[string]*entities.Booking)
+	for _, booking := range bookings {
+		bookingsByID[booking.ID] = booking
+		if booking.Status == entities.BookingStatusPending && booking.LockID != nil {
+			pendingByToken[booking.LockID.String()] = booking
+		}
+	}
+
+	r := &tripReconciliation{uc: uc, report: report, tripID: tripID}
+
+	// Confirmed bookings must own their seats on every segment they travel
+	checked := make(map[entities.SeatSegment]bool)
+	for _, booking := range bookings {
+		if booking.Status != entities.BookingStatusConfirmed {
+			continue
+		}
+		stops := booking.StopRange()
+		for _, seatNumber := range booking.Seats {
+			for segment := stops.From; segment < stops.To; segment++ {
+				key := entities.SeatSegment{SeatNumber: seatNumber, Segment: segment}
+				if checked[key] {
+					continue
+				}
+				checked[key] = true
+				r.checkConfirmedSeat(ctx, booking, seatNumber, seatsByKey[key])
+			}
+		}
+	}
+
+	// Booked and locked seats must belong to a live booking
+	for _, seat := range seats {
+		if checked[seat.Key()] {
+			continue
+		}
+
+		switch seat.Status {
+		case entities.SeatStatusBooked:
+			var booking *entities.Booking
+			if seat.BookingID != nil {
+				booking = bookingsByID[*seat.BookingID]
+			}
+			if booking == nil {
+				r.repair(ctx, seat, entities.SeatStatusAvailable, nil, entities.SeatDiscrepancyBookedWithoutBooking,
+					"seat was booked without a live booking, released it")
+			} else {
+				r.flag(ctx, seat.SeatNumber, entities.SeatDiscrepancyBookedWithoutBooking, &booking.ID,
+					fmt.Sprintf("seat is booked but booking %s is %s", booking.BookingCode, booking.Status))
+			}
+
+		case entities.SeatStatusLocked:
+			if seat.IsLockExpired() {
+				continue // The unlock-expired-seats job frees these
+			}
+
+			var booking *entities.Booking
+			if seat.LockedBy != nil {
+				booking = pendingByToken[seat.LockedBy.String()]
+			}
+			if booking == nil {
+				r.repair(ctx, seat, entities.SeatStatusAvailable, nil, entities.SeatDiscrepancyOrphanedDBLock,
+					"seat was locked by no pending booking, unlocked it")
+				continue
+			}
+
+			if lock, ok := locks[seat.Key()]; !ok || lock.Token != seat.LockedBy.String() {
+				r.restoreCacheLock(ctx, seat, booking)
+			}
+		}
+	}
+
+	// Redis locks must match a database lock of a pending booking
+	for key, lock := range locks {
+		seat := seatsByKey[key]
+		if seat != nil && seat.Status == entities.SeatStatusLocked && !seat.IsLockExpired() &&
+			seat.LockedBy != nil && seat.LockedBy.String() == lock.Token && pendingByToken[lock.Token] != nil {
+			continue
+		}
+		if uc.seatLockDuration-lock.TTL < reconcileLockGrace {
+			continue
+		}
+
+		token, err := uuid.Parse(lock.Token)
+		if err != nil {
+			r.flag(ctx, key.SeatNumber, entities.SeatDiscrepancyOrphanedCacheLock, nil, fmt.Sprintf("Redis lock has malformed token %q", lock.Token))
+			continue
+		}
+		if released, err := uc.cache.UnlockSeats(ctx, tripID, []string{key.SeatNumber}, segmentRange(key.Segment), token); err == nil && released > 0 {
+			r.record(ctx, key.SeatNumber, entities.SeatDiscrepancyOrphanedCacheLock, entities.SeatDiscrepancyRepaired, nil,
+				"Redis lock had no matching seat lock, released it")
+		}
+	}
+
+	// The cached seat map must agree with the database
+	if r.changed {
+		_ = uc.cache.InvalidateTripSeats(ctx, tripID)
+	} else if cached, err := uc.cache.GetTripSeats(ctx, tripID); err == nil && cached != nil {
+		stale := 0
+		for _, seat := range cached {
+			if current := seatsByKey[seat.Key()]; current == nil || current.Status != seat.Status {
+				stale++
+			}
+		}
+		if stale > 0 || len(cached) != len(seats) {
+			_ = uc.cache.InvalidateTripSeats(ctx, tripID)
+			r.record(ctx, "", entities.SeatDiscrepancyStaleSeatMap, entities.SeatDiscrepancyRepaired, nil,
+				fmt.Sprintf("cached seat map had %d outdated seats, invalidated it", stale))
+		}
+	}
+
+	return nil
+}
+
+// segmentRange is the stop range of a single segment
+func segmentRange(segment int) entities.StopRange {
+	return entities.StopRange{From: segment, To: segment + 1}
+}
+
+// tripReconciliation records the findings for one trip
+type tripReconciliation struct {
+	uc      *SeatReconciliationUsecase
+	report  *SeatReconciliationReport
+	tripID  uuid.UUID
+	changed bool // Whether any seat was repaired
+}
+
+// checkConfirmedSeat makes sure one segment of a seat is booked by the
+// confirmed booking travelling on it; seat is nil when the trip lacks it
+func (r *tripReconciliation) checkConfirmedSeat(ctx context.Context, booking *entities.Booking, seatNumber string, seat *entities.SeatInfo) {
+	switch {
+	case seat == nil:
+		r.flag(ctx, seatNumber, entities.SeatDiscrepancyConfirmedNotBooked, &booking.ID,
+			fmt.Sprintf("booking %s has a seat the trip does not have", booking.BookingCode))
+	case seat.Status == entities.SeatStatusBooked && seat.BookingID != nil && *seat.BookingID == booking.ID:
+	case seat.Status == entities.SeatStatusAvailable || seat.IsLockExpired():
+		r.repair(ctx, seat, entities.SeatStatusBooked, &booking.ID, entities.SeatDiscrepancyConfirmedNotBooked,
+			fmt.Sprintf("seat was %s, booked it for confirmed booking %s", seat.Status, booking.BookingCode))
+	default:
+		r.flag(ctx, seatNumber, entities.SeatDiscrepancyConfirmedNotBooked, &booking.ID,
+			fmt.Sprintf("seat of confirmed booking %s is %s by someone else", booking.BookingCode, seat.Status))
+	}
+}
+
+// repair applies a fix to a seat unless the seat changed since it was read,
+// in which case live traffic got there first and the next run will look again
+func (r *tripReconciliation) repair(ctx context.Context, seat *entities.SeatInfo, status entities.SeatStatus, bookingID *uuid.UUID, kind entities.SeatDiscrepancyKind, details string) {
+	ok, err := r.uc.seatRepo.RepairSeat(ctx, seat, status, bookingID)
+	if err != nil {
+		log.Printf("Failed to repair seat %s of trip %s: %v", seat.SeatNumber, r.tripID, err)
+		return
+	}
+	if ok {
+		r.changed = true
+		r.record(ctx, seat.SeatNumber, kind, entities.SeatDiscrepancyRepaired, bookingID, details)
+	}
+}
+
+// restoreCacheLock takes the Redis lock a pending booking is missing for the
+// rest of its database lock
+func (r *tripReconciliation) restoreCacheLock(ctx context.Context, seat *entities.SeatInfo, booking *entities.Booking) {
+	remaining := time.Until(*seat.LockedUntil)
+	err := r.uc.cache.LockSeats(ctx, r.tripID, []string{seat.SeatNumber}, segmentRange(seat.Segment), *seat.LockedBy, remaining)
+	switch {
+	case err == nil:
+		r.record(ctx, seat.SeatNumber, entities.SeatDiscrepancyMissingCacheLock, entities.SeatDiscrepancyRepaired, &booking.ID,
+			fmt.Sprintf("Redis lock of booking %s was missing, restored it", booking.BookingCode))
+	case errors.Is(err, cache.ErrSeatLocked):
+		r.flag(ctx, seat.SeatNumber, entities.SeatDiscrepancyMissingCacheLock, &booking.ID,
+			fmt.Sprintf("seat is locked for booking %s in the database but for someone else in Redis", booking.BookingCode))
+	default:
+		log.Printf("Failed to restore Redis lock on seat %s of trip %s: %v", seat.SeatNumber, r.tripID, err)
+	}
+}
+
+func (r *tripReconciliation) flag(ctx context.Context, seatNumber string, kind entities.SeatDiscrepancyKind, bookingID *uuid.UUID, details string) {
+	r.record(ctx, seatNumber, kind, entities.SeatDiscrepancyFlagged, bookingID, details)
+}
+
+func (r *tripReconciliation) record(ctx context.Context, seatNumber string, kind entities.SeatDiscrepancyKind, resolution entities.SeatDiscrepancyResolution, bookingID *uuid.UUID, details string) {
+	discrepancy := &entities.SeatDiscrepancy{
+		RunID:      r.report.RunID,
+		TripID:     r.tripID,
+		SeatNumber: seatNumber,
+		Kind:       kind,
+		Resolution: resolution,
+		BookingID:  bookingID,
+		Details:    details,
+	}
+	if err := r.uc.discrepancyRepo.Record(ctx, discrepancy); err != nil {
+		log.Printf("Failed to record %s discrepancy on trip %s: %v", kind, r.tripID, err)
+	}
+
+	r.report.Discrepancies = append(r.report.Discrepancies, discrepancy)
+	if resolution == entities.SeatDiscrepancyRepaired {
+		r.report.Repaired++
+	} else {
+		r.report.Flagged++
+	}
+}
+
+// ListDiscrepancies returns findings newest first; kind and tripID filter when set
+func (uc *SeatReconciliationUsecase) ListDiscrepancies(ctx context.Context, kind entities.SeatDiscrepancyKind, tripID *uuid.UUID, openOnly bool, page, limit int) ([]*entities.SeatDiscrepancy, error) {
+	offset := (page - 1) * limit
+	return uc.discrepancyRepo.List(ctx, kind, tripID, openOnly, limit, offset)
+}
+
+// ResolveDiscrepancy closes a flagged finding once an admin has dealt with it
+func (uc *SeatReconciliationUsecase) ResolveDiscrepancy(ctx context.Context, id uuid.UUID) (*entities.SeatDiscrepancy, error) {
+	if _, err := uc.discrepancyRepo.GetByID(ctx, id); err != nil {
+		return nil, fmt.Errorf("discrepancy not found: %w", err)
+	}
+	if err := uc.discrepancyRepo.Resolve(ctx, id); err != nil {
+		return nil, err
+	}
+	return uc.discrepancyRepo.GetByID(ctx, id)
+}
@@ -0,0 +1,568 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/infrastructure"
+	"github.com/yourusername/bus-booking/internal/repositories"
+	"github.com/yourusername/bus-booking/internal/repositories/cache"
+)
+
+// ErrCancellationChoiceClosed is returned when a passenger can no longer switch
+// from the alternative trip to a refund
+var ErrCancellationChoiceClosed = errors.New("the choice for this booking is closed")
+
+// cancellationChoiceCutoff is how long before the alternative trip departs
+// rebooked passengers must have made up their minds
+const cancellationChoiceCutoff = 2 * time.Hour
+
+// TripCancellationUsecase cancels trips on behalf of the operator and settles
+// every paid booking on them: moved to an alternative trip where seats allow,
+// refunded in full otherwise. Passengers get a link to switch to a refund.
+type TripCancellationUsecase struct {
+	tx               repositories.Transactor
+	outboxRepo       repositories.OutboxRepository
+	tripRepo         repositories.TripRepository
+	bookingRepo      repositories.BookingRepository
+	seatRepo         repositories.SeatRepository
+	paymentRepo      repositories.PaymentRepository
+	ticketRepo       repositories.TicketRepository
+	cancellationRepo repositories.TripCancellationRepository
+	cache            *cache.RedisCache
+	bookings         *BookingUsecase
+	payments         *PaymentUsecase
+	lifecycle        *TripLifecycleUsecase
+	pdfGenerator     *infrastructure.PDFGenerator
+	emailService     *infrastructure.EmailService
+	choiceBaseURL    string // Choice links are this followed by the outcome's token
+}
+
+func NewTripCancellationUsecase(
+	tx repositories.Transactor,
+	outboxRepo repositories.OutboxRepository,
+	tripRepo repositories.TripRepository,
+	bookingRepo repositories.BookingRepository,
+	seatRepo repositories.SeatRepository,
+	paymentRepo repositories.PaymentRepository,
+	ticketRepo repositories.TicketRepository,
+	cancellationRepo repositories.TripCancellationRepository,
+	cache *cache.RedisCache,
+	bookings *BookingUsecase,
+	payments *PaymentUsecase,
+	lifecycle *TripLifecycleUsecase,
+	pdfGenerator *infrastructure.PDFGenerator,
+	emailService *infrastructure.EmailService,
+	choiceBaseURL string,
+) *TripCancellationUsecase {
+	return &TripCancellationUsecase{
+		tx:               tx,
+		outboxRepo:       outboxRepo,
+		tripRepo:         tripRepo,
+		bookingRepo:      bookingRepo,
+		seatRepo:         seatRepo,
+		paymentRepo:      paymentRepo,
+		ticketRepo:       ticketRepo,
+		cancellationRepo: cancellationRepo,
+		cache:            cache,
+		bookings:         bookings,
+		payments:         payments,
+		lifecycle:        lifecycle,
+		pdfGenerator:     pdfGenerator,
+		emailService:     emailService,
+		choiceBaseURL:    choiceBaseURL,
+	}
+}
+
+// CancelTripInput describes an operator cancelling a trip
+type CancelTripInput struct {
+	TripID            uuid.UUID
+	AlternativeTripID *uuid.UUID // Trip on the same route to move passengers to; refund everyone when nil
+	Reason            string
+	CancelledBy       *uuid.UUID
+}
+
+// TripCancellationReport sums up how the bookings of a cancelled trip were settled
+type TripCancellationReport struct {
+	Cancellation     *entities.TripCancellation `json:"cancellation"`
+	Bookings         int                        `json:"bookings"`
+	Passengers       int                        `json:"passengers"`
+	Rebooked         int                        `json:"rebooked"`
+	Refunded         int                        `json:"refunded"`
+	RefundFailed     int                        `json:"refund_failed"`
+	SwitchedToRefund int                        `json:"switched_to_refund"` // Rebooked passengers who took a refund through their link
+	RefundTotal      float64                    `json:"refund_total"`
+}
+
+// CancellationChoice is what a passenger sees behind their choice link
+type CancellationChoice struct {
+	Outcome         *entities.TripCancellationOutcome `json:"outcome"`
+	AlternativeTrip *entities.Trip                    `json:"alternative_trip,omitempty"`
+	Deadline        *time.Time                        `json:"deadline,omitempty"`
+	CanRefund       bool                              `json:"can_refund"`
+}
+
+// CancelTrip cancels a trip that has not departed. Unpaid holds on it are
+// dropped; every paid booking is moved to the alternative trip if it has
+// enough free seats, or refunded in full regardless of how close to departure
+// it is. Each customer is emailed their outcome.
+func (uc *TripCancellationUsecase) CancelTrip(ctx context.Context, input CancelTripInput) (*TripCancellationReport, error) {
+	trip, err := uc.tripRepo.GetByIDWithDetails(ctx, input.TripID)
+	if err != nil {
+		return nil, fmt.Errorf("trip not found: %w", err)
+	}
+
+	var alternative *entities.Trip
+	if input.AlternativeTripID != nil {
+		alternative, err = uc.tripRepo.GetByIDWithDetails(ctx, *input.AlternativeTripID)
+		if err != nil {
+			return nil, fmt.Errorf("alternative trip not found: %w", err)
+		}
+		if err := validateAlternativeTrip(trip, alternative); err != nil {
+			return nil, err
+		}
+	}
+
+	cancellation := &entities.TripCancellation{
+		TripID:            trip.ID,
+		AlternativeTripID: input.AlternativeTripID,
+		Reason:            input.Reason,
+		CancelledBy:       input.CancelledBy,
+	}
+	if alternative != nil {
+		deadline := alternative.DepartureTime.Add(-cancellationChoiceCutoff)
+		cancellation.ChoiceDeadline = &deadline
+	}
+
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := uc.lifecycle.transition(ctx, trip.ID, entities.TripStatusCancelled, entities.EventTripCancelled, input.Reason, nil); err != nil {
+			return err
+		}
+		return uc.cancellationRepo.Create(ctx, cancellation)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Nobody paid for these, so there is nothing to settle
+	pending, err := uc.bookingRepo.GetTripBookingsByStatus(ctx, trip.ID, []entities.BookingStatus{entities.BookingStatusPending})
+	if err != nil {
+		log.Printf("Failed to load pending bookings of cancelled trip %s: %v", trip.ID, err)
+	}
+	for _, booking := range pending {
+		if err := uc.bookings.CancelBooking(ctx, booking.ID); err != nil {
+			log.Printf("Failed to cancel pending booking %s of cancelled trip %s: %v", booking.BookingCode, trip.ID, err)
+		}
+	}
+
+	if err := uc.settle(ctx, cancellation, trip, alternative); err != nil {
+		return nil, err
+	}
+	return uc.GetReport(ctx, trip.ID)
+}
+
+// Resume finishes a cancellation: refunds that failed are retried and paid
+// bookings still on the trip are settled. It is safe to call repeatedly.
+func (uc *TripCancellationUsecase) Resume(ctx context.Context, tripID uuid.UUID) (*TripCancellationReport, error) {
+	cancellation, err := uc.cancellationRepo.GetByTrip(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("trip cancellation not found: %w", err)
+	}
+	trip, err := uc.tripRepo.GetByIDWithDetails(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("trip not found: %w", err)
+	}
+
+	var alternative *entities.Trip
+	if cancellation.AlternativeTripID != nil {
+		alternative, err = uc.tripRepo.GetByIDWithDetails(ctx, *cancellation.AlternativeTripID)
+		if err != nil || validateAlternativeTrip(trip, alternative) != nil {
+			alternative = nil // Gone or departed; whoever is left gets a refund
+		}
+	}
+
+	for _, outcome := range cancellation.Outcomes {
+		if outcome.Resolution != entities.CancellationRefundFailed {
+			continue
+		}
+		booking, err := uc.bookingRepo.GetByID(ctx, outcome.BookingID)
+		if err != nil {
+			log.Printf("Failed to load booking %s to retry its refund: %v", outcome.BookingCode, err)
+			continue
+		}
+		uc.refund(ctx, booking, outcome)
+		if err := uc.cancellationRepo.UpdateOutcome(ctx, outcome); err != nil {
+			return nil, fmt.Errorf("failed to save outcome of booking %s: %w", outcome.BookingCode, err)
+		}
+		if outcome.Resolution == entities.CancellationRefunded {
+			uc.notify(ctx, cancellation, trip, nil, booking, outcome)
+		}
+	}
+
+	if err := uc.settle(ctx, cancellation, trip, alternative); err != nil {
+		return nil, err
+	}
+	return uc.GetReport(ctx, tripID)
+}
+
+// GetReport returns the cancellation of a trip with the tally of its outcomes
+func (uc *TripCancellationUsecase) GetReport(ctx context.Context, tripID uuid.UUID) (*TripCancellationReport, error) {
+	cancellation, err := uc.cancellationRepo.GetByTrip(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("trip cancellation not found: %w", err)
+	}
+
+	report := &TripCancellationReport{Cancellation: cancellation, Bookings: len(cancellation.Outcomes)}
+	for _, outcome := range cancellation.Outcomes {
+		report.Passengers += outcome.Passengers
+		report.RefundTotal += outcome.RefundAmount
+		switch outcome.Resolution {
+		case entities.CancellationRebooked:
+			report.Rebooked++
+		case entities.CancellationRefunded:
+			report.Refunded++
+			if outcome.ChosenByPassenger {
+				report.SwitchedToRefund++
+			}
+		case entities.CancellationRefundFailed:
+			report.RefundFailed++
+		}
+	}
+	return report, nil
+}
+
+// GetChoice looks up the outcome behind a passenger's choice link
+func (uc *TripCancellationUsecase) GetChoice(ctx context.Context, token string) (*CancellationChoice, error) {
+	outcome, err := uc.cancellationRepo.GetOutcomeByToken(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("choice not found: %w", err)
+	}
+	cancellation, err := uc.cancellationRepo.GetByID(ctx, outcome.CancellationID)
+	if err != nil {
+		return nil, fmt.Errorf("trip cancellation not found: %w", err)
+	}
+
+	choice := &CancellationChoice{Outcome: outcome, Deadline: cancellation.ChoiceDeadline}
+	if outcome.Resolution == entities.CancellationRebooked && cancellation.AlternativeTripID != nil {
+		choice.AlternativeTrip, err = uc.tripRepo.GetByIDWithDetails(ctx, *cancellation.AlternativeTripID)
+		if err != nil {
+			return nil, fmt.Errorf("alternative trip not found: %w", err)
+		}
+		choice.CanRefund = cancellation.ChoiceDeadline != nil && time.Now().Before(*cancellation.ChoiceDeadline) &&
+			choice.AlternativeTrip.IsBookable()
+	}
+	return choice, nil
+}
+
+// Choose records a passenger's pick behind their choice link: rebooked keeps
+// the seats on the alternative trip, refunded gives them up for a full refund
+func (uc *TripCancellationUsecase) Choose(ctx context.Context, token string, resolution entities.CancellationResolution) (*CancellationChoice, error) {
+	choice, err := uc.GetChoice(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	outcome := choice.Outcome
+
+	switch resolution {
+	case entities.CancellationRebooked:
+		if outcome.Resolution != entities.CancellationRebooked {
+			return nil, fmt.Errorf("%w: the booking has already been refunded", ErrCancellationChoiceClosed)
+		}
+	case entities.CancellationRefunded:
+		if outcome.Resolution == entities.CancellationRefunded {
+			return choice, nil
+		}
+		if !choice.CanRefund {
+			return nil, ErrCancellationChoiceClosed
+		}
+		// Only the request that claims the outcome refunds it; a concurrent
+		// one finds it no longer rebooked
+		claimed, err := uc.cancellationRepo.ClaimOutcomeRefund(ctx, outcome.ID, "Refund chosen by the passenger is in progress")
+		if err != nil {
+			return nil, fmt.Errorf("failed to save choice: %w", err)
+		}
+		if !claimed {
+			return nil, fmt.Errorf("%w: the choice has already been made", ErrCancellationChoiceClosed)
+		}
+		booking, err := uc.bookingRepo.GetByID(ctx, outcome.BookingID)
+		if err != nil {
+			return nil, fmt.Errorf("booking not found: %w", err)
+		}
+		uc.refund(ctx, booking, outcome)
+		choice.CanRefund = false
+	default:
+		return nil, fmt.Errorf("choose either %s or %s", entities.CancellationRebooked, entities.CancellationRefunded)
+	}
+
+	outcome.ChosenByPassenger = true
+	if err := uc.cancellationRepo.UpdateOutcome(ctx, outcome); err != nil {
+		return nil, fmt.Errorf("failed to save choice: %w", err)
+	}
+	return choice, nil
+}
+
+// settle works out an outcome for every paid booking still on the cancelled
+// trip and emails the customer about it. Bookings that already have an
+// outcome are left to the refund retries of Resume.
+func (uc *TripCancellationUsecase) settle(ctx context.Context, cancellation *entities.TripCancellation, trip, alternative *entities.Trip) error {
+	listed, err := uc.bookingRepo.GetTripBookings(ctx, trip.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load bookings: %w", err)
+	}
+
+	settled := make(map[uuid.UUID]bool, len(cancellation.Outcomes))
+	for _, outcome := range cancellation.Outcomes {
+		settled[outcome.BookingID] = true
+	}
+
+	for _, item := range listed {
+		if settled[item.ID] {
+			continue
+		}
+		// Loaded without associations so saving it leaves them alone
+		booking, err := uc.bookingRepo.GetByID(ctx, item.ID)
+		if err != nil {
+			return fmt.Errorf("booking %s not found: %w", item.BookingCode, err)
+		}
+		token, err := randomChoiceToken()
+		if err != nil {
+			return fmt.Errorf("failed to create choice token: %w", err)
+		}
+
+		outcome := &entities.TripCancellationOutcome{
+			CancellationID: cancellation.ID,
+			BookingID:      booking.ID,
+			BookingCode:    booking.BookingCode,
+			ContactEmail:   booking.ContactEmail,
+			Passengers:     max(len(booking.Passengers), len(booking.Seats)),
+			OldSeats:       append([]string(nil), booking.Seats...),
+			ChoiceToken:    token,
+		}
+
+		if alternative != nil {
+			seats, err := uc.rebook(ctx, trip, booking, alternative)
+			if err == nil {
+				now := time.Now()
+				outcome.Resolution = entities.CancellationRebooked
+				outcome.NewSeats = seats
+				outcome.ResolvedAt = &now
+			} else {
+				log.Printf("Could not move booking %s to trip %s, refunding instead: %v", booking.BookingCode, alternative.ID, err)
+			}
+		}
+		if outcome.Resolution == "" {
+			uc.refund(ctx, booking, outcome)
+		}
+
+		if err := uc.cancellationRepo.CreateOutcome(ctx, outcome); err != nil {
+			return fmt.Errorf("failed to record outcome of booking %s: %w", booking.BookingCode, err)
+		}
+		cancellation.Outcomes = append(cancellation.Outcomes, outcome)
+
+		uc.notify(ctx, cancellation, trip, alternative, booking, outcome)
+	}
+	return nil
+}
+
+// rebook moves a booking onto free seats of the alternative trip. The customer
+// keeps the price they paid. Returns the new seats.
+func (uc *TripCancellationUsecase) rebook(ctx context.Context, trip *entities.Trip, booking *entities.Booking, alternative *entities.Trip) ([]string, error) {
+	// Stops belong to a route, so on another route the passengers use its origin and destination
+	stops := booking.StopRange()
+	if alternative.RouteID != trip.RouteID {
+		stops = alternative.Route.FullRange()
+	}
+	allocation, err := uc.bookings.assignSeats(ctx, alternative, stops, len(booking.Seats), SeatPreferences{})
+	if err != nil {
+		return nil, err
+	}
+
+	oldTripID := booking.TripID
+	moved := *booking
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.seatRepo.ReleaseSeats(ctx, booking.ID); err != nil {
+			return fmt.Errorf("failed to release seats: %w", err)
+		}
+		if err := recordSeatsEvent(ctx, uc.outboxRepo, entities.EventSeatsReleased, booking, entities.SeatStatusAvailable); err != nil {
+			return err
+		}
+
+		// A fresh lock token matches no lock, so seats taken since they were listed fail the move
+		if err := uc.seatRepo.ConfirmSeats(ctx, alternative.ID, allocation.Seats, stops, uuid.New(), booking.ID); err != nil {
+			return err
+		}
+
+		renamed := make(map[string]string, len(booking.Seats))
+		for i, seatNumber := range booking.Seats {
+			renamed[seatNumber] = allocation.Seats[i]
+		}
+		moved.TripID = alternative.ID
+		moved.Seats = allocation.Seats
+		moved.Passengers = append(entities.Passengers(nil), booking.Passengers...)
+		for i := range moved.Passengers {
+			if newSeat, ok := renamed[moved.Passengers[i].SeatNumber]; ok {
+				moved.Passengers[i].SeatNumber = newSeat
+			}
+		}
+		moved.FromStop, moved.ToStop = stops.From, stops.To
+		if alternative.RouteID != trip.RouteID {
+			moved.PickupStopID, moved.DropoffStopID = nil, nil
+			moved.PickupStop, moved.DropoffStop = nil, nil
+		}
+		if err := uc.bookingRepo.Update(ctx, &moved); err != nil {
+			return fmt.Errorf("failed to update booking: %w", err)
+		}
+
+		tickets, err := uc.ticketRepo.GetByBookingID(ctx, booking.ID)
+		if err != nil {
+			return err
+		}
+		for _, ticket := range tickets {
+			if newSeat, ok := renamed[ticket.SeatNumber]; ok {
+				ticket.SeatNumber = newSeat
+			}
+			ticket.PDFPath = "" // Regenerated for the new trip
+			if err := uc.ticketRepo.Update(ctx, ticket); err != nil {
+				return err
+			}
+		}
+
+		return recordSeatsEvent(ctx, uc.outboxRepo, entities.EventBookingConfirmed, &moved, entities.SeatStatusBooked)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	*booking = moved
+	_ = uc.cache.InvalidateTripSeats(ctx, oldTripID)
+	_ = uc.cache.InvalidateTripSeats(ctx, alternative.ID)
+	return allocation.Seats, nil
+}
+
+// refund returns the whole journey's payment and frees the seats of all its
+// legs, recording the result in the outcome. A round trip was priced and paid
+// as one, so losing either leg refunds both.
+func (uc *TripCancellationUsecase) refund(ctx context.Context, booking *entities.Booking, outcome *entities.TripCancellationOutcome) {
+	amount, err := uc.refundJourney(ctx, booking)
+	if err != nil {
+		log.Printf("Failed to refund booking %s: %v", booking.BookingCode, err)
+		outcome.Resolution = entities.CancellationRefundFailed
+		outcome.Error = err.Error()
+		return
+	}
+
+	now := time.Now()
+	outcome.Resolution = entities.CancellationRefunded
+	outcome.RefundAmount = amount
+	outcome.Error = ""
+	outcome.ResolvedAt = &now
+}
+
+func (uc *TripCancellationUsecase) refundJourney(ctx context.Context, booking *entities.Booking) (float64, error) {
+	journey, err := uc.bookings.getJourney(ctx, booking.ID)
+	if err != nil {
+		return 0, fmt.Errorf("booking not found: %w", err)
+	}
+	pmt, err := uc.paymentRepo.GetByBookingID(ctx, journey.ID)
+	if err != nil {
+		return 0, fmt.Errorf("payment not found: %w", err)
+	}
+	if pmt.Status != entities.PaymentStatusRefunded {
+		if err := uc.payments.RefundPayment(ctx, pmt.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	// A refund leaves seats booked; the other leg may be on a trip that still runs
+	legs := journey.Legs()
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, leg := range legs {
+			if err := uc.seatRepo.ReleaseSeats(ctx, leg.ID); err != nil {
+				return fmt.Errorf("failed to release seats: %w", err)
+			}
+			if err := recordSeatsEvent(ctx, uc.outboxRepo, entities.EventSeatsReleased, leg, entities.SeatStatusAvailable); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, leg := range legs {
+		_ = uc.cache.InvalidateTripSeats(ctx, leg.TripID)
+	}
+	return pmt.Amount, nil
+}
+
+// notify emails a customer what became of their booking; rebooked customers
+// get their new e-tickets and a link to take a refund instead
+func (uc *TripCancellationUsecase) notify(ctx context.Context, cancellation *entities.TripCancellation, trip, alternative *entities.Trip, booking *entities.Booking, outcome *entities.TripCancellationOutcome) {
+	location := uc.lifecycle.location
+	details := []string{fmt.Sprintf("Cancelled trip: %s departing %s", routeName(trip), trip.DepartureTime.In(location).Format(notificationTimeLayout))}
+
+	var choiceURL string
+	var attachments []string
+	switch outcome.Resolution {
+	case entities.CancellationRebooked:
+		details = append(details, fmt.Sprintf("You have been moved to the %s trip departing %s, seats %s, at no extra cost",
+			routeName(alternative), alternative.DepartureTime.In(location).Format(notificationTimeLayout), strings.Join(outcome.NewSeats, ", ")))
+		if cancellation.ChoiceDeadline != nil {
+			details = append(details, fmt.Sprintf("You can take a full refund instead until %s", cancellation.ChoiceDeadline.In(location).Format(notificationTimeLayout)))
+			choiceURL = uc.choiceBaseURL + outcome.ChoiceToken
+		}
+		if booking.Status == entities.BookingStatusConfirmed {
+			attachments = regenerateTickets(ctx, uc.ticketRepo, uc.pdfGenerator, location, alternative, booking)
+		}
+	case entities.CancellationRefunded:
+		details = append(details, fmt.Sprintf("Your payment of %.0f VND has been refunded in full", outcome.RefundAmount))
+	default:
+		details = append(details, "Your payment will be refunded in full; we will email you once the refund has gone through")
+	}
+
+	if err := uc.emailService.SendTripCancellation(booking.ContactEmail, booking.BookingCode, cancellation.Reason, details, choiceURL, attachments...); err != nil {
+		log.Printf("Failed to send trip cancellation email for booking %s: %v", booking.BookingCode, err)
+	}
+}
+
+// validateAlternativeTrip checks that passengers of trip can be moved to alternative
+func validateAlternativeTrip(trip, alternative *entities.Trip) error {
+	if alternative.ID == trip.ID {
+		return fmt.Errorf("the alternative trip must be another trip")
+	}
+	if !alternative.IsBookable() || !alternative.DepartureTime.After(time.Now()) {
+		return fmt.Errorf("the alternative trip is not available for booking")
+	}
+	if trip.Route == nil || alternative.Route == nil {
+		return fmt.Errorf("route of the trips not found")
+	}
+	if trip.Route.FromLocationID != alternative.Route.FromLocationID || trip.Route.ToLocationID != alternative.Route.ToLocationID {
+		return fmt.Errorf("the alternative trip must go from %s to %s", trip.Route.FromCity, trip.Route.ToCity)
+	}
+	return nil
+}
+
+func routeName(trip *entities.Trip) string {
+	if trip.Route == nil {
+		return ""
+	}
+	return trip.Route.Name
+}
+
+// randomChoiceToken returns an unguessable token for a choice link
+func randomChoiceToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
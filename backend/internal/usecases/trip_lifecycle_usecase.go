@@ -0,0 +1,174 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/infrastructure"
+	"github.com/yourusername/bus-booking/internal/repositories"
+)
+
+// ErrInvalidTripTransition is returned when a trip cannot move to the requested status
+var ErrInvalidTripTransition = errors.New("invalid trip status transition")
+
+// notificationTimeLayout is how trip times are written in passenger emails
+const notificationTimeLayout = "15:04 02/01/2006"
+
+// TripLifecycleUsecase moves trips through boarding, departure, delays and
+// arrival, keeping passengers and seat-map viewers informed. Cancellations go
+// through TripCancellationUsecase, which settles the bookings as well.
+type TripLifecycleUsecase struct {
+	tx           repositories.Transactor
+	outboxRepo   repositories.OutboxRepository
+	tripRepo     repositories.TripRepository
+	bookingRepo  repositories.BookingRepository
+	emailService *infrastructure.EmailService
+	location     *time.Location // Business timezone times are written in for passengers
+}
+
+func NewTripLifecycleUsecase(
+	tx repositories.Transactor,
+	outboxRepo repositories.OutboxRepository,
+	tripRepo repositories.TripRepository,
+	bookingRepo repositories.BookingRepository,
+	emailService *infrastructure.EmailService,
+	location *time.Location,
+) *TripLifecycleUsecase {
+	return &TripLifecycleUsecase{
+		tx:           tx,
+		outboxRepo:   outboxRepo,
+		tripRepo:     tripRepo,
+		bookingRepo:  bookingRepo,
+		emailService: emailService,
+		location:     location,
+	}
+}
+
+// DelayTripInput describes a delay. DepartureTime is the new expected
+// departure and can only be given before the trip has left; ArrivalTime is the
+// new ETA.
+type DelayTripInput struct {
+	TripID        uuid.UUID
+	DepartureTime *time.Time
+	ArrivalTime   time.Time
+	Reason        string
+}
+
+// StartBoarding opens boarding of a scheduled or delayed trip
+func (uc *TripLifecycleUsecase) StartBoarding(ctx context.Context, tripID uuid.UUID) (*entities.Trip, error) {
+	return uc.transition(ctx, tripID, entities.TripStatusBoarding, entities.EventTripStatusChanged, "", nil)
+}
+
+// Depart marks a boarding trip as on its way
+func (uc *TripLifecycleUsecase) Depart(ctx context.Context, tripID uuid.UUID) (*entities.Trip, error) {
+	return uc.transition(ctx, tripID, entities.TripStatusInTransit, entities.EventTripStatusChanged, "", nil)
+}
+
+// Arrive completes a trip in transit
+func (uc *TripLifecycleUsecase) Arrive(ctx context.Context, tripID uuid.UUID) (*entities.Trip, error) {
+	return uc.transition(ctx, tripID, entities.TripStatusCompleted, entities.EventTripStatusChanged, "", nil)
+}
+
+// Delay records a new expected departure and arrival. A trip that has not left
+// yet becomes delayed; a trip in transit only gets its ETA moved. Booked
+// passengers are emailed the new times.
+func (uc *TripLifecycleUsecase) Delay(ctx context.Context, input DelayTripInput) (*entities.Trip, error) {
+	current, err := uc.tripRepo.GetByID(ctx, input.TripID)
+	if err != nil {
+		return nil, fmt.Errorf("trip not found: %w", err)
+	}
+
+	next := entities.TripStatusDelayed
+	if current.Status == entities.TripStatusInTransit {
+		if input.DepartureTime != nil {
+			return nil, fmt.Errorf("trip has already departed")
+		}
+		next = entities.TripStatusInTransit
+	}
+
+	trip, err := uc.transition(ctx, input.TripID, next, entities.EventTripDelayed, input.Reason, func(trip *entities.Trip) error {
+		if input.DepartureTime != nil {
+			if input.DepartureTime.Before(trip.DepartureTime) {
+				return fmt.Errorf("a delay cannot move the departure earlier")
+			}
+			trip.DepartureTime = *input.DepartureTime
+		}
+		if !input.ArrivalTime.After(trip.DepartureTime) {
+			return fmt.Errorf("arrival time must be after departure time")
+		}
+		trip.ArrivalTime = input.ArrivalTime
+		trip.Duration = int(trip.ArrivalTime.Sub(trip.DepartureTime).Minutes())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.notify(ctx, trip, func(booking *entities.Booking, route string) error {
+		return uc.emailService.SendTripDelay(booking.ContactEmail, booking.BookingCode, route,
+			trip.DepartureTime.In(uc.location).Format(notificationTimeLayout),
+			trip.ArrivalTime.In(uc.location).Format(notificationTimeLayout),
+			input.Reason)
+	})
+	return trip, nil
+}
+
+// transition moves a trip to next after validating the move, applying adjust
+// to the trip first when given, and records eventType in the same transaction.
+// The update only goes through if nobody changed the trip's status meanwhile.
+func (uc *TripLifecycleUsecase) transition(ctx context.Context, tripID uuid.UUID, next entities.TripStatus, eventType entities.EventType, reason string, adjust func(trip *entities.Trip) error) (*entities.Trip, error) {
+	trip, err := uc.tripRepo.GetByIDWithDetails(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("trip not found: %w", err)
+	}
+
+	previous := trip.Status
+	if !previous.CanTransitionTo(next) {
+		return nil, fmt.Errorf("%w: a %s trip cannot become %s", ErrInvalidTripTransition, previous, next)
+	}
+
+	trip.Status = next
+	if adjust != nil {
+		if err := adjust(trip); err != nil {
+			return nil, err
+		}
+	}
+
+	err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.tripRepo.UpdateStatus(ctx, trip, previous); err != nil {
+			if errors.Is(err, repositories.ErrTripStatusChanged) {
+				return fmt.Errorf("%w: %v", ErrInvalidTripTransition, err)
+			}
+			return fmt.Errorf("failed to update trip: %w", err)
+		}
+		return recordTripEvent(ctx, uc.outboxRepo, eventType, trip, previous, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Trip %s: %s -> %s", trip.ID, previous, next)
+	return trip, nil
+}
+
+// notify emails every booked passenger of the trip; failures are logged so one
+// bad address does not stop the rest
+func (uc *TripLifecycleUsecase) notify(ctx context.Context, trip *entities.Trip, send func(booking *entities.Booking, route string) error) {
+	bookings, err := uc.bookingRepo.GetTripBookings(ctx, trip.ID)
+	if err != nil {
+		log.Printf("Failed to load bookings of trip %s for notifications: %v", trip.ID, err)
+		return
+	}
+
+	route := routeName(trip)
+	for _, booking := range bookings {
+		if err := send(booking, route); err != nil {
+			log.Printf("Failed to notify booking %s of trip %s being %s: %v", booking.BookingCode, trip.ID, trip.Status, err)
+		}
+	}
+}
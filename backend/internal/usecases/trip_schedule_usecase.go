@@ -0,0 +1,375 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/bus-booking/internal/entities"
+	"github.com/yourusername/bus-booking/internal/repositories"
+)
+
+// TripScheduleUsecase manages timetable templates and generates their trips ahead of time
+type TripScheduleUsecase struct {
+	tx           repositories.Transactor
+	scheduleRepo repositories.TripScheduleRepository
+	holidayRepo  repositories.HolidayRepository
+	tripRepo     repositories.TripRepository
+	seatRepo     repositories.SeatRepository
+	bookingRepo  repositories.BookingRepository
+	busRepo      repositories.BusRepository
+	routeRepo    repositories.RouteRepository
+	driverRepo   repositories.DriverRepository
+	trips        *TripUsecase
+
+	location    *time.Location // Business timezone the timetables are written in
+	horizonDays int            // How many days ahead trips are generated
+}
+
+func NewTripScheduleUsecase(
+	tx repositories.Transactor,
+	scheduleRepo repositories.TripScheduleRepository,
+	holidayRepo repositories.HolidayRepository,
+	tripRepo repositories.TripRepository,
+	seatRepo repositories.SeatRepository,
+	bookingRepo repositories.BookingRepository,
+	busRepo repositories.BusRepository,
+	routeRepo repositories.RouteRepository,
+	driverRepo repositories.DriverRepository,
+	trips *TripUsecase,
+	location *time.Location,
+	horizonDays int,
+) *TripScheduleUsecase {
+	return &TripScheduleUsecase{
+		tx:           tx,
+		scheduleRepo: scheduleRepo,
+		holidayRepo:  holidayRepo,
+		tripRepo:     tripRepo,
+		seatRepo:     seatRepo,
+		bookingRepo:  bookingRepo,
+		busRepo:      busRepo,
+		routeRepo:    routeRepo,
+		driverRepo:   driverRepo,
+		trips:        trips,
+
+		location:    location,
+		horizonDays: horizonDays,
+	}
+}
+
+// ScheduleSyncResult tells what happened to a schedule's trips after it changed
+type ScheduleSyncResult struct {
+	Schedule *entities.TripSchedule `json:"schedule"`
+	Created  int                    `json:"created"` // Trips generated
+	Removed  int                    `json:"removed"` // Future trips that never had a booking, replaced or dropped
+	Kept     []uuid.UUID            `json:"kept"`    // Future trips left as they were because they have bookings of any status
+}
+
+// CreateSchedule stores a schedule and generates its trips right away
+func (uc *TripScheduleUsecase) CreateSchedule(ctx context.Context, schedule *entities.TripSchedule) (*ScheduleSyncResult, error) {
+	if err := uc.validateSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+	schedule.IsActive = true
+	if err := uc.scheduleRepo.Create(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return uc.sync(ctx, schedule, false)
+}
+
+func (uc *TripScheduleUsecase) GetSchedule(ctx context.Context, id uuid.UUID) (*entities.TripSchedule, error) {
+	return uc.scheduleRepo.GetByID(ctx, id)
+}
+
+func (uc *TripScheduleUsecase) ListSchedules(ctx context.Context, activeOnly bool, page, limit int) ([]*entities.TripSchedule, error) {
+	offset := (page - 1) * limit
+	return uc.scheduleRepo.List(ctx, activeOnly, limit, offset)
+}
+
+// UpdateSchedule changes a schedule. Its future trips without bookings are
+// regenerated from the new template; trips that have bookings are kept as
+// they are and reported.
+func (uc *TripScheduleUsecase) UpdateSchedule(ctx context.Context, schedule *entities.TripSchedule) (*ScheduleSyncResult, error) {
+	existing, err := uc.scheduleRepo.GetByID(ctx, schedule.ID)
+	if err != nil {
+		return nil, fmt.Errorf("schedule not found: %w", err)
+	}
+	if err := uc.validateSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	schedule.IsActive = existing.IsActive
+	schedule.CreatedAt = existing.CreatedAt
+	if err := uc.scheduleRepo.Update(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return uc.sync(ctx, schedule, true)
+}
+
+// DeactivateSchedule stops a schedule and drops its future trips without bookings
+func (uc *TripScheduleUsecase) DeactivateSchedule(ctx context.Context, id uuid.UUID) (*ScheduleSyncResult, error) {
+	schedule, err := uc.scheduleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("schedule not found: %w", err)
+	}
+
+	schedule.IsActive = false
+	if err := uc.scheduleRepo.Update(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return uc.sync(ctx, schedule, true)
+}
+
+// GenerateTrips creates the missing trips of all active schedules up to the
+// horizon. It is idempotent: a schedule gets at most one trip per day.
+func (uc *TripScheduleUsecase) GenerateTrips(ctx context.Context) error {
+	schedules, err := uc.scheduleRepo.GetActive(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load schedules: %w", err)
+	}
+
+	holidays, err := uc.holidays(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	created := 0
+	for _, schedule := range schedules {
+		n, err := uc.generate(ctx, schedule, holidays, time.Now())
+		created += n
+		if err != nil {
+			// One broken schedule must not hold up the others
+			log.Printf("Failed to generate trips for schedule %s: %v", schedule.ID, err)
+		}
+	}
+	if created > 0 {
+		log.Printf("Generated %d scheduled trips", created)
+	}
+	return nil
+}
+
+func (uc *TripScheduleUsecase) CreateHoliday(ctx context.Context, holiday *entities.Holiday) error {
+	if _, err := time.Parse(entities.ScheduleDateLayout, holiday.Date); err != nil {
+		return fmt.Errorf("invalid holiday date, use YYYY-MM-DD")
+	}
+	if holiday.Name == "" {
+		return fmt.Errorf("holiday name is required")
+	}
+	return uc.holidayRepo.Create(ctx, holiday)
+}
+
+func (uc *TripScheduleUsecase) DeleteHoliday(ctx context.Context, id uuid.UUID) error {
+	return uc.holidayRepo.Delete(ctx, id)
+}
+
+func (uc *TripScheduleUsecase) ListHolidays(ctx context.Context, from, to string) ([]*entities.Holiday, error) {
+	return uc.holidayRepo.List(ctx, from, to)
+}
+
+// sync brings a schedule's future trips in line with it: with replace, trips
+// that never had a booking are removed first so they are regenerated from the
+// current template (or not at all for an inactive schedule)
+func (uc *TripScheduleUsecase) sync(ctx context.Context, schedule *entities.TripSchedule, replace bool) (*ScheduleSyncResult, error) {
+	now := time.Now()
+	result := &ScheduleSyncResult{Schedule: schedule, Kept: []uuid.UUID{}}
+
+	if replace {
+		trips, err := uc.tripRepo.GetScheduleTrips(ctx, schedule.ID, now, now.AddDate(10, 0, 0))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load schedule trips: %w", err)
+		}
+		for _, trip := range trips {
+			removed := false
+			// The trip row stays locked until the trip is gone, so no booking can
+			// be made on it between the check and the delete
+			err := uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+				locked, err := uc.tripRepo.GetByIDForUpdate(ctx, trip.ID)
+				if err != nil {
+					return err
+				}
+				if locked.Status != entities.TripStatusScheduled {
+					return nil
+				}
+				// Bookings of any status keep the trip, so their records and
+				// payments are never lost
+				count, err := uc.bookingRepo.CountTripBookings(ctx, trip.ID)
+				if err != nil {
+					return fmt.Errorf("failed to count bookings: %w", err)
+				}
+				if count > 0 {
+					return nil
+				}
+
+				if err := uc.seatRepo.DeleteByTrip(ctx, trip.ID); err != nil {
+					return err
+				}
+				if err := uc.tripRepo.Delete(ctx, trip.ID); err != nil {
+					return err
+				}
+				removed = true
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to remove trip %s: %w", trip.ID, err)
+			}
+			if removed {
+				result.Removed++
+			} else {
+				result.Kept = append(result.Kept, trip.ID)
+			}
+		}
+	}
+
+	if !schedule.IsActive {
+		return result, nil
+	}
+
+	holidays, err := uc.holidays(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	result.Created, err = uc.generate(ctx, schedule, holidays, now)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// generate creates the schedule's trips for the days up to the horizon that
+// run and do not have one yet, returning how many it created
+func (uc *TripScheduleUsecase) generate(ctx context.Context, schedule *entities.TripSchedule, holidays map[string]bool, now time.Time) (int, error) {
+	departureClock, err := time.Parse(entities.ScheduleTimeLayout, schedule.DepartureTime)
+	if err != nil {
+		return 0, fmt.Errorf("invalid departure time %q", schedule.DepartureTime)
+	}
+
+	local := now.In(uc.location)
+	firstDay := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, uc.location)
+	end := firstDay.AddDate(0, 0, uc.horizonDays+1)
+
+	existing, err := uc.tripRepo.GetScheduleTrips(ctx, schedule.ID, firstDay, end)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load schedule trips: %w", err)
+	}
+	hasTrip := make(map[string]bool, len(existing))
+	for _, trip := range existing {
+		hasTrip[trip.DepartureTime.In(uc.location).Format(entities.ScheduleDateLayout)] = true
+	}
+
+	created := 0
+	for day := firstDay; day.Before(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(entities.ScheduleDateLayout)
+		if hasTrip[date] || !schedule.RunsOn(date, day.Weekday(), holidays) {
+			continue
+		}
+
+		departure := time.Date(day.Year(), day.Month(), day.Day(), departureClock.Hour(), departureClock.Minute(), 0, 0, uc.location)
+		if !departure.After(now) {
+			continue
+		}
+
+		scheduleID := schedule.ID
+		trip := &entities.Trip{
+			BusID:         schedule.BusID,
+			RouteID:       schedule.RouteID,
+			DepartureTime: departure,
+			ArrivalTime:   departure.Add(time.Duration(schedule.Duration) * time.Minute),
+			Duration:      schedule.Duration,
+			Price:         schedule.Price,
+			Status:        entities.TripStatusScheduled,
+			DriverID:      schedule.DriverID,
+			CoDriverID:    schedule.CoDriverID,
+			ScheduleID:    &scheduleID,
+		}
+
+		err = uc.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			return uc.trips.CreateTrip(ctx, trip)
+		})
+		if errors.Is(err, ErrScheduleConflict) {
+			log.Printf("Schedule %s: trip on %s not generated: %v", schedule.ID, date, err)
+			continue
+		}
+		if err != nil {
+			return created, fmt.Errorf("failed to create trip on %s: %w", date, err)
+		}
+		created++
+	}
+	return created, nil
+}
+
+// holidays returns the holiday dates from today up to the horizon
+func (uc *TripScheduleUsecase) holidays(ctx context.Context, now time.Time) (map[string]bool, error) {
+	local := now.In(uc.location)
+	from := local.Format(entities.ScheduleDateLayout)
+	to := local.AddDate(0, 0, uc.horizonDays+1).Format(entities.ScheduleDateLayout)
+
+	list, err := uc.holidayRepo.List(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load holidays: %w", err)
+	}
+	holidays := make(map[string]bool, len(list))
+	for _, holiday := range list {
+		holidays[holiday.Date] = true
+	}
+	return holidays, nil
+}
+
+func (uc *TripScheduleUsecase) validateSchedule(ctx context.Context, schedule *entities.TripSchedule) error {
+	if _, err := uc.routeRepo.GetByID(ctx, schedule.RouteID); err != nil {
+		return fmt.Errorf("route not found: %w", err)
+	}
+	bus, err := uc.busRepo.GetByID(ctx, schedule.BusID)
+	if err != nil {
+		return fmt.Errorf("bus not found: %w", err)
+	}
+	if err := assignDrivers(ctx, uc.driverRepo, &entities.Trip{DriverID: schedule.DriverID, CoDriverID: schedule.CoDriverID}, bus); err != nil {
+		return err
+	}
+
+	if _, err := time.Parse(entities.ScheduleTimeLayout, schedule.DepartureTime); err != nil {
+		return fmt.Errorf("invalid departure time, use HH:MM")
+	}
+	if schedule.Duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	if schedule.Price < 0 {
+		return fmt.Errorf("price must not be negative")
+	}
+
+	switch schedule.Recurrence {
+	case entities.RecurrenceDaily, entities.RecurrenceWeekdays:
+	case entities.RecurrenceDays:
+		if len(schedule.DaysOfWeek) == 0 {
+			return fmt.Errorf("days_of_week is required for recurrence %q", schedule.Recurrence)
+		}
+		for _, day := range schedule.DaysOfWeek {
+			if day < 0 || day > 6 {
+				return fmt.Errorf("days_of_week must be between 0 (Sunday) and 6 (Saturday)")
+			}
+		}
+	default:
+		return fmt.Errorf("invalid recurrence: %s", schedule.Recurrence)
+	}
+
+	if _, err := time.Parse(entities.ScheduleDateLayout, schedule.StartDate); err != nil {
+		return fmt.Errorf("invalid start date, use YYYY-MM-DD")
+	}
+	if schedule.EndDate != "" {
+		if _, err := time.Parse(entities.ScheduleDateLayout, schedule.EndDate); err != nil {
+			return fmt.Errorf("invalid end date, use YYYY-MM-DD")
+		}
+		if schedule.EndDate < schedule.StartDate {
+			return fmt.Errorf("end date must not be before start date")
+		}
+	}
+	for _, date := range schedule.ExceptDates {
+		if _, err := time.Parse(entities.ScheduleDateLayout, date); err != nil {
+			return fmt.Errorf("invalid exception date %q, use YYYY-MM-DD", date)
+		}
+	}
+
+	return nil
+}
@@ -2,7 +2,9 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,24 +12,53 @@ import (
 	"github.com/yourusername/bus-booking/internal/repositories"
 )
 
+// ErrInvalidTripSearch is returned for searches with malformed or contradictory filters
+var ErrInvalidTripSearch = errors.New("invalid trip search")
+
 type TripUsecase struct {
-	tripRepo  repositories.TripRepository
-	busRepo   repositories.BusRepository
-	routeRepo repositories.RouteRepository
-	seatRepo  repositories.SeatRepository
+	tx          repositories.Transactor
+	outboxRepo  repositories.OutboxRepository
+	tripRepo    repositories.TripRepository
+	busRepo     repositories.BusRepository
+	routeRepo   repositories.RouteRepository
+	seatRepo    repositories.SeatRepository
+	bookingRepo repositories.BookingRepository
+	driverRepo  repositories.DriverRepository
+	conflicts   *ScheduleConflictUsecase
+	locations   *LocationUsecase
+
+	roundTripDiscount float64
+	location          *time.Location // Business timezone search dates are days in and times are shown in
 }
 
 func NewTripUsecase(
+	tx repositories.Transactor,
+	outboxRepo repositories.OutboxRepository,
 	tripRepo repositories.TripRepository,
 	busRepo repositories.BusRepository,
 	routeRepo repositories.RouteRepository,
 	seatRepo repositories.SeatRepository,
+	bookingRepo repositories.BookingRepository,
+	driverRepo repositories.DriverRepository,
+	conflicts *ScheduleConflictUsecase,
+	locations *LocationUsecase,
+	roundTripDiscount float64,
+	location *time.Location,
 ) *TripUsecase {
 	return &TripUsecase{
-		tripRepo:  tripRepo,
-		busRepo:   busRepo,
-		routeRepo: routeRepo,
-		seatRepo:  seatRepo,
+		tx:          tx,
+		outboxRepo:  outboxRepo,
+		tripRepo:    tripRepo,
+		busRepo:     busRepo,
+		routeRepo:   routeRepo,
+		seatRepo:    seatRepo,
+		bookingRepo: bookingRepo,
+		driverRepo:  driverRepo,
+		conflicts:   conflicts,
+		locations:   locations,
+
+		roundTripDiscount: roundTripDiscount,
+		location:          location,
 	}
 }
 
@@ -50,6 +81,16 @@ func (uc *TripUsecase) CreateTrip(ctx context.Context, trip *entities.Trip) erro
 		return fmt.Errorf("route is not active")
 	}
 
+	if !trip.ArrivalTime.After(trip.DepartureTime) {
+		return fmt.Errorf("arrival time must be after departure time")
+	}
+	if err := assignDrivers(ctx, uc.driverRepo, trip, bus); err != nil {
+		return err
+	}
+	if err := uc.conflicts.CheckTrip(ctx, trip); err != nil {
+		return err
+	}
+
 	// Calculate duration if not provided
 	if trip.Duration == 0 {
 		trip.Duration = int(trip.ArrivalTime.Sub(trip.DepartureTime).Minutes())
@@ -66,18 +107,27 @@ func (uc *TripUsecase) CreateTrip(ctx context.Context, trip *entities.Trip) erro
 		return fmt.Errorf("failed to create trip: %w", err)
 	}
 
-	// Initialize seats for this trip
+	// Initialize seats for this trip, one row per segment between its stops
 	seatNumbers := generateSeatNumbers(bus.SeatLayout)
-	err = uc.seatRepo.InitializeSeatsForTrip(ctx, trip.ID, seatNumbers)
+	err = uc.seatRepo.InitializeSeatsForTrip(ctx, trip.ID, seatNumbers, route.SegmentCount())
 	if err != nil {
 		return fmt.Errorf("failed to initialize seats: %w", err)
 	}
 
+	if err := blockBusDefaultSeats(ctx, uc.seatRepo, trip.ID, bus); err != nil {
+		return fmt.Errorf("failed to block seats: %w", err)
+	}
+
 	return nil
 }
 
 func (uc *TripUsecase) GetTripByID(ctx context.Context, id uuid.UUID) (*entities.Trip, error) {
-	return uc.tripRepo.GetByIDWithDetails(ctx, id)
+	trip, err := uc.tripRepo.GetByIDWithDetails(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	trip.InLocation(uc.location)
+	return trip, nil
 }
 
 func (uc *TripUsecase) UpdateTrip(ctx context.Context, trip *entities.Trip) error {
@@ -91,10 +141,62 @@ func (uc *TripUsecase) UpdateTrip(ctx context.Context, trip *entities.Trip) erro
 	if existingTrip.Status != entities.TripStatusScheduled {
 		return fmt.Errorf("cannot update trip that is not in scheduled status")
 	}
+	if trip.BusID != existingTrip.BusID {
+		return fmt.Errorf("the bus of a trip can only be changed by replacing it")
+	}
+	if trip.Status != existingTrip.Status {
+		return fmt.Errorf("the status of a trip can only be changed through its lifecycle operations")
+	}
+
+	if trip.RouteID != existingTrip.RouteID {
+		if err := uc.checkRouteSegments(ctx, trip); err != nil {
+			return err
+		}
+	}
+
+	driversChanged := !sameDriver(trip.DriverID, existingTrip.DriverID) || !sameDriver(trip.CoDriverID, existingTrip.CoDriverID)
+	if driversChanged {
+		bus, err := uc.busRepo.GetByID(ctx, trip.BusID)
+		if err != nil {
+			return fmt.Errorf("bus not found: %w", err)
+		}
+		if err := assignDrivers(ctx, uc.driverRepo, trip, bus); err != nil {
+			return err
+		}
+	}
+
+	if driversChanged || !trip.DepartureTime.Equal(existingTrip.DepartureTime) || !trip.ArrivalTime.Equal(existingTrip.ArrivalTime) || trip.RouteID != existingTrip.RouteID {
+		if !trip.ArrivalTime.After(trip.DepartureTime) {
+			return fmt.Errorf("arrival time must be after departure time")
+		}
+		if err := uc.conflicts.CheckTrip(ctx, trip); err != nil {
+			return err
+		}
+	}
+	if !trip.DepartureTime.Equal(existingTrip.DepartureTime) || !trip.ArrivalTime.Equal(existingTrip.ArrivalTime) {
+		trip.Duration = int(trip.ArrivalTime.Sub(trip.DepartureTime).Minutes())
+	}
 
 	return uc.tripRepo.Update(ctx, trip)
 }
 
+// checkRouteSegments makes sure a trip moved onto another route keeps the
+// same segments, as its seat inventory is split by the stops of its route
+func (uc *TripUsecase) checkRouteSegments(ctx context.Context, trip *entities.Trip) error {
+	route, err := uc.routeRepo.GetByID(ctx, trip.RouteID)
+	if err != nil {
+		return fmt.Errorf("route not found: %w", err)
+	}
+	segments, err := uc.seatRepo.CountSegments(ctx, trip.ID)
+	if err != nil {
+		return fmt.Errorf("failed to count segments: %w", err)
+	}
+	if route.SegmentCount() != segments {
+		return fmt.Errorf("the new route has %d stops but the trip is sold over %d", route.SegmentCount()-1, segments-1)
+	}
+	return nil
+}
+
 func (uc *TripUsecase) DeleteTrip(ctx context.Context, id uuid.UUID) error {
 	trip, err := uc.tripRepo.GetByID(ctx, id)
 	if err != nil {
@@ -106,31 +208,320 @@ func (uc *TripUsecase) DeleteTrip(ctx context.Context, id uuid.UUID) error {
 		return fmt.Errorf("cannot delete trip in progress")
 	}
 
+	bookings, err := uc.bookingRepo.GetTripBookingsByStatus(ctx, id, []entities.BookingStatus{
+		entities.BookingStatusPending,
+		entities.BookingStatusPaid,
+		entities.BookingStatusConfirmed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check bookings: %w", err)
+	}
+	if len(bookings) > 0 {
+		return fmt.Errorf("trip has %d active bookings, cancel it instead", len(bookings))
+	}
+
 	return uc.tripRepo.Delete(ctx, id)
 }
 
-func (uc *TripUsecase) SearchTrips(ctx context.Context, fromCity, toCity string, date time.Time, page, limit int) ([]*entities.Trip, error) {
-	offset := (page - 1) * limit
-	return uc.tripRepo.Search(ctx, fromCity, toCity, date, limit, offset)
+// TripSearchInput is a one-way trip search as a customer enters it; empty
+// filters match every trip
+type TripSearchInput struct {
+	FromCity       string // Resolved to a location of the catalog unless FromLocationID is set
+	ToCity         string // Resolved to a location of the catalog unless ToLocationID is set
+	FromLocationID *uuid.UUID
+	ToLocationID   *uuid.UUID
+	Date           time.Time // Calendar day in the business timezone, whatever the location of Date
+	DepartAfter    string    // HH:MM local time, inclusive
+	DepartBefore   string    // HH:MM local time, exclusive
+	MinPrice       *float64
+	MaxPrice       *float64
+	BusTypes       []string
+	Operators      []string
+	Amenities      []string // The bus must have all of them
+	MinSeats       int
+	Sort           entities.TripSearchSort
+	Descending     bool
+	Page           int
+	Limit          int
+}
+
+// TripSearchResults is a page of the trips found with the facet counts of the whole search
+type TripSearchResults struct {
+	From   *entities.Location           `json:"from"` // Locations the cities were taken to mean
+	To     *entities.Location           `json:"to"`
+	Trips  []*entities.TripSearchResult `json:"trips"`
+	Facets *entities.TripSearchFacets   `json:"facets"`
+}
+
+// SearchTrips finds the trips matching the search on its day with their free
+// seats and adult fares, and counts the trips per filter value for the
+// filters a customer can still pick
+func (uc *TripUsecase) SearchTrips(ctx context.Context, input TripSearchInput) (*TripSearchResults, error) {
+	from, to, err := uc.searchLocations(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+	search, err := uc.tripSearch(input)
+	if err != nil {
+		return nil, err
+	}
+
+	trips, err := uc.tripRepo.Search(ctx, search, input.Limit, (input.Page-1)*input.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search trips: %w", err)
+	}
+	facets, err := uc.tripRepo.SearchFacets(ctx, search)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count search facets: %w", err)
+	}
+
+	for _, trip := range trips {
+		trip.InLocation(uc.location)
+	}
+
+	return &TripSearchResults{From: from, To: to, Trips: trips, Facets: facets}, nil
+}
+
+// searchLocations resolves the cities of a search to the locations of the
+// catalog they mean, however they were spelled, and sets the search's
+// location IDs to them
+func (uc *TripUsecase) searchLocations(ctx context.Context, input *TripSearchInput) (from, to *entities.Location, err error) {
+	resolve := func(id **uuid.UUID, city string) (*entities.Location, error) {
+		if *id != nil {
+			location, err := uc.locations.GetLocation(ctx, **id)
+			if err != nil {
+				return nil, fmt.Errorf("%w: location %s not found", ErrInvalidTripSearch, **id)
+			}
+			return location, nil
+		}
+		location, err := uc.locations.ResolveLocation(ctx, city)
+		if errors.Is(err, ErrLocationNotFound) {
+			return nil, fmt.Errorf("%w: no city matches %q", ErrInvalidTripSearch, city)
+		}
+		if err != nil {
+			return nil, err
+		}
+		*id = &location.ID
+		return location, nil
+	}
+
+	if from, err = resolve(&input.FromLocationID, input.FromCity); err != nil {
+		return nil, nil, err
+	}
+	if to, err = resolve(&input.ToLocationID, input.ToCity); err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// tripSearch checks the filters of a search and turns its day and departure
+// window into periods in the business timezone
+func (uc *TripUsecase) tripSearch(input TripSearchInput) (*entities.TripSearch, error) {
+	from, to := dayRange(input.Date, uc.location)
+	search := &entities.TripSearch{
+		FromLocationID: *input.FromLocationID,
+		ToLocationID:   *input.ToLocationID,
+		From:           from,
+		To:             to,
+		DepartFrom:     from,
+		DepartTo:       to,
+		MinPrice:       input.MinPrice,
+		MaxPrice:       input.MaxPrice,
+		BusTypes:       input.BusTypes,
+		Operators:      input.Operators,
+		Amenities:      input.Amenities,
+		MinSeats:       input.MinSeats,
+		Sort:           input.Sort,
+		Descending:     input.Descending,
+	}
+
+	atClock := func(clock string) (time.Time, error) {
+		parsed, err := time.Parse(entities.ScheduleTimeLayout, clock)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: invalid departure time %q, use HH:MM", ErrInvalidTripSearch, clock)
+		}
+		return time.Date(from.Year(), from.Month(), from.Day(), parsed.Hour(), parsed.Minute(), 0, 0, uc.location), nil
+	}
+	var err error
+	if input.DepartAfter != "" {
+		if search.DepartFrom, err = atClock(input.DepartAfter); err != nil {
+			return nil, err
+		}
+	}
+	if input.DepartBefore != "" {
+		if search.DepartTo, err = atClock(input.DepartBefore); err != nil {
+			return nil, err
+		}
+	}
+	if !search.DepartFrom.Before(search.DepartTo) {
+		return nil, fmt.Errorf("%w: departure window must end after it starts", ErrInvalidTripSearch)
+	}
+
+	if (search.MinPrice != nil && *search.MinPrice < 0) || (search.MaxPrice != nil && *search.MaxPrice < 0) {
+		return nil, fmt.Errorf("%w: prices must not be negative", ErrInvalidTripSearch)
+	}
+	if search.MinPrice != nil && search.MaxPrice != nil && *search.MinPrice > *search.MaxPrice {
+		return nil, fmt.Errorf("%w: minimum price must not be above maximum price", ErrInvalidTripSearch)
+	}
+	if search.MinSeats < 0 {
+		return nil, fmt.Errorf("%w: minimum seats must not be negative", ErrInvalidTripSearch)
+	}
+	if search.Sort == "" {
+		search.Sort = entities.TripSortDeparture
+	}
+	if !search.Sort.IsValid() {
+		return nil, fmt.Errorf("%w: invalid sort: %s", ErrInvalidTripSearch, search.Sort)
+	}
+	return search, nil
+}
+
+// dayRange returns the half-open period the calendar day of date covers in
+// location; days around a DST change are not 24 hours long
+func dayRange(date time.Time, location *time.Location) (from, to time.Time) {
+	from = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+	return from, from.AddDate(0, 0, 1)
+}
+
+// RoundTripOption pairs an outbound trip with a return trip it connects to.
+// Prices are per passenger, with the round-trip discount taken off the return fare.
+type RoundTripOption struct {
+	OutboundTrip   *entities.Trip `json:"outbound_trip"`
+	ReturnTrip     *entities.Trip `json:"return_trip"`
+	OutboundPrice  float64        `json:"outbound_price"`
+	ReturnPrice    float64        `json:"return_price"`
+	DiscountAmount float64        `json:"discount_amount"`
+	TotalPrice     float64        `json:"total_price"`
+}
+
+// RoundTripSearchResult holds both directions of a round-trip search and their
+// valid pairings; the facets are those of the outbound search
+type RoundTripSearchResult struct {
+	OutboundTrips []*entities.TripSearchResult `json:"trips"`
+	ReturnTrips   []*entities.TripSearchResult `json:"return_trips"`
+	Options       []*RoundTripOption           `json:"round_trips"`
+	Facets        *entities.TripSearchFacets   `json:"facets"`
+}
+
+// SearchRoundTrips searches both directions and pairs every outbound trip with
+// each return trip that departs after it arrives, cheapest first. The return
+// search uses the same filters but the departure window, which is for the
+// outbound day.
+func (uc *TripUsecase) SearchRoundTrips(ctx context.Context, input TripSearchInput, returnDate time.Time) (*RoundTripSearchResult, error) {
+	if returnDate.Before(input.Date) {
+		return nil, fmt.Errorf("%w: return date must not be before departure date", ErrInvalidTripSearch)
+	}
+
+	if _, _, err := uc.searchLocations(ctx, &input); err != nil {
+		return nil, err
+	}
+	outbound, err := uc.SearchTrips(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	back := input
+	back.FromCity, back.ToCity = input.ToCity, input.FromCity
+	back.FromLocationID, back.ToLocationID = input.ToLocationID, input.FromLocationID
+	back.Date = returnDate
+	back.DepartAfter, back.DepartBefore = "", ""
+	returns, err := uc.SearchTrips(ctx, back)
+	if err != nil {
+		return nil, err
+	}
+
+	options := make([]*RoundTripOption, 0)
+	for _, out := range outbound.Trips {
+		for _, back := range returns.Trips {
+			if !back.DepartureTime.After(out.ArrivalTime) {
+				continue
+			}
+			discount := roundTripDiscount(back.Price, uc.roundTripDiscount)
+			options = append(options, &RoundTripOption{
+				OutboundTrip:   out.Trip,
+				ReturnTrip:     back.Trip,
+				OutboundPrice:  out.Price,
+				ReturnPrice:    back.Price - discount,
+				DiscountAmount: discount,
+				TotalPrice:     out.Price + back.Price - discount,
+			})
+		}
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].TotalPrice < options[j].TotalPrice
+	})
+	if len(options) > input.Limit {
+		options = options[:input.Limit]
+	}
+
+	return &RoundTripSearchResult{
+		OutboundTrips: outbound.Trips,
+		ReturnTrips:   returns.Trips,
+		Options:       options,
+		Facets:        outbound.Facets,
+	}, nil
 }
 
 func (uc *TripUsecase) ListTrips(ctx context.Context, status entities.TripStatus, page, limit int) ([]*entities.Trip, error) {
 	offset := (page - 1) * limit
-	return uc.tripRepo.List(ctx, status, limit, offset)
+	return uc.inLocation(uc.tripRepo.List(ctx, status, limit, offset))
 }
 
 func (uc *TripUsecase) GetUpcomingTrips(ctx context.Context, limit int) ([]*entities.Trip, error) {
-	return uc.tripRepo.GetUpcomingTrips(ctx, limit)
+	return uc.inLocation(uc.tripRepo.GetUpcomingTrips(ctx, limit))
+}
+
+// inLocation puts the times of the trips loaded in the business timezone
+func (uc *TripUsecase) inLocation(trips []*entities.Trip, err error) ([]*entities.Trip, error) {
+	for _, trip := range trips {
+		trip.InLocation(uc.location)
+	}
+	return trips, err
+}
+
+// recordTripEvent stores a trip event in the outbox
+func recordTripEvent(ctx context.Context, outboxRepo repositories.OutboxRepository, eventType entities.EventType, trip *entities.Trip, previous entities.TripStatus, reason string) error {
+	return recordEvent(ctx, outboxRepo, entities.AggregateTrip, trip.ID, eventType, entities.TripEventPayload{
+		TripID:         trip.ID,
+		Status:         trip.Status,
+		PreviousStatus: previous,
+		DepartureTime:  trip.DepartureTime,
+		ArrivalTime:    trip.ArrivalTime,
+		Reason:         reason,
+	})
+}
+
+// blockBusDefaultSeats applies a bus's default blocks to the seats of a trip,
+// grouped so each reason is one update
+func blockBusDefaultSeats(ctx context.Context, seatRepo repositories.SeatRepository, tripID uuid.UUID, bus *entities.Bus) error {
+	seatsByReason := make(map[string][]string)
+	for _, block := range bus.BlockedSeats {
+		seatsByReason[block.Reason] = append(seatsByReason[block.Reason], block.SeatNumber)
+	}
+	for reason, seats := range seatsByReason {
+		if err := seatRepo.BlockSeats(ctx, tripID, seats, reason, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func generateSeatNumbers(layout entities.SeatLayout) []string {
 	seats := make([]string, 0, layout.TotalSeats)
 	for _, row := range layout.Layout {
 		for _, seat := range row {
-			if seat != "aisle" && seat != "empty" && seat != "" {
+			if entities.IsSeatCell(seat) {
 				seats = append(seats, seat)
 			}
 		}
 	}
 	return seats
 }
+
+// sameDriver reports whether two optional driver assignments are the same
+func sameDriver(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -16,9 +16,9 @@ CREATE TABLE IF NOT EXISTS users (
     oauth_provider VARCHAR(20),
     avatar TEXT,
     is_active BOOLEAN DEFAULT true,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    deleted_at TIMESTAMP
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ
 );
 
 CREATE INDEX idx_users_email ON users(email);
@@ -36,72 +36,186 @@ CREATE TABLE IF NOT EXISTS buses (
     operator_name VARCHAR(255),
     seat_layout JSONB NOT NULL,
     amenities TEXT[],
+    rating DECIMAL(2, 1) CHECK (rating BETWEEN 1 AND 5),
+    blocked_seats JSONB NOT NULL DEFAULT '[]',
     status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'maintenance', 'inactive')),
-    last_maintenance TIMESTAMP,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    last_maintenance TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX idx_buses_status ON buses(status);
 CREATE INDEX idx_buses_license ON buses(license_plate);
 
+-- Drivers table
+CREATE TABLE IF NOT EXISTS drivers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    operator_name VARCHAR(255) NOT NULL, -- Matches buses.operator_name
+    full_name VARCHAR(255) NOT NULL,
+    phone VARCHAR(20) NOT NULL,
+    email VARCHAR(255),
+    licence_number VARCHAR(20) UNIQUE NOT NULL,
+    licence_class VARCHAR(2) NOT NULL CHECK (licence_class IN ('D', 'E')), -- D: up to 30 seats, E: more
+    licence_expiry TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'inactive')),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_drivers_operator ON drivers(operator_name);
+CREATE INDEX idx_drivers_status ON drivers(status);
+
+-- Locations table: the canonical cities and towns routes run between.
+-- search_name is the name folded by the application: lower case, without
+-- Vietnamese diacritics or a leading "TP."/"Tỉnh", e.g. 'ho chi minh'
+CREATE TABLE IF NOT EXISTS locations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    search_name VARCHAR(100) NOT NULL UNIQUE,
+    province VARCHAR(100),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_locations_search_trgm ON locations USING gin (search_name gin_trgm_ops);
+
+-- Location aliases table: other names and abbreviations of a location,
+-- e.g. 'Sài Gòn' or 'HCM', folded the same way
+CREATE TABLE IF NOT EXISTS location_aliases (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    search_name VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_location_aliases_location ON location_aliases(location_id);
+CREATE INDEX idx_location_aliases_search_trgm ON location_aliases USING gin (search_name gin_trgm_ops);
+
 -- Routes table
 CREATE TABLE IF NOT EXISTS routes (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     name VARCHAR(255) NOT NULL,
-    from_city VARCHAR(100) NOT NULL,
+    from_location_id UUID NOT NULL REFERENCES locations(id),
+    to_location_id UUID NOT NULL REFERENCES locations(id),
+    from_city VARCHAR(100) NOT NULL, -- Name of the from location, for display
     to_city VARCHAR(100) NOT NULL,
     distance DECIMAL(10, 2) NOT NULL,
     base_price DECIMAL(10, 2) NOT NULL,
     description TEXT,
     is_active BOOLEAN DEFAULT true,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE INDEX idx_routes_cities ON routes(from_city, to_city);
+CREATE INDEX idx_routes_locations ON routes(from_location_id, to_location_id);
+CREATE INDEX idx_routes_to_location ON routes(to_location_id);
 CREATE INDEX idx_routes_active ON routes(is_active);
 
+-- Route stops table: pickup and drop-off points in the order the bus calls at them
+CREATE TABLE IF NOT EXISTS route_stops (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    sequence INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    address TEXT,
+    city VARCHAR(100) NOT NULL,
+    latitude DECIMAL(9, 6),
+    longitude DECIMAL(9, 6),
+    offset_minutes INTEGER NOT NULL DEFAULT 0 CHECK (offset_minutes >= 0),
+    distance DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (distance >= 0), -- km from the route's origin
+    allows_pickup BOOLEAN NOT NULL DEFAULT true,
+    allows_dropoff BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CHECK (allows_pickup OR allows_dropoff)
+);
+
+CREATE INDEX idx_route_stops_route ON route_stops(route_id, sequence);
+
 -- Trips table
 CREATE TABLE IF NOT EXISTS trips (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     bus_id UUID NOT NULL REFERENCES buses(id) ON DELETE CASCADE,
     route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
-    departure_time TIMESTAMP NOT NULL,
-    arrival_time TIMESTAMP NOT NULL,
+    departure_time TIMESTAMPTZ NOT NULL,
+    arrival_time TIMESTAMPTZ NOT NULL,
     duration INTEGER NOT NULL,
     price DECIMAL(10, 2) NOT NULL,
     status VARCHAR(20) NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'boarding', 'in_transit', 'completed', 'cancelled', 'delayed')),
-    driver_name VARCHAR(255),
+    driver_id UUID REFERENCES drivers(id),
+    co_driver_id UUID REFERENCES drivers(id),
+    driver_name VARCHAR(255), -- Copied from the main driver
     driver_phone VARCHAR(20),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    schedule_id UUID, -- References trip_schedules, set on generated trips
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX idx_trips_bus ON trips(bus_id);
+CREATE UNIQUE INDEX idx_trips_schedule_departure ON trips(schedule_id, departure_time);
 CREATE INDEX idx_trips_route ON trips(route_id);
 CREATE INDEX idx_trips_departure ON trips(departure_time);
 CREATE INDEX idx_trips_status ON trips(status);
+CREATE INDEX idx_trips_driver ON trips(driver_id);
+CREATE INDEX idx_trips_co_driver ON trips(co_driver_id);
+
+-- Trip schedules table (timetable templates trips are generated from)
+CREATE TABLE IF NOT EXISTS trip_schedules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    bus_id UUID NOT NULL REFERENCES buses(id) ON DELETE CASCADE,
+    departure_time VARCHAR(5) NOT NULL, -- HH:MM in the business timezone
+    duration INTEGER NOT NULL,
+    price DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    recurrence VARCHAR(20) NOT NULL CHECK (recurrence IN ('daily', 'weekdays', 'days')),
+    days_of_week INTEGER[],
+    except_dates TEXT[],
+    skip_holidays BOOLEAN NOT NULL DEFAULT false,
+    start_date VARCHAR(10) NOT NULL,
+    end_date VARCHAR(10),
+    driver_id UUID REFERENCES drivers(id),
+    co_driver_id UUID REFERENCES drivers(id),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_trip_schedules_active ON trip_schedules(is_active);
+
+-- Holidays table (dates schedules can skip)
+CREATE TABLE IF NOT EXISTS holidays (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    date VARCHAR(10) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
 
 -- Seats status table (critical for concurrent booking)
 CREATE TABLE IF NOT EXISTS seats_status (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
     seat_number VARCHAR(10) NOT NULL,
-    status VARCHAR(20) NOT NULL DEFAULT 'available' CHECK (status IN ('available', 'locked', 'booked')),
-    locked_until TIMESTAMP,
+    segment INTEGER NOT NULL DEFAULT 0 CHECK (segment >= 0), -- Between stop points segment and segment + 1
+    status VARCHAR(20) NOT NULL DEFAULT 'available' CHECK (status IN ('available', 'locked', 'booked', 'blocked')),
+    locked_until TIMESTAMPTZ,
     locked_by UUID,
     booking_id UUID,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(trip_id, seat_number)
+    block_reason VARCHAR(255),
+    blocked_until TIMESTAMPTZ,
+    blocked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(trip_id, seat_number, segment)
 );
 
 CREATE INDEX idx_seats_trip ON seats_status(trip_id);
 CREATE INDEX idx_seats_status ON seats_status(status);
 CREATE INDEX idx_seats_locked_until ON seats_status(locked_until);
 CREATE INDEX idx_seats_booking ON seats_status(booking_id);
-CREATE UNIQUE INDEX idx_seats_trip_seat ON seats_status(trip_id, seat_number);
+CREATE INDEX idx_seats_blocked_until ON seats_status(blocked_until) WHERE status = 'blocked';
+CREATE UNIQUE INDEX idx_trip_seat ON seats_status(trip_id, seat_number, segment); -- Name AutoMigrate uses
 
 -- Bookings table
 CREATE TABLE IF NOT EXISTS bookings (
@@ -112,13 +226,27 @@ CREATE TABLE IF NOT EXISTS bookings (
     contact_phone VARCHAR(20) NOT NULL,
     contact_name VARCHAR(255) NOT NULL,
     seats TEXT[] NOT NULL,
+    passengers JSONB NOT NULL DEFAULT '[]',
     total_price DECIMAL(10, 2) NOT NULL,
     status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'confirmed', 'expired', 'cancelled', 'refunded')),
-    expires_at TIMESTAMP,
+    expires_at TIMESTAMPTZ,
     booking_code VARCHAR(50) UNIQUE NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    cancelled_at TIMESTAMP
+    lock_id UUID,
+    client_ip VARCHAR(45),
+    device_id VARCHAR(100),
+    parent_booking_id UUID REFERENCES bookings(id) ON DELETE CASCADE,
+    discount_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    promo_code VARCHAR(50),
+    promo_discount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    points_redeemed INTEGER NOT NULL DEFAULT 0,
+    points_discount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    pickup_stop_id UUID REFERENCES route_stops(id),
+    dropoff_stop_id UUID REFERENCES route_stops(id),
+    from_stop INTEGER NOT NULL DEFAULT 0, -- Stop points travelled between, 0 being the origin
+    to_stop INTEGER NOT NULL DEFAULT 1 CHECK (to_stop > from_stop),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    cancelled_at TIMESTAMPTZ
 );
 
 CREATE INDEX idx_bookings_trip ON bookings(trip_id);
@@ -126,6 +254,7 @@ CREATE INDEX idx_bookings_user ON bookings(user_id);
 CREATE INDEX idx_bookings_status ON bookings(status);
 CREATE INDEX idx_bookings_code ON bookings(booking_code);
 CREATE INDEX idx_bookings_expires ON bookings(expires_at);
+CREATE INDEX idx_bookings_parent ON bookings(parent_booking_id);
 
 -- Payments table
 CREATE TABLE IF NOT EXISTS payments (
@@ -135,14 +264,14 @@ CREATE TABLE IF NOT EXISTS payments (
     gateway_payment_id VARCHAR(255) UNIQUE,
     amount DECIMAL(10, 2) NOT NULL,
     currency VARCHAR(3) DEFAULT 'VND',
-    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed', 'refunded', 'cancelled')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed', 'refunding', 'refunded', 'cancelled')),
     payment_method VARCHAR(50),
     transaction_id VARCHAR(255),
     failure_reason TEXT,
     idempotency_key VARCHAR(255) UNIQUE NOT NULL,
-    paid_at TIMESTAMP,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    paid_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX idx_payments_booking ON payments(booking_id);
@@ -157,28 +286,271 @@ CREATE TABLE IF NOT EXISTS tickets (
     passenger_name VARCHAR(255) NOT NULL,
     passenger_phone VARCHAR(20),
     passenger_email VARCHAR(255),
-    seat_number VARCHAR(10) NOT NULL,
+    seat_number VARCHAR(10) NOT NULL, -- Empty for lap infants
+    fare_category VARCHAR(20) NOT NULL DEFAULT 'adult' CHECK (fare_category IN ('adult', 'child', 'senior', 'student', 'infant')),
+    fare DECIMAL(10, 2) NOT NULL DEFAULT 0,
     ticket_code VARCHAR(50) UNIQUE NOT NULL,
     qr_code_path TEXT,
     pdf_path TEXT,
     is_checked_in BOOLEAN DEFAULT false,
-    checked_in_at TIMESTAMP,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    checked_in_at TIMESTAMPTZ,
+    needs_seat BOOLEAN NOT NULL DEFAULT false, -- Lost its seat in a bus swap
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX idx_tickets_booking ON tickets(booking_id);
 CREATE INDEX idx_tickets_code ON tickets(ticket_code);
 
+-- Fare rules table (passenger fare categories, optionally per operator/route)
+CREATE TABLE IF NOT EXISTS fare_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    category VARCHAR(20) NOT NULL CHECK (category IN ('adult', 'child', 'senior', 'student', 'infant')),
+    operator_name VARCHAR(255),
+    route_id UUID REFERENCES routes(id) ON DELETE CASCADE,
+    discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percentage', 'fixed')),
+    discount_value DECIMAL(10, 2) NOT NULL,
+    min_age INTEGER,
+    max_age INTEGER,
+    requires_id BOOLEAN DEFAULT false,
+    requires_seat BOOLEAN NOT NULL DEFAULT true,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_fare_rules_category ON fare_rules(category);
+CREATE INDEX idx_fare_rules_route ON fare_rules(route_id);
+
+-- Promotions table (discount campaigns redeemed through promo codes)
+CREATE TABLE IF NOT EXISTS promotions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percentage', 'fixed')),
+    discount_value DECIMAL(10, 2) NOT NULL,
+    min_spend DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    max_discount DECIMAL(10, 2),
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    route_ids TEXT[],
+    operator_names TEXT[],
+    departure_from TIMESTAMPTZ,
+    departure_to TIMESTAMPTZ,
+    first_time_only BOOLEAN DEFAULT false,
+    usage_limit INTEGER CHECK (usage_limit > 0),
+    per_user_limit INTEGER CHECK (per_user_limit > 0),
+    times_used INTEGER NOT NULL DEFAULT 0 CHECK (usage_limit IS NULL OR times_used <= usage_limit),
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Promo codes table (shared codes and single-use voucher batches)
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    promotion_id UUID NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+    code VARCHAR(50) UNIQUE NOT NULL,
+    usage_limit INTEGER CHECK (usage_limit > 0),
+    times_used INTEGER NOT NULL DEFAULT 0 CHECK (usage_limit IS NULL OR times_used <= usage_limit),
+    batch_id UUID,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_promo_codes_promotion ON promo_codes(promotion_id);
+CREATE INDEX idx_promo_codes_batch ON promo_codes(batch_id);
+
+-- Promo redemptions table (one per booking; released ones no longer count)
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    promotion_id UUID NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+    promo_code_id UUID NOT NULL REFERENCES promo_codes(id) ON DELETE CASCADE,
+    booking_id UUID UNIQUE NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    contact_email VARCHAR(255) NOT NULL,
+    discount_amount DECIMAL(10, 2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'released')),
+    released_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_promo_redemptions_promotion ON promo_redemptions(promotion_id, status);
+CREATE INDEX idx_promo_redemptions_user ON promo_redemptions(user_id);
+CREATE INDEX idx_promo_redemptions_email ON promo_redemptions(LOWER(contact_email));
+
+-- Loyalty accounts table (running balance per member; locked to serialise postings)
+CREATE TABLE IF NOT EXISTS loyalty_accounts (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    balance INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Loyalty transactions table (append-only ledger postings)
+CREATE TABLE IF NOT EXISTS loyalty_transactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('earn', 'earn_reversal', 'redeem', 'redeem_reversal', 'expire')),
+    points INTEGER NOT NULL,
+    description TEXT,
+    attempt INTEGER NOT NULL DEFAULT 0, -- Redemptions made again after their points were returned count up
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_loyalty_transactions_user ON loyalty_transactions(user_id, created_at DESC);
+CREATE UNIQUE INDEX idx_loyalty_txn_booking_attempt ON loyalty_transactions(booking_id, type, attempt);
+
+-- Loyalty entries table (double-entry lines; each transaction's entries sum to zero)
+CREATE TABLE IF NOT EXISTS loyalty_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transaction_id UUID NOT NULL REFERENCES loyalty_transactions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account VARCHAR(20) NOT NULL CHECK (account IN ('member', 'issued', 'redeemed', 'expired')),
+    amount INTEGER NOT NULL,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_loyalty_entries_transaction ON loyalty_entries(transaction_id);
+CREATE INDEX idx_loyalty_entries_member ON loyalty_entries(user_id, account);
+
+-- Hold violations table (seat holds refused by anti-hoarding limits, for fraud review)
+CREATE TABLE IF NOT EXISTS hold_violations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    policy VARCHAR(30) NOT NULL CHECK (policy IN ('seats_per_booking', 'pending_bookings', 'trip_seats', 'cooldown')),
+    identity VARCHAR(255) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    client_ip VARCHAR(45),
+    device_id VARCHAR(100),
+    trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    seat_count INTEGER NOT NULL,
+    "limit" INTEGER NOT NULL,
+    retry_after INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_hold_violations_policy ON hold_violations(policy, created_at DESC);
+CREATE INDEX idx_hold_violations_identity ON hold_violations(identity);
+CREATE INDEX idx_hold_violations_user ON hold_violations(user_id);
+
+-- Seat discrepancies table (findings of the Redis/seats_status/bookings reconciler)
+CREATE TABLE IF NOT EXISTS seat_discrepancies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    run_id UUID NOT NULL,
+    trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    seat_number VARCHAR(10) NOT NULL DEFAULT '',
+    kind VARCHAR(30) NOT NULL CHECK (kind IN ('booked_without_booking', 'confirmed_not_booked', 'orphaned_db_lock', 'orphaned_redis_lock', 'missing_redis_lock', 'stale_seat_map')),
+    resolution VARCHAR(20) NOT NULL CHECK (resolution IN ('repaired', 'flagged')),
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    details TEXT,
+    occurrences INTEGER NOT NULL DEFAULT 1,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_seat_discrepancies_trip ON seat_discrepancies(trip_id, seat_number, kind);
+CREATE INDEX idx_seat_discrepancies_open ON seat_discrepancies(last_seen_at DESC) WHERE resolved_at IS NULL;
+
+-- Bus swaps table (history of trips moved onto another bus)
+CREATE TABLE IF NOT EXISTS bus_swaps (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    old_bus_id UUID NOT NULL REFERENCES buses(id),
+    new_bus_id UUID NOT NULL REFERENCES buses(id),
+    reason TEXT,
+    swapped_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    moves JSONB NOT NULL DEFAULT '[]',
+    unseated INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_bus_swaps_trip ON bus_swaps(trip_id, created_at DESC);
+
+-- Trip cancellations table (operator cancellations and how each booking was settled)
+CREATE TABLE IF NOT EXISTS trip_cancellations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    trip_id UUID UNIQUE NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    alternative_trip_id UUID REFERENCES trips(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    cancelled_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    choice_deadline TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS trip_cancellation_outcomes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    cancellation_id UUID NOT NULL REFERENCES trip_cancellations(id) ON DELETE CASCADE,
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    booking_code VARCHAR(50) NOT NULL,
+    contact_email VARCHAR(255) NOT NULL,
+    passengers INTEGER NOT NULL DEFAULT 0,
+    old_seats TEXT[],
+    new_seats TEXT[],
+    resolution VARCHAR(20) NOT NULL CHECK (resolution IN ('rebooked', 'refunded', 'refund_failed')),
+    refund_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    error TEXT,
+    choice_token VARCHAR(64) UNIQUE NOT NULL,
+    chosen_by_passenger BOOLEAN NOT NULL DEFAULT false,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(cancellation_id, booking_id)
+);
+
+CREATE INDEX idx_trip_cancellation_outcomes_cancellation ON trip_cancellation_outcomes(cancellation_id);
+CREATE INDEX idx_trip_cancellation_outcomes_booking ON trip_cancellation_outcomes(booking_id);
+
+-- Fulfilments table (post-payment confirm/ticket/email saga)
+CREATE TABLE IF NOT EXISTS fulfilments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    booking_id UUID UNIQUE NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'compensated', 'failed')),
+    step VARCHAR(20) NOT NULL DEFAULT 'confirm' CHECK (step IN ('confirm', 'documents', 'email', 'compensate', 'done')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_fulfilments_status ON fulfilments(status);
+CREATE INDEX idx_fulfilments_next_attempt ON fulfilments(next_attempt_at);
+
+-- Outbox events table (domain events relayed to Redis Streams)
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID UNIQUE NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMPTZ,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    claimed_until TIMESTAMPTZ -- Lease of the relay publishing it
+);
+
+CREATE INDEX idx_outbox_events_unpublished ON outbox_events(id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_events_aggregate ON outbox_events(aggregate_id);
+CREATE INDEX idx_outbox_events_published ON outbox_events(published_at);
+
 -- Refresh tokens table
 CREATE TABLE IF NOT EXISTS refresh_tokens (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     token TEXT UNIQUE NOT NULL,
-    expires_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
     is_revoked BOOLEAN DEFAULT false,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    revoked_at TIMESTAMP
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMPTZ
 );
 
 CREATE INDEX idx_refresh_tokens_user ON refresh_tokens(user_id);
@@ -197,9 +569,19 @@ $$ language 'plpgsql';
 -- Create triggers for updated_at
 CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_buses_updated_at BEFORE UPDATE ON buses FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_drivers_updated_at BEFORE UPDATE ON drivers FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_routes_updated_at BEFORE UPDATE ON routes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_route_stops_updated_at BEFORE UPDATE ON route_stops FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_trips_updated_at BEFORE UPDATE ON trips FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_seats_status_updated_at BEFORE UPDATE ON seats_status FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_bookings_updated_at BEFORE UPDATE ON bookings FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_payments_updated_at BEFORE UPDATE ON payments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_tickets_updated_at BEFORE UPDATE ON tickets FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_fare_rules_updated_at BEFORE UPDATE ON fare_rules FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_promotions_updated_at BEFORE UPDATE ON promotions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_promo_codes_updated_at BEFORE UPDATE ON promo_codes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_loyalty_accounts_updated_at BEFORE UPDATE ON loyalty_accounts FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_promo_redemptions_updated_at BEFORE UPDATE ON promo_redemptions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_fulfilments_updated_at BEFORE UPDATE ON fulfilments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_trip_schedules_updated_at BEFORE UPDATE ON trip_schedules FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_trip_cancellation_outcomes_updated_at BEFORE UPDATE ON trip_cancellation_outcomes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
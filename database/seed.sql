@@ -1,6 +1,9 @@
 -- Seed data for VietBusBooking - Vietnamese intercity bus booking system
 -- This includes real Vietnamese cities, popular bus operators, and realistic routes
 
+-- Departure times below are wall-clock times in the business timezone
+SET TIME ZONE 'Asia/Ho_Chi_Minh';
+
 -- Insert admin user and test passengers
 INSERT INTO users (email, name, phone, role, password_hash, is_active) VALUES
 ('admin@vietbusbooking.com', 'Quản trị viên hệ thống', '0901234567', 'admin', '$2a$10$xQPQkjrXkOxGMXkzWZxs6eH7vZSNJ5d7lKqF8YqnQjGxQ4yxJxQ4G', true),
@@ -34,8 +37,54 @@ INSERT INTO buses (license_plate, bus_type, manufacturer, model, year, operator_
  '{"rows": 9, "columns": 4, "total_seats": 36, "floors": 1, "type": "36_semi_sleeper"}',
  ARRAY['wifi', 'ac', 'blanket', 'water'], 'active');
 
+-- Insert the cities and towns routes run between, with their folded search names
+INSERT INTO locations (name, search_name, province) VALUES
+('Hà Nội', 'ha noi', 'Hà Nội'),
+('TP. Hồ Chí Minh', 'ho chi minh', 'TP. Hồ Chí Minh'),
+('Đà Nẵng', 'da nang', 'Đà Nẵng'),
+('Vinh', 'vinh', 'Nghệ An'),
+('Huế', 'hue', 'Thừa Thiên Huế'),
+('Nha Trang', 'nha trang', 'Khánh Hòa'),
+('Đà Lạt', 'da lat', 'Lâm Đồng'),
+('Vũng Tàu', 'vung tau', 'Bà Rịa - Vũng Tàu'),
+('Cần Thơ', 'can tho', 'Cần Thơ'),
+('Phú Quốc', 'phu quoc', 'Kiên Giang'),
+('Hội An', 'hoi an', 'Quảng Nam'),
+('Quy Nhơn', 'quy nhon', 'Bình Định'),
+('Hạ Long', 'ha long', 'Quảng Ninh'),
+('Sapa', 'sapa', 'Lào Cai'),
+('Ninh Bình', 'ninh binh', 'Ninh Bình'),
+('Hải Phòng', 'hai phong', 'Hải Phòng');
+
+-- Other names customers type for them
+INSERT INTO location_aliases (location_id, name, search_name)
+SELECT locations.id, aliases.name, aliases.search_name
+FROM (VALUES
+    ('ha noi', 'Hanoi', 'hanoi'),
+    ('ha noi', 'HN', 'hn'),
+    ('ho chi minh', 'Sài Gòn', 'sai gon'),
+    ('ho chi minh', 'Saigon', 'saigon'),
+    ('ho chi minh', 'SG', 'sg'),
+    ('ho chi minh', 'HCM', 'hcm'),
+    ('ho chi minh', 'TPHCM', 'tphcm'),
+    ('ho chi minh', 'HCMC', 'hcmc'),
+    ('ho chi minh', 'Ho Chi Minh City', 'ho chi minh city'),
+    ('da nang', 'Danang', 'danang'),
+    ('da nang', 'ĐN', 'dn'),
+    ('da lat', 'Dalat', 'dalat'),
+    ('vung tau', 'Vungtau', 'vungtau'),
+    ('quy nhon', 'Qui Nhơn', 'qui nhon'),
+    ('ha long', 'Halong', 'halong'),
+    ('sapa', 'Sa Pa', 'sa pa'),
+    ('hai phong', 'Haiphong', 'haiphong'),
+    ('hai phong', 'HP', 'hp')
+) AS aliases (location, name, search_name)
+JOIN locations ON locations.search_name = aliases.location;
+
 -- Insert major Vietnamese routes with realistic distances and prices
-INSERT INTO routes (name, from_city, to_city, distance, base_price, description, is_active) VALUES
+INSERT INTO routes (name, from_city, to_city, distance, base_price, description, is_active, from_location_id, to_location_id)
+SELECT new_routes.*, from_location.id, to_location.id
+FROM (VALUES
 -- North to South routes
 ('Hà Nội - TP. Hồ Chí Minh', 'Hà Nội', 'TP. Hồ Chí Minh', 1710, 450000, 'Tuyến xuyên Việt - Giường nằm cao cấp, ít điểm dừng', true),
 ('Hà Nội - Đà Nẵng', 'Hà Nội', 'Đà Nẵng', 764, 280000, 'Tuyến ven biển - Xe limousine đời mới', true),
@@ -64,7 +113,17 @@ INSERT INTO routes (name, from_city, to_city, distance, base_price, description,
 -- Cross-country routes  
 ('Đà Lạt - Nha Trang', 'Đà Lạt', 'Nha Trang', 140, 100000, 'Núi xuống biển - Limousine', true),
 ('Huế - Hội An', 'Huế', 'Hội An', 125, 90000, 'Di sản miền Trung - Ghế ngồi', true),
-('Nha Trang - Quy Nhơn', 'Nha Trang', 'Quy Nhơn', 238, 130000, 'Duyên hải miền Trung - Giường nằm', true);
+('Nha Trang - Quy Nhơn', 'Nha Trang', 'Quy Nhơn', 238, 130000, 'Duyên hải miền Trung - Giường nằm', true)
+) AS new_routes (name, from_city, to_city, distance, base_price, description, is_active)
+JOIN locations from_location ON from_location.name = new_routes.from_city
+JOIN locations to_location ON to_location.name = new_routes.to_city;
+
+-- Default fare categories (adults pay the full trip price)
+INSERT INTO fare_rules (category, discount_type, discount_value, min_age, max_age, requires_id, requires_seat) VALUES
+('child', 'percentage', 25, 2, 9, false, true),      -- Trẻ em dưới 10 tuổi
+('senior', 'percentage', 15, 60, NULL, true, true),  -- Người cao tuổi, cần CCCD
+('student', 'percentage', 10, NULL, NULL, true, true), -- Học sinh, sinh viên, cần thẻ
+('infant', 'percentage', 100, 0, 1, false, false);   -- Em bé ngồi cùng người lớn
 
 -- Insert realistic trip schedules (next 7 days)
 -- Hà Nội - TP. Hồ Chí Minh (Premium Limousine - Phương Trang)